@@ -11,9 +11,12 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"gitlab.com/gitlab-org/gitaly/internal/bundleuri"
 	"gitlab.com/gitlab-org/gitaly/internal/config"
 	"gitlab.com/gitlab-org/gitaly/internal/connectioncounter"
 	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/httpgateway"
+	"gitlab.com/gitlab-org/gitaly/internal/janitor"
 	"gitlab.com/gitlab-org/gitaly/internal/linguist"
 	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
 	"gitlab.com/gitlab-org/gitaly/internal/server"
@@ -105,8 +108,16 @@ func main() {
 	config.ConfigureSentry(version.GetVersion())
 	config.ConfigurePrometheus()
 	config.ConfigureConcurrencyLimits()
+	config.ConfigureWriteRateLimits()
+	config.ConfigureRPCCoalescing()
+	config.ConfigureStorageFailover()
+	config.ConfigureMemoryWatchdog()
+	config.ConfigureRepositoryBlocks()
+	config.ConfigureStrictValidation()
 
 	tempdir.StartCleaning()
+	janitor.StartRepositoryScan()
+	bundleuri.StartGenerator()
 
 	var listeners []net.Listener
 
@@ -169,6 +180,13 @@ func run(listeners []net.Listener) error {
 	server := server.New(ruby)
 	defer server.Stop()
 
+	if addr := config.Config.HTTPGateway.ListenAddr; addr != "" {
+		log.WithField("address", addr).Info("starting HTTP gateway")
+		go func() {
+			http.ListenAndServe(addr, httpgateway.NewHandler(ruby))
+		}()
+	}
+
 	serverErrors := make(chan error, len(listeners))
 	for _, listener := range listeners {
 		// Must pass the listener as a function argument because there is a race
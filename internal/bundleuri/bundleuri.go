@@ -0,0 +1,175 @@
+// Package bundleuri maintains periodic 'git bundle' snapshots of
+// configured hot repositories and advertises them through the bundle-uri
+// capability of git-upload-pack. Clients that understand the capability
+// download the bulk of an initial clone from the bundle location (e.g. a
+// CDN or object storage bucket synced from the bundle directory) and only
+// fetch the remainder from Gitaly.
+package bundleuri
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+)
+
+const defaultRefreshInterval = 24 * time.Hour
+
+var bundleGenerations = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gitaly_bundle_generations_total",
+		Help: "Counter of bundle snapshot generations, labeled by status (success/error/skipped)",
+	},
+	[]string{"status"},
+)
+
+func init() {
+	prometheus.MustRegister(bundleGenerations)
+}
+
+// StartGenerator starts a goroutine that periodically regenerates the
+// bundle snapshot of every configured repository. It is a no-op when
+// bundle-URI support is disabled in config.
+func StartGenerator() {
+	cfg := config.Config.BundleURI
+	if !cfg.Enabled {
+		return
+	}
+
+	interval := defaultRefreshInterval
+	if cfg.RefreshIntervalSeconds > 0 {
+		interval = time.Duration(cfg.RefreshIntervalSeconds) * time.Second
+	}
+
+	go func() {
+		for {
+			for _, repo := range cfg.Repositories {
+				entry := log.WithFields(log.Fields{
+					"storage":       repo.Storage,
+					"relative_path": repo.RelativePath,
+				})
+
+				generated, err := generateBundle(repo.Storage, repo.RelativePath, cfg.Dir)
+				if err != nil {
+					bundleGenerations.WithLabelValues("error").Inc()
+					entry.WithError(err).Warn("bundle snapshot generation failed")
+					continue
+				}
+
+				if !generated {
+					bundleGenerations.WithLabelValues("skipped").Inc()
+					entry.Debug("bundle snapshot up to date")
+					continue
+				}
+
+				bundleGenerations.WithLabelValues("success").Inc()
+				entry.Info("generated bundle snapshot")
+			}
+
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// UploadPackArgs returns git command line arguments that make
+// git-upload-pack advertise the bundle snapshot of the given repository,
+// if one has been generated. The returned slice is empty when bundle-URI
+// support is disabled or no snapshot exists yet.
+func UploadPackArgs(repo *pb.Repository) []string {
+	cfg := config.Config.BundleURI
+	if !cfg.Enabled {
+		return nil
+	}
+
+	name := bundleName(repo.GetStorageName(), repo.GetRelativePath())
+	if _, err := os.Stat(filepath.Join(cfg.Dir, name)); err != nil {
+		return nil
+	}
+
+	return []string{
+		"-c", "uploadpack.advertiseBundleURIs=true",
+		"-c", "bundle.version=1",
+		"-c", "bundle.mode=all",
+		"-c", "bundle.snapshot.uri=" + cfg.URIPrefix + "/" + name,
+	}
+}
+
+// generateBundle writes a bundle of all refs of the repository to
+// bundleDir, replacing any previous snapshot atomically. Generation is
+// skipped, returning false, when no ref changed since the last
+// snapshot.
+func generateBundle(storageName, relativePath, bundleDir string) (bool, error) {
+	storagePath, ok := config.StoragePath(storageName)
+	if !ok {
+		return false, fmt.Errorf("unknown storage %q", storageName)
+	}
+
+	if err := os.MkdirAll(bundleDir, 0755); err != nil {
+		return false, err
+	}
+
+	name := bundleName(storageName, relativePath)
+	bundlePath := filepath.Join(bundleDir, name)
+	tmpPath := bundlePath + ".tmp"
+
+	repoPath := filepath.Join(storagePath, relativePath)
+
+	if info, err := os.Stat(bundlePath); err == nil && !refsChangedSince(repoPath, info.ModTime()) {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	osCommand := exec.Command(command.GitPath(), "--git-dir", repoPath, "bundle", "create", tmpPath, "--all")
+
+	cmd, err := command.New(ctx, osCommand, nil, nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		os.Remove(tmpPath)
+		return false, err
+	}
+
+	return true, os.Rename(tmpPath, bundlePath)
+}
+
+// refsChangedSince reports whether any ref of the repository was
+// updated at or after t. Loose refs and packed-refs together cover all
+// ref updates.
+func refsChangedSince(repoPath string, t time.Time) bool {
+	changed := false
+
+	filepath.Walk(filepath.Join(repoPath, "refs"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.ModTime().Before(t) {
+			changed = true
+		}
+		return nil
+	})
+
+	if info, err := os.Stat(filepath.Join(repoPath, "packed-refs")); err == nil && !info.ModTime().Before(t) {
+		changed = true
+	}
+
+	return changed
+}
+
+// bundleName derives a stable file name for a repository. Hashing avoids
+// leaking repository paths in public bundle URLs and sidesteps path
+// separator issues.
+func bundleName(storageName, relativePath string) string {
+	return fmt.Sprintf("%x.bundle", sha1.Sum([]byte(storageName+"\x00"+relativePath)))
+}
@@ -0,0 +1,134 @@
+package bundleuri
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+)
+
+func TestGenerateBundleAndUploadPackArgs(t *testing.T) {
+	storagePath, err := ioutil.TempDir("", "gitaly-bundleuri-storage")
+	require.NoError(t, err)
+	defer os.RemoveAll(storagePath)
+
+	bundleDir, err := ioutil.TempDir("", "gitaly-bundleuri-bundles")
+	require.NoError(t, err)
+	defer os.RemoveAll(bundleDir)
+
+	oldStorages := config.Config.Storages
+	oldBundleURI := config.Config.BundleURI
+	config.Config.Storages = append(config.Config.Storages, config.Storage{Name: "bundle-test", Path: storagePath})
+	config.Config.BundleURI = config.BundleURI{
+		Enabled:   true,
+		Dir:       bundleDir,
+		URIPrefix: "https://cdn.example.com/bundles",
+	}
+	defer func() {
+		config.Config.Storages = oldStorages
+		config.Config.BundleURI = oldBundleURI
+	}()
+
+	relativePath := "hot/repo.git"
+	repoPath := filepath.Join(storagePath, relativePath)
+	createRepoWithCommit(t, repoPath)
+
+	repo := &pb.Repository{StorageName: "bundle-test", RelativePath: relativePath}
+
+	require.Empty(t, UploadPackArgs(repo), "no advertisement before a snapshot exists")
+
+	generated, err := generateBundle("bundle-test", relativePath, bundleDir)
+	require.NoError(t, err)
+	require.True(t, generated)
+
+	bundlePath := filepath.Join(bundleDir, bundleName("bundle-test", relativePath))
+	runGit(t, "", "bundle", "verify", bundlePath)
+
+	args := UploadPackArgs(repo)
+	require.Contains(t, args, "uploadpack.advertiseBundleURIs=true")
+	require.Contains(t, args, "bundle.snapshot.uri=https://cdn.example.com/bundles/"+bundleName("bundle-test", relativePath))
+}
+
+func TestGenerateBundleSkipsUnchangedRefs(t *testing.T) {
+	storagePath, err := ioutil.TempDir("", "gitaly-bundleuri-storage")
+	require.NoError(t, err)
+	defer os.RemoveAll(storagePath)
+
+	bundleDir, err := ioutil.TempDir("", "gitaly-bundleuri-bundles")
+	require.NoError(t, err)
+	defer os.RemoveAll(bundleDir)
+
+	oldStorages := config.Config.Storages
+	config.Config.Storages = append(config.Config.Storages, config.Storage{Name: "bundle-test", Path: storagePath})
+	defer func() { config.Config.Storages = oldStorages }()
+
+	relativePath := "hot/repo.git"
+	repoPath := filepath.Join(storagePath, relativePath)
+	createRepoWithCommit(t, repoPath)
+
+	generated, err := generateBundle("bundle-test", relativePath, bundleDir)
+	require.NoError(t, err)
+	require.True(t, generated)
+
+	// Pretend the refs predate the snapshot by a comfortable margin
+	backdateRefs(t, repoPath)
+
+	generated, err = generateBundle("bundle-test", relativePath, bundleDir)
+	require.NoError(t, err)
+	require.False(t, generated, "unchanged refs should skip regeneration")
+
+	// A ref update makes the next run regenerate the snapshot
+	commitID := runGit(t, repoPath, "rev-parse", "refs/heads/master")
+	runGit(t, repoPath, "update-ref", "refs/heads/other", commitID)
+
+	generated, err = generateBundle("bundle-test", relativePath, bundleDir)
+	require.NoError(t, err)
+	require.True(t, generated, "a ref update should trigger regeneration")
+}
+
+func backdateRefs(t *testing.T, repoPath string) {
+	past := time.Now().Add(-time.Hour)
+
+	err := filepath.Walk(filepath.Join(repoPath, "refs"), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Chtimes(path, past, past)
+	})
+	require.NoError(t, err)
+
+	if _, err := os.Stat(filepath.Join(repoPath, "packed-refs")); err == nil {
+		require.NoError(t, os.Chtimes(filepath.Join(repoPath, "packed-refs"), past, past))
+	}
+}
+
+func createRepoWithCommit(t *testing.T, repoPath string) {
+	require.NoError(t, os.MkdirAll(repoPath, 0755))
+	runGit(t, "", "init", "--bare", repoPath)
+
+	treeID := runGit(t, repoPath, "mktree") // empty stdin yields the empty tree
+	commitID := runGit(t, repoPath,
+		"-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit-tree", treeID, "-m", "initial commit")
+	runGit(t, repoPath, "update-ref", "refs/heads/master", commitID)
+}
+
+func runGit(t *testing.T, repoPath string, args ...string) string {
+	if repoPath != "" {
+		args = append([]string{"--git-dir", repoPath}, args...)
+	}
+
+	cmd := exec.Command("git", args...)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %v: %s", args, out)
+
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxCachedPayload bounds the size of a single cached advertisement so a
+// pathological repository cannot pin an excessive amount of memory.
+const maxCachedPayload = 10 * 1024 * 1024
+
+var infoRefsCacheRequests = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gitaly_inforefs_cache_requests_total",
+		Help: "Counter of InfoRefs cache lookups, labeled by result (hit/miss)",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prometheus.MustRegister(infoRefsCacheRequests)
+}
+
+// InfoRefsCache stores git ref advertisement payloads keyed by repository
+// state. A cached payload is only returned while the refs of the
+// repository are unchanged; any ref update changes the state hash and
+// implicitly invalidates the entry.
+type InfoRefsCache struct {
+	mu      sync.Mutex
+	entries map[string]infoRefsEntry
+}
+
+type infoRefsEntry struct {
+	stateHash string
+	payload   []byte
+}
+
+// NewInfoRefsCache returns an empty InfoRefsCache.
+func NewInfoRefsCache() *InfoRefsCache {
+	return &InfoRefsCache{entries: make(map[string]infoRefsEntry)}
+}
+
+// Get returns the cached payload for the repository at repoPath, if one
+// exists and the refs of the repository have not changed since it was
+// stored. The key distinguishes payload variants of the same repository,
+// e.g. upload-pack versus receive-pack advertisements.
+func (c *InfoRefsCache) Get(repoPath, key string) ([]byte, bool) {
+	hash, err := refsStateHash(repoPath)
+	if err != nil {
+		infoRefsCacheRequests.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[repoPath+"\x00"+key]
+	c.mu.Unlock()
+
+	if !ok || entry.stateHash != hash {
+		infoRefsCacheRequests.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	infoRefsCacheRequests.WithLabelValues("hit").Inc()
+	return entry.payload, true
+}
+
+// Put stores payload for the repository at repoPath under the given key.
+// Oversized payloads are silently not cached.
+func (c *InfoRefsCache) Put(repoPath, key string, payload []byte) {
+	if len(payload) > maxCachedPayload {
+		return
+	}
+
+	hash, err := refsStateHash(repoPath)
+	if err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	c.entries[repoPath+"\x00"+key] = infoRefsEntry{stateHash: hash, payload: payload}
+	c.mu.Unlock()
+}
+
+// Invalidate drops all cached payloads for the repository at repoPath.
+// Write RPCs call this so a subsequent lookup never has to rely on the
+// state hash alone.
+func (c *InfoRefsCache) Invalidate(repoPath string) {
+	prefix := repoPath + "\x00"
+
+	c.mu.Lock()
+	for key := range c.entries {
+		if len(key) >= len(prefix) && key[:len(prefix)] == prefix {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// refsStateHash derives a hash from the parts of a repository that
+// determine its ref advertisement: HEAD, packed-refs and the loose refs
+// directory. File modification times and sizes stand in for content.
+func refsStateHash(repoPath string) (string, error) {
+	hasher := sha1.New()
+
+	for _, file := range []string{"HEAD", "packed-refs"} {
+		if err := hashFileInfo(hasher, filepath.Join(repoPath, file)); err != nil {
+			return "", err
+		}
+	}
+
+	err := filepath.Walk(filepath.Join(repoPath, "refs"), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(hasher, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hasher.Sum(nil)), nil
+}
+
+func hashFileInfo(w io.Writer, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Not all repositories have a packed-refs file; its absence is
+			// part of the state.
+			fmt.Fprintf(w, "%s absent\n", path)
+			return nil
+		}
+		return err
+	}
+
+	fmt.Fprintf(w, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	return nil
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInfoRefsCache(t *testing.T) {
+	repoPath := createTestRepoDir(t)
+	defer os.RemoveAll(repoPath)
+
+	c := NewInfoRefsCache()
+	payload := []byte("advertisement")
+
+	_, ok := c.Get(repoPath, "upload-pack")
+	require.False(t, ok, "empty cache must miss")
+
+	c.Put(repoPath, "upload-pack", payload)
+
+	cached, ok := c.Get(repoPath, "upload-pack")
+	require.True(t, ok)
+	require.Equal(t, payload, cached)
+
+	_, ok = c.Get(repoPath, "receive-pack")
+	require.False(t, ok, "different key must miss")
+}
+
+func TestInfoRefsCacheStateChange(t *testing.T) {
+	repoPath := createTestRepoDir(t)
+	defer os.RemoveAll(repoPath)
+
+	c := NewInfoRefsCache()
+	c.Put(repoPath, "upload-pack", []byte("advertisement"))
+
+	// Updating a ref must invalidate the entry via the state hash.
+	refFile := filepath.Join(repoPath, "refs", "heads", "master")
+	require.NoError(t, ioutil.WriteFile(refFile, []byte("0000000000000000000000000000000000000000\n"), 0644))
+	futureTime := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(refFile, futureTime, futureTime))
+
+	_, ok := c.Get(repoPath, "upload-pack")
+	require.False(t, ok, "ref update must invalidate the cache")
+}
+
+func TestInfoRefsCacheInvalidate(t *testing.T) {
+	repoPath := createTestRepoDir(t)
+	defer os.RemoveAll(repoPath)
+
+	c := NewInfoRefsCache()
+	c.Put(repoPath, "upload-pack", []byte("advertisement"))
+	c.Put(repoPath, "receive-pack", []byte("advertisement"))
+
+	c.Invalidate(repoPath)
+
+	_, ok := c.Get(repoPath, "upload-pack")
+	require.False(t, ok)
+	_, ok = c.Get(repoPath, "receive-pack")
+	require.False(t, ok)
+}
+
+func createTestRepoDir(t *testing.T) string {
+	repoPath, err := ioutil.TempDir("", "gitaly-inforefs-cache-test")
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "refs", "heads"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repoPath, "HEAD"), []byte("ref: refs/heads/master\n"), 0644))
+
+	return repoPath
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// maxTreeCacheBytes bounds the approximate memory the tree cache may
+// hold. Trees are immutable so entries never go stale; they are only
+// evicted to stay within this budget.
+const maxTreeCacheBytes = 32 * 1024 * 1024
+
+// treeEntryOverhead is the approximate fixed cost per entry, on top of
+// the byte slices, used for budget accounting.
+const treeEntryOverhead = 64
+
+var treeCacheRequests = prom.NewCounterVec(
+	prom.CounterOpts{
+		Namespace: "gitaly",
+		Subsystem: "treeentries_cache",
+		Name:      "requests_total",
+		Help:      "Counter of tree listing cache requests, partitioned by hit/miss",
+	},
+	[]string{"result"},
+)
+
+func init() {
+	prom.MustRegister(treeCacheRequests)
+}
+
+// TreeEntryData is one raw tree entry as stored in the tree object:
+// just enough to rebuild a response entry for any requesting revision
+// and root path.
+type TreeEntryData struct {
+	Mode     []byte
+	Oid      []byte
+	Filename []byte
+}
+
+type treeCacheItem struct {
+	oid     string
+	entries []TreeEntryData
+	size    int
+}
+
+// TreeCache is an LRU cache of tree listings keyed by tree OID. Tree
+// objects are content-addressed, so identical directories across
+// branches or repositories share one cache slot.
+type TreeCache struct {
+	mu    sync.Mutex
+	bytes int
+	order *list.List // front is most recently used
+	index map[string]*list.Element
+}
+
+// NewTreeCache returns an empty tree cache.
+func NewTreeCache() *TreeCache {
+	return &TreeCache{
+		order: list.New(),
+		index: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached listing for a tree OID, marking it as recently
+// used.
+func (c *TreeCache) Get(oid string) ([]TreeEntryData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.index[oid]
+	if !ok {
+		treeCacheRequests.WithLabelValues("miss").Inc()
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	treeCacheRequests.WithLabelValues("hit").Inc()
+	return elem.Value.(*treeCacheItem).entries, true
+}
+
+// Put stores a tree listing, evicting the least recently used trees if
+// the byte budget is exceeded.
+func (c *TreeCache) Put(oid string, entries []TreeEntryData) {
+	size := 0
+	for _, entry := range entries {
+		size += len(entry.Mode) + len(entry.Oid) + len(entry.Filename) + treeEntryOverhead
+	}
+
+	if size > maxTreeCacheBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.index[oid]; ok {
+		return
+	}
+
+	c.index[oid] = c.order.PushFront(&treeCacheItem{oid: oid, entries: entries, size: size})
+	c.bytes += size
+
+	for c.bytes > maxTreeCacheBytes {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+
+		item := c.order.Remove(oldest).(*treeCacheItem)
+		delete(c.index, item.oid)
+		c.bytes -= item.size
+	}
+}
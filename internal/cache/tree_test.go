@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testTreeEntries(n int) []TreeEntryData {
+	var entries []TreeEntryData
+	for i := 0; i < n; i++ {
+		entries = append(entries, TreeEntryData{
+			Mode:     []byte("100644"),
+			Oid:      bytes.Repeat([]byte{byte(i)}, 20),
+			Filename: []byte(fmt.Sprintf("file-%d.txt", i)),
+		})
+	}
+	return entries
+}
+
+func TestTreeCacheHitAndMiss(t *testing.T) {
+	c := NewTreeCache()
+
+	_, ok := c.Get("aaaa")
+	require.False(t, ok)
+
+	entries := testTreeEntries(3)
+	c.Put("aaaa", entries)
+
+	cached, ok := c.Get("aaaa")
+	require.True(t, ok)
+	require.Equal(t, entries, cached)
+}
+
+func TestTreeCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewTreeCache()
+
+	// Each tree is large enough that only two fit in the budget.
+	entrySize := maxTreeCacheBytes / 2
+	bigEntries := []TreeEntryData{
+		{Mode: []byte("100644"), Oid: make([]byte, 20), Filename: make([]byte, entrySize-treeEntryOverhead-26)},
+	}
+
+	c.Put("first", bigEntries)
+	c.Put("second", bigEntries)
+
+	// Touch "first" so "second" becomes the eviction candidate.
+	_, ok := c.Get("first")
+	require.True(t, ok)
+
+	c.Put("third", bigEntries)
+
+	_, ok = c.Get("first")
+	require.True(t, ok, "recently used tree should survive eviction")
+
+	_, ok = c.Get("second")
+	require.False(t, ok, "least recently used tree should be evicted")
+
+	_, ok = c.Get("third")
+	require.True(t, ok)
+}
+
+func TestTreeCacheRejectsOversizedTree(t *testing.T) {
+	c := NewTreeCache()
+
+	huge := []TreeEntryData{
+		{Mode: []byte("100644"), Oid: make([]byte, 20), Filename: make([]byte, maxTreeCacheBytes)},
+	}
+
+	c.Put("huge", huge)
+
+	_, ok := c.Get("huge")
+	require.False(t, ok)
+}
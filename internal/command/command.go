@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"os"
 	"os/exec"
 	"sync"
 	"syscall"
@@ -14,23 +13,31 @@ import (
 	"gitlab.com/gitlab-org/gitaly/internal/config"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
+	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 )
 
-// exportedEnvVars contains a list of environment variables
-// that are always exported to child processes on spawn
-var exportedEnvVars = []string{
-	"HOME",
-	"PATH",
-	"LD_LIBRARY_PATH",
-	"TZ",
-
-	// Export git tracing variables for easier debugging
-	"GIT_TRACE",
-	"GIT_TRACE_PACK_ACCESS",
-	"GIT_TRACE_PACKET",
-	"GIT_TRACE_PERFORMANCE",
-	"GIT_TRACE_SETUP",
+var spawnedCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gitaly_commands_spawned_total",
+		Help: "Counter of child processes spawned, labeled by the storage of the request that spawned them",
+	},
+	[]string{"storage"},
+)
+
+func init() {
+	prometheus.MustRegister(spawnedCounter)
+}
+
+// storageFromContext returns the storage name of the repository the
+// current RPC operates on, as tagged by the ctxtags interceptor, or
+// "unknown" for commands spawned outside a repository-scoped RPC.
+func storageFromContext(ctx context.Context) string {
+	if value, ok := grpc_ctxtags.Extract(ctx).Values()["grpc.request.repoStorage"].(string); ok && value != "" {
+		return value
+	}
+	return "unknown"
 }
 
 // Command encapsulates a running exec.Cmd. The embedded exec.Cmd is
@@ -111,7 +118,7 @@ func New(ctx context.Context, cmd *exec.Cmd, stdin io.Reader, stdout, stderr io.
 		grpc_logrus.Extract(ctx).WithFields(log.Fields{
 			"pid":  logPid,
 			"path": cmd.Path,
-			"args": cmd.Args,
+			"args": SanitizeArgs(cmd.Args),
 		}).Info("spawn")
 	}()
 
@@ -121,11 +128,9 @@ func New(ctx context.Context, cmd *exec.Cmd, stdin io.Reader, stdout, stderr io.
 		context:   ctx,
 	}
 
-	// Explicitly set the environment for the command
-	env = append(env, "GIT_TERMINAL_PROMPT=0")
-
-	// Export env vars
-	cmd.Env = exportEnvironment(env)
+	// Explicitly set the environment for the command: the allowlisted
+	// base environment first, then the caller's injections so they win
+	cmd.Env = append(AllowedEnvironment(), env...)
 
 	// Start the command in its own process group (nice for signalling)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
@@ -155,9 +160,11 @@ func New(ctx context.Context, cmd *exec.Cmd, stdin io.Reader, stdout, stderr io.
 	}
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("GitCommand: start %v: %v", cmd.Args, err)
+		return nil, fmt.Errorf("GitCommand: start %v: %v", SanitizeArgs(cmd.Args), SanitizeError(err))
 	}
 
+	spawnedCounter.WithLabelValues(storageFromContext(ctx)).Inc()
+
 	// The goroutine below is responsible for terminating and reaping the
 	// process when ctx is canceled.
 	wg.Add(1)
@@ -177,16 +184,6 @@ func New(ctx context.Context, cmd *exec.Cmd, stdin io.Reader, stdout, stderr io.
 	return command, nil
 }
 
-func exportEnvironment(env []string) []string {
-	for _, envVarName := range exportedEnvVars {
-		if val, ok := os.LookupEnv(envVarName); ok {
-			env = append(env, fmt.Sprintf("%s=%s", envVarName, val))
-		}
-	}
-
-	return env
-}
-
 // This function should never be called directly, use Wait().
 func (c *Command) wait() {
 	if c.reader != nil {
@@ -233,9 +230,13 @@ func (c *Command) logProcessComplete(ctx context.Context, exitCode int) {
 	userTime := cmd.ProcessState.UserTime()
 	realTime := time.Since(c.startTime)
 
+	if stats := StatsFromContext(ctx); stats != nil {
+		stats.recordSpawn(systemTime + userTime)
+	}
+
 	entry := grpc_logrus.Extract(ctx).WithFields(log.Fields{
 		"path":                   cmd.Path,
-		"args":                   cmd.Args,
+		"args":                   SanitizeArgs(cmd.Args),
 		"command.exitCode":       exitCode,
 		"command.system_time_ms": systemTime.Seconds() * 1000,
 		"command.user_time_ms":   userTime.Seconds() * 1000,
@@ -250,5 +251,19 @@ func (c *Command) logProcessComplete(ctx context.Context, exitCode int) {
 		})
 	}
 
-	entry.Info("spawn complete")
+	if isSlowCommand(realTime) {
+		entry.Warn("spawn complete: slow command")
+	} else {
+		entry.Info("spawn complete")
+	}
+}
+
+// isSlowCommand returns whether the wall time of a finished command
+// crossed the configured slow-command threshold. The resulting warning
+// carries the same structured fields as the regular completion log:
+// sanitized args, repository tags, timings and rusage.
+func isSlowCommand(realTime time.Duration) bool {
+	threshold := time.Duration(config.Config.Logging.SlowCommandThresholdSeconds) * time.Second
+
+	return threshold > 0 && realTime >= threshold
 }
@@ -9,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -105,3 +107,15 @@ wait:
 	_, ok := err.(spawnTimeoutError)
 	require.True(t, ok, "type of error should be spawnTimeoutError")
 }
+
+func TestIsSlowCommand(t *testing.T) {
+	oldThreshold := config.Config.Logging.SlowCommandThresholdSeconds
+	defer func() { config.Config.Logging.SlowCommandThresholdSeconds = oldThreshold }()
+
+	config.Config.Logging.SlowCommandThresholdSeconds = 0
+	require.False(t, isSlowCommand(time.Hour), "zero threshold disables the slow-command log")
+
+	config.Config.Logging.SlowCommandThresholdSeconds = 10
+	require.False(t, isSlowCommand(9*time.Second))
+	require.True(t, isSlowCommand(10*time.Second))
+}
@@ -0,0 +1,68 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// exportedEnvVars contains the only environment variables of the Gitaly
+// process that are passed on to child processes. The server environment
+// can hold secrets — tokens, proxy credentials — which must not leak
+// into git subprocesses or hooks; anything beyond this allowlist has to
+// be injected explicitly through the env arguments of New.
+var exportedEnvVars = []string{
+	"PATH",
+	"LANG",
+	"LD_LIBRARY_PATH",
+	"TZ",
+
+	// Export git tracing variables for easier debugging
+	"GIT_TRACE",
+	"GIT_TRACE_PACK_ACCESS",
+	"GIT_TRACE_PACKET",
+	"GIT_TRACE_PERFORMANCE",
+	"GIT_TRACE_SETUP",
+}
+
+var (
+	scratchHomeOnce sync.Once
+	scratchHome     string
+)
+
+// scratchHomeDir returns a throwaway HOME for child processes, so git
+// never reads the server account's ~/.gitconfig or ~/.netrc.
+func scratchHomeDir() string {
+	scratchHomeOnce.Do(func() {
+		dir, err := ioutil.TempDir("", "gitaly-scratch-home-")
+		if err != nil {
+			log.WithError(err).Warn("create scratch HOME for child processes")
+			dir = os.TempDir()
+		}
+		scratchHome = dir
+	})
+
+	return scratchHome
+}
+
+// AllowedEnvironment builds the base environment for a child process:
+// the allowlisted variables inherited from the Gitaly process, HOME
+// pointing at a scratch directory, and prompting disabled. Variables the
+// caller injects come after it and take precedence.
+func AllowedEnvironment() []string {
+	env := []string{
+		"HOME=" + scratchHomeDir(),
+		"GIT_TERMINAL_PROMPT=0",
+	}
+
+	for _, envVarName := range exportedEnvVars {
+		if val, ok := os.LookupEnv(envVarName); ok {
+			env = append(env, fmt.Sprintf("%s=%s", envVarName, val))
+		}
+	}
+
+	return env
+}
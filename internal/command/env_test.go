@@ -0,0 +1,60 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCommandDoesNotLeakEnvironment(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	defer os.Unsetenv("GITALY_TEST_SECRET_TOKEN")
+	os.Setenv("GITALY_TEST_SECRET_TOKEN", "hunter2")
+
+	buff := &bytes.Buffer{}
+	cmd, err := New(ctx, exec.Command("env"), nil, buff, nil)
+
+	require.NoError(t, err)
+	require.NoError(t, cmd.Wait())
+
+	require.NotContains(t, buff.String(), "GITALY_TEST_SECRET_TOKEN")
+}
+
+func TestNewCommandScratchHome(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	buff := &bytes.Buffer{}
+	cmd, err := New(ctx, exec.Command("env"), nil, buff, nil)
+
+	require.NoError(t, err)
+	require.NoError(t, cmd.Wait())
+
+	env := strings.Split(buff.String(), "\n")
+	require.Contains(t, env, "HOME="+scratchHomeDir())
+	if home := os.Getenv("HOME"); home != "" && home != scratchHomeDir() {
+		require.NotContains(t, env, "HOME="+home)
+	}
+}
+
+func TestNewCommandInjectedEnvWins(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	buff := &bytes.Buffer{}
+	cmd, err := New(ctx, exec.Command("env"), nil, buff, nil, "HOME=/custom/home")
+
+	require.NoError(t, err)
+	require.NoError(t, cmd.Wait())
+
+	env := strings.Split(buff.String(), "\n")
+	require.Contains(t, env, "HOME=/custom/home")
+	require.NotContains(t, env, "HOME="+scratchHomeDir())
+}
@@ -0,0 +1,49 @@
+package command
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Stats accumulates resource usage of all commands spawned during a single
+// gRPC request.
+type Stats struct {
+	sync.Mutex
+	spawns  int
+	cpuTime time.Duration
+}
+
+type statsKey struct{}
+
+// InitContextStats returns a copy of ctx with an empty Stats attached.
+// Commands spawned with the returned context record their resource usage
+// in the returned Stats.
+func InitContextStats(ctx context.Context) (context.Context, *Stats) {
+	stats := &Stats{}
+	return context.WithValue(ctx, statsKey{}, stats), stats
+}
+
+// StatsFromContext returns the Stats attached to ctx, or nil if there is
+// none.
+func StatsFromContext(ctx context.Context) *Stats {
+	stats, _ := ctx.Value(statsKey{}).(*Stats)
+	return stats
+}
+
+// Totals returns the number of commands spawned and the CPU time (system
+// plus user) they consumed.
+func (stats *Stats) Totals() (spawns int, cpuTime time.Duration) {
+	stats.Lock()
+	defer stats.Unlock()
+
+	return stats.spawns, stats.cpuTime
+}
+
+func (stats *Stats) recordSpawn(cpuTime time.Duration) {
+	stats.Lock()
+	defer stats.Unlock()
+
+	stats.spawns++
+	stats.cpuTime += cpuTime
+}
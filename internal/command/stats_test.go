@@ -0,0 +1,30 @@
+package command
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsRecordSpawn(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ctx, stats := InitContextStats(ctx)
+	require.Equal(t, stats, StatsFromContext(ctx))
+
+	for i := 0; i < 2; i++ {
+		cmd, err := New(ctx, exec.Command("true"), nil, nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, cmd.Wait())
+	}
+
+	spawns, _ := stats.Totals()
+	require.Equal(t, 2, spawns)
+}
+
+func TestStatsFromContextMissing(t *testing.T) {
+	require.Nil(t, StatsFromContext(context.Background()))
+}
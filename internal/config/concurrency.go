@@ -1,7 +1,14 @@
 package config
 
 import (
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/coalesce"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/glcheck"
 	"gitlab.com/gitlab-org/gitaly/internal/middleware/limithandler"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/repoblock"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/storagefailover"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/writelimit"
 )
 
 // ConfigureConcurrencyLimits configures the per-repo, per RPC rate limits
@@ -13,4 +20,86 @@ func ConfigureConcurrencyLimits() {
 	}
 
 	limithandler.SetMaxRepoConcurrency(maxConcurrencyPerRepoPerRPC)
+
+	maxConcurrencyPerStorage := make(map[string]int)
+
+	for _, v := range Config.StorageConcurrency {
+		maxConcurrencyPerStorage[v.Name] = v.MaxPerStorage
+	}
+
+	limithandler.SetMaxStorageConcurrency(maxConcurrencyPerStorage)
+}
+
+// ConfigureWriteRateLimits installs the per-repository token-bucket
+// limits on mutator RPCs
+func ConfigureWriteRateLimits() {
+	cfg := Config.WriteRateLimit
+
+	writelimit.SetGlobalLimit(writelimit.Limit{
+		RatePerSecond: cfg.RatePerSecond,
+		Burst:         cfg.Burst,
+	})
+
+	for _, v := range cfg.Repositories {
+		writelimit.SetRepoLimit(v.Storage, v.RelativePath, writelimit.Limit{
+			RatePerSecond: v.RatePerSecond,
+			Burst:         v.Burst,
+		})
+	}
+}
+
+// ConfigureStrictValidation enables strict validation of the GitLab
+// identity fields on mutator requests when configured
+func ConfigureStrictValidation() {
+	glcheck.SetStrict(Config.StrictValidation.GlFields)
+}
+
+// ConfigureRPCCoalescing configures which read-only RPCs have identical
+// concurrent invocations coalesced into one
+func ConfigureRPCCoalescing() {
+	rpcs := make([]string, 0, len(Config.Coalesce))
+
+	for _, v := range Config.Coalesce {
+		rpcs = append(rpcs, v.RPC)
+	}
+
+	coalesce.SetCoalescedRPCs(rpcs)
+}
+
+// ConfigureStorageFailover configures which read-only RPCs may be
+// retried against candidate storages
+func ConfigureStorageFailover() {
+	rpcs := make([]string, 0, len(Config.StorageFailover))
+
+	for _, v := range Config.StorageFailover {
+		rpcs = append(rpcs, v.RPC)
+	}
+
+	storagefailover.SetFailoverRPCs(rpcs)
+}
+
+// ConfigureRepositoryBlocks seeds the repository circuit breaker with
+// the blocks from config
+func ConfigureRepositoryBlocks() {
+	for _, v := range Config.BlockedRepositories {
+		repoblock.SetBlock(v.Storage, v.RelativePath, repoblock.Block{
+			Message:      v.Message,
+			MutatorsOnly: v.MutatorsOnly,
+		})
+	}
+}
+
+// ConfigureMemoryWatchdog starts the memory pressure watchdog when it is
+// enabled in config
+func ConfigureMemoryWatchdog() {
+	cfg := Config.MemoryWatchdog
+	if !cfg.Enabled {
+		return
+	}
+
+	limithandler.StartMemoryWatchdog(limithandler.MemoryWatchdogConfig{
+		CriticalUtilization: cfg.CriticalUtilization,
+		Interval:            time.Duration(cfg.IntervalSeconds) * time.Second,
+		HeavyRPCs:           cfg.HeavyRPCs,
+	})
 }
@@ -19,18 +19,98 @@ var (
 )
 
 type config struct {
-	SocketPath           string        `toml:"socket_path" split_words:"true"`
-	ListenAddr           string        `toml:"listen_addr" split_words:"true"`
-	PrometheusListenAddr string        `toml:"prometheus_listen_addr" split_words:"true"`
-	BinDir               string        `toml:"bin_dir"`
-	Git                  Git           `toml:"git" envconfig:"git"`
-	Storages             []Storage     `toml:"storage" envconfig:"storage"`
-	Logging              Logging       `toml:"logging" envconfig:"logging"`
-	Prometheus           Prometheus    `toml:"prometheus"`
-	Auth                 Auth          `toml:"auth"`
-	Ruby                 Ruby          `toml:"gitaly-ruby"`
-	GitlabShell          GitlabShell   `toml:"gitlab-shell"`
-	Concurrency          []Concurrency `toml:"concurrency"`
+	SocketPath           string               `toml:"socket_path" split_words:"true"`
+	ListenAddr           string               `toml:"listen_addr" split_words:"true"`
+	PrometheusListenAddr string               `toml:"prometheus_listen_addr" split_words:"true"`
+	BinDir               string               `toml:"bin_dir"`
+	Git                  Git                  `toml:"git" envconfig:"git"`
+	Storages             []Storage            `toml:"storage" envconfig:"storage"`
+	Logging              Logging              `toml:"logging" envconfig:"logging"`
+	Prometheus           Prometheus           `toml:"prometheus"`
+	Auth                 Auth                 `toml:"auth"`
+	Ruby                 Ruby                 `toml:"gitaly-ruby"`
+	GitlabShell          GitlabShell          `toml:"gitlab-shell"`
+	Hooks                Hooks                `toml:"hooks"`
+	Concurrency          []Concurrency        `toml:"concurrency"`
+	Coalesce             []Coalesce           `toml:"coalesce"`
+	StorageFailover      []StorageFailover    `toml:"storage_failover"`
+	StorageConcurrency   []StorageConcurrency `toml:"storage_concurrency"`
+	SSH                  SSH                  `toml:"ssh"`
+	TransferTimeouts     TransferTimeouts     `toml:"transfer_timeouts"`
+	MemoryWatchdog       MemoryWatchdog       `toml:"memory_watchdog"`
+	Housekeeping         Housekeeping         `toml:"housekeeping"`
+	BundleURI            BundleURI            `toml:"bundle_uri"`
+	RepositoryDefaults   RepositoryDefaults   `toml:"repository_defaults"`
+	BlockedRepositories  []BlockedRepository  `toml:"blocked_repository"`
+	Lfs                  Lfs                  `toml:"lfs"`
+	UploadPackStats      UploadPackStats      `toml:"upload_pack_stats"`
+	Archive              Archive              `toml:"archive"`
+	WriteRateLimit       WriteRateLimit       `toml:"write_rate_limit"`
+	StrictValidation     StrictValidation     `toml:"strict_validation"`
+	HTTPGateway          HTTPGateway          `toml:"http_gateway"`
+}
+
+// HTTPGateway serves a read-only subset of the API over HTTP/JSON. The
+// gateway performs no authentication, so the address should be on a
+// trusted interface, like the prometheus listener.
+type HTTPGateway struct {
+	// ListenAddr enables the gateway when set, e.g. "localhost:9237"
+	ListenAddr string `toml:"listen_addr"`
+}
+
+// StrictValidation rejects requests missing fields that are formally
+// optional in the protocol.
+type StrictValidation struct {
+	// GlFields requires mutator requests to carry the gl_repository and
+	// gl_project_path of their target repository, which hooks and audit
+	// callbacks rely on
+	GlFields bool `toml:"gl_fields"`
+}
+
+// WriteRateLimit is a per-repository token-bucket limit on mutator
+// RPCs. Reads are never limited.
+type WriteRateLimit struct {
+	// RatePerSecond refills each repository's bucket; 0 disables limiting
+	RatePerSecond float64 `toml:"rate_per_second"`
+	// Burst is the bucket capacity; defaults to the rate, rounded up
+	Burst int `toml:"burst"`
+	// Repositories override the global limit for individual repositories
+	Repositories []WriteRateLimitOverride `toml:"repository"`
+}
+
+// WriteRateLimitOverride sets a different limit for one repository. A
+// rate of 0 exempts it from write limiting.
+type WriteRateLimitOverride struct {
+	Storage       string  `toml:"storage"`
+	RelativePath  string  `toml:"relative_path"`
+	RatePerSecond float64 `toml:"rate_per_second"`
+	Burst         int     `toml:"burst"`
+}
+
+// Archive configures the external compression commands used by
+// GetArchive.
+type Archive struct {
+	// GzipCommand replaces the default "gzip -c -n" for tar.gz archives,
+	// e.g. to use pigz on multi-core machines. Split on whitespace.
+	GzipCommand string `toml:"gzip_command"`
+	// Bzip2Command replaces the default "bzip2 -c" for tar.bz2 archives.
+	Bzip2Command string `toml:"bzip2_command"`
+}
+
+// Lfs configures resolution of LFS pointers through the
+// gitaly-lfs-smudge helper.
+type Lfs struct {
+	// SmudgeBinPath is the gitaly-lfs-smudge executable. When empty,
+	// RPCs asking for LFS smudging fail instead of serving pointer files.
+	SmudgeBinPath string `toml:"smudge_bin_path"`
+}
+
+// UploadPackStats configures the summary emitted after each completed
+// clone or fetch.
+type UploadPackStats struct {
+	// CallbackBinPath is an optional executable that receives the
+	// summary of each completed upload-pack as JSON on stdin.
+	CallbackBinPath string `toml:"callback_bin_path"`
 }
 
 // GitlabShell contains the settings required for executing `gitlab-shell`
@@ -38,9 +118,38 @@ type GitlabShell struct {
 	Dir string `toml:"dir"`
 }
 
+// Hooks contains the settings for custom git hook execution
+type Hooks struct {
+	// CustomHooksDir holds global custom hooks in `<name>.d` directories,
+	// run for every repository after its own custom hooks
+	CustomHooksDir string `toml:"custom_hooks_dir"`
+	// TimeoutSeconds limits each custom hook invocation; zero disables
+	// the timeout
+	TimeoutSeconds int `toml:"timeout_seconds"`
+	// Per-hook-type overrides for TimeoutSeconds; zero falls back to
+	// TimeoutSeconds
+	PreReceiveTimeoutSeconds  int `toml:"pre_receive_timeout_seconds"`
+	UpdateTimeoutSeconds      int `toml:"update_timeout_seconds"`
+	PostReceiveTimeoutSeconds int `toml:"post_receive_timeout_seconds"`
+}
+
+// Housekeeping configures automatic background repository optimization
+// after write RPCs
+type Housekeeping struct {
+	// Auto enables background optimization; off by default
+	Auto bool `toml:"auto"`
+	// MaxConcurrencyPerStorage bounds concurrent optimization tasks per
+	// storage shard; zero means one
+	MaxConcurrencyPerStorage int `toml:"max_concurrency_per_storage"`
+}
+
 // Git contains the settings for the Git executable
 type Git struct {
 	BinPath string `toml:"bin_path"`
+	// UploadPackAllowFilter advertises partial clone support
+	// (uploadpack.allowFilter, uploadpack.allowAnySHA1InWant) on
+	// upload-pack RPCs
+	UploadPackAllowFilter bool `toml:"upload_pack_allow_filter"`
 }
 
 // Storage contains a single storage-shard
@@ -53,6 +162,9 @@ type Storage struct {
 type Logging struct {
 	Format    string
 	SentryDSN string `toml:"sentry_dsn"`
+	// Spawned commands whose wall time exceeds this many seconds are
+	// logged at warning level. Zero disables the slow-command log.
+	SlowCommandThresholdSeconds int `toml:"slow_command_threshold_seconds"`
 }
 
 // Prometheus contains additional configuration data for prometheus
@@ -60,14 +172,125 @@ type Prometheus struct {
 	GRPCLatencyBuckets []float64 `toml:"grpc_latency_buckets"`
 }
 
+// SSH contains timeouts for SSH transfer sessions. Zero values leave the
+// corresponding timeout disabled.
+type SSH struct {
+	// InactivityTimeoutSeconds terminates a session when no data has been
+	// transferred in either direction for this long.
+	InactivityTimeoutSeconds int `toml:"inactivity_timeout_seconds"`
+	// MaxSessionSeconds terminates a session that has been running longer
+	// than this, regardless of activity.
+	MaxSessionSeconds int `toml:"max_session_seconds"`
+}
+
+// TransferTimeouts holds per-class timeouts for git data transfers. They
+// apply to both the SSH and Smart HTTP services; for SSH, zero values
+// fall back to the legacy [ssh] settings.
+type TransferTimeouts struct {
+	UploadPack  TransferTimeout `toml:"upload_pack"`
+	ReceivePack TransferTimeout `toml:"receive_pack"`
+}
+
+// TransferTimeout contains timeouts for one transfer class. Zero values
+// leave the corresponding timeout disabled.
+type TransferTimeout struct {
+	// InactivityTimeoutSeconds terminates a transfer when no data has been
+	// transferred in either direction for this long.
+	InactivityTimeoutSeconds int `toml:"inactivity_timeout_seconds"`
+	// MaxSessionSeconds terminates a transfer that has been running longer
+	// than this, regardless of activity.
+	MaxSessionSeconds int `toml:"max_session_seconds"`
+}
+
 // Concurrency allows endpoints to be limited to a maximum concurrency per repo
 type Concurrency struct {
 	RPC        string `toml:"rpc"`
 	MaxPerRepo int    `toml:"max_per_repo"`
 }
 
+// Coalesce marks a read-only RPC whose identical concurrent invocations
+// are collapsed into a single git execution
+type Coalesce struct {
+	RPC string `toml:"rpc"`
+}
+
+// StorageFailover marks a read-only RPC that may be retried against the
+// candidate storages a request carries in its metadata
+type StorageFailover struct {
+	RPC string `toml:"rpc"`
+}
+
+// StorageConcurrency caps the total number of limited RPCs in flight
+// against a storage, isolating fast storages from slow ones
+type StorageConcurrency struct {
+	Name          string `toml:"name"`
+	MaxPerStorage int    `toml:"max_per_storage"`
+}
+
+// MemoryWatchdog rejects heavy RPCs while the process is close to its
+// memory limit, as a last resort before the kernel OOM-kills Gitaly.
+type MemoryWatchdog struct {
+	Enabled bool `toml:"enabled"`
+	// Fraction (0..1] of the memory limit above which heavy RPCs are
+	// rejected; defaults to 0.9
+	CriticalUtilization float64 `toml:"critical_utilization"`
+	// Sampling interval; defaults to 15 seconds
+	IntervalSeconds int `toml:"interval_seconds"`
+	// Full gRPC method names to reject under pressure,
+	// e.g. "/gitaly.SSHService/SSHUploadPack"
+	HeavyRPCs []string `toml:"heavy_rpcs"`
+}
+
+// BundleURI configures bundle snapshots that offload initial clones of
+// hot repositories to a CDN or object storage.
+type BundleURI struct {
+	Enabled bool `toml:"enabled"`
+	// Dir is where bundle snapshots are written. Its contents must be
+	// served publicly under URIPrefix, e.g. by syncing it to a bucket.
+	Dir string `toml:"dir"`
+	// URIPrefix is the public base URL advertised to clients
+	URIPrefix string `toml:"uri_prefix"`
+	// Seconds between snapshot regenerations; defaults to 24 hours
+	RefreshIntervalSeconds int `toml:"refresh_interval_seconds"`
+	// Repositories lists the hot repositories to snapshot
+	Repositories []BundleURIRepository `toml:"repository"`
+}
+
+// BundleURIRepository identifies one repository to snapshot.
+type BundleURIRepository struct {
+	Storage      string `toml:"storage"`
+	RelativePath string `toml:"relative_path"`
+}
+
+// RepositoryDefaults is applied to every repository Gitaly creates, so
+// instance-wide settings don't have to be patched in afterwards.
+type RepositoryDefaults struct {
+	// GitConfig entries are set in the repository config after creation
+	GitConfig []GitConfigEntry `toml:"git_config"`
+	// Description replaces the stock contents of the description file
+	Description string `toml:"description"`
+}
+
+// GitConfigEntry is a single git config key/value pair.
+type GitConfigEntry struct {
+	Key   string `toml:"key"`
+	Value string `toml:"value"`
+}
+
+// BlockedRepository blocks a repository at startup, so an emergency
+// block survives a server restart.
+type BlockedRepository struct {
+	Storage      string `toml:"storage"`
+	RelativePath string `toml:"relative_path"`
+	// Message is returned to clients hitting the block
+	Message string `toml:"message"`
+	// MutatorsOnly leaves read RPCs unaffected
+	MutatorsOnly bool `toml:"mutators_only"`
+}
+
 // Load initializes the Config variable from file and the environment.
-//  Environment variables take precedence over the file.
+//
+//	Environment variables take precedence over the file.
 func Load(file io.Reader) error {
 	Config = config{}
 
@@ -25,6 +25,8 @@ type Diff struct {
 	Binary   bool
 	Status   byte
 	Patch    []byte
+	// TooLarge means the patch exceeded the per-file size limit and was dropped.
+	TooLarge bool
 	// OverflowMarker is used to inform caller (GitLab) that there are more diffs to display but a limit was reached instead.
 	OverflowMarker bool
 	// Collapsed means a soft limit was reached and the patch was pruned.
@@ -67,6 +69,9 @@ type Limits struct {
 	SafeMaxLines int
 	// Number of bytes to parse (including lines preceded with --- or +++), after which all subsequent files are collapsed.
 	SafeMaxBytes int
+	// Per-file patch size in bytes above which the patch is dropped and the
+	// diff is marked as TooLarge. Zero means no limit.
+	MaxPatchBytes int
 }
 
 var (
@@ -74,8 +79,24 @@ var (
 	diffHeaderRegexp = regexp.MustCompile(`(?m)^diff --git "?a/(.*?)"? "?b/(.*?)"?$`)
 )
 
+// sanitize clamps negative budgets to zero. Without this a negative
+// limit would mark the very first diff as overflowing or collapsed.
+func (limits *Limits) sanitize() {
+	for _, limit := range []*int{
+		&limits.MaxFiles, &limits.MaxLines, &limits.MaxBytes,
+		&limits.SafeMaxFiles, &limits.SafeMaxLines, &limits.SafeMaxBytes,
+		&limits.MaxPatchBytes,
+	} {
+		if *limit < 0 {
+			*limit = 0
+		}
+	}
+}
+
 // NewDiffParser returns a new Parser
 func NewDiffParser(src io.Reader, limits Limits) *Parser {
+	limits.sanitize()
+
 	parser := &Parser{}
 	reader := bufio.NewReader(src)
 
@@ -138,7 +159,9 @@ func (parser *Parser) Parse() bool {
 			parser.consumeChunkLine()
 		} else if helper.ByteSliceHasAnyPrefix(line, "---", "+++") && !parser.isParsingChunkLines() {
 			parser.consumeLine(true)
-		} else if helper.ByteSliceHasAnyPrefix(line, "-", "+", " ", "\\", "Binary") {
+		} else if helper.ByteSliceHasAnyPrefix(line, "-", "+", " ", "\\", "~", "Binary") {
+			// The "~" prefix only occurs in --word-diff=porcelain output,
+			// where it separates the tokens of consecutive lines.
 			parser.consumeChunkLine()
 		} else {
 			parser.consumeLine(false)
@@ -149,6 +172,14 @@ func (parser *Parser) Parse() bool {
 		}
 	}
 
+	if parser.limits.MaxPatchBytes > 0 && len(parser.currentDiff.Patch) > parser.limits.MaxPatchBytes {
+		parser.linesProcessed -= parser.currentDiff.lineCount
+		parser.bytesProcessed -= len(parser.currentDiff.Patch)
+		parser.currentDiff.TooLarge = true
+		parser.currentDiff.Patch = nil
+		parser.currentDiff.lineCount = 0
+	}
+
 	if parser.overSafeLimits && parser.currentDiff.lineCount > 0 {
 		parser.linesProcessed -= parser.currentDiff.lineCount
 		parser.bytesProcessed -= len(parser.currentDiff.Patch)
@@ -0,0 +1,98 @@
+// Package events distributes notifications about ref updates applied
+// through Gitaly to in-process subscribers, such as the RefEvents admin
+// RPC. Publishing never blocks: a subscriber that does not drain its
+// channel fast enough loses events rather than holding up the ref update.
+package events
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var droppedEvents = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "gitaly_ref_events_dropped_total",
+		Help: "Number of ref update events dropped because a subscriber was too slow",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(droppedEvents)
+}
+
+// RefUpdate describes one ref update that has been applied to a
+// repository on this server.
+type RefUpdate struct {
+	StorageName  string
+	RelativePath string
+	Ref          string
+	OldOid       string
+	NewOid       string
+	// Actor is the GL_ID of the user whose action caused the update, if
+	// known.
+	Actor string
+	// Transport is the channel the update came in through, e.g. "web".
+	Transport string
+}
+
+// Broker fans out ref update events to its current subscribers.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[chan RefUpdate]struct{}
+}
+
+// NewBroker returns a Broker with no subscribers.
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[chan RefUpdate]struct{})}
+}
+
+// Subscribe registers a new subscriber with the given channel buffer
+// size. The returned function removes the subscription and closes the
+// channel; it must be called exactly once when the subscriber is done.
+func (b *Broker) Subscribe(buffer int) (<-chan RefUpdate, func()) {
+	ch := make(chan RefUpdate, buffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+
+		close(ch)
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish delivers the event to every subscriber that has room in its
+// buffer. Slow subscribers miss the event.
+func (b *Broker) Publish(event RefUpdate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			droppedEvents.Inc()
+		}
+	}
+}
+
+// defaultBroker carries events from the ref update call sites to the
+// RefEvents RPC.
+var defaultBroker = NewBroker()
+
+// Subscribe registers a subscriber on the default broker.
+func Subscribe(buffer int) (<-chan RefUpdate, func()) {
+	return defaultBroker.Subscribe(buffer)
+}
+
+// PublishRefUpdate publishes an event on the default broker.
+func PublishRefUpdate(event RefUpdate) {
+	defaultBroker.Publish(event)
+}
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrokerFanOut(t *testing.T) {
+	broker := NewBroker()
+
+	first, unsubscribeFirst := broker.Subscribe(1)
+	defer unsubscribeFirst()
+
+	second, unsubscribeSecond := broker.Subscribe(1)
+	defer unsubscribeSecond()
+
+	event := RefUpdate{
+		StorageName:  "default",
+		RelativePath: "foo/bar.git",
+		Ref:          "refs/heads/master",
+		OldOid:       "old",
+		NewOid:       "new",
+		Actor:        "user-123",
+		Transport:    "web",
+	}
+	broker.Publish(event)
+
+	require.Equal(t, event, <-first)
+	require.Equal(t, event, <-second)
+}
+
+func TestBrokerDropsWhenSubscriberIsFull(t *testing.T) {
+	broker := NewBroker()
+
+	ch, unsubscribe := broker.Subscribe(1)
+	defer unsubscribe()
+
+	broker.Publish(RefUpdate{Ref: "refs/heads/first"})
+	broker.Publish(RefUpdate{Ref: "refs/heads/second"}) // dropped: buffer full
+
+	require.Equal(t, "refs/heads/first", (<-ch).Ref)
+
+	select {
+	case event := <-ch:
+		t.Fatalf("expected no event, got %+v", event)
+	default:
+	}
+}
+
+func TestBrokerUnsubscribe(t *testing.T) {
+	broker := NewBroker()
+
+	ch, unsubscribe := broker.Subscribe(1)
+	unsubscribe()
+
+	// Publishing after unsubscribe must not panic or deliver.
+	broker.Publish(RefUpdate{Ref: "refs/heads/master"})
+
+	_, open := <-ch
+	require.False(t, open, "channel should be closed after unsubscribe")
+}
@@ -0,0 +1,121 @@
+package catfile
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"sync"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git/alternates"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Batch wraps a long-lived `git cat-file --batch` process whose lifetime
+// is bound to the context it was created with. Unlike CatFile, which
+// hands the process to a single handler, a Batch can serve object
+// lookups one at a time for as long as the context lives.
+type Batch struct {
+	sync.Mutex
+	stdin  io.Writer
+	stdout *bufio.Reader
+}
+
+type batchCacheKey struct {
+	storage, relativePath string
+}
+
+type batchCache struct {
+	sync.Mutex
+	batches map[batchCacheKey]*Batch
+}
+
+type batchCacheContextKey struct{}
+
+// WithCache returns a context in which New reuses one cat-file process
+// per repository. The cached processes are terminated when the context
+// is done, so the cache should be installed per request.
+func WithCache(ctx context.Context) context.Context {
+	cache := &batchCache{batches: make(map[batchCacheKey]*Batch)}
+	return context.WithValue(ctx, batchCacheContextKey{}, cache)
+}
+
+// New returns a Batch for repo. If ctx carries a cache installed with
+// WithCache, sequential calls for the same repository share a single
+// cat-file process instead of spawning one per lookup.
+func New(ctx context.Context, repo *pb.Repository) (*Batch, error) {
+	cache, ok := ctx.Value(batchCacheContextKey{}).(*batchCache)
+	if !ok {
+		return newBatch(ctx, repo)
+	}
+
+	cache.Lock()
+	defer cache.Unlock()
+
+	key := batchCacheKey{storage: repo.GetStorageName(), relativePath: repo.GetRelativePath()}
+	if batch, ok := cache.batches[key]; ok {
+		return batch, nil
+	}
+
+	batch, err := newBatch(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	cache.batches[key] = batch
+
+	return batch, nil
+}
+
+func newBatch(ctx context.Context, repo *pb.Repository) (*Batch, error) {
+	repoPath, env, err := alternates.PathAndEnv(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	stdinReader, stdinWriter := io.Pipe()
+	cmdArgs := []string{"--git-dir", repoPath, "cat-file", "--batch"}
+	cmd, err := command.New(ctx, exec.Command(command.GitPath(), cmdArgs...), stdinReader, nil, nil, env...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "CatFile: cmd: %v", err)
+	}
+
+	return &Batch{stdin: stdinWriter, stdout: bufio.NewReader(cmd)}, nil
+}
+
+// ReadObject requests the object at revspec and returns its info along
+// with its full contents. For a missing object the returned data is nil
+// and the ObjectInfo has an empty Oid.
+func (b *Batch) ReadObject(revspec string) (*ObjectInfo, []byte, error) {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, err := fmt.Fprintln(b.stdin, revspec); err != nil {
+		return nil, nil, err
+	}
+
+	objectInfo, err := ParseObjectInfo(b.stdout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if objectInfo.Oid == "" {
+		return objectInfo, nil, nil
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(b.stdout, objectInfo.Size))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := b.stdout.Discard(1); err != nil {
+		return nil, nil, fmt.Errorf("error discarding newline: %v", err)
+	}
+
+	return objectInfo, data, nil
+}
@@ -12,11 +12,19 @@ import (
 
 // Command creates a git.Command with the given args
 func Command(ctx context.Context, repo *pb.Repository, args ...string) (*command.Command, error) {
-	repoPath, env, err := alternates.PathAndEnv(repo)
+	return CommandWithEnv(ctx, repo, nil, args...)
+}
+
+// CommandWithEnv creates a git.Command with the given args and additional
+// environment variables
+func CommandWithEnv(ctx context.Context, repo *pb.Repository, env []string, args ...string) (*command.Command, error) {
+	repoPath, alternatesEnv, err := alternates.PathAndEnv(repo)
 	if err != nil {
 		return nil, err
 	}
 
+	env = append(env, alternatesEnv...)
+
 	args = append([]string{"--git-dir", repoPath}, args...)
 	return command.New(ctx, exec.Command(command.GitPath(), args...), nil, nil, nil, env...)
 }
@@ -0,0 +1,79 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+)
+
+var (
+	// unknownIdent replaces author and committer names and emails that are
+	// missing from a malformed commit object
+	unknownIdent = []byte("<unknown>")
+	// utf8Replacement replaces bytes that are not valid UTF-8
+	utf8Replacement = []byte(string(utf8.RuneError))
+)
+
+// SanitizeCommit repairs a commit parsed from a malformed object in
+// place. Imported repositories regularly contain commits with missing
+// committer lines, broken timezones or non-UTF-8 metadata; instead of
+// failing the RPC, lenient mode substitutes placeholders and returns a
+// warning describing each repair.
+func SanitizeCommit(commit *pb.GitCommit) []string {
+	var warnings []string
+
+	commit.Subject, warnings = sanitizeUTF8(commit.Subject, commit.Id, "subject", warnings)
+	commit.Body, warnings = sanitizeUTF8(commit.Body, commit.Id, "body", warnings)
+
+	commit.Author, warnings = sanitizeCommitAuthor(commit.Author, commit.Id, "author", warnings)
+	commit.Committer, warnings = sanitizeCommitAuthor(commit.Committer, commit.Id, "committer", warnings)
+
+	return warnings
+}
+
+func sanitizeCommitAuthor(author *pb.CommitAuthor, commitID, role string, warnings []string) (*pb.CommitAuthor, []string) {
+	if author == nil {
+		return &pb.CommitAuthor{
+			Name:  unknownIdent,
+			Email: unknownIdent,
+			Date:  &timestamp.Timestamp{},
+		}, append(warnings, fmt.Sprintf("commit %s: missing %s", commitID, role))
+	}
+
+	if len(author.Name) == 0 {
+		author.Name = unknownIdent
+		warnings = append(warnings, fmt.Sprintf("commit %s: empty %s name", commitID, role))
+	} else {
+		author.Name, warnings = sanitizeUTF8(author.Name, commitID, role+" name", warnings)
+	}
+
+	if len(author.Email) == 0 {
+		author.Email = unknownIdent
+		warnings = append(warnings, fmt.Sprintf("commit %s: empty %s email", commitID, role))
+	} else {
+		author.Email, warnings = sanitizeUTF8(author.Email, commitID, role+" email", warnings)
+	}
+
+	// A missing date means the ident line could not be parsed; a date equal
+	// to FallbackTimeValue means the timestamp or timezone was broken.
+	if author.Date == nil || author.Date.Seconds == FallbackTimeValue.Unix() {
+		author.Date = &timestamp.Timestamp{}
+		warnings = append(warnings, fmt.Sprintf("commit %s: invalid %s date", commitID, role))
+	}
+
+	return author, warnings
+}
+
+func sanitizeUTF8(value []byte, commitID, field string, warnings []string) ([]byte, []string) {
+	if utf8.Valid(value) {
+		return value, warnings
+	}
+
+	value = bytes.ToValidUTF8(value, utf8Replacement)
+	warnings = append(warnings, fmt.Sprintf("commit %s: %s is not valid UTF-8", commitID, field))
+
+	return value, warnings
+}
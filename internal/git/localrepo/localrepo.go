@@ -0,0 +1,74 @@
+// Package localrepo bundles a pb.Repository with its resolved location on
+// disk so that handlers don't have to re-resolve the path and object
+// directory environment for every git invocation.
+package localrepo
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"strings"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git/alternates"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+)
+
+// Repo is a repository that exists on the local Gitaly storage.
+type Repo struct {
+	*pb.Repository
+	path string
+	env  []string
+}
+
+// New resolves the repository on disk. The errors returned are gRPC errors
+// from helper.GetRepoPath and can be passed back to gRPC without further
+// decoration.
+func New(repo *pb.Repository) (*Repo, error) {
+	path, env, err := alternates.PathAndEnv(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repo{Repository: repo, path: path, env: env}, nil
+}
+
+// Path returns the full path of the repository on disk.
+func (repo *Repo) Path() string { return repo.path }
+
+// Env returns the object directory environment variables encoded in the
+// pb.Repository instance.
+func (repo *Repo) Env() []string { return repo.env }
+
+// Exec runs a git command against the repository. Alternate object
+// directories from the request are passed along in the environment.
+func (repo *Repo) Exec(ctx context.Context, args ...string) (*command.Command, error) {
+	return repo.ExecWithStdin(ctx, nil, args...)
+}
+
+// ExecWithStdin is like Exec but connects stdin to the git command.
+func (repo *Repo) ExecWithStdin(ctx context.Context, stdin io.Reader, args ...string) (*command.Command, error) {
+	args = append([]string{"--git-dir", repo.path}, args...)
+	return command.New(ctx, exec.Command(command.GitPath(), args...), stdin, nil, nil, repo.env...)
+}
+
+// Run executes a git command against the repository with extra
+// environment variables and returns its trimmed stdout.
+func (repo *Repo) Run(ctx context.Context, env []string, stdin io.Reader, args ...string) (string, error) {
+	args = append([]string{"--git-dir", repo.path}, args...)
+	env = append(env, repo.env...)
+
+	var stdout bytes.Buffer
+	cmd, err := command.New(ctx, exec.Command(command.GitPath(), args...), stdin, &stdout, nil, env...)
+	if err != nil {
+		return "", err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
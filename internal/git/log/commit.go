@@ -1,14 +1,18 @@
 package log
 
 import (
+	"bytes"
 	"context"
+	"strconv"
 	"strings"
 
 	"gitlab.com/gitlab-org/gitaly/internal/command"
 	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/git/catfile"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 
+	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	log "github.com/sirupsen/logrus"
 )
@@ -50,6 +54,93 @@ func GetCommit(ctx context.Context, repo *pb.Repository, revision string, path s
 	return logParser.Commit(), nil
 }
 
+// GetCommitCatfile looks up a commit with the catfile Batch for ctx. If
+// the context carries a cache (catfile.WithCache), sequential lookups
+// reuse a single cat-file process instead of spawning git log each time.
+func GetCommitCatfile(ctx context.Context, repo *pb.Repository, revision string) (*pb.GitCommit, error) {
+	batch, err := catfile.New(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	info, rawCommit, err := batch.ReadObject(revision + "^{commit}")
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Oid == "" {
+		return nil, nil
+	}
+
+	return parseRawCommit(info.Oid, rawCommit), nil
+}
+
+// parseRawCommit builds a GitCommit from the raw object contents as
+// printed by `git cat-file --batch`.
+func parseRawCommit(oid string, rawCommit []byte) *pb.GitCommit {
+	commit := &pb.GitCommit{Id: oid}
+
+	var headers, body []byte
+	if split := bytes.SplitN(rawCommit, []byte("\n\n"), 2); len(split) == 2 {
+		headers, body = split[0], split[1]
+	} else {
+		headers = rawCommit
+	}
+
+	if len(body) > 0 {
+		commit.Body = body
+		commit.Subject = bytes.TrimRight(bytes.SplitN(body, []byte("\n"), 2)[0], "\r\n")
+	}
+
+	for _, header := range bytes.Split(headers, []byte("\n")) {
+		if len(header) == 0 || header[0] == ' ' {
+			// Continuation lines (e.g. of a gpgsig header) carry no
+			// commit metadata.
+			continue
+		}
+
+		headerSplit := bytes.SplitN(header, []byte(" "), 2)
+		if len(headerSplit) != 2 {
+			continue
+		}
+
+		switch string(headerSplit[0]) {
+		case "parent":
+			commit.ParentIds = append(commit.ParentIds, string(headerSplit[1]))
+		case "author":
+			commit.Author = parseCommitAuthor(headerSplit[1])
+		case "committer":
+			commit.Committer = parseCommitAuthor(headerSplit[1])
+		}
+	}
+
+	return commit
+}
+
+// parseCommitAuthor parses an ident line of the form
+// `Name <email> 1234567890 +0000`.
+func parseCommitAuthor(ident []byte) *pb.CommitAuthor {
+	author := &pb.CommitAuthor{}
+
+	if openBracket := bytes.IndexByte(ident, '<'); openBracket >= 0 {
+		author.Name = bytes.TrimSpace(ident[:openBracket])
+		ident = ident[openBracket+1:]
+	}
+
+	if closeBracket := bytes.IndexByte(ident, '>'); closeBracket >= 0 {
+		author.Email = ident[:closeBracket]
+		ident = ident[closeBracket+1:]
+	}
+
+	if fields := bytes.Fields(ident); len(fields) > 0 {
+		if seconds, err := strconv.ParseInt(string(fields[0]), 10, 64); err == nil {
+			author.Date = &timestamp.Timestamp{Seconds: seconds}
+		}
+	}
+
+	return author
+}
+
 // GitLogCommand returns a Command that executes git log with the given the arguments
 func GitLogCommand(ctx context.Context, repo *pb.Repository, revisions []string, paths []string, extraArgs ...string) (*command.Command, error) {
 	grpc_logrus.Extract(ctx).WithFields(log.Fields{
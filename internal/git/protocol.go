@@ -0,0 +1,20 @@
+package git
+
+// ProtocolV2 is the value of the GIT_PROTOCOL environment variable that
+// enables Git wire protocol v2.
+const ProtocolV2 = "version=2"
+
+type requestWithGitProtocol interface {
+	GetGitProtocol() string
+}
+
+// AddGitProtocolEnv returns env with the GIT_PROTOCOL variable appended
+// when the request negotiated Git protocol v2. Other protocol values are
+// ignored; git then falls back to protocol v0.
+func AddGitProtocolEnv(req requestWithGitProtocol, env []string) []string {
+	if req.GetGitProtocol() == ProtocolV2 {
+		return append(env, "GIT_PROTOCOL="+ProtocolV2)
+	}
+
+	return env
+}
@@ -0,0 +1,117 @@
+// Package treebuilder stages changes to a git tree in a temporary index,
+// so commits can be built without ever touching the repository's own
+// index file.
+package treebuilder
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+)
+
+const (
+	// ModeFile is the tree entry mode of a regular file.
+	ModeFile = "100644"
+	// ModeExecutable is the tree entry mode of an executable file.
+	ModeExecutable = "100755"
+)
+
+// Builder accumulates tree modifications in a temporary index and
+// writes the result out as a tree object.
+type Builder struct {
+	repo     *localrepo.Repo
+	indexEnv string
+}
+
+// New creates a Builder for repo. The returned cleanup function removes
+// the temporary index and must be called when the Builder is no longer
+// needed.
+func New(repo *localrepo.Repo) (*Builder, func(), error) {
+	indexFile, err := ioutil.TempFile("", "gitaly-tree-builder-index-")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := indexFile.Close(); err != nil {
+		os.Remove(indexFile.Name())
+		return nil, nil, err
+	}
+
+	tb := &Builder{repo: repo, indexEnv: "GIT_INDEX_FILE=" + indexFile.Name()}
+	return tb, func() { os.Remove(indexFile.Name()) }, nil
+}
+
+func (tb *Builder) git(ctx context.Context, stdin io.Reader, args ...string) (string, error) {
+	return tb.repo.Run(ctx, []string{tb.indexEnv}, stdin, args...)
+}
+
+// Init populates the index from the parent commit, or leaves it empty
+// for a root commit.
+func (tb *Builder) Init(ctx context.Context, parentID string) error {
+	args := []string{"read-tree", "--empty"}
+	if parentID != "" {
+		args = []string{"read-tree", parentID}
+	}
+
+	_, err := tb.git(ctx, nil, args...)
+	return err
+}
+
+// FileEntry looks up the index entry at exactly path. An empty mode
+// means there is no such entry.
+func (tb *Builder) FileEntry(ctx context.Context, path string) (mode string, oid string, err error) {
+	out, err := tb.git(ctx, nil, "ls-files", "-s", "--", path)
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		tab := strings.IndexByte(line, '\t')
+		if tab < 0 || line[tab+1:] != path {
+			continue
+		}
+
+		fields := strings.Fields(line[:tab])
+		if len(fields) < 2 {
+			continue
+		}
+
+		return fields[0], fields[1], nil
+	}
+
+	return "", "", nil
+}
+
+// DirExists reports whether the index contains any entry below path.
+func (tb *Builder) DirExists(ctx context.Context, path string) (bool, error) {
+	out, err := tb.git(ctx, nil, "ls-files", "--", path+"/")
+	return out != "", err
+}
+
+// AddEntry adds or replaces the index entry at path.
+func (tb *Builder) AddEntry(ctx context.Context, mode, oid, path string) error {
+	_, err := tb.git(ctx, nil, "update-index", "--add", "--cacheinfo", mode+","+oid+","+path)
+	return err
+}
+
+// RemoveEntry removes the index entry at path.
+func (tb *Builder) RemoveEntry(ctx context.Context, path string) error {
+	_, err := tb.git(ctx, nil, "update-index", "--force-remove", "--", path)
+	return err
+}
+
+// HashObject writes content into the object database and returns its
+// blob OID.
+func (tb *Builder) HashObject(ctx context.Context, content []byte) (string, error) {
+	return tb.git(ctx, bytes.NewReader(content), "hash-object", "-w", "--stdin")
+}
+
+// WriteTree writes the index out as a tree object and returns its OID.
+func (tb *Builder) WriteTree(ctx context.Context) (string, error) {
+	return tb.git(ctx, nil, "write-tree")
+}
@@ -0,0 +1,19 @@
+package git
+
+import (
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+)
+
+// UploadPackFilterArgs returns git command line arguments that make
+// git-upload-pack advertise and honor partial clone filters, e.g.
+// 'filter=blob:none', when that is enabled in config.
+func UploadPackFilterArgs() []string {
+	if !config.Config.Git.UploadPackAllowFilter {
+		return nil
+	}
+
+	return []string{
+		"-c", "uploadpack.allowFilter=true",
+		"-c", "uploadpack.allowAnySHA1InWant=true",
+	}
+}
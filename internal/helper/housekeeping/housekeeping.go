@@ -1,6 +1,7 @@
 package housekeeping
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,6 +14,10 @@ import (
 
 const deleteTempFilesOlderThanDuration = 7 * 24 * time.Hour
 
+// A gc.pid or *.lock file this old cannot belong to a running git
+// process anymore; its owner crashed without cleaning up.
+const staleLockOlderThanDuration = 24 * time.Hour
+
 // Perform will perform housekeeping duties on a repository
 func Perform(ctx context.Context, repoPath string) error {
 	logger := grpc_logrus.Extract(ctx).WithField("system", "housekeeping")
@@ -56,6 +61,33 @@ func Perform(ctx context.Context, repoPath string) error {
 	return err
 }
 
+// RemoveStaleLocks deletes gc.pid and top-level `*.lock` files (e.g.
+// config.lock, packed-refs.lock) left behind by a crashed git process.
+// Fresh locks are left alone since they may belong to a running process.
+func RemoveStaleLocks(repoPath string) error {
+	entries, err := ioutil.ReadDir(repoPath)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if name != "gc.pid" && !strings.HasSuffix(name, ".lock") {
+			continue
+		}
+
+		if !entry.Mode().IsRegular() || time.Since(entry.ModTime()) < staleLockOlderThanDuration {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(repoPath, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func fixPermissions(path string) {
 	filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
 		if info.IsDir() {
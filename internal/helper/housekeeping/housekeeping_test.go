@@ -310,3 +310,35 @@ func TestDeleteRootOwnerObjects(t *testing.T) {
 	_, err = os.Stat(filepath.Join(rootPath, "tmp_DIR2"))
 	assert.Error(t, err, "Expected tmp_DIR2 to be missing")
 }
+
+func TestRemoveStaleLocks(t *testing.T) {
+	repoPath, err := ioutil.TempDir("", "test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(repoPath)
+
+	staleTime := time.Now().Add(-48 * time.Hour)
+
+	makeFile := func(name string, age time.Time) string {
+		path := filepath.Join(repoPath, name)
+		assert.NoError(t, ioutil.WriteFile(path, nil, 0644))
+		assert.NoError(t, os.Chtimes(path, age, age))
+		return path
+	}
+
+	staleGcPid := makeFile("gc.pid", staleTime)
+	staleLock := makeFile("packed-refs.lock", staleTime)
+	freshLock := makeFile("config.lock", time.Now())
+	unrelated := makeFile("packed-refs", staleTime)
+
+	assert.NoError(t, RemoveStaleLocks(repoPath))
+
+	for _, gone := range []string{staleGcPid, staleLock} {
+		_, err := os.Stat(gone)
+		assert.True(t, os.IsNotExist(err), "%s should have been removed", gone)
+	}
+
+	for _, kept := range []string{freshLock, unrelated} {
+		_, err := os.Stat(kept)
+		assert.NoError(t, err, "%s should have been kept", kept)
+	}
+}
@@ -0,0 +1,42 @@
+// Package worktree holds the naming convention for the throwaway
+// worktrees that operations RPCs create inside a repository, so state
+// inspection RPCs can find them again without the two packages
+// importing each other.
+package worktree
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SubDir is the directory inside a repository under which throwaway
+// worktrees are created.
+const SubDir = "gitlab-worktree"
+
+// Worktree name prefixes per operation type.
+const (
+	RebasePrefix = "rebase"
+	SquashPrefix = "squash"
+)
+
+// A worktree untouched for this long belongs to a crashed operation and
+// no longer counts as in progress.
+const freshDuration = 15 * time.Minute
+
+// Path returns where the worktree for the given operation type and ID
+// lives inside the repository.
+func Path(repoPath, prefix, id string) string {
+	return filepath.Join(repoPath, SubDir, prefix+"-"+id)
+}
+
+// InProgress returns whether a worktree exists at path and was modified
+// recently enough to belong to a running operation.
+func InProgress(path string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return time.Since(fi.ModTime()) < freshDuration
+}
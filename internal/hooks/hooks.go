@@ -0,0 +1,158 @@
+// Package hooks discovers and runs custom git hooks the way
+// gitlab-shell does: per-repository hooks under `custom_hooks/` and
+// global hooks under the configured directory. Write RPCs run these in
+// addition to the gitlab-shell hooks proper, so pushes keep their
+// site-specific behavior when hook execution moves into Gitaly.
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+
+	"golang.org/x/net/context"
+)
+
+// Error is returned when a custom hook exits non-zero or exceeds the
+// configured timeout. Its output has already been written to the stdout
+// and stderr writers passed to RunCustom.
+type Error struct {
+	Hook string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("custom hook %s declined", e.Hook)
+}
+
+// Timeout returns the execution timeout for one invocation of the named
+// hook type. Zero means no timeout. The per-hook-type settings override
+// the catch-all timeout_seconds.
+func Timeout(name string) time.Duration {
+	cfg := config.Config.Hooks
+
+	seconds := cfg.TimeoutSeconds
+	switch name {
+	case "pre-receive":
+		if cfg.PreReceiveTimeoutSeconds > 0 {
+			seconds = cfg.PreReceiveTimeoutSeconds
+		}
+	case "update":
+		if cfg.UpdateTimeoutSeconds > 0 {
+			seconds = cfg.UpdateTimeoutSeconds
+		}
+	case "post-receive":
+		if cfg.PostReceiveTimeoutSeconds > 0 {
+			seconds = cfg.PostReceiveTimeoutSeconds
+		}
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// Custom returns the custom hooks to run for a hook name, in execution
+// order: the repository's `custom_hooks/<name>` file, the files in the
+// repository's `custom_hooks/<name>.d` directory, then the files in
+// `<name>.d` under the global custom hooks directory from config. Only
+// executable regular files count; editor backups ending in `~` are
+// skipped.
+func Custom(repoPath, name string) []string {
+	var hooks []string
+
+	projectHook := filepath.Join(repoPath, "custom_hooks", name)
+	if isExecutable(projectHook) {
+		hooks = append(hooks, projectHook)
+	}
+
+	hooks = append(hooks, hookDir(filepath.Join(repoPath, "custom_hooks", name+".d"))...)
+
+	if globalDir := config.Config.Hooks.CustomHooksDir; globalDir != "" {
+		hooks = append(hooks, hookDir(filepath.Join(globalDir, name+".d"))...)
+	}
+
+	return hooks
+}
+
+// RunCustom runs all custom hooks for a hook name sequentially with the
+// repository as working directory, replaying stdin to each and streaming
+// their combined output to stdout and stderr as it is produced. The
+// first hook to fail or time out stops the chain and is reported as an
+// Error.
+func RunCustom(ctx context.Context, repoPath, name string, env []string, stdin []byte, stdout, stderr io.Writer, args ...string) error {
+	for _, hook := range Custom(repoPath, name) {
+		if err := runOne(ctx, repoPath, name, hook, env, stdin, stdout, stderr, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func runOne(ctx context.Context, repoPath, name, hook string, env []string, stdin []byte, stdout, stderr io.Writer, args ...string) error {
+	timeout := Timeout(name)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	osCmd := exec.Command(hook, args...)
+	osCmd.Dir = repoPath
+
+	cmd, err := command.New(ctx, osCmd, bytes.NewReader(stdin), stdout, stderr, env...)
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			// The message ends up in the output the client sees.
+			fmt.Fprintf(stderr, "custom %s hook timed out after %v\n", name, timeout)
+			return Error{Hook: hook}
+		}
+		if _, ok := command.ExitStatus(err); ok {
+			return Error{Hook: hook}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func hookDir(dir string) []string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var hooks []string
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), "~") {
+			continue
+		}
+
+		hook := filepath.Join(dir, entry.Name())
+		if isExecutable(hook) {
+			hooks = append(hooks, hook)
+		}
+	}
+
+	return hooks
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	return info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
@@ -0,0 +1,123 @@
+package hooks
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeHook(t *testing.T, path, script string, mode os.FileMode) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"+script), mode))
+}
+
+func TestCustomDiscoveryOrder(t *testing.T) {
+	repoPath, err := ioutil.TempDir("", "gitaly-hooks-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(repoPath)
+
+	globalDir, err := ioutil.TempDir("", "gitaly-hooks-global")
+	require.NoError(t, err)
+	defer os.RemoveAll(globalDir)
+
+	defer func(cfg config.Hooks) { config.Config.Hooks = cfg }(config.Config.Hooks)
+	config.Config.Hooks.CustomHooksDir = globalDir
+
+	writeHook(t, filepath.Join(repoPath, "custom_hooks", "pre-receive"), "true", 0755)
+	writeHook(t, filepath.Join(repoPath, "custom_hooks", "pre-receive.d", "01-first"), "true", 0755)
+	writeHook(t, filepath.Join(repoPath, "custom_hooks", "pre-receive.d", "02-second"), "true", 0755)
+	writeHook(t, filepath.Join(repoPath, "custom_hooks", "pre-receive.d", "03-not-executable"), "true", 0644)
+	writeHook(t, filepath.Join(repoPath, "custom_hooks", "pre-receive.d", "04-backup~"), "true", 0755)
+	writeHook(t, filepath.Join(globalDir, "pre-receive.d", "global"), "true", 0755)
+
+	require.Equal(t, []string{
+		filepath.Join(repoPath, "custom_hooks", "pre-receive"),
+		filepath.Join(repoPath, "custom_hooks", "pre-receive.d", "01-first"),
+		filepath.Join(repoPath, "custom_hooks", "pre-receive.d", "02-second"),
+		filepath.Join(globalDir, "pre-receive.d", "global"),
+	}, Custom(repoPath, "pre-receive"))
+
+	require.Empty(t, Custom(repoPath, "update"))
+}
+
+func TestRunCustomReplaysStdinAndEnv(t *testing.T) {
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	repoPath, err := ioutil.TempDir("", "gitaly-hooks-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(repoPath)
+
+	writeHook(t, filepath.Join(repoPath, "custom_hooks", "post-receive.d", "01-stdin"), "cat", 0755)
+	writeHook(t, filepath.Join(repoPath, "custom_hooks", "post-receive.d", "02-env"), "echo $GL_ID", 0755)
+
+	var stdout, stderr bytes.Buffer
+	err = RunCustom(ctx, repoPath, "post-receive", []string{"GL_ID=user-123"}, []byte("changes\n"), &stdout, &stderr)
+
+	require.NoError(t, err)
+	require.Equal(t, "changes\nuser-123\n", stdout.String())
+	require.Empty(t, stderr.String())
+}
+
+func TestRunCustomStopsAtFirstFailure(t *testing.T) {
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	repoPath, err := ioutil.TempDir("", "gitaly-hooks-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(repoPath)
+
+	failingHook := filepath.Join(repoPath, "custom_hooks", "pre-receive.d", "01-fail")
+	writeHook(t, failingHook, "echo rejected >&2; exit 1", 0755)
+	writeHook(t, filepath.Join(repoPath, "custom_hooks", "pre-receive.d", "02-never"), "echo should not run", 0755)
+
+	var stdout, stderr bytes.Buffer
+	err = RunCustom(ctx, repoPath, "pre-receive", nil, nil, &stdout, &stderr)
+
+	require.Equal(t, Error{Hook: failingHook}, err)
+	require.Empty(t, stdout.String())
+	require.Equal(t, "rejected\n", stderr.String())
+}
+
+func TestRunCustomTimeout(t *testing.T) {
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	repoPath, err := ioutil.TempDir("", "gitaly-hooks-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(repoPath)
+
+	defer func(cfg config.Hooks) { config.Config.Hooks = cfg }(config.Config.Hooks)
+	config.Config.Hooks.TimeoutSeconds = 1
+
+	hook := filepath.Join(repoPath, "custom_hooks", "pre-receive.d", "01-sleep")
+	writeHook(t, hook, "sleep 10", 0755)
+
+	var output bytes.Buffer
+	err = RunCustom(ctx, repoPath, "pre-receive", nil, nil, &output, &output)
+
+	require.Equal(t, Error{Hook: hook}, err)
+	require.Contains(t, output.String(), "timed out")
+}
+
+func TestTimeoutPerHookType(t *testing.T) {
+	defer func(cfg config.Hooks) { config.Config.Hooks = cfg }(config.Config.Hooks)
+
+	config.Config.Hooks.TimeoutSeconds = 10
+	config.Config.Hooks.UpdateTimeoutSeconds = 3
+
+	require.Equal(t, 10*time.Second, Timeout("pre-receive"), "fallback to the catch-all timeout")
+	require.Equal(t, 3*time.Second, Timeout("update"), "per-hook-type override wins")
+	require.Equal(t, 10*time.Second, Timeout("post-receive"))
+
+	config.Config.Hooks.TimeoutSeconds = 0
+	require.Equal(t, time.Duration(0), Timeout("pre-receive"), "zero disables the timeout")
+}
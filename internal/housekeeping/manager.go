@@ -0,0 +1,190 @@
+// Package housekeeping schedules background repository optimization
+// after write RPCs. Instead of external cron-driven `git gc`, each write
+// gives Gitaly a chance to inspect the repository and, when heuristics
+// say it degraded enough, repack it asynchronously with a bounded number
+// of concurrent tasks per storage shard.
+package housekeeping
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	helperhousekeeping "gitlab.com/gitlab-org/gitaly/internal/helper/housekeeping"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// A repository with more loose objects than this gets an incremental
+	// repack.
+	looseObjectsThreshold = 1024
+	// A repository with more packfiles than this gets a full repack.
+	packfilesThreshold = 10
+)
+
+var tasksCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "gitaly_housekeeping_auto_tasks_total",
+		Help: "Number of automatic housekeeping tasks triggered after write RPCs",
+	},
+	[]string{"task"},
+)
+
+func init() {
+	prometheus.MustRegister(tasksCounter)
+}
+
+// Manager limits how many background optimizations run at once on each
+// storage shard and prevents piling up more than one per repository.
+type Manager struct {
+	mu         sync.Mutex
+	perStorage map[string]int
+	inflight   map[string]bool
+}
+
+// NewManager returns a Manager with no tasks in flight.
+func NewManager() *Manager {
+	return &Manager{
+		perStorage: make(map[string]int),
+		inflight:   make(map[string]bool),
+	}
+}
+
+var defaultManager = NewManager()
+
+// MaybeOptimize inspects the repository in the background and repacks it
+// when it crossed the loose object or packfile thresholds. It returns
+// immediately; when automatic housekeeping is disabled in config, the
+// storage already runs its maximum number of tasks, or the repository is
+// already being optimized, it does nothing.
+func MaybeOptimize(repo *pb.Repository) {
+	if !config.Config.Housekeeping.Auto {
+		return
+	}
+
+	defaultManager.maybeOptimize(repo)
+}
+
+func (m *Manager) maybeOptimize(repo *pb.Repository) {
+	repoPath, err := helper.GetRepoPath(repo)
+	if err != nil {
+		return
+	}
+
+	if !m.tryAcquire(repo.GetStorageName(), repoPath) {
+		return
+	}
+
+	go func() {
+		defer m.release(repo.GetStorageName(), repoPath)
+
+		if err := m.optimize(repoPath); err != nil {
+			log.WithField("repoPath", repoPath).WithError(err).Warn("housekeeping: optimization failed")
+		}
+	}()
+}
+
+func (m *Manager) tryAcquire(storage, repoPath string) bool {
+	maxPerStorage := config.Config.Housekeeping.MaxConcurrencyPerStorage
+	if maxPerStorage < 1 {
+		maxPerStorage = 1
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.inflight[repoPath] || m.perStorage[storage] >= maxPerStorage {
+		return false
+	}
+
+	m.inflight[repoPath] = true
+	m.perStorage[storage]++
+	return true
+}
+
+func (m *Manager) release(storage, repoPath string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.inflight, repoPath)
+	m.perStorage[storage]--
+}
+
+// optimize runs outside any request scope, so it builds its own
+// cancellable context.
+func (m *Manager) optimize(repoPath string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := helperhousekeeping.RemoveStaleLocks(repoPath); err != nil {
+		return err
+	}
+
+	if err := helperhousekeeping.Perform(ctx, repoPath); err != nil {
+		return err
+	}
+
+	switch {
+	case countPackfiles(repoPath) > packfilesThreshold:
+		tasksCounter.WithLabelValues("repack_full").Inc()
+		return runGit(ctx, repoPath, "-c", "repack.writeBitmaps=true", "repack", "-A", "-d", "--pack-kept-objects")
+	case countLooseObjects(repoPath) > looseObjectsThreshold:
+		tasksCounter.WithLabelValues("repack_incremental").Inc()
+		return runGit(ctx, repoPath, "repack", "-d")
+	}
+
+	return nil
+}
+
+func runGit(ctx context.Context, repoPath string, args ...string) error {
+	args = append([]string{"--git-dir", repoPath}, args...)
+
+	cmd, err := command.New(ctx, exec.Command(command.GitPath(), args...), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	return cmd.Wait()
+}
+
+func countPackfiles(repoPath string) int {
+	packs, err := filepath.Glob(filepath.Join(repoPath, "objects", "pack", "*.pack"))
+	if err != nil {
+		return 0
+	}
+
+	return len(packs)
+}
+
+func countLooseObjects(repoPath string) int {
+	fanOutDirs, err := filepath.Glob(filepath.Join(repoPath, "objects", "??"))
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, dir := range fanOutDirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.Mode().IsRegular() {
+				count++
+			}
+		}
+	}
+
+	return count
+}
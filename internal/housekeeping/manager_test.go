@@ -0,0 +1,49 @@
+package housekeeping
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryAcquireLimitsPerStorage(t *testing.T) {
+	defer func(cfg config.Housekeeping) { config.Config.Housekeeping = cfg }(config.Config.Housekeeping)
+	config.Config.Housekeeping.MaxConcurrencyPerStorage = 2
+
+	m := NewManager()
+
+	require.True(t, m.tryAcquire("default", "/repo/a"))
+	require.False(t, m.tryAcquire("default", "/repo/a"), "same repository must not be optimized twice")
+	require.True(t, m.tryAcquire("default", "/repo/b"))
+	require.False(t, m.tryAcquire("default", "/repo/c"), "storage concurrency limit reached")
+	require.True(t, m.tryAcquire("other", "/repo/d"), "limits are per storage")
+
+	m.release("default", "/repo/a")
+	require.True(t, m.tryAcquire("default", "/repo/c"))
+}
+
+func TestCountPackfilesAndLooseObjects(t *testing.T) {
+	repoPath, err := ioutil.TempDir("", "gitaly-housekeeping-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(repoPath)
+
+	packDir := filepath.Join(repoPath, "objects", "pack")
+	require.NoError(t, os.MkdirAll(packDir, 0755))
+	for _, name := range []string{"pack-1.pack", "pack-1.idx", "pack-2.pack"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(packDir, name), nil, 0644))
+	}
+
+	fanOutDir := filepath.Join(repoPath, "objects", "ab")
+	require.NoError(t, os.MkdirAll(fanOutDir, 0755))
+	for _, name := range []string{"cdef", "1234"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(fanOutDir, name), nil, 0644))
+	}
+
+	require.Equal(t, 2, countPackfiles(repoPath))
+	require.Equal(t, 2, countLooseObjects(repoPath))
+}
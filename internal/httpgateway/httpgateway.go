@@ -0,0 +1,183 @@
+// Package httpgateway exposes a small read-only subset of the Gitaly
+// API over plain HTTP/JSON, for monitoring and lightweight integrations
+// that cannot speak gRPC. Like the prometheus listener it performs no
+// authentication, so it should only be bound to a trusted interface.
+package httpgateway
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/service/ref"
+	"gitlab.com/gitlab-org/gitaly/internal/service/repository"
+	serverservice "gitlab.com/gitlab-org/gitaly/internal/service/server"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type gateway struct {
+	serverService     pb.ServerServiceServer
+	repositoryService pb.RepositoryServiceServer
+	refService        pb.RefServiceServer
+	marshaler         *jsonpb.Marshaler
+}
+
+// NewHandler returns an http.Handler serving the read-only gateway
+// endpoints. The RPCs are invoked in-process, on the same service
+// implementations the gRPC listeners use.
+func NewHandler(rs *rubyserver.Server) http.Handler {
+	gw := &gateway{
+		serverService:     serverservice.NewServer(),
+		repositoryService: repository.NewServer(rs),
+		refService:        ref.NewServer(rs),
+		marshaler:         &jsonpb.Marshaler{EmitDefaults: true, OrigName: true},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/health", gw.health)
+	mux.HandleFunc("/v1/server/info", gw.serverInfo)
+	mux.HandleFunc("/v1/repository/size", gw.repositorySize)
+	mux.HandleFunc("/v1/repository/default_branch", gw.defaultBranch)
+
+	return mux
+}
+
+func (gw *gateway) health(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	writeJSON(w, map[string]string{"status": "SERVING"})
+}
+
+func (gw *gateway) serverInfo(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	resp, err := gw.serverService.ServerInfo(ctx, &pb.ServerInfoRequest{})
+	gw.writeResponse(w, resp, err)
+}
+
+func (gw *gateway) repositorySize(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	repo, err := repositoryFromQuery(r)
+	if err != nil {
+		gw.writeResponse(w, nil, err)
+		return
+	}
+
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	resp, err := gw.repositoryService.RepositorySize(ctx, &pb.RepositorySizeRequest{Repository: repo})
+	gw.writeResponse(w, resp, err)
+}
+
+func (gw *gateway) defaultBranch(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+
+	repo, err := repositoryFromQuery(r)
+	if err != nil {
+		gw.writeResponse(w, nil, err)
+		return
+	}
+
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	resp, err := gw.refService.FindDefaultBranchName(ctx, &pb.FindDefaultBranchNameRequest{Repository: repo})
+	gw.writeResponse(w, resp, err)
+}
+
+// requestContext derives a cancelable context from the request, so git
+// processes spawned for it are cleaned up when the handler returns.
+func requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithCancel(r.Context())
+}
+
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+
+	return true
+}
+
+// repositoryFromQuery builds the target repository from the `storage`
+// and `relative_path` query parameters.
+func repositoryFromQuery(r *http.Request) (*pb.Repository, error) {
+	storage := r.URL.Query().Get("storage")
+	relativePath := r.URL.Query().Get("relative_path")
+	if storage == "" || relativePath == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "storage and relative_path parameters are required")
+	}
+
+	return &pb.Repository{StorageName: storage, RelativePath: relativePath}, nil
+}
+
+// writeResponse renders the RPC result as JSON, translating gRPC status
+// codes into HTTP status codes.
+func (gw *gateway) writeResponse(w http.ResponseWriter, resp proto.Message, err error) {
+	if err != nil {
+		writeStatus(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := gw.marshaler.Marshal(w, resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeStatus(w http.ResponseWriter, err error) {
+	st, ok := status.FromError(err)
+	if !ok {
+		st = status.New(codes.Internal, err.Error())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(st.Code()))
+
+	json.NewEncoder(w).Encode(map[string]string{"error": st.Message()})
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
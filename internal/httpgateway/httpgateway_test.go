@@ -0,0 +1,90 @@
+package httpgateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	defer testhelper.MustHaveNoChildProcess()
+
+	testhelper.ConfigureTestStorage()
+
+	return m.Run()
+}
+
+func TestGatewayHealth(t *testing.T) {
+	rec := doGatewayRequest(t, "/v1/health")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Equal(t, "SERVING", body["status"])
+}
+
+func TestGatewayServerInfo(t *testing.T) {
+	rec := doGatewayRequest(t, "/v1/server/info")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), "server_version")
+}
+
+func TestGatewayRepositorySize(t *testing.T) {
+	testRepo, _, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	rec := doGatewayRequest(t, "/v1/repository/size?storage=default&relative_path="+testRepo.RelativePath)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// jsonpb renders int64 fields as JSON strings
+	var body struct {
+		Size int64 `json:"size,string"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.True(t, body.Size > 0, "a fresh bare repository is not empty")
+}
+
+func TestGatewayErrors(t *testing.T) {
+	testCases := []struct {
+		desc string
+		path string
+		code int
+	}{
+		{desc: "missing parameters", path: "/v1/repository/size", code: http.StatusBadRequest},
+		{desc: "unknown storage", path: "/v1/repository/size?storage=fake&relative_path=repo.git", code: http.StatusBadRequest},
+		{desc: "unknown endpoint", path: "/v1/nonsense", code: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			rec := doGatewayRequest(t, tc.path)
+			require.Equal(t, tc.code, rec.Code)
+		})
+	}
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/health", nil)
+		NewHandler(nil).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+}
+
+func doGatewayRequest(t *testing.T, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", path, nil)
+	NewHandler(nil).ServeHTTP(rec, req)
+
+	return rec
+}
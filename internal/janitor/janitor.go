@@ -0,0 +1,140 @@
+// Package janitor contains scheduled maintenance walks over the storage
+// roots. Unlike internal/tempdir it never deletes anything: it only reports
+// what it finds so operators can reconcile disk state with the application.
+package janitor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Like tempdir's '+gitaly/tmp', the '+' prefix cannot clash with GitLab
+	// namespace or repository names.
+	janitorDir = "+gitaly/janitor"
+
+	// reportFile lists the relative path of every directory on the storage
+	// that looks like a git repository, one per line. The application (or an
+	// operator) can diff this against its own list of known repositories to
+	// find orphans.
+	reportFile = "repositories.list"
+
+	scanInterval = 24 * time.Hour
+)
+
+// StartRepositoryScan starts a goroutine per storage that periodically walks
+// the storage root and writes a report of all directories that look like git
+// repositories.
+func StartRepositoryScan() {
+	for _, st := range config.Config.Storages {
+		go func(name string, dir string) {
+			for {
+				start := time.Now()
+				numRepos, err := scanStorage(dir)
+
+				entry := log.WithFields(log.Fields{
+					"time_ms":      int(1000 * time.Since(start).Seconds()),
+					"storage":      name,
+					"repositories": numRepos,
+				})
+				if err != nil {
+					entry = entry.WithError(err)
+				}
+				entry.Info("finished janitor repository scan")
+
+				time.Sleep(scanInterval)
+			}
+		}(st.Name, st.Path)
+	}
+}
+
+func scanStorage(storageRoot string) (int, error) {
+	var repoPaths []string
+
+	err := filepath.Walk(storageRoot, func(walkedPath string, info os.FileInfo, errIncoming error) error {
+		if errIncoming != nil {
+			if os.IsNotExist(errIncoming) {
+				return nil
+			}
+			return fmt.Errorf("incoming %q: %v", walkedPath, errIncoming)
+		}
+
+		if info == nil || !info.IsDir() {
+			return nil
+		}
+
+		// Never descend into Gitaly's own scratch directories.
+		if strings.HasPrefix(info.Name(), "+gitaly") {
+			return filepath.SkipDir
+		}
+
+		if !looksLikeGitRepository(walkedPath) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(storageRoot, walkedPath)
+		if err != nil {
+			return err
+		}
+		repoPaths = append(repoPaths, relPath)
+
+		// Repositories do not nest; no point walking their internals.
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(repoPaths), writeReport(storageRoot, repoPaths)
+}
+
+// looksLikeGitRepository returns true for directories with the layout of a
+// bare git repository.
+func looksLikeGitRepository(dir string) bool {
+	if info, err := os.Stat(path.Join(dir, "HEAD")); err != nil || info.IsDir() {
+		return false
+	}
+
+	for _, subDir := range []string{"objects", "refs"} {
+		if info, err := os.Stat(path.Join(dir, subDir)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func writeReport(storageRoot string, repoPaths []string) error {
+	reportDir := path.Join(storageRoot, janitorDir)
+	if err := os.MkdirAll(reportDir, 0700); err != nil {
+		return err
+	}
+
+	tempFile, err := ioutil.TempFile(reportDir, reportFile)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	for _, repoPath := range repoPaths {
+		if _, err := fmt.Fprintln(tempFile, repoPath); err != nil {
+			tempFile.Close()
+			return err
+		}
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), path.Join(reportDir, reportFile))
+}
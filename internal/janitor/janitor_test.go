@@ -0,0 +1,50 @@
+package janitor
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanStorage(t *testing.T) {
+	storageRoot, err := ioutil.TempDir("", "gitaly-janitor-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(storageRoot)
+
+	testhelper.MustRunCommand(t, nil, "git", "init", "--bare", path.Join(storageRoot, "group", "project.git"))
+	testhelper.MustRunCommand(t, nil, "git", "init", "--bare", path.Join(storageRoot, "orphan.git"))
+
+	// Directories that should not show up in the report
+	require.NoError(t, os.MkdirAll(path.Join(storageRoot, "group", "not-a-repo"), 0755))
+	require.NoError(t, os.MkdirAll(path.Join(storageRoot, "+gitaly", "tmp", "fake.git"), 0755))
+
+	numRepos, err := scanStorage(storageRoot)
+	require.NoError(t, err)
+	require.Equal(t, 2, numRepos)
+
+	report, err := ioutil.ReadFile(path.Join(storageRoot, janitorDir, reportFile))
+	require.NoError(t, err)
+
+	reportedPaths := strings.Split(strings.TrimSpace(string(report)), "\n")
+	require.Equal(t, []string{"group/project.git", "orphan.git"}, reportedPaths)
+}
+
+func TestLooksLikeGitRepository(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gitaly-janitor-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	repoPath := path.Join(dir, "repo.git")
+	testhelper.MustRunCommand(t, nil, "git", "init", "--bare", repoPath)
+	require.True(t, looksLikeGitRepository(repoPath))
+
+	plainDir := path.Join(dir, "plain")
+	require.NoError(t, os.MkdirAll(plainDir, 0755))
+	require.False(t, looksLikeGitRepository(plainDir))
+}
@@ -0,0 +1,67 @@
+// Package license classifies license texts into short SPDX-style
+// identifiers. It recognizes the handful of licenses that cover the
+// vast majority of GitLab projects by matching characteristic phrases,
+// which is cheap enough to run on every project page load.
+package license
+
+import (
+	"regexp"
+	"strings"
+)
+
+// rule maps characteristic phrases to a license identifier. All phrases
+// must occur in the normalized text for the rule to match; rules are
+// tried in order, so more specific licenses come first.
+type rule struct {
+	id      string
+	phrases []string
+}
+
+var rules = []rule{
+	{"agpl-3.0", []string{"gnu affero general public license"}},
+	{"lgpl-3.0", []string{"gnu lesser general public license", "version 3"}},
+	{"lgpl-2.1", []string{"gnu lesser general public license", "version 2.1"}},
+	{"gpl-3.0", []string{"gnu general public license", "version 3"}},
+	{"gpl-2.0", []string{"gnu general public license", "version 2"}},
+	{"apache-2.0", []string{"apache license", "version 2.0"}},
+	{"mpl-2.0", []string{"mozilla public license", "2.0"}},
+	{"unlicense", []string{"this is free and unencumbered software released into the public domain"}},
+	{"isc", []string{"permission to use, copy, modify, and/or distribute this software for any purpose with or without fee"}},
+	{"mit", []string{"permission is hereby granted, free of charge, to any person obtaining a copy"}},
+	{"bsd-3-clause", []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"bsd-2-clause", []string{"redistribution and use in source and binary forms"}},
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// Identify returns the short identifier of the license text, or the
+// empty string when the license is not recognized.
+func Identify(text []byte) string {
+	normalized := whitespace.ReplaceAllString(strings.ToLower(string(text)), " ")
+
+	for _, r := range rules {
+		if matchesAll(normalized, r.phrases) {
+			return r.id
+		}
+	}
+
+	return ""
+}
+
+func matchesAll(text string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if !strings.Contains(text, phrase) {
+			return false
+		}
+	}
+
+	return true
+}
+
+var fileNamePattern = regexp.MustCompile(`(?i)^((un)?licen[sc]e|copying|mit-licen[sc]e)(\.(md|txt|rst))?$`)
+
+// IsLicenseFile reports whether a root-level file name looks like a
+// license file.
+func IsLicenseFile(name string) bool {
+	return fileNamePattern.MatchString(name)
+}
@@ -0,0 +1,87 @@
+package license
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentify(t *testing.T) {
+	testCases := []struct {
+		desc string
+		text string
+		id   string
+	}{
+		{
+			desc: "MIT",
+			text: "MIT License\n\nCopyright (c) 2018\n\nPermission is hereby granted, free of charge, to any person obtaining a copy of this software...",
+			id:   "mit",
+		},
+		{
+			desc: "Apache 2.0",
+			text: "Apache License\nVersion 2.0, January 2004\nhttp://www.apache.org/licenses/",
+			id:   "apache-2.0",
+		},
+		{
+			desc: "GPLv3",
+			text: "GNU GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007",
+			id:   "gpl-3.0",
+		},
+		{
+			desc: "GPLv2",
+			text: "GNU GENERAL PUBLIC LICENSE\nVersion 2, June 1991",
+			id:   "gpl-2.0",
+		},
+		{
+			desc: "LGPLv2.1",
+			text: "GNU LESSER GENERAL PUBLIC LICENSE\nVersion 2.1, February 1999",
+			id:   "lgpl-2.1",
+		},
+		{
+			desc: "AGPLv3",
+			text: "GNU AFFERO GENERAL PUBLIC LICENSE\nVersion 3, 19 November 2007",
+			id:   "agpl-3.0",
+		},
+		{
+			desc: "ISC",
+			text: "Permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted...",
+			id:   "isc",
+		},
+		{
+			desc: "BSD 3-clause",
+			text: "Redistribution and use in source and binary forms, with or without modification, are permitted provided that...\nNeither the name of the copyright holder...",
+			id:   "bsd-3-clause",
+		},
+		{
+			desc: "BSD 2-clause",
+			text: "Redistribution and use in source and binary forms, with or without modification, are permitted provided that...",
+			id:   "bsd-2-clause",
+		},
+		{
+			desc: "Unlicense",
+			text: "This is free and unencumbered software released into the public domain.",
+			id:   "unlicense",
+		},
+		{
+			desc: "unknown",
+			text: "You may do whatever you like with this code.",
+			id:   "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.id, Identify([]byte(tc.text)))
+		})
+	}
+}
+
+func TestIsLicenseFile(t *testing.T) {
+	for _, name := range []string{"LICENSE", "License.md", "LICENCE.txt", "COPYING", "UNLICENSE", "MIT-LICENSE"} {
+		require.True(t, IsLicenseFile(name), name)
+	}
+
+	for _, name := range []string{"README.md", "license.go", "docs/LICENSE", "LICENSES"} {
+		require.False(t, IsLicenseFile(name), name)
+	}
+}
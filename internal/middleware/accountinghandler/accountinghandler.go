@@ -0,0 +1,85 @@
+package accountinghandler
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// Trailer keys under which per-request resource usage is reported to the
+// client.
+const (
+	// GitProcessesKey reports the number of git processes spawned.
+	GitProcessesKey = "gitaly-git-processes"
+	// CPUTimeKey reports the CPU time (system plus user) consumed by those
+	// processes, in milliseconds.
+	CPUTimeKey = "gitaly-cpu-ms"
+	// BytesStreamedKey reports the serialized size of all response messages.
+	BytesStreamedKey = "gitaly-bytes-streamed"
+)
+
+// Unary is a unary server interceptor that reports per-request resource
+// usage in the response trailers.
+func Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, stats := command.InitContextStats(ctx)
+
+	resp, err := handler(ctx, req)
+
+	var bytesSent int64
+	if msg, ok := resp.(proto.Message); ok && msg != nil {
+		bytesSent = int64(proto.Size(msg))
+	}
+
+	grpc.SetTrailer(ctx, usageTrailer(stats, bytesSent))
+
+	return resp, err
+}
+
+// Stream is a stream server interceptor that reports per-request resource
+// usage in the response trailers.
+func Stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, stats := command.InitContextStats(stream.Context())
+	wrapped := &countingServerStream{ServerStream: stream, ctx: ctx}
+
+	err := handler(srv, wrapped)
+
+	stream.SetTrailer(usageTrailer(stats, atomic.LoadInt64(&wrapped.bytesSent)))
+
+	return err
+}
+
+func usageTrailer(stats *command.Stats, bytesSent int64) metadata.MD {
+	spawns, cpuTime := stats.Totals()
+
+	return metadata.Pairs(
+		GitProcessesKey, strconv.Itoa(spawns),
+		CPUTimeKey, strconv.FormatInt(int64(cpuTime.Seconds()*1000), 10),
+		BytesStreamedKey, strconv.FormatInt(bytesSent, 10),
+	)
+}
+
+// countingServerStream wraps a grpc.ServerStream to count the serialized
+// size of all messages sent on it.
+type countingServerStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	bytesSent int64
+}
+
+func (css *countingServerStream) Context() context.Context {
+	return css.ctx
+}
+
+func (css *countingServerStream) SendMsg(m interface{}) error {
+	if msg, ok := m.(proto.Message); ok {
+		atomic.AddInt64(&css.bytesSent, int64(proto.Size(msg)))
+	}
+
+	return css.ServerStream.SendMsg(m)
+}
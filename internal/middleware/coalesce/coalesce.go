@@ -0,0 +1,164 @@
+// Package coalesce implements single-flight coalescing of identical
+// concurrent read RPCs: when many callers issue the same method against
+// the same repository with the same request at once — a CI fan-out
+// hitting FindDefaultBranchName, say — only one invocation runs git and
+// the others receive a copy of its result.
+//
+// Only unary RPCs on the configured allowlist are coalesced. Streaming
+// reads are not; the hot streaming case, InfoRefs, is covered by the
+// info-refs cache instead.
+package coalesce
+
+import (
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	coalescedRPCs map[string]bool
+
+	requestsCounter = prom.NewCounterVec(
+		prom.CounterOpts{
+			Namespace: "gitaly",
+			Subsystem: "coalesce",
+			Name:      "requests_total",
+			Help:      "Counter of coalescable requests, partitioned by whether they led or shared an invocation",
+		},
+		[]string{"result"},
+	)
+)
+
+func init() {
+	prom.MustRegister(requestsCounter)
+}
+
+// SetCoalescedRPCs configures which full method names are eligible for
+// coalescing. Only safe for read-only RPCs.
+func SetCoalescedRPCs(rpcs []string) {
+	config := make(map[string]bool)
+	for _, rpc := range rpcs {
+		config[rpc] = true
+	}
+	coalescedRPCs = config
+}
+
+// call is one in-flight invocation that followers can attach to.
+type call struct {
+	done chan struct{}
+	resp interface{}
+	err  error
+}
+
+// Middleware holds the in-flight call table.
+type Middleware struct {
+	mu       sync.Mutex
+	inflight map[string]*call
+}
+
+// New creates a new coalescing middleware.
+func New() *Middleware {
+	return &Middleware{inflight: make(map[string]*call)}
+}
+
+// requestKey normalizes a request into a coalescing key by serializing
+// the protobuf message. Identical requests serialize identically since
+// they are built from the same generated struct.
+func requestKey(fullMethod string, req interface{}) (string, bool) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return "", false
+	}
+
+	raw, err := proto.Marshal(msg)
+	if err != nil {
+		return "", false
+	}
+
+	return fullMethod + "\x00" + string(raw), true
+}
+
+// join returns the in-flight call for key, creating it if there is none.
+// The boolean indicates whether the caller is the leader who must run
+// the handler.
+func (m *Middleware) join(key string) (*call, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.inflight[key]; ok {
+		return c, false
+	}
+
+	c := &call{done: make(chan struct{})}
+	m.inflight[key] = c
+	return c, true
+}
+
+func (m *Middleware) finish(key string, c *call) {
+	m.mu.Lock()
+	delete(m.inflight, key)
+	m.mu.Unlock()
+
+	close(c.done)
+}
+
+// canceled reports whether an error is the leader's own context expiring
+// rather than a result the followers can share.
+func canceled(err error) bool {
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Canceled, codes.DeadlineExceeded:
+		return true
+	}
+
+	return false
+}
+
+// UnaryInterceptor returns a unary interceptor that coalesces identical
+// concurrent requests to the configured RPCs.
+func (m *Middleware) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !coalescedRPCs[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		key, ok := requestKey(info.FullMethod, req)
+		if !ok {
+			return handler(ctx, req)
+		}
+
+		c, leader := m.join(key)
+		if !leader {
+			requestsCounter.WithLabelValues("shared").Inc()
+
+			select {
+			case <-c.done:
+				if canceled(c.err) {
+					// The leader's client went away; its context error says
+					// nothing about this request. Run the handler ourselves
+					// under our own, still healthy, context.
+					requestsCounter.WithLabelValues("retried").Inc()
+					return handler(ctx, req)
+				}
+
+				return c.resp, c.err
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		requestsCounter.WithLabelValues("leader").Inc()
+		defer m.finish(key, c)
+
+		c.resp, c.err = handler(ctx, req)
+		return c.resp, c.err
+	}
+}
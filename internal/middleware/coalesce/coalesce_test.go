@@ -0,0 +1,162 @@
+package coalesce
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+)
+
+const testMethod = "/test.Service/Method"
+
+func testUnaryInfo() *grpc.UnaryServerInfo {
+	return &grpc.UnaryServerInfo{FullMethod: testMethod}
+}
+
+func TestCoalescesIdenticalRequests(t *testing.T) {
+	SetCoalescedRPCs([]string{testMethod})
+	defer SetCoalescedRPCs(nil)
+
+	var invocations int64
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt64(&invocations, 1)
+		time.Sleep(100 * time.Millisecond)
+		return "response", nil
+	}
+
+	interceptor := New().UnaryInterceptor()
+	req := &pb.FindDefaultBranchNameRequest{
+		Repository: &pb.Repository{StorageName: "default", RelativePath: "foo.git"},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			resp, err := interceptor(context.Background(), req, testUnaryInfo(), handler)
+			require.NoError(t, err)
+			require.Equal(t, "response", resp)
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(1), atomic.LoadInt64(&invocations))
+}
+
+func TestDifferentRequestsNotCoalesced(t *testing.T) {
+	SetCoalescedRPCs([]string{testMethod})
+	defer SetCoalescedRPCs(nil)
+
+	var invocations int64
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt64(&invocations, 1)
+		time.Sleep(50 * time.Millisecond)
+		return nil, nil
+	}
+
+	interceptor := New().UnaryInterceptor()
+
+	var wg sync.WaitGroup
+	for _, relativePath := range []string{"foo.git", "bar.git"} {
+		req := &pb.FindDefaultBranchNameRequest{
+			Repository: &pb.Repository{StorageName: "default", RelativePath: relativePath},
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := interceptor(context.Background(), req, testUnaryInfo(), handler)
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(2), atomic.LoadInt64(&invocations))
+}
+
+func TestLeaderCancellationNotInheritedByFollowers(t *testing.T) {
+	SetCoalescedRPCs([]string{testMethod})
+	defer SetCoalescedRPCs(nil)
+
+	leaderStarted := make(chan struct{})
+	var invocations int64
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		if atomic.AddInt64(&invocations, 1) == 1 {
+			close(leaderStarted)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+
+		return "response", nil
+	}
+
+	interceptor := New().UnaryInterceptor()
+	req := &pb.FindDefaultBranchNameRequest{
+		Repository: &pb.Repository{StorageName: "default", RelativePath: "foo.git"},
+	}
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		_, err := interceptor(leaderCtx, req, testUnaryInfo(), handler)
+		require.Equal(t, context.Canceled, err)
+	}()
+
+	<-leaderStarted
+	followerDone := make(chan struct{})
+	go func() {
+		defer close(followerDone)
+		resp, err := interceptor(context.Background(), req, testUnaryInfo(), handler)
+		require.NoError(t, err, "the leader's cancellation must not fail the follower")
+		require.Equal(t, "response", resp)
+	}()
+
+	// Give the follower time to attach to the in-flight call, then
+	// abort the leader's client.
+	time.Sleep(50 * time.Millisecond)
+	cancelLeader()
+
+	<-leaderDone
+	<-followerDone
+	require.Equal(t, int64(2), atomic.LoadInt64(&invocations), "the follower re-ran the handler itself")
+}
+
+func TestUnlistedMethodNotCoalesced(t *testing.T) {
+	SetCoalescedRPCs(nil)
+
+	var invocations int64
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt64(&invocations, 1)
+		time.Sleep(50 * time.Millisecond)
+		return nil, nil
+	}
+
+	interceptor := New().UnaryInterceptor()
+	req := &pb.FindDefaultBranchNameRequest{
+		Repository: &pb.Repository{StorageName: "default", RelativePath: "foo.git"},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := interceptor(context.Background(), req, testUnaryInfo(), handler)
+			require.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, int64(2), atomic.LoadInt64(&invocations))
+}
@@ -0,0 +1,106 @@
+// Package glcheck optionally enforces that mutator RPCs carry the
+// GitLab identity fields of their target repository. GlRepository and
+// GlProjectPath are formally optional in the protocol, but hooks and
+// audit callbacks silently misbehave when they are missing; in strict
+// mode such requests are rejected with a clear error instead.
+package glcheck
+
+import (
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/repoblock"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var strict bool
+
+// SetStrict enables or disables strict validation. It is called once
+// during startup, before the server accepts requests.
+func SetStrict(enabled bool) {
+	strict = enabled
+}
+
+type repositoryRequest interface {
+	GetRepository() *pb.Repository
+}
+
+// glRepositoryRequest covers ReceivePack-style requests that carry
+// GL_REPOSITORY as a request field instead of on the repository.
+type glRepositoryRequest interface {
+	GetGlRepository() string
+}
+
+// check returns the error to fail the RPC with, or nil when the request
+// may proceed.
+func check(fullMethod string, req interface{}) error {
+	if !strict || !repoblock.IsMutator(fullMethod) {
+		return nil
+	}
+
+	repoReq, ok := req.(repositoryRequest)
+	if !ok {
+		return nil
+	}
+
+	repo := repoReq.GetRepository()
+	if repo == nil {
+		return nil
+	}
+
+	glRepository := repo.GetGlRepository()
+	if glRepository == "" {
+		if r, ok := req.(glRepositoryRequest); ok {
+			glRepository = r.GetGlRepository()
+		}
+	}
+
+	if glRepository == "" {
+		return status.Errorf(codes.InvalidArgument, "%s: empty GlRepository", fullMethod)
+	}
+
+	if repo.GetGlProjectPath() == "" {
+		return status.Errorf(codes.InvalidArgument, "%s: empty Repository.GlProjectPath", fullMethod)
+	}
+
+	return nil
+}
+
+// Unary is a grpc server interceptor validating mutator requests.
+func Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := check(info.FullMethod, req); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// Stream is a grpc server interceptor validating mutator streams. The
+// target repository is only known once the handler reads the first
+// request, so the check happens in RecvMsg.
+func Stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	wrapper := &recvWrapper{ServerStream: stream, fullMethod: info.FullMethod}
+	return handler(srv, wrapper)
+}
+
+type recvWrapper struct {
+	grpc.ServerStream
+	fullMethod string
+	checked    bool
+}
+
+func (s *recvWrapper) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if s.checked {
+		return nil
+	}
+	s.checked = true
+
+	return check(s.fullMethod, m)
+}
@@ -0,0 +1,90 @@
+package glcheck
+
+import (
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheckStrictFields(t *testing.T) {
+	defer SetStrict(false)
+	SetStrict(true)
+
+	fullRepo := &pb.Repository{
+		StorageName:   "default",
+		RelativePath:  "@hashed/ab/cd/abcd.git",
+		GlRepository:  "project-1",
+		GlProjectPath: "gitlab-org/gitaly",
+	}
+
+	testCases := []struct {
+		desc       string
+		fullMethod string
+		req        interface{}
+		code       codes.Code
+	}{
+		{
+			desc:       "mutator with all fields",
+			fullMethod: "/gitaly.RepositoryService/WriteRef",
+			req:        &pb.WriteRefRequest{Repository: fullRepo},
+			code:       codes.OK,
+		},
+		{
+			desc:       "mutator missing GlRepository",
+			fullMethod: "/gitaly.RepositoryService/WriteRef",
+			req: &pb.WriteRefRequest{
+				Repository: &pb.Repository{StorageName: "default", RelativePath: "repo.git", GlProjectPath: "gitlab-org/gitaly"},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc:       "mutator missing GlProjectPath",
+			fullMethod: "/gitaly.RepositoryService/WriteRef",
+			req: &pb.WriteRefRequest{
+				Repository: &pb.Repository{StorageName: "default", RelativePath: "repo.git", GlRepository: "project-1"},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc:       "read without fields",
+			fullMethod: "/gitaly.RefService/FindDefaultBranchName",
+			req: &pb.FindDefaultBranchNameRequest{
+				Repository: &pb.Repository{StorageName: "default", RelativePath: "repo.git"},
+			},
+			code: codes.OK,
+		},
+		{
+			desc:       "receive-pack carries GlRepository on the request",
+			fullMethod: "/gitaly.SmartHTTPService/PostReceivePack",
+			req: &pb.PostReceivePackRequest{
+				Repository:   &pb.Repository{StorageName: "default", RelativePath: "repo.git", GlProjectPath: "gitlab-org/gitaly"},
+				GlRepository: "project-1",
+			},
+			code: codes.OK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := check(tc.fullMethod, tc.req)
+			if tc.code == codes.OK {
+				require.NoError(t, err)
+			} else {
+				require.Equal(t, tc.code, status.Code(err))
+			}
+		})
+	}
+}
+
+func TestCheckDisabled(t *testing.T) {
+	SetStrict(false)
+
+	err := check("/gitaly.RepositoryService/WriteRef", &pb.WriteRefRequest{
+		Repository: &pb.Repository{StorageName: "default", RelativePath: "repo.git"},
+	})
+	require.NoError(t, err)
+}
@@ -8,14 +8,18 @@ import (
 
 // LimiterMiddleware contains rate limiter state
 type LimiterMiddleware struct {
-	methodLimiters map[string]*ConcurrencyLimiter
+	methodLimiters  map[string]*ConcurrencyLimiter
+	storageLimiters map[string]*ConcurrencyLimiter
 }
 
-var maxConcurrencyPerRepoPerRPC map[string]int
+var (
+	maxConcurrencyPerRepoPerRPC map[string]int
+	maxConcurrencyPerStorage    map[string]int
+)
 
-func getRepoPath(ctx context.Context) string {
+func getCtxTag(ctx context.Context, tag string) string {
 	tags := grpc_ctxtags.Extract(ctx)
-	ctxValue := tags.Values()["grpc.request.repoPath"]
+	ctxValue := tags.Values()[tag]
 	if ctxValue == nil {
 		return ""
 	}
@@ -28,22 +32,52 @@ func getRepoPath(ctx context.Context) string {
 	return ""
 }
 
+func getRepoPath(ctx context.Context) string {
+	return getCtxTag(ctx, "grpc.request.repoPath")
+}
+
+func getStorageName(ctx context.Context) string {
+	return getCtxTag(ctx, "grpc.request.repoStorage")
+}
+
+// limitStorage applies the concurrency limit of the storage the request
+// operates on, if one is configured, before running f. Unlike the
+// per-repo method limiters it caps the total number of limited requests
+// in flight against the storage, so a slow storage cannot starve the
+// rest of the node.
+func (c *LimiterMiddleware) limitStorage(ctx context.Context, f LimitedFunc) (interface{}, error) {
+	storageName := getStorageName(ctx)
+
+	limiter := c.storageLimiters[storageName]
+	if limiter == nil {
+		return f()
+	}
+
+	return limiter.Limit(ctx, storageName, f)
+}
+
 // UnaryInterceptor returns a Unary Interceptor
 func (c *LimiterMiddleware) UnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := rejectUnderPressure(info.FullMethod); err != nil {
+			return nil, err
+		}
+
 		repoPath := getRepoPath(ctx)
 		if repoPath == "" {
 			return handler(ctx, req)
 		}
 
-		limiter := c.methodLimiters[info.FullMethod]
-		if limiter == nil {
-			// No concurrency limiting
-			return handler(ctx, req)
-		}
+		return c.limitStorage(ctx, func() (interface{}, error) {
+			limiter := c.methodLimiters[info.FullMethod]
+			if limiter == nil {
+				// No concurrency limiting
+				return handler(ctx, req)
+			}
 
-		return limiter.Limit(ctx, repoPath, func() (interface{}, error) {
-			return handler(ctx, req)
+			return limiter.Limit(ctx, repoPath, func() (interface{}, error) {
+				return handler(ctx, req)
+			})
 		})
 	}
 }
@@ -51,6 +85,10 @@ func (c *LimiterMiddleware) UnaryInterceptor() grpc.UnaryServerInterceptor {
 // StreamInterceptor returns a Stream Interceptor
 func (c *LimiterMiddleware) StreamInterceptor() grpc.StreamServerInterceptor {
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := rejectUnderPressure(info.FullMethod); err != nil {
+			return err
+		}
+
 		ctx := stream.Context()
 
 		repoPath := getRepoPath(ctx)
@@ -58,14 +96,16 @@ func (c *LimiterMiddleware) StreamInterceptor() grpc.StreamServerInterceptor {
 			return handler(srv, stream)
 		}
 
-		limiter := c.methodLimiters[info.FullMethod]
-		if limiter == nil {
-			// No concurrency limiting
-			return handler(srv, stream)
-		}
+		_, err := c.limitStorage(ctx, func() (interface{}, error) {
+			limiter := c.methodLimiters[info.FullMethod]
+			if limiter == nil {
+				// No concurrency limiting
+				return nil, handler(srv, stream)
+			}
 
-		_, err := limiter.Limit(ctx, repoPath, func() (interface{}, error) {
-			return nil, handler(srv, stream)
+			return limiter.Limit(ctx, repoPath, func() (interface{}, error) {
+				return nil, handler(srv, stream)
+			})
 		})
 
 		return err
@@ -75,7 +115,8 @@ func (c *LimiterMiddleware) StreamInterceptor() grpc.StreamServerInterceptor {
 // New creates a new rate limiter
 func New() LimiterMiddleware {
 	return LimiterMiddleware{
-		methodLimiters: createLimiterConfig(),
+		methodLimiters:  createLimiterConfig(),
+		storageLimiters: createStorageLimiterConfig(),
 	}
 }
 
@@ -89,7 +130,22 @@ func createLimiterConfig() map[string]*ConcurrencyLimiter {
 	return result
 }
 
+func createStorageLimiterConfig() map[string]*ConcurrencyLimiter {
+	result := make(map[string]*ConcurrencyLimiter)
+
+	for storageName, max := range maxConcurrencyPerStorage {
+		result[storageName] = NewLimiter(max, newStoragePromMonitor(storageName))
+	}
+
+	return result
+}
+
 // SetMaxRepoConcurrency Configures the max concurrency per repo per RPC
 func SetMaxRepoConcurrency(config map[string]int) {
 	maxConcurrencyPerRepoPerRPC = config
 }
+
+// SetMaxStorageConcurrency configures the max concurrency per storage
+func SetMaxStorageConcurrency(config map[string]int) {
+	maxConcurrencyPerStorage = config
+}
@@ -0,0 +1,210 @@
+package limithandler
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MemoryWatchdogConfig configures the memory pressure watchdog.
+type MemoryWatchdogConfig struct {
+	// CriticalUtilization is the fraction of available memory above which
+	// heavy RPCs are rejected.
+	CriticalUtilization float64
+	Interval            time.Duration
+	// HeavyRPCs lists full gRPC method names that are rejected while the
+	// process is under memory pressure.
+	HeavyRPCs []string
+}
+
+const (
+	cgroupMemoryUsageFile = "/sys/fs/cgroup/memory/memory.usage_in_bytes"
+	cgroupMemoryLimitFile = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
+	procSelfStatusFile    = "/proc/self/status"
+	procMeminfoFile       = "/proc/meminfo"
+)
+
+var (
+	watchdogHeavyRPCs     map[string]bool
+	watchdogUnderPressure int32 // accessed atomically; 1 while under pressure
+
+	underPressureGauge = prom.NewGauge(prom.GaugeOpts{
+		Namespace: "gitaly",
+		Subsystem: "memory_watchdog",
+		Name:      "under_pressure",
+		Help:      "1 while memory utilization exceeds the critical threshold, 0 otherwise",
+	})
+
+	rejectedCounterVec = prom.NewCounterVec(
+		prom.CounterOpts{
+			Namespace: "gitaly",
+			Subsystem: "memory_watchdog",
+			Name:      "rejected_total",
+			Help:      "Number of RPCs rejected because of memory pressure",
+		},
+		[]string{"grpc_service", "grpc_method"},
+	)
+)
+
+func init() {
+	prom.MustRegister(underPressureGauge, rejectedCounterVec)
+}
+
+// StartMemoryWatchdog starts a goroutine that periodically samples memory
+// utilization and flips the limiter into rejection mode for heavy RPCs
+// while utilization stays above the critical threshold. The watchdog runs
+// for the lifetime of the process.
+func StartMemoryWatchdog(cfg MemoryWatchdogConfig) {
+	if cfg.CriticalUtilization <= 0 || cfg.CriticalUtilization > 1 {
+		cfg.CriticalUtilization = 0.9
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 15 * time.Second
+	}
+
+	watchdogHeavyRPCs = make(map[string]bool)
+	for _, rpc := range cfg.HeavyRPCs {
+		watchdogHeavyRPCs[rpc] = true
+	}
+
+	go func() {
+		for range time.Tick(cfg.Interval) {
+			utilization, err := memoryUtilization()
+			if err != nil {
+				log.WithError(err).Warn("memory watchdog: cannot sample memory usage")
+				continue
+			}
+
+			underPressure := utilization >= cfg.CriticalUtilization
+			if underPressure != (atomic.LoadInt32(&watchdogUnderPressure) == 1) {
+				log.WithFields(log.Fields{
+					"memory_utilization":   utilization,
+					"critical_utilization": cfg.CriticalUtilization,
+					"under_pressure":       underPressure,
+				}).Warn("memory watchdog: pressure state changed")
+			}
+
+			if underPressure {
+				atomic.StoreInt32(&watchdogUnderPressure, 1)
+				underPressureGauge.Set(1)
+			} else {
+				atomic.StoreInt32(&watchdogUnderPressure, 0)
+				underPressureGauge.Set(0)
+			}
+		}
+	}()
+}
+
+// rejectUnderPressure returns a ResourceExhausted error when fullMethod
+// is a heavy RPC and the process is under memory pressure, nil otherwise.
+func rejectUnderPressure(fullMethod string) error {
+	if atomic.LoadInt32(&watchdogUnderPressure) == 0 || !watchdogHeavyRPCs[fullMethod] {
+		return nil
+	}
+
+	serviceName, methodName := splitMethodName(fullMethod)
+	rejectedCounterVec.WithLabelValues(serviceName, methodName).Inc()
+
+	return status.Errorf(codes.ResourceExhausted, "%s: temporarily unavailable due to memory pressure", fullMethod)
+}
+
+// memoryUtilization returns memory usage as a fraction of the applicable
+// limit. It prefers cgroup accounting and falls back to process RSS
+// against total host memory when no effective cgroup limit is set.
+func memoryUtilization() (float64, error) {
+	if usage, limit, err := cgroupMemory(cgroupMemoryUsageFile, cgroupMemoryLimitFile); err == nil {
+		return float64(usage) / float64(limit), nil
+	}
+
+	rss, err := processRSS(procSelfStatusFile)
+	if err != nil {
+		return 0, err
+	}
+
+	total, err := totalMemory(procMeminfoFile)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(rss) / float64(total), nil
+}
+
+// cgroupMemory reads usage and limit from the memory cgroup. An
+// unrestricted cgroup (limit close to the maximum value) is reported as
+// an error so the caller falls back to host accounting.
+func cgroupMemory(usageFile, limitFile string) (uint64, uint64, error) {
+	usage, err := readUintFile(usageFile)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	limit, err := readUintFile(limitFile)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if limit == 0 || limit > uint64(1)<<60 {
+		return 0, 0, fmt.Errorf("no effective cgroup memory limit")
+	}
+
+	return usage, limit, nil
+}
+
+// processRSS returns the resident set size in bytes, parsed from the
+// VmRSS line of /proc/<pid>/status.
+func processRSS(statusFile string) (uint64, error) {
+	return readProcStatusKB(statusFile, "VmRSS:")
+}
+
+// totalMemory returns the host memory size in bytes, parsed from the
+// MemTotal line of /proc/meminfo.
+func totalMemory(meminfoFile string) (uint64, error) {
+	return readProcStatusKB(meminfoFile, "MemTotal:")
+}
+
+// readProcStatusKB finds a "<key> <value> kB" line and returns the value
+// in bytes.
+func readProcStatusKB(file, key string) (uint64, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if !strings.HasPrefix(line, key) {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			break
+		}
+
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		return kb * 1024, nil
+	}
+
+	return 0, fmt.Errorf("no %q line in %s", key, file)
+}
+
+func readUintFile(file string) (uint64, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+}
@@ -0,0 +1,78 @@
+package limithandler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReadProcStatusKB(t *testing.T) {
+	statusFile := writeTempFile(t, "Name:\tgitaly\nVmPeak:\t  999 kB\nVmRSS:\t  1024 kB\n")
+	defer os.Remove(statusFile)
+
+	rss, err := processRSS(statusFile)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1024*1024), rss)
+
+	_, err = totalMemory(statusFile)
+	require.Error(t, err, "MemTotal is not present in a status file")
+}
+
+func TestCgroupMemory(t *testing.T) {
+	usageFile := writeTempFile(t, "524288000\n")
+	defer os.Remove(usageFile)
+
+	limitFile := writeTempFile(t, "1048576000\n")
+	defer os.Remove(limitFile)
+
+	usage, limit, err := cgroupMemory(usageFile, limitFile)
+	require.NoError(t, err)
+	require.Equal(t, uint64(524288000), usage)
+	require.Equal(t, uint64(1048576000), limit)
+
+	// An unrestricted cgroup reports a huge limit; that must not be
+	// treated as a real limit.
+	unlimitedFile := writeTempFile(t, "9223372036854771712\n")
+	defer os.Remove(unlimitedFile)
+
+	_, _, err = cgroupMemory(usageFile, unlimitedFile)
+	require.Error(t, err)
+}
+
+func TestRejectUnderPressure(t *testing.T) {
+	heavyRPC := "/gitaly.SSHService/SSHUploadPack"
+
+	oldHeavyRPCs := watchdogHeavyRPCs
+	watchdogHeavyRPCs = map[string]bool{heavyRPC: true}
+	defer func() {
+		watchdogHeavyRPCs = oldHeavyRPCs
+		atomic.StoreInt32(&watchdogUnderPressure, 0)
+	}()
+
+	require.NoError(t, rejectUnderPressure(heavyRPC), "no pressure: heavy RPC passes")
+
+	atomic.StoreInt32(&watchdogUnderPressure, 1)
+
+	require.NoError(t, rejectUnderPressure("/gitaly.RefService/FindDefaultBranchName"), "light RPC passes under pressure")
+
+	err := rejectUnderPressure(heavyRPC)
+	require.Error(t, err)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func writeTempFile(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "memory-watchdog-test")
+	require.NoError(t, err)
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	require.NoError(t, err)
+
+	return filepath.Join(file.Name())
+}
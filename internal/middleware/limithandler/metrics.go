@@ -34,6 +34,26 @@ var (
 		},
 		[]string{"grpc_service", "grpc_method"},
 	)
+
+	storageInprogressGaugeVec = prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Namespace: "gitaly",
+			Subsystem: "rate_limiting",
+			Name:      "storage_in_progress",
+			Help:      "Gauge of number of concurrent invocations currently in progress for this storage",
+		},
+		[]string{"storage"},
+	)
+
+	storageQueuedGaugeVec = prom.NewGaugeVec(
+		prom.GaugeOpts{
+			Namespace: "gitaly",
+			Subsystem: "rate_limiting",
+			Name:      "storage_queued",
+			Help:      "Gauge of number of invocations currently queued for this storage",
+		},
+		[]string{"storage"},
+	)
 )
 
 type promMonitor struct {
@@ -43,7 +63,7 @@ type promMonitor struct {
 }
 
 func init() {
-	prom.MustRegister(inprogressGaugeVec, queuedGaugeVec)
+	prom.MustRegister(inprogressGaugeVec, queuedGaugeVec, storageInprogressGaugeVec, storageQueuedGaugeVec)
 }
 
 func splitMethodName(fullMethodName string) (string, string) {
@@ -111,3 +131,10 @@ func newPromMonitor(fullMethod string) ConcurrencyMonitor {
 
 	return &promMonitor{queuedGauge, inprogressGauge, histogram}
 }
+
+func newStoragePromMonitor(storageName string) ConcurrencyMonitor {
+	queuedGauge := storageQueuedGaugeVec.WithLabelValues(storageName)
+	inprogressGauge := storageInprogressGaugeVec.WithLabelValues(storageName)
+
+	return &promMonitor{queuedGauge, inprogressGauge, nil}
+}
@@ -31,6 +31,9 @@ const CallSiteKey = "grpc.meta.call_site"
 // ClientNameKey is the key used in ctx_tags to store the client name
 const ClientNameKey = "grpc.meta.client_name"
 
+// CorrelationIDKey is the key used in ctx_tags to store the correlation ID
+const CorrelationIDKey = "grpc.meta.correlation_id"
+
 // Unknown client and feature. Matches the prometheus grpc unknown value
 const unknownValue = "unknown"
 
@@ -69,6 +72,11 @@ func addMetadataTags(ctx context.Context) (clientName string, callSite string) {
 		tags.Set(ClientNameKey, metadata)
 	}
 
+	metadata = getFromMD(md, "correlation_id")
+	if metadata != "" {
+		tags.Set(CorrelationIDKey, metadata)
+	}
+
 	return clientName, callSite
 }
 
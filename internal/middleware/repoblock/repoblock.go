@@ -0,0 +1,184 @@
+// Package repoblock implements a per-repository emergency circuit
+// breaker. A blocked repository refuses all RPCs — or only mutators —
+// with a custom message, which buys time while the repository is being
+// repaired or is causing incident-level load.
+//
+// Blocks live in process memory and can be seeded from config for
+// restarts; the ServerService admin RPCs flip them at runtime. RPCs on
+// ServerService itself are never blocked so an operator can always
+// reach UnblockRepository.
+package repoblock
+
+import (
+	"strings"
+	"sync"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Block describes why and how a repository is blocked.
+type Block struct {
+	// Message is returned to clients hitting the block
+	Message string
+	// MutatorsOnly leaves read RPCs unaffected
+	MutatorsOnly bool
+}
+
+var (
+	blocksLock sync.RWMutex
+	blocks     = make(map[string]Block)
+)
+
+// mutatorRPCs lists the non-OperationService RPCs that write to a
+// repository. All of OperationService mutates and is matched by prefix
+// instead.
+var mutatorRPCs = map[string]bool{
+	"/gitaly.RepositoryService/RepackIncremental":          true,
+	"/gitaly.RepositoryService/RepackFull":                 true,
+	"/gitaly.RepositoryService/GarbageCollect":             true,
+	"/gitaly.RepositoryService/ApplyGitattributes":         true,
+	"/gitaly.RepositoryService/FetchRemote":                true,
+	"/gitaly.RepositoryService/CreateRepository":           true,
+	"/gitaly.RepositoryService/FetchSourceBranch":          true,
+	"/gitaly.RepositoryService/WriteRef":                   true,
+	"/gitaly.RepositoryService/CreateFork":                 true,
+	"/gitaly.RepositoryService/WriteConfig":                true,
+	"/gitaly.RepositoryService/WriteMergeRequestRefs":      true,
+	"/gitaly.RepositoryService/CreateRepositoryFromBundle": true,
+	"/gitaly.RepositoryService/CreateRepositoryFromURL":    true,
+	"/gitaly.RemoteService/AddRemote":                      true,
+	"/gitaly.RemoteService/FetchInternalRemote":            true,
+	"/gitaly.RemoteService/RemoveRemote":                   true,
+	"/gitaly.RemoteService/PushRemote":                     true,
+	"/gitaly.RemoteService/UpdateRemoteMirror":             true,
+	"/gitaly.RefService/CreateBranch":                      true,
+	"/gitaly.RefService/DeleteBranch":                      true,
+	"/gitaly.RefService/DeleteRefs":                        true,
+	"/gitaly.WikiService/WikiWritePage":                    true,
+	"/gitaly.WikiService/WikiUpdatePage":                   true,
+	"/gitaly.WikiService/WikiDeletePage":                   true,
+	"/gitaly.ConflictsService/ResolveConflicts":            true,
+	"/gitaly.SmartHTTPService/PostReceivePack":             true,
+	"/gitaly.SSHService/SSHReceivePack":                    true,
+}
+
+func key(storageName, relativePath string) string {
+	return storageName + "\x00" + relativePath
+}
+
+// SetBlock blocks a repository. An existing block is replaced.
+func SetBlock(storageName, relativePath string, block Block) {
+	blocksLock.Lock()
+	defer blocksLock.Unlock()
+
+	blocks[key(storageName, relativePath)] = block
+}
+
+// RemoveBlock unblocks a repository and reports whether it was blocked.
+func RemoveBlock(storageName, relativePath string) bool {
+	blocksLock.Lock()
+	defer blocksLock.Unlock()
+
+	k := key(storageName, relativePath)
+	_, wasBlocked := blocks[k]
+	delete(blocks, k)
+
+	return wasBlocked
+}
+
+func lookup(storageName, relativePath string) (Block, bool) {
+	blocksLock.RLock()
+	defer blocksLock.RUnlock()
+
+	block, ok := blocks[key(storageName, relativePath)]
+	return block, ok
+}
+
+// IsMutator reports whether fullMethod writes to its target repository.
+func IsMutator(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "/gitaly.OperationService/") || mutatorRPCs[fullMethod]
+}
+
+type repositoryRequest interface {
+	GetRepository() *pb.Repository
+}
+
+// check returns the error to fail the RPC with, or nil when the request
+// may proceed.
+func check(fullMethod string, req interface{}) error {
+	// Admin RPCs must keep working on a blocked repository, otherwise it
+	// could never be unblocked.
+	if strings.HasPrefix(fullMethod, "/gitaly.ServerService/") {
+		return nil
+	}
+
+	repoReq, ok := req.(repositoryRequest)
+	if !ok {
+		return nil
+	}
+
+	repo := repoReq.GetRepository()
+	if repo == nil {
+		return nil
+	}
+
+	block, blocked := lookup(repo.GetStorageName(), repo.GetRelativePath())
+	if !blocked {
+		return nil
+	}
+
+	if block.MutatorsOnly && !IsMutator(fullMethod) {
+		return nil
+	}
+
+	message := block.Message
+	if message == "" {
+		message = "repository is temporarily blocked by the administrator"
+	}
+
+	return status.Errorf(codes.Unavailable, "repository blocked: %s", message)
+}
+
+// Unary is a grpc server interceptor rejecting requests for blocked
+// repositories.
+func Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := check(info.FullMethod, req); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// Stream is a grpc server interceptor rejecting streams for blocked
+// repositories. The target repository is only known once the handler
+// reads the first request, so the check happens in RecvMsg.
+func Stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	wrapper := &recvWrapper{ServerStream: stream, fullMethod: info.FullMethod}
+	return handler(srv, wrapper)
+}
+
+type recvWrapper struct {
+	grpc.ServerStream
+	fullMethod string
+	checked    bool
+}
+
+func (s *recvWrapper) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if !s.checked {
+		s.checked = true
+		if err := check(s.fullMethod, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
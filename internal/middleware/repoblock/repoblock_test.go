@@ -0,0 +1,120 @@
+package repoblock
+
+import (
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func passthroughHandler(calls *int) grpc.UnaryHandler {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		*calls++
+		return "ok", nil
+	}
+}
+
+func repoRequest(storage, relativePath string) *pb.FindDefaultBranchNameRequest {
+	return &pb.FindDefaultBranchNameRequest{
+		Repository: &pb.Repository{StorageName: storage, RelativePath: relativePath},
+	}
+}
+
+func unaryInfo(fullMethod string) *grpc.UnaryServerInfo {
+	return &grpc.UnaryServerInfo{FullMethod: fullMethod}
+}
+
+func TestBlockedRepositoryIsRejected(t *testing.T) {
+	SetBlock("default", "blocked.git", Block{Message: "being repaired"})
+	defer RemoveBlock("default", "blocked.git")
+
+	calls := 0
+	_, err := Unary(context.Background(), repoRequest("default", "blocked.git"),
+		unaryInfo("/gitaly.RefService/FindDefaultBranchName"), passthroughHandler(&calls))
+
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Contains(t, err.Error(), "being repaired")
+	require.Equal(t, 0, calls)
+}
+
+func TestOtherRepositoriesAreUnaffected(t *testing.T) {
+	SetBlock("default", "blocked.git", Block{})
+	defer RemoveBlock("default", "blocked.git")
+
+	calls := 0
+	_, err := Unary(context.Background(), repoRequest("default", "other.git"),
+		unaryInfo("/gitaly.RefService/FindDefaultBranchName"), passthroughHandler(&calls))
+	require.NoError(t, err)
+
+	// Same relative path on another storage is a different repository
+	_, err = Unary(context.Background(), repoRequest("backup", "blocked.git"),
+		unaryInfo("/gitaly.RefService/FindDefaultBranchName"), passthroughHandler(&calls))
+	require.NoError(t, err)
+
+	require.Equal(t, 2, calls)
+}
+
+func TestMutatorsOnlyBlockAllowsReads(t *testing.T) {
+	SetBlock("default", "blocked.git", Block{MutatorsOnly: true})
+	defer RemoveBlock("default", "blocked.git")
+
+	calls := 0
+	_, err := Unary(context.Background(), repoRequest("default", "blocked.git"),
+		unaryInfo("/gitaly.RefService/FindDefaultBranchName"), passthroughHandler(&calls))
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+
+	for _, mutator := range []string{
+		"/gitaly.RefService/DeleteBranch",
+		"/gitaly.OperationService/UserSquash",
+		"/gitaly.SSHService/SSHReceivePack",
+	} {
+		_, err = Unary(context.Background(), repoRequest("default", "blocked.git"),
+			unaryInfo(mutator), passthroughHandler(&calls))
+		require.Equal(t, codes.Unavailable, status.Code(err), mutator)
+	}
+	require.Equal(t, 1, calls)
+}
+
+func TestServerServiceIsExempt(t *testing.T) {
+	SetBlock("default", "blocked.git", Block{})
+	defer RemoveBlock("default", "blocked.git")
+
+	calls := 0
+	_, err := Unary(context.Background(),
+		&pb.UnblockRepositoryRequest{Repository: &pb.Repository{StorageName: "default", RelativePath: "blocked.git"}},
+		unaryInfo("/gitaly.ServerService/UnblockRepository"), passthroughHandler(&calls))
+
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestRemoveBlock(t *testing.T) {
+	SetBlock("default", "blocked.git", Block{})
+
+	require.True(t, RemoveBlock("default", "blocked.git"))
+	require.False(t, RemoveBlock("default", "blocked.git"), "removing twice reports not blocked")
+
+	calls := 0
+	_, err := Unary(context.Background(), repoRequest("default", "blocked.git"),
+		unaryInfo("/gitaly.RefService/FindDefaultBranchName"), passthroughHandler(&calls))
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+}
+
+func TestDefaultBlockMessage(t *testing.T) {
+	SetBlock("default", "blocked.git", Block{})
+	defer RemoveBlock("default", "blocked.git")
+
+	calls := 0
+	_, err := Unary(context.Background(), repoRequest("default", "blocked.git"),
+		unaryInfo("/gitaly.RefService/FindDefaultBranchName"), passthroughHandler(&calls))
+
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Contains(t, err.Error(), "temporarily blocked by the administrator")
+}
@@ -1,11 +1,13 @@
 package sentryhandler
 
 import (
+	"errors"
 	"strings"
 	"time"
 
 	raven "github.com/getsentry/raven-go"
 	"github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
 	"gitlab.com/gitlab-org/gitaly/internal/helper"
 
 	"fmt"
@@ -74,8 +76,12 @@ func generateRavenPacket(ctx context.Context, method string, start time.Time, er
 	ravenDetails["grpc.time_ms"] = fmt.Sprintf("%.0f", time.Since(start).Seconds()*1000)
 	ravenDetails["system"] = "grpc"
 
+	// Error messages may embed URLs with credentials, e.g. from failed
+	// fetches. Redact those before the message leaves the process.
+	sanitizedErr := errors.New(command.SanitizeError(err))
+
 	// Skip the stacktrace as it's not helpful in this context
-	packet := raven.NewPacket(err.Error(), raven.NewException(err, nil))
+	packet := raven.NewPacket(sanitizedErr.Error(), raven.NewException(sanitizedErr, nil))
 	grpcMethod := methodToCulprit(method)
 
 	// Details on fingerprinting
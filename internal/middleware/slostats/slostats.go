@@ -0,0 +1,152 @@
+// Package slostats keeps per-RPC success/error and apdex latency
+// counters over a short rolling window. Unlike the Prometheus metrics,
+// which are cumulative since process start, these counters age out, so
+// a router polling the ServerStats RPC sees recent behavior only.
+package slostats
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// The window is divided into one-minute slots; a slot is reused
+	// once it falls out of the window.
+	slotDuration = time.Minute
+	windowSlots  = 5
+
+	// WindowDuration is the length of the rolling window the counters
+	// cover.
+	WindowDuration = slotDuration * windowSlots
+
+	// Apdex thresholds: successful calls faster than satisfiedThreshold
+	// count as satisfied, those below toleratingThreshold as tolerating.
+	satisfiedThreshold  = 1 * time.Second
+	toleratingThreshold = 4 * time.Second
+)
+
+// RPCStats are the aggregated counters for one gRPC method.
+type RPCStats struct {
+	Method     string
+	Total      uint64
+	Errors     uint64
+	Satisfied  uint64
+	Tolerating uint64
+}
+
+type slot struct {
+	start   time.Time
+	methods map[string]*RPCStats
+}
+
+// Collector accumulates per-method counters in a ring of time slots.
+type Collector struct {
+	mu    sync.Mutex
+	slots [windowSlots]slot
+	now   func() time.Time
+}
+
+// NewCollector returns an empty Collector.
+func NewCollector() *Collector {
+	return &Collector{now: time.Now}
+}
+
+var defaultCollector = NewCollector()
+
+// Record adds one finished call to the current time slot. Calls the
+// client cancelled do not count against the error budget.
+func (c *Collector) Record(fullMethod string, rpcErr error, elapsed time.Duration) {
+	start := c.now().Truncate(slotDuration)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := &c.slots[(start.Unix()/int64(slotDuration/time.Second))%windowSlots]
+	if !s.start.Equal(start) {
+		s.start = start
+		s.methods = make(map[string]*RPCStats)
+	}
+
+	stats := s.methods[fullMethod]
+	if stats == nil {
+		stats = &RPCStats{Method: fullMethod}
+		s.methods[fullMethod] = stats
+	}
+
+	stats.Total++
+
+	switch {
+	case rpcErr != nil:
+		if status.Code(rpcErr) != codes.Canceled {
+			stats.Errors++
+		}
+	case elapsed < satisfiedThreshold:
+		stats.Satisfied++
+	case elapsed < toleratingThreshold:
+		stats.Tolerating++
+	}
+}
+
+// Snapshot aggregates all slots still inside the window, sorted by
+// method name.
+func (c *Collector) Snapshot() []RPCStats {
+	cutoff := c.now().Add(-WindowDuration)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	byMethod := make(map[string]*RPCStats)
+	for i := range c.slots {
+		s := &c.slots[i]
+		if s.start.Before(cutoff) {
+			continue
+		}
+
+		for method, stats := range s.methods {
+			total := byMethod[method]
+			if total == nil {
+				total = &RPCStats{Method: method}
+				byMethod[method] = total
+			}
+
+			total.Total += stats.Total
+			total.Errors += stats.Errors
+			total.Satisfied += stats.Satisfied
+			total.Tolerating += stats.Tolerating
+		}
+	}
+
+	snapshot := make([]RPCStats, 0, len(byMethod))
+	for _, stats := range byMethod {
+		snapshot = append(snapshot, *stats)
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].Method < snapshot[j].Method })
+
+	return snapshot
+}
+
+// Snapshot returns the aggregated counters of the collector fed by the
+// server interceptors.
+func Snapshot() []RPCStats { return defaultCollector.Snapshot() }
+
+// Unary is a unary server interceptor feeding the default collector.
+func Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	defaultCollector.Record(info.FullMethod, err, time.Since(start))
+	return resp, err
+}
+
+// Stream is a stream server interceptor feeding the default collector.
+func Stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	defaultCollector.Record(info.FullMethod, err, time.Since(start))
+	return err
+}
@@ -0,0 +1,64 @@
+package slostats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCollectorRecordAndSnapshot(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	c := NewCollector()
+	c.now = func() time.Time { return now }
+
+	c.Record("/gitaly.CommitService/CommitIsAncestor", nil, 100*time.Millisecond)
+	c.Record("/gitaly.CommitService/CommitIsAncestor", nil, 2*time.Second)
+	c.Record("/gitaly.CommitService/CommitIsAncestor", nil, 10*time.Second)
+	c.Record("/gitaly.CommitService/CommitIsAncestor", status.Errorf(codes.Internal, "boom"), time.Millisecond)
+	c.Record("/gitaly.CommitService/CommitIsAncestor", status.Errorf(codes.Canceled, "bye"), time.Millisecond)
+	c.Record("/gitaly.RefService/FindAllBranchNames", nil, time.Millisecond)
+
+	snapshot := c.Snapshot()
+	require.Equal(t, []RPCStats{
+		{Method: "/gitaly.CommitService/CommitIsAncestor", Total: 5, Errors: 1, Satisfied: 1, Tolerating: 1},
+		{Method: "/gitaly.RefService/FindAllBranchNames", Total: 1, Satisfied: 1},
+	}, snapshot)
+}
+
+func TestCollectorWindowExpiry(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	c := NewCollector()
+	c.now = func() time.Time { return now }
+
+	c.Record("/gitaly.CommitService/CommitIsAncestor", nil, time.Millisecond)
+
+	now = now.Add(2 * slotDuration)
+	c.Record("/gitaly.RefService/FindAllBranchNames", nil, time.Millisecond)
+
+	snapshot := c.Snapshot()
+	require.Len(t, snapshot, 2, "both slots are inside the window")
+
+	now = now.Add(WindowDuration)
+	snapshot = c.Snapshot()
+	require.Empty(t, snapshot, "all slots aged out")
+}
+
+func TestCollectorSlotReuse(t *testing.T) {
+	now := time.Unix(1000000, 0)
+	c := NewCollector()
+	c.now = func() time.Time { return now }
+
+	c.Record("/gitaly.CommitService/CommitIsAncestor", nil, time.Millisecond)
+
+	// windowSlots slots later the ring wraps around to the same slot;
+	// the old counters must not leak into it.
+	now = now.Add(windowSlots * slotDuration)
+	c.Record("/gitaly.CommitService/CommitIsAncestor", nil, time.Millisecond)
+
+	snapshot := c.Snapshot()
+	require.Len(t, snapshot, 1)
+	require.Equal(t, uint64(1), snapshot[0].Total)
+}
@@ -0,0 +1,145 @@
+// Package storagefailover lets clients smooth reads during storage
+// migrations: a request may carry a list of candidate storages in its
+// metadata, and when the repository cannot be served from the storage
+// named in the request the RPC is retried against the next candidate.
+//
+// Failover is opt-in per request, and the server only retries RPCs on
+// the configured read-only allowlist; a retried write could re-apply a
+// half-finished mutation to a stale copy, so client metadata alone is
+// never trusted. Only unary RPCs are retried since a stream may already
+// have sent data by the time the failure surfaces.
+package storagefailover
+
+import (
+	"strings"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	prom "github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// CandidatesMetadataKey is the request metadata key carrying the
+// comma-separated storage names to fall back to, in order.
+const CandidatesMetadataKey = "gitaly-storage-candidates"
+
+// servedByMetadataKey is the response header telling the client which
+// storage ultimately served the request.
+const servedByMetadataKey = "gitaly-storage-served-by"
+
+var failoverRPCs map[string]bool
+
+// SetFailoverRPCs configures which full method names may be retried
+// against candidate storages. Only safe for read-only RPCs.
+func SetFailoverRPCs(rpcs []string) {
+	config := make(map[string]bool)
+	for _, rpc := range rpcs {
+		config[rpc] = true
+	}
+	failoverRPCs = config
+}
+
+var attemptsCounter = prom.NewCounterVec(
+	prom.CounterOpts{
+		Namespace: "gitaly",
+		Subsystem: "storage_failover",
+		Name:      "attempts_total",
+		Help:      "Counter of storage failover attempts, partitioned by candidate storage and outcome",
+	},
+	[]string{"storage", "result"},
+)
+
+func init() {
+	prom.MustRegister(attemptsCounter)
+}
+
+type repositoryRequest interface {
+	GetRepository() *pb.Repository
+}
+
+// candidates extracts the candidate storage names from the request
+// metadata, or nil when the client did not opt in to failover.
+func candidates(ctx context.Context) []string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	for _, header := range md[CandidatesMetadataKey] {
+		for _, name := range strings.Split(header, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
+
+// retryable reports whether an error indicates the repository could not
+// be served from the storage it was looked up on: the repository is
+// missing (NotFound) or the storage itself is down (Unavailable).
+// Internal is deliberately not retried — it is the catch-all for git
+// and parse failures, which another storage copy would hit just as well.
+func retryable(err error) bool {
+	code := status.Code(err)
+	return code == codes.NotFound || code == codes.Unavailable
+}
+
+// Unary is a unary interceptor retrying failed reads against the
+// candidate storages from the request metadata.
+func Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if !failoverRPCs[info.FullMethod] {
+		return handler(ctx, req)
+	}
+
+	repoReq, ok := req.(repositoryRequest)
+	if !ok || repoReq.GetRepository() == nil {
+		return handler(ctx, req)
+	}
+
+	storages := candidates(ctx)
+	if len(storages) == 0 {
+		return handler(ctx, req)
+	}
+
+	repo := repoReq.GetRepository()
+	primaryStorage := repo.GetStorageName()
+
+	resp, firstErr := handler(ctx, req)
+	if firstErr == nil || !retryable(firstErr) {
+		return resp, firstErr
+	}
+	attemptsCounter.WithLabelValues(primaryStorage, "failed").Inc()
+
+	for _, storage := range storages {
+		if storage == primaryStorage {
+			continue
+		}
+
+		repo.StorageName = storage
+
+		resp, err := handler(ctx, req)
+		if err == nil {
+			attemptsCounter.WithLabelValues(storage, "served").Inc()
+			// Best effort: headers cannot be set once the response went out,
+			// which cannot happen for a unary RPC at this point.
+			grpc.SetHeader(ctx, metadata.Pairs(servedByMetadataKey, storage))
+			return resp, nil
+		}
+
+		attemptsCounter.WithLabelValues(storage, "failed").Inc()
+		if !retryable(err) {
+			return resp, err
+		}
+	}
+
+	// All candidates failed; report the primary storage's error.
+	repo.StorageName = primaryStorage
+	return nil, firstErr
+}
@@ -0,0 +1,109 @@
+package storagefailover
+
+import (
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var testUnaryInfo = &grpc.UnaryServerInfo{FullMethod: "/gitaly.TestService/TestRead"}
+
+// storageHandler fails with the given code unless the request names one
+// of the good storages, and counts its invocations.
+func storageHandler(code codes.Code, calls *int, goodStorages ...string) grpc.UnaryHandler {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		*calls++
+
+		storage := req.(*pb.FindDefaultBranchNameRequest).GetRepository().GetStorageName()
+		for _, good := range goodStorages {
+			if storage == good {
+				return storage, nil
+			}
+		}
+
+		return nil, status.Errorf(code, "testhandler: not found on %q", storage)
+	}
+}
+
+func candidatesContext(names string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(CandidatesMetadataKey, names))
+}
+
+func TestFailoverServesFromCandidate(t *testing.T) {
+	SetFailoverRPCs([]string{testUnaryInfo.FullMethod})
+	defer SetFailoverRPCs(nil)
+
+	req := &pb.FindDefaultBranchNameRequest{Repository: &pb.Repository{StorageName: "broken", RelativePath: "foo.git"}}
+
+	calls := 0
+	resp, err := Unary(candidatesContext("broken, backup"), req, testUnaryInfo, storageHandler(codes.NotFound, &calls, "backup"))
+
+	require.NoError(t, err)
+	require.Equal(t, "backup", resp)
+	require.Equal(t, 2, calls, "primary attempt plus one candidate")
+	require.Equal(t, "backup", req.GetRepository().GetStorageName())
+}
+
+func TestFailoverWithoutMetadataDoesNotRetry(t *testing.T) {
+	SetFailoverRPCs([]string{testUnaryInfo.FullMethod})
+	defer SetFailoverRPCs(nil)
+
+	req := &pb.FindDefaultBranchNameRequest{Repository: &pb.Repository{StorageName: "broken", RelativePath: "foo.git"}}
+
+	calls := 0
+	_, err := Unary(context.Background(), req, testUnaryInfo, storageHandler(codes.NotFound, &calls, "backup"))
+
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, 1, calls)
+}
+
+func TestFailoverReturnsPrimaryErrorWhenAllCandidatesFail(t *testing.T) {
+	SetFailoverRPCs([]string{testUnaryInfo.FullMethod})
+	defer SetFailoverRPCs(nil)
+
+	req := &pb.FindDefaultBranchNameRequest{Repository: &pb.Repository{StorageName: "broken", RelativePath: "foo.git"}}
+
+	calls := 0
+	_, err := Unary(candidatesContext("other,another"), req, testUnaryInfo, storageHandler(codes.NotFound, &calls))
+
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Contains(t, err.Error(), `"broken"`)
+	require.Equal(t, 3, calls)
+	require.Equal(t, "broken", req.GetRepository().GetStorageName(), "storage name restored for the caller")
+}
+
+func TestFailoverDoesNotRetryNonStorageErrors(t *testing.T) {
+	SetFailoverRPCs([]string{testUnaryInfo.FullMethod})
+	defer SetFailoverRPCs(nil)
+
+	// Internal is the catch-all for git failures and must not trigger a
+	// cross-storage retry
+	for _, code := range []codes.Code{codes.InvalidArgument, codes.Internal} {
+		req := &pb.FindDefaultBranchNameRequest{Repository: &pb.Repository{StorageName: "broken", RelativePath: "foo.git"}}
+
+		calls := 0
+		_, err := Unary(candidatesContext("backup"), req, testUnaryInfo, storageHandler(code, &calls, "backup"))
+
+		require.Equal(t, code, status.Code(err))
+		require.Equal(t, 1, calls)
+	}
+}
+
+func TestFailoverSkipsUnlistedMethods(t *testing.T) {
+	SetFailoverRPCs(nil)
+
+	req := &pb.FindDefaultBranchNameRequest{Repository: &pb.Repository{StorageName: "broken", RelativePath: "foo.git"}}
+
+	calls := 0
+	_, err := Unary(candidatesContext("backup"), req, testUnaryInfo, storageHandler(codes.NotFound, &calls, "backup"))
+
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, 1, calls, "client metadata alone must not trigger retries")
+}
@@ -0,0 +1,190 @@
+// Package writelimit applies a per-repository token-bucket rate limit
+// to mutator RPCs. Each repository's bucket refills at a configured
+// rate with a burst allowance; writes arriving with an empty bucket are
+// rejected with ResourceExhausted. The global limit can be overridden
+// for individual repositories, and read RPCs are never limited.
+package writelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/repoblock"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var rejectedWrites = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "gitaly_write_rate_limit_rejections_total",
+		Help: "Number of mutator RPCs rejected by the per-repository write rate limit",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(rejectedWrites)
+}
+
+// Limit is a token-bucket configuration. A rate of 0 means no limit.
+type Limit struct {
+	// RatePerSecond is how fast the bucket refills
+	RatePerSecond float64
+	// Burst is the bucket capacity. When 0 it defaults to the rate,
+	// rounded up, so short bursts at the sustained rate are not rejected.
+	Burst int
+}
+
+func (l Limit) enabled() bool { return l.RatePerSecond > 0 }
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+
+	return math.Ceil(l.RatePerSecond)
+}
+
+// bucket is the token-bucket state of one repository.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+var (
+	mu        sync.Mutex
+	global    Limit
+	overrides = make(map[string]Limit)
+	buckets   = make(map[string]*bucket)
+)
+
+func key(storageName, relativePath string) string {
+	return storageName + "\x00" + relativePath
+}
+
+// SetGlobalLimit installs the limit applied to repositories without an
+// override, dropping all existing bucket state.
+func SetGlobalLimit(l Limit) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	global = l
+	buckets = make(map[string]*bucket)
+}
+
+// SetRepoLimit overrides the global limit for one repository. A rate of
+// 0 exempts the repository from write limiting.
+func SetRepoLimit(storageName, relativePath string, l Limit) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	k := key(storageName, relativePath)
+	overrides[k] = l
+	delete(buckets, k)
+}
+
+// allow takes a token from the repository's bucket at time now. It
+// reports true when the write may proceed, which includes repositories
+// no limit applies to.
+func allow(storageName, relativePath string, now time.Time) bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	k := key(storageName, relativePath)
+
+	l, ok := overrides[k]
+	if !ok {
+		l = global
+	}
+
+	if !l.enabled() {
+		return true
+	}
+
+	b, ok := buckets[k]
+	if !ok {
+		b = &bucket{tokens: l.capacity(), last: now}
+		buckets[k] = b
+	}
+
+	b.tokens = math.Min(l.capacity(), b.tokens+now.Sub(b.last).Seconds()*l.RatePerSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+type repositoryRequest interface {
+	GetRepository() *pb.Repository
+}
+
+// check returns the error to fail the RPC with, or nil when the request
+// may proceed.
+func check(fullMethod string, req interface{}) error {
+	if !repoblock.IsMutator(fullMethod) {
+		return nil
+	}
+
+	repoReq, ok := req.(repositoryRequest)
+	if !ok {
+		return nil
+	}
+
+	repo := repoReq.GetRepository()
+	if repo == nil {
+		return nil
+	}
+
+	if !allow(repo.GetStorageName(), repo.GetRelativePath(), time.Now()) {
+		rejectedWrites.Inc()
+		return status.Errorf(codes.ResourceExhausted, "repository write rate limit exceeded")
+	}
+
+	return nil
+}
+
+// Unary is a grpc server interceptor rate limiting mutator requests.
+func Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := check(info.FullMethod, req); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// Stream is a grpc server interceptor rate limiting mutator streams.
+// The target repository is only known once the handler reads the first
+// request, so the check happens in RecvMsg.
+func Stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	wrapper := &recvWrapper{ServerStream: stream, fullMethod: info.FullMethod}
+	return handler(srv, wrapper)
+}
+
+type recvWrapper struct {
+	grpc.ServerStream
+	fullMethod string
+	checked    bool
+}
+
+func (s *recvWrapper) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	if s.checked {
+		return nil
+	}
+	s.checked = true
+
+	return check(s.fullMethod, m)
+}
@@ -0,0 +1,100 @@
+package writelimit
+
+import (
+	"testing"
+	"time"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func resetLimits() {
+	SetGlobalLimit(Limit{})
+
+	mu.Lock()
+	defer mu.Unlock()
+	overrides = make(map[string]Limit)
+}
+
+func TestAllowRefillsOverTime(t *testing.T) {
+	defer resetLimits()
+	SetGlobalLimit(Limit{RatePerSecond: 1, Burst: 2})
+
+	now := time.Now()
+	require.True(t, allow("default", "repo.git", now))
+	require.True(t, allow("default", "repo.git", now))
+	require.False(t, allow("default", "repo.git", now), "burst exhausted")
+
+	require.True(t, allow("default", "repo.git", now.Add(time.Second)), "bucket refills at the configured rate")
+	require.False(t, allow("default", "repo.git", now.Add(time.Second)))
+
+	// Another repository has its own bucket
+	require.True(t, allow("default", "other.git", now))
+}
+
+func TestRepoOverrides(t *testing.T) {
+	defer resetLimits()
+	SetGlobalLimit(Limit{RatePerSecond: 1, Burst: 1})
+	SetRepoLimit("default", "exempt.git", Limit{})
+	SetRepoLimit("default", "busy.git", Limit{RatePerSecond: 1, Burst: 3})
+
+	now := time.Now()
+
+	// A zero-rate override exempts the repository entirely
+	for i := 0; i < 10; i++ {
+		require.True(t, allow("default", "exempt.git", now))
+	}
+
+	for i := 0; i < 3; i++ {
+		require.True(t, allow("default", "busy.git", now))
+	}
+	require.False(t, allow("default", "busy.git", now))
+
+	require.True(t, allow("default", "unrelated.git", now))
+	require.False(t, allow("default", "unrelated.git", now), "global limit still applies elsewhere")
+}
+
+func passthroughHandler(calls *int) grpc.UnaryHandler {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		*calls++
+		return "ok", nil
+	}
+}
+
+func unaryInfo(fullMethod string) *grpc.UnaryServerInfo {
+	return &grpc.UnaryServerInfo{FullMethod: fullMethod}
+}
+
+func TestUnaryLimitsOnlyMutators(t *testing.T) {
+	defer resetLimits()
+	SetGlobalLimit(Limit{RatePerSecond: 0.001, Burst: 1})
+
+	writeReq := &pb.WriteRefRequest{
+		Repository: &pb.Repository{StorageName: "default", RelativePath: "repo.git"},
+	}
+	readReq := &pb.FindDefaultBranchNameRequest{
+		Repository: &pb.Repository{StorageName: "default", RelativePath: "repo.git"},
+	}
+
+	calls := 0
+	_, err := Unary(context.Background(), writeReq,
+		unaryInfo("/gitaly.RepositoryService/WriteRef"), passthroughHandler(&calls))
+	require.NoError(t, err)
+	require.Equal(t, 1, calls)
+
+	_, err = Unary(context.Background(), writeReq,
+		unaryInfo("/gitaly.RepositoryService/WriteRef"), passthroughHandler(&calls))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, 1, calls)
+
+	// Reads pass even with the bucket empty
+	_, err = Unary(context.Background(), readReq,
+		unaryInfo("/gitaly.RefService/FindDefaultBranchName"), passthroughHandler(&calls))
+	require.NoError(t, err)
+	require.Equal(t, 2, calls)
+}
@@ -0,0 +1,153 @@
+// Package packstats emits a structured summary after each completed
+// clone or fetch. The summary always goes to the request log; when a
+// callback binary is configured it additionally receives the summary as
+// JSON on stdin, so per-project bandwidth accounting can be built
+// outside of Gitaly.
+package packstats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+// Summary describes one finished upload-pack invocation.
+type Summary struct {
+	RPC            string `json:"rpc"`
+	StorageName    string `json:"storage_name"`
+	RelativePath   string `json:"relative_path"`
+	Wants          int    `json:"wants"`
+	PackBytes      int64  `json:"pack_bytes"`
+	DurationMs     int64  `json:"duration_ms"`
+	ClientIdentity string `json:"client_identity"`
+}
+
+// NewSummary seeds a summary with the repository and client identity of
+// the current request.
+func NewSummary(ctx context.Context, rpc string, repo *pb.Repository) *Summary {
+	return &Summary{
+		RPC:            rpc,
+		StorageName:    repo.GetStorageName(),
+		RelativePath:   repo.GetRelativePath(),
+		ClientIdentity: clientIdentity(ctx),
+	}
+}
+
+// Record logs the summary and, if a callback binary is configured,
+// feeds it to the binary as JSON on stdin. Callback failures are logged
+// and otherwise ignored: statistics must never fail a fetch.
+func (s *Summary) Record(ctx context.Context, duration time.Duration) {
+	s.DurationMs = int64(duration / time.Millisecond)
+
+	grpc_logrus.Extract(ctx).WithFields(log.Fields{
+		"Wants":          s.Wants,
+		"PackBytes":      s.PackBytes,
+		"DurationMs":     s.DurationMs,
+		"ClientIdentity": s.ClientIdentity,
+	}).Info("upload-pack summary")
+
+	callbackBin := config.Config.UploadPackStats.CallbackBinPath
+	if callbackBin == "" {
+		return
+	}
+
+	if err := runCallback(ctx, callbackBin, s); err != nil {
+		grpc_logrus.Extract(ctx).WithError(err).Error("upload-pack summary callback failed")
+	}
+}
+
+func runCallback(ctx context.Context, callbackBin string, s *Summary) error {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, callbackBin)
+	cmd.Stdin = bytes.NewReader(payload)
+	return cmd.Run()
+}
+
+// clientIdentity returns the best identity available from the request
+// metadata: the user ID set by the calling service, the client name, or
+// "unknown".
+func clientIdentity(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "unknown"
+	}
+
+	for _, header := range []string{"user_id", "client_name"} {
+		if values := md[header]; len(values) == 1 && values[0] != "" {
+			return values[0]
+		}
+	}
+
+	return "unknown"
+}
+
+// CountWants counts the 'want' pkt-lines in an upload-pack request
+// body. Because the caller connects it via an io.Pipe and io.TeeReader
+// it consumes all data.
+func CountWants(body io.Reader) int {
+	wants := 0
+
+	scanner := bufio.NewScanner(body)
+	scanner.Split(pktLineSplitter)
+	for scanner.Scan() {
+		if bytes.HasPrefix(scanner.Bytes(), []byte("want ")) {
+			wants++
+		}
+	}
+
+	io.Copy(ioutil.Discard, body)
+	return wants
+}
+
+// pktLineSplitter is a bufio.SplitFunc for the Git pkt-line format.
+func pktLineSplitter(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if len(data) < 4 {
+		if atEOF && len(data) > 0 {
+			return 0, nil, fmt.Errorf("pktLineSplitter: incomplete length prefix on %q", data)
+		}
+		return 0, nil, nil // want more data
+	}
+
+	if bytes.HasPrefix(data, []byte("0000")) {
+		// special case: "0000" terminator packet: return empty token
+		return 4, data[:0], nil
+	}
+
+	pktLength64, err := strconv.ParseInt(string(data[:4]), 16, 0)
+	if err != nil {
+		return 0, nil, fmt.Errorf("pktLineSplitter: decode length: %v", err)
+	}
+
+	pktLength := int(pktLength64)
+	if pktLength < 0 {
+		return 0, nil, fmt.Errorf("pktLineSplitter: invalid length: %d", pktLength)
+	}
+
+	if len(data) < pktLength {
+		if atEOF {
+			return 0, nil, fmt.Errorf("pktLineSplitter: less than %d bytes in input %q", pktLength, data)
+		}
+		return 0, nil, nil // want more data
+	}
+
+	return pktLength, data[4:pktLength], nil
+}
@@ -0,0 +1,136 @@
+package packstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+func pktline(payload string) string {
+	return fmt.Sprintf("%04x%s", len(payload)+4, payload)
+}
+
+func TestCountWants(t *testing.T) {
+	testCases := []struct {
+		desc  string
+		body  string
+		wants int
+	}{
+		{
+			desc:  "empty body",
+			body:  "",
+			wants: 0,
+		},
+		{
+			desc: "clone with two wants",
+			body: pktline("want 4b825dc642cb6eb9a060e54bf8d69288fbee4904 multi_ack\n") +
+				pktline("want 0c304a93cb8430108629bbbcaa27db3343299bc0\n") +
+				"0000" + pktline("done\n"),
+			wants: 2,
+		},
+		{
+			desc: "fetch with haves",
+			body: pktline("want 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n") +
+				"0000" + pktline("have 0c304a93cb8430108629bbbcaa27db3343299bc0\n") + pktline("done\n"),
+			wants: 1,
+		},
+		{
+			desc:  "garbage",
+			body:  "not pktline data",
+			wants: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Equal(t, tc.wants, CountWants(strings.NewReader(tc.body)))
+		})
+	}
+}
+
+func TestRecordInvokesCallback(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "packstats")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	outPath := filepath.Join(tmpDir, "summary.json")
+	callbackBin := filepath.Join(tmpDir, "callback")
+	script := fmt.Sprintf("#!/bin/sh\ncat > %s\n", outPath)
+	require.NoError(t, ioutil.WriteFile(callbackBin, []byte(script), 0755))
+
+	defer func(old string) { config.Config.UploadPackStats.CallbackBinPath = old }(config.Config.UploadPackStats.CallbackBinPath)
+	config.Config.UploadPackStats.CallbackBinPath = callbackBin
+
+	md := metadata.Pairs("user_id", "user-123")
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	repo := &pb.Repository{StorageName: "default", RelativePath: "foo/bar.git"}
+	summary := NewSummary(ctx, "PostUploadPack", repo)
+	summary.Wants = 3
+	summary.PackBytes = 1024
+
+	summary.Record(ctx, 1500*time.Millisecond)
+
+	data, err := ioutil.ReadFile(outPath)
+	require.NoError(t, err)
+
+	var received Summary
+	require.NoError(t, json.Unmarshal(data, &received))
+
+	require.Equal(t, Summary{
+		RPC:            "PostUploadPack",
+		StorageName:    "default",
+		RelativePath:   "foo/bar.git",
+		Wants:          3,
+		PackBytes:      1024,
+		DurationMs:     1500,
+		ClientIdentity: "user-123",
+	}, received)
+}
+
+func TestClientIdentity(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		md       metadata.MD
+		expected string
+	}{
+		{
+			desc:     "no metadata",
+			expected: "unknown",
+		},
+		{
+			desc:     "user ID",
+			md:       metadata.Pairs("user_id", "user-1", "client_name", "gitlab-web"),
+			expected: "user-1",
+		},
+		{
+			desc:     "client name only",
+			md:       metadata.Pairs("client_name", "gitlab-web"),
+			expected: "gitlab-web",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.md != nil {
+				ctx = metadata.NewIncomingContext(ctx, tc.md)
+			}
+
+			require.Equal(t, tc.expected, clientIdentity(ctx))
+		})
+	}
+}
@@ -4,11 +4,18 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"gitlab.com/gitlab-org/gitaly/internal/helper/fieldextractors"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/accountinghandler"
 	"gitlab.com/gitlab-org/gitaly/internal/middleware/cancelhandler"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/coalesce"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/glcheck"
 	"gitlab.com/gitlab-org/gitaly/internal/middleware/limithandler"
 	"gitlab.com/gitlab-org/gitaly/internal/middleware/metadatahandler"
 	"gitlab.com/gitlab-org/gitaly/internal/middleware/panichandler"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/repoblock"
 	"gitlab.com/gitlab-org/gitaly/internal/middleware/sentryhandler"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/slostats"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/storagefailover"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/writelimit"
 	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
 	"gitlab.com/gitlab-org/gitaly/internal/server/auth"
 	"gitlab.com/gitlab-org/gitaly/internal/service"
@@ -32,15 +39,21 @@ func New(rubyServer *rubyserver.Server) *grpc.Server {
 	}
 
 	lh := limithandler.New()
+	co := coalesce.New()
 
 	server := grpc.NewServer(
 		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
 			grpc_ctxtags.StreamServerInterceptor(ctxTagOpts...),
 			metadatahandler.StreamInterceptor,
 			grpc_prometheus.StreamServerInterceptor,
+			slostats.Stream,
 			grpc_logrus.StreamServerInterceptor(logrusEntry),
 			sentryhandler.StreamLogHandler,
 			cancelhandler.Stream, // Should be below LogHandler
+			accountinghandler.Stream,
+			repoblock.Stream,  // Above the limit handler so blocked requests hold no limiter slots
+			glcheck.Stream,    // Reject malformed writes before they consume rate limit tokens
+			writelimit.Stream, // Rejected writes hold no limiter slots either
 			lh.StreamInterceptor(),
 			auth.StreamServerInterceptor(),
 			// Panic handler should remain last so that application panics will be
@@ -51,9 +64,16 @@ func New(rubyServer *rubyserver.Server) *grpc.Server {
 			grpc_ctxtags.UnaryServerInterceptor(ctxTagOpts...),
 			metadatahandler.UnaryInterceptor,
 			grpc_prometheus.UnaryServerInterceptor,
+			slostats.Unary,
 			grpc_logrus.UnaryServerInterceptor(logrusEntry),
 			sentryhandler.UnaryLogHandler,
 			cancelhandler.Unary, // Should be below LogHandler
+			accountinghandler.Unary,
+			repoblock.Unary,       // Above the limit handler so blocked requests hold no limiter slots
+			glcheck.Unary,         // Reject malformed writes before they consume rate limit tokens
+			writelimit.Unary,      // Rejected writes hold no limiter slots either
+			co.UnaryInterceptor(), // Above the limit handler so coalesced followers hold no limiter slots
+			storagefailover.Unary, // Each failover attempt takes its own limiter slot
 			lh.UnaryInterceptor(),
 			auth.UnaryServerInterceptor(),
 			// Panic handler should remain last so that application panics will be
@@ -2,12 +2,14 @@ package blob
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"io"
-	"os/exec"
 
-	"gitlab.com/gitlab-org/gitaly/internal/command"
 	"gitlab.com/gitlab-org/gitaly/internal/git/catfile"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
 	"gitlab.com/gitlab-org/gitaly/internal/helper"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
@@ -22,15 +24,14 @@ func (s *server) GetBlob(in *pb.GetBlobRequest, stream pb.BlobService_GetBlobSer
 		return status.Errorf(codes.InvalidArgument, "GetBlob: %v", err)
 	}
 
-	repoPath, err := helper.GetRepoPath(in.Repository)
+	repo, err := localrepo.New(in.Repository)
 	if err != nil {
 		return err
 	}
 
 	stdinReader, stdinWriter := io.Pipe()
 
-	cmdArgs := []string{"--git-dir", repoPath, "cat-file", "--batch"}
-	cmd, err := command.New(stream.Context(), exec.Command(command.GitPath(), cmdArgs...), stdinReader, nil, nil)
+	cmd, err := repo.ExecWithStdin(stream.Context(), stdinReader, "cat-file", "--batch")
 	if err != nil {
 		return status.Errorf(codes.Internal, "GetBlob: cmd: %v", err)
 	}
@@ -61,7 +62,15 @@ func (s *server) GetBlob(in *pb.GetBlobRequest, stream pb.BlobService_GetBlobSer
 		Oid:  objectInfo.Oid,
 	}
 
+	var hasher hash.Hash
+	if in.GetComputeChecksum() {
+		hasher = sha256.New()
+	}
+
 	if readLimit == 0 {
+		if hasher != nil {
+			firstMessage.Sha256 = hex.EncodeToString(hasher.Sum(nil))
+		}
 		return helper.DecorateError(codes.Unavailable, stream.Send(firstMessage))
 	}
 
@@ -75,7 +84,12 @@ func (s *server) GetBlob(in *pb.GetBlobRequest, stream pb.BlobService_GetBlobSer
 		return stream.Send(msg)
 	})
 
-	n, err := io.Copy(sw, io.LimitReader(stdout, readLimit))
+	var dst io.Writer = sw
+	if hasher != nil {
+		dst = io.MultiWriter(sw, hasher)
+	}
+
+	n, err := io.Copy(dst, io.LimitReader(stdout, readLimit))
 	if err != nil {
 		return status.Errorf(codes.Unavailable, "GetBlob: send: %v", err)
 	}
@@ -83,6 +97,13 @@ func (s *server) GetBlob(in *pb.GetBlobRequest, stream pb.BlobService_GetBlobSer
 		return status.Errorf(codes.Unavailable, "GetBlob: short send: %d/%d bytes", n, objectInfo.Size)
 	}
 
+	if hasher != nil {
+		// The checksum goes into a trailing message of its own so clients
+		// can verify they received all data chunks.
+		checksumMessage := &pb.GetBlobResponse{Sha256: hex.EncodeToString(hasher.Sum(nil))}
+		return helper.DecorateError(codes.Unavailable, stream.Send(checksumMessage))
+	}
+
 	return nil
 }
 
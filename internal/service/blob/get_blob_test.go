@@ -2,6 +2,8 @@ package blob
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"testing"
@@ -119,6 +121,53 @@ func TestGetBlobNotFound(t *testing.T) {
 	require.Zero(t, len(data))
 }
 
+func TestGetBlobWithChecksum(t *testing.T) {
+	server, serverSocketPath := runBlobServer(t)
+	defer server.Stop()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	client, conn := newBlobClient(t, serverSocketPath)
+	defer conn.Close()
+
+	maintenanceMdBlobData := testhelper.MustReadFile(t, "testdata/maintenance-md-blob.txt")
+
+	request := &pb.GetBlobRequest{
+		Repository:      testRepo,
+		Oid:             "95d9f0a5e7bb054e9dd3975589b8dfc689e20e88",
+		Limit:           -1,
+		ComputeChecksum: true,
+	}
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	stream, err := client.GetBlob(ctx, request)
+	require.NoError(t, err, "initiate RPC")
+
+	data := &bytes.Buffer{}
+	var reportedSha256 string
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err, "consume response")
+
+		data.Write(response.GetData())
+		if response.GetSha256() != "" {
+			require.Empty(t, response.GetData(), "checksum arrives in a message of its own")
+			reportedSha256 = response.GetSha256()
+		}
+	}
+
+	require.True(t, bytes.Equal(maintenanceMdBlobData, data.Bytes()), "returned data exactly as expected")
+
+	wantSum := sha256.Sum256(maintenanceMdBlobData)
+	require.Equal(t, hex.EncodeToString(wantSum[:]), reportedSha256, "SHA-256 of streamed data")
+}
+
 func getBlob(stream pb.BlobService_GetBlobClient) (int64, string, []byte, error) {
 	firstResponse, err := stream.Recv()
 	if err != nil {
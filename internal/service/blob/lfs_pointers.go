@@ -1,16 +1,38 @@
 package blob
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
+	"os/exec"
 
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/git/catfile"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 
+	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+const (
+	// Size heuristics to ignore blobs which can't be LFS pointers. The
+	// format is described in
+	// https://github.com/git-lfs/git-lfs/blob/master/docs/spec.md#the-pointer
+	lfsPointerMinSize = 120
+	lfsPointerMaxSize = 200
+
+	// lfsPointerSliceSize bounds the number of pointers in one response
+	// message
+	lfsPointerSliceSize = 100
+)
+
+var lfsPointerPrefix = []byte("version https://git-lfs.github.com/spec")
+
 func (s *server) GetLFSPointers(req *pb.GetLFSPointersRequest, stream pb.BlobService_GetLFSPointersServer) error {
 	ctx := stream.Context()
 
@@ -18,39 +40,254 @@ func (s *server) GetLFSPointers(req *pb.GetLFSPointersRequest, stream pb.BlobSer
 		return status.Errorf(codes.InvalidArgument, "GetLFSPointers: %v", err)
 	}
 
-	client, err := s.BlobServiceClient(ctx)
+	pointers, err := findLFSPointersByIds(ctx, req.GetRepository(), req.GetBlobIds())
 	if err != nil {
-		return err
+		return status.Errorf(codes.Internal, "GetLFSPointers: %v", err)
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, req.GetRepository())
+	return sliceLFSPointers(pointers, func(slice []*pb.LFSPointer) error {
+		return stream.Send(&pb.GetLFSPointersResponse{LfsPointers: slice})
+	})
+}
+
+func validateGetLFSPointersRequest(req *pb.GetLFSPointersRequest) error {
+	if req.GetRepository() == nil {
+		return fmt.Errorf("empty Repository")
+	}
+
+	if len(req.GetBlobIds()) == 0 {
+		return fmt.Errorf("empty BlobIds")
+	}
+
+	return nil
+}
+
+func (s *server) GetNewLFSPointers(req *pb.GetNewLFSPointersRequest, stream pb.BlobService_GetNewLFSPointersServer) error {
+	ctx := stream.Context()
+
+	if err := validateGetNewLFSPointersRequest(req); err != nil {
+		return status.Errorf(codes.InvalidArgument, "GetNewLFSPointers: %v", err)
+	}
+
+	args := []string{"rev-list", string(req.GetRevision()), "--objects"}
+	if req.GetNotInAll() || len(req.GetNotInRefs()) == 0 {
+		args = append(args, "--not", "--all")
+	} else {
+		args = append(args, "--not")
+		for _, ref := range req.GetNotInRefs() {
+			args = append(args, string(ref))
+		}
+	}
+
+	candidateIds, err := revListObjects(ctx, req.GetRepository(), int(req.GetLimit()), args...)
 	if err != nil {
-		return err
+		return status.Errorf(codes.Internal, "GetNewLFSPointers: %v", err)
 	}
 
-	rubyStream, err := client.GetLFSPointers(clientCtx, req)
+	pointers, err := findLFSPointersByIds(ctx, req.GetRepository(), candidateIds)
 	if err != nil {
-		return err
+		return status.Errorf(codes.Internal, "GetNewLFSPointers: %v", err)
 	}
 
-	return rubyserver.Proxy(func() error {
-		resp, err := rubyStream.Recv()
-		if err != nil {
-			md := rubyStream.Trailer()
-			stream.SetTrailer(md)
-			return err
-		}
-		return stream.Send(resp)
+	return sliceLFSPointers(pointers, func(slice []*pb.LFSPointer) error {
+		return stream.Send(&pb.GetNewLFSPointersResponse{LfsPointers: slice})
 	})
 }
 
-func validateGetLFSPointersRequest(req *pb.GetLFSPointersRequest) error {
+func validateGetNewLFSPointersRequest(req *pb.GetNewLFSPointersRequest) error {
 	if req.GetRepository() == nil {
 		return fmt.Errorf("empty Repository")
 	}
 
-	if len(req.GetBlobIds()) == 0 {
-		return fmt.Errorf("empty BlobIds")
+	if err := git.ValidateRevision(req.GetRevision()); err != nil {
+		return fmt.Errorf("invalid Revision: %v", err)
+	}
+
+	for _, ref := range req.GetNotInRefs() {
+		if err := git.ValidateRevision(ref); err != nil {
+			return fmt.Errorf("invalid NotInRefs: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *server) GetAllLFSPointers(req *pb.GetAllLFSPointersRequest, stream pb.BlobService_GetAllLFSPointersServer) error {
+	ctx := stream.Context()
+
+	if req.GetRepository() == nil {
+		return status.Errorf(codes.InvalidArgument, "GetAllLFSPointers: empty Repository")
+	}
+
+	args := []string{"rev-list", "--all", "--objects"}
+	if revision := req.GetRevision(); len(revision) > 0 {
+		if err := git.ValidateRevision(revision); err != nil {
+			return status.Errorf(codes.InvalidArgument, "GetAllLFSPointers: invalid Revision: %v", err)
+		}
+
+		args = []string{"rev-list", string(revision), "--objects"}
+	}
+
+	candidateIds, err := revListObjects(ctx, req.GetRepository(), 0, args...)
+	if err != nil {
+		return status.Errorf(codes.Internal, "GetAllLFSPointers: %v", err)
+	}
+
+	pointers, err := findLFSPointersByIds(ctx, req.GetRepository(), candidateIds)
+	if err != nil {
+		return status.Errorf(codes.Internal, "GetAllLFSPointers: %v", err)
+	}
+
+	return sliceLFSPointers(pointers, func(slice []*pb.LFSPointer) error {
+		return stream.Send(&pb.GetAllLFSPointersResponse{LfsPointers: slice})
+	})
+}
+
+// revListObjects returns the IDs of the objects `git rev-list` prints
+// with a path, i.e. blobs and trees. A limit of zero means no limit.
+func revListObjects(ctx context.Context, repo *pb.Repository, limit int, args ...string) ([]string, error) {
+	cmd, err := git.Command(ctx, repo, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var objectIds []string
+
+	scanner := bufio.NewScanner(cmd)
+	for scanner.Scan() {
+		split := bytes.SplitN(scanner.Bytes(), []byte(" "), 2)
+		if len(split) != 2 || len(split[1]) == 0 {
+			continue
+		}
+
+		if limit > 0 && len(objectIds) >= limit {
+			continue
+		}
+
+		objectIds = append(objectIds, string(split[0]))
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+
+	return objectIds, nil
+}
+
+// findLFSPointersByIds narrows candidate blob IDs down with `git
+// cat-file --batch-check` to blobs in the LFS pointer size range, then
+// reads those through `git cat-file --batch` and keeps the ones whose
+// content matches the pointer spec.
+func findLFSPointersByIds(ctx context.Context, repo *pb.Repository, blobIds []string) ([]*pb.LFSPointer, error) {
+	candidateIds, err := filterLFSPointerCandidates(ctx, repo, blobIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var pointers []*pb.LFSPointer
+
+	err = catfile.CatFile(ctx, repo, func(stdin io.Writer, stdout *bufio.Reader) error {
+		for _, blobId := range candidateIds {
+			if _, err := fmt.Fprintln(stdin, blobId); err != nil {
+				return err
+			}
+
+			objectInfo, err := catfile.ParseObjectInfo(stdout)
+			if err != nil {
+				return err
+			}
+
+			data := make([]byte, objectInfo.Size)
+			if _, err := io.ReadFull(stdout, data); err != nil {
+				return err
+			}
+			// Discard the trailing newline of the object stream
+			if _, err := stdout.Discard(1); err != nil {
+				return err
+			}
+
+			if !bytes.HasPrefix(data, lfsPointerPrefix) {
+				continue
+			}
+
+			pointers = append(pointers, &pb.LFSPointer{
+				Oid:  objectInfo.Oid,
+				Size: objectInfo.Size,
+				Data: data,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pointers, nil
+}
+
+// filterLFSPointerCandidates returns the subset of blobIds naming blobs
+// whose size is plausible for an LFS pointer, preserving order and
+// dropping duplicates. Missing objects and non-blobs are skipped.
+func filterLFSPointerCandidates(ctx context.Context, repo *pb.Repository, blobIds []string) ([]string, error) {
+	if len(blobIds) == 0 {
+		return nil, nil
+	}
+
+	repoPath, err := helper.GetRepoPath(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdin bytes.Buffer
+	for _, blobId := range blobIds {
+		fmt.Fprintln(&stdin, blobId)
+	}
+
+	osCmd := exec.Command(command.GitPath(), "--git-dir", repoPath, "cat-file", "--batch-check")
+	cmd, err := command.New(ctx, osCmd, &stdin, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidateIds []string
+	seen := make(map[string]bool)
+
+	stdout := bufio.NewReader(cmd)
+	for range blobIds {
+		info, err := catfile.ParseObjectInfo(stdout)
+		if err != nil {
+			return nil, err
+		}
+
+		if info.Type != "blob" || info.Size < lfsPointerMinSize || info.Size > lfsPointerMaxSize || seen[info.Oid] {
+			continue
+		}
+
+		seen[info.Oid] = true
+		candidateIds = append(candidateIds, info.Oid)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+
+	return candidateIds, nil
+}
+
+// sliceLFSPointers calls send with successive bounded slices of
+// pointers, so no single gRPC message grows too large.
+func sliceLFSPointers(pointers []*pb.LFSPointer, send func([]*pb.LFSPointer) error) error {
+	for len(pointers) > 0 {
+		slice := pointers
+		if len(slice) > lfsPointerSliceSize {
+			slice = slice[:lfsPointerSliceSize]
+		}
+		pointers = pointers[len(slice):]
+
+		if err := send(slice); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -1,7 +1,9 @@
 package blob
 
 import (
+	"fmt"
 	"io"
+	"strings"
 	"testing"
 
 	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
@@ -123,3 +125,284 @@ func TestFailedGetLFSPointersRequestDueToValidations(t *testing.T) {
 		})
 	}
 }
+
+func TestSuccessfulGetNewLFSPointersRequest(t *testing.T) {
+	server, serverSocketPath := runBlobServer(t)
+	defer server.Stop()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	client, conn := newBlobClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	// The pointer reachable from master must never show up as new
+	_, newPointer, newCommit := writeLFSTestObjects(t, testRepoPath)
+
+	testCases := []struct {
+		desc     string
+		request  *pb.GetNewLFSPointersRequest
+		expected []*pb.LFSPointer
+	}{
+		{
+			desc: "not in all refs",
+			request: &pb.GetNewLFSPointersRequest{
+				Repository: testRepo,
+				Revision:   []byte(newCommit),
+			},
+			expected: []*pb.LFSPointer{newPointer},
+		},
+		{
+			desc: "not in given refs",
+			request: &pb.GetNewLFSPointersRequest{
+				Repository: testRepo,
+				Revision:   []byte(newCommit),
+				NotInRefs:  [][]byte{[]byte("refs/heads/master")},
+			},
+			expected: []*pb.LFSPointer{newPointer},
+		},
+		{
+			desc: "revision excluded by NotInRefs",
+			request: &pb.GetNewLFSPointersRequest{
+				Repository: testRepo,
+				Revision:   []byte(newCommit),
+				NotInRefs:  [][]byte{[]byte(newCommit)},
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			stream, err := client.GetNewLFSPointers(ctx, tc.request)
+			require.NoError(t, err)
+
+			var received []*pb.LFSPointer
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+
+				received = append(received, resp.GetLfsPointers()...)
+			}
+
+			require.ElementsMatch(t, tc.expected, received)
+		})
+	}
+
+}
+
+func TestSuccessfulGetAllLFSPointersRequest(t *testing.T) {
+	server, serverSocketPath := runBlobServer(t)
+	defer server.Stop()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	client, conn := newBlobClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	oldPointer, newPointer, newCommit := writeLFSTestObjects(t, testRepoPath)
+
+	testCases := []struct {
+		desc     string
+		request  *pb.GetAllLFSPointersRequest
+		expected []*pb.LFSPointer
+	}{
+		{
+			desc:     "all refs",
+			request:  &pb.GetAllLFSPointersRequest{Repository: testRepo},
+			expected: []*pb.LFSPointer{oldPointer},
+		},
+		{
+			desc: "from revision",
+			request: &pb.GetAllLFSPointersRequest{
+				Repository: testRepo,
+				Revision:   []byte(newCommit),
+			},
+			expected: []*pb.LFSPointer{oldPointer, newPointer},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			stream, err := client.GetAllLFSPointers(ctx, tc.request)
+			require.NoError(t, err)
+
+			var received []*pb.LFSPointer
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+
+				received = append(received, resp.GetLfsPointers()...)
+			}
+
+			require.ElementsMatch(t, tc.expected, received)
+		})
+	}
+}
+
+func TestFailedGetNewLFSPointersRequestDueToValidations(t *testing.T) {
+	server, serverSocketPath := runBlobServer(t)
+	defer server.Stop()
+
+	testRepo, _, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	client, conn := newBlobClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testCases := []struct {
+		desc    string
+		request *pb.GetNewLFSPointersRequest
+	}{
+		{
+			desc: "empty Repository",
+			request: &pb.GetNewLFSPointersRequest{
+				Revision: []byte("master"),
+			},
+		},
+		{
+			desc: "empty Revision",
+			request: &pb.GetNewLFSPointersRequest{
+				Repository: testRepo,
+			},
+		},
+		{
+			desc: "Revision looking like an option",
+			request: &pb.GetNewLFSPointersRequest{
+				Repository: testRepo,
+				Revision:   []byte("--all"),
+			},
+		},
+		{
+			desc: "NotInRefs entry looking like an option",
+			request: &pb.GetNewLFSPointersRequest{
+				Repository: testRepo,
+				Revision:   []byte("master"),
+				NotInRefs:  [][]byte{[]byte("--glob=refs/*")},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			stream, err := client.GetNewLFSPointers(ctx, tc.request)
+			require.NoError(t, err)
+
+			_, err = stream.Recv()
+			require.NotEqual(t, io.EOF, err)
+			testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+		})
+	}
+}
+
+func TestFailedGetAllLFSPointersRequestDueToValidations(t *testing.T) {
+	server, serverSocketPath := runBlobServer(t)
+	defer server.Stop()
+
+	testRepo, _, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	client, conn := newBlobClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testCases := []struct {
+		desc    string
+		request *pb.GetAllLFSPointersRequest
+	}{
+		{
+			desc:    "empty Repository",
+			request: &pb.GetAllLFSPointersRequest{},
+		},
+		{
+			desc: "Revision looking like an option",
+			request: &pb.GetAllLFSPointersRequest{
+				Repository: testRepo,
+				Revision:   []byte("--all"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			stream, err := client.GetAllLFSPointers(ctx, tc.request)
+			require.NoError(t, err)
+
+			_, err = stream.Recv()
+			require.NotEqual(t, io.EOF, err)
+			testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+		})
+	}
+}
+
+// writeLFSTestObjects sets up a repository with one LFS pointer reachable
+// from master and one only reachable from a dangling commit, next to
+// blobs that must not be reported: one in the pointer size range without
+// the spec prefix and one with the prefix but below the minimum size.
+// It returns the two pointers and the dangling commit ID.
+func writeLFSTestObjects(t *testing.T, repoPath string) (*pb.LFSPointer, *pb.LFSPointer, string) {
+	oldPointerData := "version https://git-lfs.github.com/spec/v1\noid sha256:f2b0a1e7550e9b718dafc9b525a04879a766de62e4fbdfc46593d47f7ab74636\nsize 20\n"
+	newPointerData := "version https://git-lfs.github.com/spec/v1\noid sha256:bad71f905b60729f502ca339f7c9f001281a3d12c68a5da7f15de8009f4bd63d\nsize 18\n"
+	nonPointerData := "this blob sits in the LFS pointer size range but does not follow the pointer spec at all " + strings.Repeat("x", 40) + "\n"
+	tinyPointerData := "version https://git-lfs.github.com/spec\n"
+
+	oldPointerID := writeTestBlob(t, repoPath, oldPointerData)
+	newPointerID := writeTestBlob(t, repoPath, newPointerData)
+	nonPointerID := writeTestBlob(t, repoPath, nonPointerData)
+	tinyPointerID := writeTestBlob(t, repoPath, tinyPointerData)
+
+	oldCommit := writeTestCommit(t, repoPath, fmt.Sprintf("100644 blob %s\told.bin\n", oldPointerID))
+	testhelper.MustRunCommand(t, nil, "git", "-C", repoPath, "update-ref", "refs/heads/master", oldCommit)
+
+	newTree := fmt.Sprintf("100644 blob %s\tnew.bin\n100644 blob %s\tnot-a-pointer.bin\n100644 blob %s\told.bin\n100644 blob %s\ttiny.bin\n",
+		newPointerID, nonPointerID, oldPointerID, tinyPointerID)
+	newCommit := writeTestCommit(t, repoPath, newTree, "-p", oldCommit)
+
+	oldPointer := &pb.LFSPointer{
+		Oid:  oldPointerID,
+		Size: int64(len(oldPointerData)),
+		Data: []byte(oldPointerData),
+	}
+	newPointer := &pb.LFSPointer{
+		Oid:  newPointerID,
+		Size: int64(len(newPointerData)),
+		Data: []byte(newPointerData),
+	}
+
+	return oldPointer, newPointer, newCommit
+}
+
+func writeTestBlob(t *testing.T, repoPath, content string) string {
+	return strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader(content),
+		"git", "-C", repoPath, "hash-object", "-w", "--stdin")))
+}
+
+func writeTestCommit(t *testing.T, repoPath, treeSpec string, extraArgs ...string) string {
+	tree := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader(treeSpec),
+		"git", "-C", repoPath, "mktree")))
+
+	args := []string{"-C", repoPath, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit-tree", "-m", "add lfs pointers"}
+	args = append(args, extraArgs...)
+	args = append(args, tree)
+
+	return strings.TrimSpace(string(testhelper.MustRunCommand(t, nil, "git", args...)))
+}
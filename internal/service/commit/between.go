@@ -20,11 +20,25 @@ func (s *server) CommitsBetween(in *pb.CommitsBetweenRequest, stream pb.CommitSe
 	if err := git.ValidateRevision(in.GetTo()); err != nil {
 		return status.Errorf(codes.InvalidArgument, "CommitsBetween: to: %v", err)
 	}
+	if in.GetMaxCount() < 0 {
+		return status.Errorf(codes.InvalidArgument, "CommitsBetween: negative MaxCount")
+	}
+	if in.GetSkip() < 0 {
+		return status.Errorf(codes.InvalidArgument, "CommitsBetween: negative Skip")
+	}
 
 	sender := &commitsBetweenSender{stream}
 	revisionRange := fmt.Sprintf("%s..%s", in.GetFrom(), in.GetTo())
 
-	return sendCommits(stream.Context(), sender, in.GetRepository(), []string{revisionRange}, nil, "--reverse")
+	gitLogExtraOptions := []string{"--reverse"}
+	if maxCount := in.GetMaxCount(); maxCount > 0 {
+		gitLogExtraOptions = append(gitLogExtraOptions, fmt.Sprintf("--max-count=%d", maxCount))
+	}
+	if skip := in.GetSkip(); skip > 0 {
+		gitLogExtraOptions = append(gitLogExtraOptions, fmt.Sprintf("--skip=%d", skip))
+	}
+
+	return sendCommits(stream.Context(), sender, in.GetRepository(), []string{revisionRange}, nil, gitLogExtraOptions...)
 }
 
 func (sender *commitsBetweenSender) Send(commits []*pb.GitCommit) error {
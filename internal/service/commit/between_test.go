@@ -186,6 +186,49 @@ func TestSuccessfulCommitsBetween(t *testing.T) {
 	}
 }
 
+func TestCommitsBetweenWithPagination(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	// git log limits the commit list with --skip and --max-count before
+	// applying --reverse, so the window is counted from the newest commit.
+	rpcRequest := pb.CommitsBetweenRequest{
+		Repository: testRepo,
+		From:       []byte("498214de67004b1da3d820901307bed2a68a8ef6"), // branch-merged
+		To:         []byte("ba3343bc4fa403a8dfbfcab7fc1a8c29ee34bd69"), // spooky-stuff
+		MaxCount:   2,
+		Skip:       1,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := client.CommitsBetween(ctx, &rpcRequest)
+	require.NoError(t, err)
+
+	var commitIDs []string
+	for {
+		resp, err := c.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		for _, commit := range resp.GetCommits() {
+			commitIDs = append(commitIDs, commit.Id)
+		}
+	}
+
+	require.Equal(t, []string{
+		"4a24d82dbca5c11c61556f3b35ca472b7463187e",
+		"e63f41fe459e62e1228fcef60d7189127aeba95a",
+	}, commitIDs)
+}
+
 func TestFailedCommitsBetweenRequest(t *testing.T) {
 	server, serverSocketPath := startTestServices(t)
 	defer server.Stop()
@@ -205,6 +248,8 @@ func TestFailedCommitsBetweenRequest(t *testing.T) {
 		repository  *pb.Repository
 		from        []byte
 		to          []byte
+		maxCount    int32
+		skip        int32
 		code        codes.Code
 	}{
 		{
@@ -247,12 +292,29 @@ func TestFailedCommitsBetweenRequest(t *testing.T) {
 			to:          append([]byte("-"), to...),
 			code:        codes.InvalidArgument,
 		},
+		{
+			description: "MaxCount is negative",
+			repository:  testRepo,
+			from:        from,
+			to:          to,
+			maxCount:    -1,
+			code:        codes.InvalidArgument,
+		},
+		{
+			description: "Skip is negative",
+			repository:  testRepo,
+			from:        from,
+			to:          to,
+			skip:        -1,
+			code:        codes.InvalidArgument,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.description, func(t *testing.T) {
 			rpcRequest := pb.CommitsBetweenRequest{
 				Repository: tc.repository, From: tc.from, To: tc.to,
+				MaxCount: tc.maxCount, Skip: tc.skip,
 			}
 
 			ctx, cancel := context.WithCancel(context.Background())
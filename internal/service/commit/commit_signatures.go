@@ -0,0 +1,138 @@
+package commit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git/catfile"
+	"gitlab.com/gitlab-org/gitaly/streamio"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var commitIDRegex = regexp.MustCompile(`\A[0-9a-f]{40}\z`)
+
+func (s *server) GetCommitSignatures(request *pb.GetCommitSignaturesRequest, stream pb.CommitService_GetCommitSignaturesServer) error {
+	if err := validateGetCommitSignaturesRequest(request); err != nil {
+		return status.Errorf(codes.InvalidArgument, "GetCommitSignatures: %v", err)
+	}
+
+	return catfile.CatFile(stream.Context(), request.GetRepository(), func(stdin io.Writer, stdout *bufio.Reader) error {
+		for _, commitID := range request.GetCommitIds() {
+			rawCommit, err := readRawCommit(commitID, stdin, stdout)
+			if err != nil {
+				return status.Errorf(codes.Internal, "GetCommitSignatures: %v", err)
+			}
+			if rawCommit == nil {
+				continue
+			}
+
+			signature, signedText := splitRawCommitSignature(rawCommit)
+			if len(signature) == 0 {
+				continue
+			}
+
+			if err := sendCommitSignature(stream, commitID, signature, signedText); err != nil {
+				return status.Errorf(codes.Unavailable, "GetCommitSignatures: send: %v", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func validateGetCommitSignaturesRequest(request *pb.GetCommitSignaturesRequest) error {
+	if len(request.GetCommitIds()) == 0 {
+		return fmt.Errorf("empty CommitIds")
+	}
+
+	for _, commitID := range request.GetCommitIds() {
+		if !commitIDRegex.MatchString(commitID) {
+			return fmt.Errorf("invalid commit id: %q", commitID)
+		}
+	}
+
+	return nil
+}
+
+func sendCommitSignature(stream pb.CommitService_GetCommitSignaturesServer, commitID string, signature, signedText []byte) error {
+	if err := stream.Send(&pb.GetCommitSignaturesResponse{CommitId: commitID, Signature: signature}); err != nil {
+		return err
+	}
+
+	sw := streamio.NewWriter(func(p []byte) error {
+		return stream.Send(&pb.GetCommitSignaturesResponse{SignedText: p})
+	})
+
+	_, err := sw.Write(signedText)
+	return err
+}
+
+// readRawCommit requests commitID from `git cat-file --batch` and returns
+// the raw commit object. It returns nil when the object does not exist or
+// is not a commit.
+func readRawCommit(commitID string, stdin io.Writer, stdout *bufio.Reader) ([]byte, error) {
+	if _, err := fmt.Fprintln(stdin, commitID); err != nil {
+		return nil, fmt.Errorf("stdin write: %v", err)
+	}
+
+	objectInfo, err := catfile.ParseObjectInfo(stdout)
+	if err != nil {
+		return nil, err
+	}
+	if objectInfo.Oid == "" {
+		return nil, nil
+	}
+
+	rawCommit := make([]byte, objectInfo.Size)
+	if _, err := io.ReadFull(stdout, rawCommit); err != nil {
+		return nil, fmt.Errorf("read object: %v", err)
+	}
+	if _, err := stdout.Discard(1); err != nil {
+		return nil, fmt.Errorf("discard object newline: %v", err)
+	}
+
+	if objectInfo.Type != "commit" {
+		return nil, nil
+	}
+
+	return rawCommit, nil
+}
+
+// splitRawCommitSignature splits a raw commit object into its GPG signature
+// and the signed text the signature covers: the commit object without the
+// gpgsig header.
+func splitRawCommitSignature(rawCommit []byte) (signature, signedText []byte) {
+	inHeader := true
+	inSignature := false
+
+	for _, line := range bytes.SplitAfter(rawCommit, []byte("\n")) {
+		if inHeader {
+			if inSignature && bytes.HasPrefix(line, []byte(" ")) {
+				// Continuation lines of the signature are indented by one space
+				signature = append(signature, line[1:]...)
+				continue
+			}
+			inSignature = false
+
+			if bytes.HasPrefix(line, []byte("gpgsig ")) {
+				inSignature = true
+				signature = append(signature, line[len("gpgsig "):]...)
+				continue
+			}
+
+			if bytes.Equal(line, []byte("\n")) {
+				inHeader = false
+			}
+		}
+
+		signedText = append(signedText, line...)
+	}
+
+	return signature, signedText
+}
@@ -0,0 +1,116 @@
+package commit
+
+import (
+	"io"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulGetCommitSignaturesRequest(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	request := &pb.GetCommitSignaturesRequest{
+		Repository: testRepo,
+		CommitIds: []string{
+			"5937ac0a7beb003549fc5fd26fc247adbce4a52e", // signed
+			"6907208d755b60ebeacb2e9dfea74c92c3449a1f", // unsigned
+		},
+	}
+
+	c, err := client.GetCommitSignatures(ctx, request)
+	require.NoError(t, err)
+
+	type commitSignature struct {
+		signature  []byte
+		signedText []byte
+	}
+	signatures := make(map[string]*commitSignature)
+
+	var current *commitSignature
+	for {
+		resp, err := c.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		if commitID := resp.GetCommitId(); commitID != "" {
+			current = &commitSignature{signature: resp.GetSignature()}
+			signatures[commitID] = current
+		} else {
+			require.NotNil(t, current, "signed text chunk before first commit id")
+			current.signedText = append(current.signedText, resp.GetSignedText()...)
+		}
+	}
+
+	require.Len(t, signatures, 1, "only the signed commit should be returned")
+
+	signed := signatures["5937ac0a7beb003549fc5fd26fc247adbce4a52e"]
+	require.NotNil(t, signed)
+	require.Contains(t, string(signed.signature), "-----BEGIN PGP SIGNATURE-----")
+	require.Contains(t, string(signed.signedText), "\nAdd submodule from gitlab.com")
+	require.NotContains(t, string(signed.signedText), "gpgsig")
+}
+
+func TestFailedGetCommitSignaturesRequest(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	testCases := []struct {
+		desc      string
+		commitIds []string
+	}{
+		{
+			desc:      "empty CommitIds",
+			commitIds: []string{},
+		},
+		{
+			desc:      "commit id not a full sha",
+			commitIds: []string{"5937ac0a"},
+		},
+		{
+			desc:      "commit id is a ref name",
+			commitIds: []string{"master"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			c, err := client.GetCommitSignatures(ctx, &pb.GetCommitSignaturesRequest{
+				Repository: testRepo,
+				CommitIds:  tc.commitIds,
+			})
+			require.NoError(t, err)
+
+			var recvErr error
+			for recvErr == nil {
+				_, recvErr = c.Recv()
+			}
+			testhelper.AssertGrpcError(t, recvErr, codes.InvalidArgument, "")
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package commit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) GetCommitCountsPerAuthor(ctx context.Context, in *pb.GetCommitCountsPerAuthorRequest) (*pb.GetCommitCountsPerAuthorResponse, error) {
+	if err := validateGetCommitCountsPerAuthorRequest(in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "GetCommitCountsPerAuthor: %v", err)
+	}
+
+	cmdArgs := []string{"log", "--pretty=format:%an\x1f%ae", string(in.GetRevision())}
+
+	if before := in.GetBefore(); before != nil {
+		cmdArgs = append(cmdArgs, "--before="+timestampToRFC3339(before.Seconds))
+	}
+	if after := in.GetAfter(); after != nil {
+		cmdArgs = append(cmdArgs, "--after="+timestampToRFC3339(after.Seconds))
+	}
+
+	cmd, err := git.Command(ctx, in.Repository, cmdArgs...)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "GetCommitCountsPerAuthor: cmd: %v", err)
+	}
+
+	counts := make(map[string]*pb.GetCommitCountsPerAuthorResponse_AuthorCount)
+
+	scanner := bufio.NewScanner(cmd)
+	for scanner.Scan() {
+		elements := bytes.Split(scanner.Bytes(), []byte("\x1f"))
+		if len(elements) != 2 {
+			return nil, status.Errorf(codes.Internal, "GetCommitCountsPerAuthor: parse log line: %q", scanner.Text())
+		}
+
+		email := string(elements[1])
+		authorCount, ok := counts[email]
+		if !ok {
+			// The name of the first commit we encounter for this email wins,
+			// like `git shortlog -sne` without mailmap lookups.
+			authorCount = &pb.GetCommitCountsPerAuthorResponse_AuthorCount{
+				Name:  append([]byte(nil), elements[0]...),
+				Email: []byte(email),
+			}
+			counts[email] = authorCount
+		}
+		authorCount.Count++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "GetCommitCountsPerAuthor: scan: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, status.Errorf(codes.Internal, "GetCommitCountsPerAuthor: cmd wait: %v", err)
+	}
+
+	authorCounts := make([]*pb.GetCommitCountsPerAuthorResponse_AuthorCount, 0, len(counts))
+	for _, authorCount := range counts {
+		authorCounts = append(authorCounts, authorCount)
+	}
+	sort.Slice(authorCounts, func(i, j int) bool {
+		if authorCounts[i].Count != authorCounts[j].Count {
+			return authorCounts[i].Count > authorCounts[j].Count
+		}
+		return bytes.Compare(authorCounts[i].Email, authorCounts[j].Email) < 0
+	})
+
+	return &pb.GetCommitCountsPerAuthorResponse{AuthorCounts: authorCounts}, nil
+}
+
+func validateGetCommitCountsPerAuthorRequest(in *pb.GetCommitCountsPerAuthorRequest) error {
+	if len(in.GetRevision()) == 0 {
+		return fmt.Errorf("empty Revision")
+	}
+
+	return nil
+}
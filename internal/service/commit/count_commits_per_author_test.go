@@ -0,0 +1,101 @@
+package commit
+
+import (
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulGetCommitCountsPerAuthorRequest(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepoWithWorktree(t)
+	defer cleanupFn()
+
+	authors := []struct {
+		name  string
+		email string
+	}{
+		{"Scrooge McDuck", "scrooge@mcduck.com"},
+		{"Scrooge McDuck", "scrooge@mcduck.com"},
+		{"Launchpad McQuack", "launchpad@duckburg.com"},
+	}
+
+	for i, author := range authors {
+		testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath,
+			"-c", "user.name="+author.name,
+			"-c", "user.email="+author.email,
+			"commit", "--allow-empty", "-m", "An empty commit "+string('a'+rune(i)))
+	}
+
+	request := &pb.GetCommitCountsPerAuthorRequest{
+		Repository: testRepo,
+		Revision:   []byte("HEAD"),
+		// All commits in the gitlab-test fixture predate this timestamp, so
+		// only the commits created above are counted.
+		After: &timestamp.Timestamp{Seconds: 1600000000},
+	}
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	response, err := client.GetCommitCountsPerAuthor(ctx, request)
+	require.NoError(t, err)
+
+	expected := []*pb.GetCommitCountsPerAuthorResponse_AuthorCount{
+		{
+			Name:  []byte("Scrooge McDuck"),
+			Email: []byte("scrooge@mcduck.com"),
+			Count: 2,
+		},
+		{
+			Name:  []byte("Launchpad McQuack"),
+			Email: []byte("launchpad@duckburg.com"),
+			Count: 1,
+		},
+	}
+
+	require.Equal(t, expected, response.GetAuthorCounts())
+}
+
+func TestFailedGetCommitCountsPerAuthorRequest(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	testCases := []struct {
+		desc    string
+		request *pb.GetCommitCountsPerAuthorRequest
+		code    codes.Code
+	}{
+		{
+			desc:    "empty revision",
+			request: &pb.GetCommitCountsPerAuthorRequest{Repository: testRepo},
+			code:    codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			_, err := client.GetCommitCountsPerAuthor(ctx, tc.request)
+			testhelper.AssertGrpcError(t, err, tc.code, "")
+		})
+	}
+}
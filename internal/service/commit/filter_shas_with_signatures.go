@@ -1,8 +1,11 @@
 package commit
 
 import (
+	"bufio"
+	"io"
+
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/git/catfile"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -17,45 +20,46 @@ func (s *server) FilterShasWithSignatures(bidi pb.CommitService_FilterShasWithSi
 		return err
 	}
 
-	ctx := bidi.Context()
-	client, err := s.CommitServiceClient(ctx)
-	if err != nil {
-		return err
-	}
-
-	clientCtx, err := rubyserver.SetHeaders(ctx, firstRequest.GetRepository())
-	if err != nil {
-		return err
-	}
-
-	rubyBidi, err := client.FilterShasWithSignatures(clientCtx)
-	if err != nil {
-		return err
-	}
-
-	if err := rubyBidi.Send(firstRequest); err != nil {
-		return err
-	}
-
-	return rubyserver.ProxyBidi(
-		func() error {
-			request, err := bidi.Recv()
+	return catfile.CatFile(bidi.Context(), firstRequest.GetRepository(), func(stdin io.Writer, stdout *bufio.Reader) error {
+		request := firstRequest
+		for {
+			shas, err := filterCommitShasWithSignatures(stdin, stdout, request.GetShas())
 			if err != nil {
-				return err
+				return status.Errorf(codes.Internal, "FilterShasWithSignatures: %v", err)
+			}
+
+			if err := bidi.Send(&pb.FilterShasWithSignaturesResponse{Shas: shas}); err != nil {
+				return status.Errorf(codes.Unavailable, "FilterShasWithSignatures: send: %v", err)
 			}
 
-			return rubyBidi.Send(request)
-		},
-		rubyBidi,
-		func() error {
-			response, err := rubyBidi.Recv()
+			request, err = bidi.Recv()
+			if err == io.EOF {
+				return nil
+			}
 			if err != nil {
 				return err
 			}
+		}
+	})
+}
+
+func filterCommitShasWithSignatures(stdin io.Writer, stdout *bufio.Reader, shas [][]byte) ([][]byte, error) {
+	var foundShas [][]byte
+	for _, sha := range shas {
+		rawCommit, err := readRawCommit(string(sha), stdin, stdout)
+		if err != nil {
+			return nil, err
+		}
+		if rawCommit == nil {
+			continue
+		}
+
+		if signature, _ := splitRawCommitSignature(rawCommit); len(signature) > 0 {
+			foundShas = append(foundShas, sha)
+		}
+	}
 
-			return bidi.Send(response)
-		},
-	)
+	return foundShas, nil
 }
 
 func verifyFirstFilterShasWithSignaturesRequest(in *pb.FilterShasWithSignaturesRequest) error {
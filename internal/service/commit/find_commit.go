@@ -15,10 +15,15 @@ func (s *server) FindCommit(ctx context.Context, in *pb.FindCommitRequest) (*pb.
 		return nil, status.Errorf(codes.InvalidArgument, "FindCommit: revision: %v", err)
 	}
 
-	commit, err := log.GetCommit(ctx, in.GetRepository(), string(in.GetRevision()), "")
+	commit, err := log.GetCommitCatfile(ctx, in.GetRepository(), string(in.GetRevision()))
 	if err != nil {
 		return nil, err
 	}
 
-	return &pb.FindCommitResponse{Commit: commit}, nil
+	resp := &pb.FindCommitResponse{Commit: commit}
+	if in.GetLenient() && commit != nil {
+		resp.Warnings = git.SanitizeCommit(commit)
+	}
+
+	return resp, nil
 }
@@ -2,7 +2,9 @@ package commit
 
 import (
 	"io/ioutil"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/stretchr/testify/require"
@@ -200,3 +202,55 @@ func TestFailedFindCommitRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestFindCommitLenient(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	commitID := writeMalformedCommit(t, testRepoPath)
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	strict, err := client.FindCommit(ctx, &pb.FindCommitRequest{
+		Repository: testRepo,
+		Revision:   []byte(commitID),
+	})
+	require.NoError(t, err)
+	require.Nil(t, strict.Commit.Committer, "strict mode must not fill in a committer")
+	require.Empty(t, strict.Warnings)
+
+	lenient, err := client.FindCommit(ctx, &pb.FindCommitRequest{
+		Repository: testRepo,
+		Revision:   []byte(commitID),
+		Lenient:    true,
+	})
+	require.NoError(t, err)
+
+	commit := lenient.Commit
+	require.Equal(t, []byte("<unknown>"), commit.Committer.Name)
+	require.Equal(t, []byte("<unknown>"), commit.Committer.Email)
+	require.True(t, utf8.Valid(commit.Author.Name), "author name must be repaired to valid UTF-8")
+	require.NotEmpty(t, lenient.Warnings)
+}
+
+// writeMalformedCommit writes a commit object with a non-UTF-8 author
+// name, a broken timezone and no committer line, as regularly found in
+// imported repositories.
+func writeMalformedCommit(t *testing.T, repoPath string) string {
+	rawCommit := "tree 4b825dc642cb6eb9a060e54bf8d69288fbee4904\n" +
+		"author B\xe1d Author <bad@example.com> 1234567890 +badzone\n" +
+		"\nbroken commit\n"
+
+	commitID := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader(rawCommit),
+		"git", "-C", repoPath, "hash-object", "-w", "-t", "commit", "--stdin", "--literally")))
+	testhelper.MustRunCommand(t, nil, "git", "-C", repoPath, "update-ref", "refs/heads/master", commitID)
+
+	return commitID
+}
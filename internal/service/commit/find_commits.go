@@ -1,20 +1,32 @@
 package commit
 
 import (
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"fmt"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+type findCommitsSender struct {
+	stream  pb.CommitService_FindCommitsServer
+	lenient bool
+}
+
 func (s *server) FindCommits(req *pb.FindCommitsRequest, stream pb.CommitService_FindCommitsServer) error {
 	ctx := stream.Context()
 
+	if revision := req.GetRevision(); len(revision) > 0 {
+		if err := git.ValidateRevision(revision); err != nil {
+			return status.Errorf(codes.InvalidArgument, "FindCommits: revision: %v", err)
+		}
+	}
+
 	// Use Gitaly's default branch lookup function because that is already
 	// migrated.
-	if revision := req.Revision; len(revision) == 0 {
+	if revision := req.Revision; len(revision) == 0 && !req.GetAll() {
 		var err error
 		req.Revision, err = defaultBranchName(ctx, req.Repository)
 		if err != nil {
@@ -29,28 +41,57 @@ func (s *server) FindCommits(req *pb.FindCommitsRequest, stream pb.CommitService
 		}
 	}
 
-	client, err := s.CommitServiceClient(ctx)
-	if err != nil {
-		return err
+	paths := make([]string, 0, len(req.GetPaths()))
+	for _, path := range req.GetPaths() {
+		paths = append(paths, string(path))
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, req.GetRepository())
-	if err != nil {
-		return err
+	sender := &findCommitsSender{stream, req.GetLenient()}
+
+	revisions := []string{string(req.GetRevision())}
+	if req.GetAll() {
+		revisions = []string{"--all"}
 	}
 
-	rubyStream, err := client.FindCommits(clientCtx, req)
-	if err != nil {
-		return err
+	return sendCommits(ctx, sender, req.GetRepository(), revisions, paths, findCommitsExtraOptions(req)...)
+}
+
+func findCommitsExtraOptions(req *pb.FindCommitsRequest) []string {
+	var options []string
+
+	if limit := req.GetLimit(); limit > 0 {
+		options = append(options, fmt.Sprintf("--max-count=%d", limit))
+	}
+	if offset := req.GetOffset(); offset > 0 {
+		options = append(options, fmt.Sprintf("--skip=%d", offset))
+	}
+	if req.GetFollow() && len(req.GetPaths()) > 0 {
+		options = append(options, "--follow")
+	}
+	if req.GetSkipMerges() {
+		options = append(options, "--no-merges")
+	}
+	if before := req.GetBefore(); before != nil {
+		options = append(options, "--before="+timestampToRFC3339(before.Seconds))
+	}
+	if after := req.GetAfter(); after != nil {
+		options = append(options, "--after="+timestampToRFC3339(after.Seconds))
+	}
+	if author := req.GetAuthor(); len(author) > 0 {
+		options = append(options, "--author="+string(author))
 	}
 
-	return rubyserver.Proxy(func() error {
-		resp, err := rubyStream.Recv()
-		if err != nil {
-			md := rubyStream.Trailer()
-			stream.SetTrailer(md)
-			return err
+	return options
+}
+
+func (sender *findCommitsSender) Send(commits []*pb.GitCommit) error {
+	resp := &pb.FindCommitsResponse{Commits: commits}
+
+	if sender.lenient {
+		for _, commit := range commits {
+			resp.Warnings = append(resp.Warnings, git.SanitizeCommit(commit)...)
 		}
-		return stream.Send(resp)
-	})
+	}
+
+	return sender.stream.Send(resp)
 }
@@ -9,6 +9,7 @@ import (
 	"os/exec"
 	"path"
 	"testing"
+	"unicode/utf8"
 
 	"google.golang.org/grpc/codes"
 
@@ -167,7 +168,33 @@ func TestSuccessfulFindCommitsRequest(t *testing.T) {
 				Revision:    []byte("0031876facac3f2b2702a0e53a26e89939a42209"),
 				DisableWalk: true,
 			},
-		}, {
+			minCommits: 14,
+		},
+		{
+			desc: "revision and offset",
+			request: &pb.FindCommitsRequest{
+				Repository: testRepo,
+				Revision:   []byte("0031876facac3f2b2702a0e53a26e89939a42209"),
+				Offset:     3,
+				Limit:      3,
+			},
+			ids: []string{
+				"9d526f87b82e2b2fd231ca44c95508e5e85624ca",
+				"335bc94d5b7369b10251e612158da2e4a4aaa2a5",
+				"1039376155a0d507eba0ea95c29f8f5b983ea34b",
+			},
+		},
+		{
+			desc: "revision and author",
+			request: &pb.FindCommitsRequest{
+				Repository: testRepo,
+				Revision:   []byte("b83d6e391c22777fca1ed3012fce84f633d7fed0"),
+				Author:     []byte("Job van der Voort"),
+				Limit:      1,
+			},
+			ids: []string{"b83d6e391c22777fca1ed3012fce84f633d7fed0"},
+		},
+		{
 			desc: "revision and paths",
 			request: &pb.FindCommitsRequest{
 				Repository: testRepo,
@@ -177,6 +204,16 @@ func TestSuccessfulFindCommitsRequest(t *testing.T) {
 			},
 			ids: []string{"1a0b36b3cdad1d2ee32457c102a8c0b7056fa863"},
 		},
+		{
+			desc: "all refs",
+			request: &pb.FindCommitsRequest{
+				Repository: testRepo,
+				All:        true,
+			},
+			// More commits are reachable from all refs together than from the
+			// default branch alone.
+			minCommits: 15,
+		},
 		{
 			desc: "empty revision",
 			request: &pb.FindCommitsRequest{
@@ -383,6 +420,14 @@ func TestFailureFindCommitsRequest(t *testing.T) {
 			},
 			code: codes.InvalidArgument,
 		},
+		{
+			desc: "revision looks like an option",
+			request: &pb.FindCommitsRequest{
+				Repository: testRepo,
+				Revision:   []byte("--output=/tmp/arbitrary-path"),
+			},
+			code: codes.InvalidArgument,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -401,3 +446,48 @@ func TestFailureFindCommitsRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestFindCommitsLenient(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	writeMalformedCommit(t, testRepoPath)
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	stream, err := client.FindCommits(ctx, &pb.FindCommitsRequest{
+		Repository: testRepo,
+		Revision:   []byte("master"),
+		Lenient:    true,
+	})
+	require.NoError(t, err)
+
+	var commits []*pb.GitCommit
+	var warnings []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		commits = append(commits, resp.GetCommits()...)
+		warnings = append(warnings, resp.GetWarnings()...)
+	}
+
+	require.Len(t, commits, 1)
+	require.NotEmpty(t, warnings)
+
+	commit := commits[0]
+	require.Equal(t, []byte("<unknown>"), commit.Committer.Name)
+	require.Equal(t, []byte("<unknown>"), commit.Committer.Email)
+	require.Zero(t, commit.Committer.Date.Seconds, "broken committer date must be replaced by the epoch placeholder")
+	require.True(t, utf8.Valid(commit.Author.Name), "author name must be repaired to valid UTF-8")
+}
@@ -25,6 +25,51 @@ func (s *server) CommitIsAncestor(ctx context.Context, in *pb.CommitIsAncestorRe
 	return &pb.CommitIsAncestorResponse{Value: ret}, err
 }
 
+// maxIsAncestorBatchPairs bounds the work a single batched ancestry
+// check may ask for.
+const maxIsAncestorBatchPairs = 1000
+
+// CommitIsAncestorBatch answers many ancestry checks in one call so
+// callers checking whole pipelines against protected branches don't
+// issue hundreds of unary requests. Results come back in request order;
+// duplicate pairs are only evaluated once.
+func (s *server) CommitIsAncestorBatch(ctx context.Context, in *pb.CommitIsAncestorBatchRequest) (*pb.CommitIsAncestorBatchResponse, error) {
+	pairs := in.GetPairs()
+	if len(pairs) > maxIsAncestorBatchPairs {
+		return nil, status.Errorf(codes.InvalidArgument, "CommitIsAncestorBatch: too many pairs (%d > %d)", len(pairs), maxIsAncestorBatchPairs)
+	}
+
+	for i, pair := range pairs {
+		if pair.GetAncestorId() == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "CommitIsAncestorBatch: empty ancestor sha in pair %d", i)
+		}
+		if pair.GetChildId() == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "CommitIsAncestorBatch: empty child sha in pair %d", i)
+		}
+	}
+
+	cache := make(map[pb.CommitIsAncestorBatchRequest_Pair]bool)
+	values := make([]bool, 0, len(pairs))
+
+	for _, pair := range pairs {
+		key := pb.CommitIsAncestorBatchRequest_Pair{AncestorId: pair.GetAncestorId(), ChildId: pair.GetChildId()}
+
+		value, ok := cache[key]
+		if !ok {
+			var err error
+			value, err = commitIsAncestorName(ctx, in.Repository, pair.GetAncestorId(), pair.GetChildId())
+			if err != nil {
+				return nil, err
+			}
+			cache[key] = value
+		}
+
+		values = append(values, value)
+	}
+
+	return &pb.CommitIsAncestorBatchResponse{Values: values}, nil
+}
+
 // Assumes that `path`, `ancestorID` and `childID` are populated :trollface:
 func commitIsAncestorName(ctx context.Context, repo *pb.Repository, ancestorID, childID string) (bool, error) {
 	grpc_logrus.Extract(ctx).WithFields(log.Fields{
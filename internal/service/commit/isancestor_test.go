@@ -260,3 +260,117 @@ func TestSuccessfulIsAncestorRequestWithAltGitObjectDirs(t *testing.T) {
 		})
 	}
 }
+
+func TestCommitIsAncestorBatchSuccess(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	request := &pb.CommitIsAncestorBatchRequest{
+		Repository: testRepo,
+		Pairs: []*pb.CommitIsAncestorBatchRequest_Pair{
+			{
+				AncestorId: "8a0f2ee90d940bfb0ba1e14e8214b0649056e4ab",
+				ChildId:    "372ab6950519549b14d220271ee2322caa44d4eb",
+			},
+			{
+				AncestorId: "372ab6950519549b14d220271ee2322caa44d4eb",
+				ChildId:    "8a0f2ee90d940bfb0ba1e14e8214b0649056e4ab",
+			},
+			{
+				AncestorId: "1234123412341234123412341234123412341234",
+				ChildId:    "b83d6e391c22777fca1ed3012fce84f633d7fed0",
+			},
+			// Duplicate of the first pair; must only be evaluated once but
+			// still occupy its slot in the response.
+			{
+				AncestorId: "8a0f2ee90d940bfb0ba1e14e8214b0649056e4ab",
+				ChildId:    "372ab6950519549b14d220271ee2322caa44d4eb",
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	response, err := client.CommitIsAncestorBatch(ctx, request)
+	if err != nil {
+		t.Fatalf("CommitIsAncestorBatch threw error unexpectedly: %v", err)
+	}
+
+	expected := []bool{true, false, false, true}
+	if len(response.GetValues()) != len(expected) {
+		t.Fatalf("Expected %d values, got %d", len(expected), len(response.GetValues()))
+	}
+	for i, value := range response.GetValues() {
+		if value != expected[i] {
+			t.Errorf("Expected value %d to be %v, got %v", i, expected[i], value)
+		}
+	}
+}
+
+func TestCommitIsAncestorBatchFailure(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	tooManyPairs := make([]*pb.CommitIsAncestorBatchRequest_Pair, 1001)
+	for i := range tooManyPairs {
+		tooManyPairs[i] = &pb.CommitIsAncestorBatchRequest_Pair{
+			AncestorId: "b83d6e391c22777fca1ed3012fce84f633d7fed0",
+			ChildId:    "8a0f2ee90d940bfb0ba1e14e8214b0649056e4ab",
+		}
+	}
+
+	queries := []struct {
+		Request *pb.CommitIsAncestorBatchRequest
+		ErrMsg  string
+	}{
+		{
+			Request: &pb.CommitIsAncestorBatchRequest{
+				Repository: testRepo,
+				Pairs: []*pb.CommitIsAncestorBatchRequest_Pair{
+					{ChildId: "b83d6e391c22777fca1ed3012fce84f633d7fed0"},
+				},
+			},
+			ErrMsg: "Expected to throw because of empty ancestor sha",
+		},
+		{
+			Request: &pb.CommitIsAncestorBatchRequest{
+				Repository: testRepo,
+				Pairs: []*pb.CommitIsAncestorBatchRequest_Pair{
+					{AncestorId: "b83d6e391c22777fca1ed3012fce84f633d7fed0"},
+				},
+			},
+			ErrMsg: "Expected to throw because of empty child sha",
+		},
+		{
+			Request: &pb.CommitIsAncestorBatchRequest{
+				Repository: testRepo,
+				Pairs:      tooManyPairs,
+			},
+			ErrMsg: "Expected to throw because of too many pairs",
+		},
+	}
+
+	for _, v := range queries {
+		t.Run(v.ErrMsg, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if _, err := client.CommitIsAncestorBatch(ctx, v.Request); err == nil {
+				t.Error(v.ErrMsg)
+			} else {
+				testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+			}
+		})
+	}
+}
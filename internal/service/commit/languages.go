@@ -23,7 +23,7 @@ func (*server) CommitLanguages(ctx context.Context, req *pb.CommitLanguagesReque
 
 	revision := string(req.Revision)
 	if revision == "" {
-		defaultBranch, err := ref.DefaultBranchName(ctx, req.Repository)
+		defaultBranch, err := ref.CachedDefaultBranchName(ctx, req.Repository)
 		if err != nil {
 			return nil, err
 		}
@@ -32,7 +32,10 @@ func (*server) CommitLanguages(ctx context.Context, req *pb.CommitLanguagesReque
 
 	commitID, err := lookupRevision(ctx, repo, revision)
 	if err != nil {
-		return nil, err
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "CommitLanguages: lookup revision: %v", err)
 	}
 
 	repoPath, err := helper.GetRepoPath(repo)
@@ -41,7 +44,7 @@ func (*server) CommitLanguages(ctx context.Context, req *pb.CommitLanguagesReque
 	}
 	stats, err := linguist.Stats(ctx, repoPath, commitID)
 	if err != nil {
-		return nil, err
+		return nil, status.Errorf(codes.Internal, "CommitLanguages: gather stats: %v", err)
 	}
 
 	resp := &pb.CommitLanguagesResponse{}
@@ -76,10 +79,15 @@ type languageSorter []*pb.CommitLanguagesResponse_Language
 
 func (ls languageSorter) Len() int           { return len(ls) }
 func (ls languageSorter) Swap(i, j int)      { ls[i], ls[j] = ls[j], ls[i] }
-func (ls languageSorter) Less(i, j int) bool { return ls[i].Share > ls[j].Share }
+func (ls languageSorter) Less(i, j int) bool {
+	if ls[i].Share == ls[j].Share {
+		return ls[i].Name < ls[j].Name
+	}
+	return ls[i].Share > ls[j].Share
+}
 
 func lookupRevision(ctx context.Context, repo *pb.Repository, revision string) (string, error) {
-	revParse, err := git.Command(ctx, repo, "rev-parse", revision)
+	revParse, err := git.Command(ctx, repo, "rev-parse", "--verify", revision)
 	if err != nil {
 		return "", err
 	}
@@ -89,5 +97,9 @@ func lookupRevision(ctx context.Context, repo *pb.Repository, revision string) (
 		return "", err
 	}
 
+	if err := revParse.Wait(); err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "CommitLanguages: revision not found %q", revision)
+	}
+
 	return strings.TrimSpace(string(revParseBytes)), nil
 }
@@ -8,6 +8,7 @@ import (
 	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
 )
 
 func TestLanguages(t *testing.T) {
@@ -63,6 +64,27 @@ func languageEqual(expected, actual *pb.CommitLanguagesResponse_Language) bool {
 	return true
 }
 
+func TestLanguagesInvalidRevision(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	request := &pb.CommitLanguagesRequest{
+		Repository: testRepo,
+		Revision:   []byte("not-a-ref"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := client.CommitLanguages(ctx, request)
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "revision not found")
+}
+
 func TestLanguagesEmptyRevision(t *testing.T) {
 	server, serverSocketPath := startTestServices(t)
 	defer server.Stop()
@@ -0,0 +1,80 @@
+package commit
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) ListCommitsByAuthorDateRange(ctx context.Context, in *pb.ListCommitsByAuthorDateRangeRequest) (*pb.ListCommitsByAuthorDateRangeResponse, error) {
+	if err := validateListCommitsByAuthorDateRangeRequest(in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "ListCommitsByAuthorDateRange: %v", err)
+	}
+
+	cmdArgs := []string{"log", "--pretty=format:%ad", "--date=short", string(in.GetRevision())}
+
+	if author := in.GetAuthor(); len(author) > 0 {
+		cmdArgs = append(cmdArgs, "--author="+string(author))
+	}
+	if before := in.GetBefore(); before != nil {
+		cmdArgs = append(cmdArgs, "--before="+timestampToRFC3339(before.Seconds))
+	}
+	if after := in.GetAfter(); after != nil {
+		cmdArgs = append(cmdArgs, "--after="+timestampToRFC3339(after.Seconds))
+	}
+
+	cmd, err := git.Command(ctx, in.Repository, cmdArgs...)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "ListCommitsByAuthorDateRange: cmd: %v", err)
+	}
+
+	counts := make(map[string]int32)
+
+	scanner := bufio.NewScanner(cmd)
+	for scanner.Scan() {
+		counts[scanner.Text()]++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "ListCommitsByAuthorDateRange: scan: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, status.Errorf(codes.Internal, "ListCommitsByAuthorDateRange: cmd wait: %v", err)
+	}
+
+	dateCounts := make([]*pb.ListCommitsByAuthorDateRangeResponse_DateCount, 0, len(counts))
+	for date, count := range counts {
+		dateCounts = append(dateCounts, &pb.ListCommitsByAuthorDateRangeResponse_DateCount{
+			Date:  date,
+			Count: count,
+		})
+	}
+	sort.Slice(dateCounts, func(i, j int) bool {
+		return dateCounts[i].Date < dateCounts[j].Date
+	})
+
+	return &pb.ListCommitsByAuthorDateRangeResponse{DateCounts: dateCounts}, nil
+}
+
+func validateListCommitsByAuthorDateRangeRequest(in *pb.ListCommitsByAuthorDateRangeRequest) error {
+	if len(in.GetRevision()) == 0 {
+		return fmt.Errorf("empty Revision")
+	}
+
+	if err := git.ValidateRevision(in.GetRevision()); err != nil {
+		return fmt.Errorf("invalid Revision: %v", err)
+	}
+
+	return nil
+}
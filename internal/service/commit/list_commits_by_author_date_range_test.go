@@ -0,0 +1,113 @@
+package commit
+
+import (
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulListCommitsByAuthorDateRangeRequest(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepoWithWorktree(t)
+	defer cleanupFn()
+
+	commits := []struct {
+		author string
+		date   string
+	}{
+		{"Scrooge McDuck", "2020-01-02T12:00:00"},
+		{"Scrooge McDuck", "2020-01-02T15:00:00"},
+		{"Launchpad McQuack", "2020-01-03T09:00:00"},
+	}
+
+	for i, commit := range commits {
+		testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath,
+			"-c", "user.name="+commit.author,
+			"-c", "user.email=author@example.com",
+			"commit", "--allow-empty", "--date", commit.date,
+			"-m", "An empty commit "+string('a'+rune(i)))
+	}
+
+	// All commits in the gitlab-test fixture predate this timestamp, so
+	// only the commits created above are counted.
+	after := &timestamp.Timestamp{Seconds: 1600000000}
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	response, err := client.ListCommitsByAuthorDateRange(ctx, &pb.ListCommitsByAuthorDateRangeRequest{
+		Repository: testRepo,
+		Revision:   []byte("HEAD"),
+		After:      after,
+	})
+	require.NoError(t, err)
+
+	expected := []*pb.ListCommitsByAuthorDateRangeResponse_DateCount{
+		{Date: "2020-01-02", Count: 2},
+		{Date: "2020-01-03", Count: 1},
+	}
+	require.Equal(t, expected, response.GetDateCounts())
+
+	response, err = client.ListCommitsByAuthorDateRange(ctx, &pb.ListCommitsByAuthorDateRangeRequest{
+		Repository: testRepo,
+		Revision:   []byte("HEAD"),
+		Author:     []byte("Scrooge"),
+		After:      after,
+	})
+	require.NoError(t, err)
+
+	expected = []*pb.ListCommitsByAuthorDateRangeResponse_DateCount{
+		{Date: "2020-01-02", Count: 2},
+	}
+	require.Equal(t, expected, response.GetDateCounts())
+}
+
+func TestFailedListCommitsByAuthorDateRangeRequest(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	testCases := []struct {
+		desc    string
+		request *pb.ListCommitsByAuthorDateRangeRequest
+	}{
+		{
+			desc: "empty Revision",
+			request: &pb.ListCommitsByAuthorDateRangeRequest{
+				Repository: testRepo,
+			},
+		},
+		{
+			desc: "Revision looking like an option",
+			request: &pb.ListCommitsByAuthorDateRangeRequest{
+				Repository: testRepo,
+				Revision:   []byte("--all"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			_, err := client.ListCommitsByAuthorDateRange(ctx, tc.request)
+			testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package commit
+
+import (
+	"bytes"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/helper/lines"
+)
+
+func (s *server) ListDirectories(in *pb.ListDirectoriesRequest, stream pb.CommitService_ListDirectoriesServer) error {
+	grpc_logrus.Extract(stream.Context()).WithFields(log.Fields{
+		"Revision": in.GetRevision(),
+		"Depth":    in.GetDepth(),
+	}).Debug("ListDirectories")
+
+	repo := in.Repository
+	if _, err := helper.GetRepoPath(repo); err != nil {
+		return err
+	}
+
+	revision := in.GetRevision()
+	if len(revision) == 0 {
+		var err error
+
+		revision, err = defaultBranchName(stream.Context(), repo)
+		if err != nil {
+			if _, ok := status.FromError(err); ok {
+				return err
+			}
+			return status.Errorf(codes.NotFound, "Revision not found %q", in.GetRevision())
+		}
+	}
+	if !git.IsValidRef(stream.Context(), repo, string(revision)) {
+		return stream.Send(&pb.ListDirectoriesResponse{})
+	}
+
+	cmd, err := git.Command(stream.Context(), repo, "ls-tree", "-z", "-r", "-d", "--full-tree", "--full-name", string(revision))
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Errorf(codes.Internal, err.Error())
+	}
+
+	return lines.Send(cmd, listDirectoriesWriter(stream, in.GetDepth()), []byte{'\x00'})
+}
+
+func listDirectoriesWriter(stream pb.CommitService_ListDirectoriesServer, depth uint32) lines.Sender {
+	return func(objs [][]byte) error {
+		paths := make([][]byte, 0)
+		for _, obj := range objs {
+			data := bytes.SplitN(obj, []byte{'\t'}, 2)
+			if len(data) != 2 {
+				return status.Errorf(codes.Internal, "ListDirectories: failed parsing line")
+			}
+
+			path := data[1]
+			if depth > 0 && uint32(bytes.Count(path, []byte{'/'}))+1 > depth {
+				continue
+			}
+
+			paths = append(paths, path)
+		}
+		return stream.Send(&pb.ListDirectoriesResponse{Paths: paths})
+	}
+}
@@ -0,0 +1,108 @@
+package commit
+
+import (
+	"io"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"google.golang.org/grpc/codes"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDirectoriesSuccess(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	tests := []struct {
+		desc     string
+		revision string
+		depth    uint32
+		paths    [][]byte
+	}{
+		{
+			desc:     "all depths",
+			revision: "54fcc214b94e78d7a41a9a8fe6d87a5e59500e51",
+			paths: [][]byte{
+				[]byte("encoding"), []byte("files"), []byte("files/html"),
+				[]byte("files/images"), []byte("files/js"), []byte("files/lfs"),
+				[]byte("files/markdown"), []byte("files/ruby"),
+				[]byte("foo"), []byte("foo/bar"),
+			},
+		},
+		{
+			desc:     "top level only",
+			revision: "54fcc214b94e78d7a41a9a8fe6d87a5e59500e51",
+			depth:    1,
+			paths:    [][]byte{[]byte("encoding"), []byte("files"), []byte("foo")},
+		},
+		{
+			desc:     "invalid revision",
+			revision: "1234123412341234",
+			paths:    [][]byte{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			rpcRequest := pb.ListDirectoriesRequest{
+				Repository: testRepo, Revision: []byte(test.revision), Depth: test.depth,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			c, err := client.ListDirectories(ctx, &rpcRequest)
+			require.NoError(t, err)
+
+			var paths [][]byte
+			for {
+				resp, err := c.Recv()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+				paths = append(paths, resp.GetPaths()...)
+			}
+
+			require.Len(t, paths, len(test.paths))
+			for i := range paths {
+				require.Equal(t, string(test.paths[i]), string(paths[i]))
+			}
+		})
+	}
+}
+
+func TestListDirectoriesFailure(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	rpcRequest := pb.ListDirectoriesRequest{
+		Repository: &pb.Repository{StorageName: "foo", RelativePath: "bar"},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := client.ListDirectories(ctx, &rpcRequest)
+	require.NoError(t, err)
+
+	for {
+		_, err = c.Recv()
+		if err != nil {
+			break
+		}
+	}
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+}
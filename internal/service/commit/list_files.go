@@ -40,7 +40,12 @@ func (s *server) ListFiles(in *pb.ListFilesRequest, stream pb.CommitService_List
 		return stream.Send(&pb.ListFilesResponse{})
 	}
 
-	cmd, err := git.Command(stream.Context(), repo, "ls-tree", "-z", "-r", "--full-tree", "--full-name", "--", string(revision))
+	cmdArgs := []string{"ls-tree", "-z", "-r", "--full-tree", "--full-name", string(revision), "--"}
+	if prefix := in.GetPathPrefix(); len(prefix) > 0 {
+		cmdArgs = append(cmdArgs, string(prefix))
+	}
+
+	cmd, err := git.Command(stream.Context(), repo, cmdArgs...)
 	if err != nil {
 		if _, ok := status.FromError(err); ok {
 			return err
@@ -136,6 +136,57 @@ func TestListFilesSuccess(t *testing.T) {
 	}
 }
 
+func TestListFilesWithPathPrefix(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	rpcRequest := pb.ListFilesRequest{
+		Repository: testRepo,
+		Revision:   []byte("test-do-not-touch"),
+		PathPrefix: []byte("files/ruby"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := client.ListFiles(ctx, &rpcRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var files [][]byte
+	for {
+		resp, err := c.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, resp.GetPaths()...)
+	}
+
+	expectedFiles := [][]byte{
+		[]byte("files/ruby/popen.rb"),
+		[]byte("files/ruby/regex.rb"),
+		[]byte("files/ruby/version_info.rb"),
+	}
+
+	if len(files) != len(expectedFiles) {
+		t.Fatalf("incorrect number of files: %d != %d", len(files), len(expectedFiles))
+	}
+
+	for i := range files {
+		if !bytes.Equal(files[i], expectedFiles[i]) {
+			t.Errorf("%q != %q", files[i], expectedFiles[i])
+		}
+	}
+}
+
 func TestListFilesFailure(t *testing.T) {
 	server, serverSocketPath := startTestServices(t)
 	defer server.Stop()
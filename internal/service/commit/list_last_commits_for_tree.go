@@ -0,0 +1,213 @@
+package commit
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/git/catfile"
+	gitlog "gitlab.com/gitlab-org/gitaly/internal/git/log"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var commitsForTreePerMessage = 20
+
+func (s *server) ListLastCommitsForTree(in *pb.ListLastCommitsForTreeRequest, stream pb.CommitService_ListLastCommitsForTreeServer) error {
+	if err := validateListLastCommitsForTreeRequest(in); err != nil {
+		return status.Errorf(codes.InvalidArgument, "ListLastCommitsForTree: %v", err)
+	}
+
+	// A context-scoped cat-file cache lets the commit lookups below
+	// share one process for the whole request.
+	ctx := catfile.WithCache(stream.Context())
+
+	handler := listLastCommitsForTreeHandler(ctx, stream, in)
+	return catfile.CatFile(ctx, in.Repository, handler)
+}
+
+func validateListLastCommitsForTreeRequest(in *pb.ListLastCommitsForTreeRequest) error {
+	if len(in.GetRevision()) == 0 {
+		return fmt.Errorf("empty Revision")
+	}
+
+	if in.GetLimit() < 0 {
+		return fmt.Errorf("negative Limit")
+	}
+
+	if in.GetOffset() < 0 {
+		return fmt.Errorf("negative Offset")
+	}
+
+	return nil
+}
+
+func listLastCommitsForTreeHandler(ctx context.Context, stream pb.CommitService_ListLastCommitsForTreeServer, in *pb.ListLastCommitsForTreeRequest) catfile.Handler {
+	return func(stdin io.Writer, stdout *bufio.Reader) error {
+		revision := string(in.GetRevision())
+		path := string(in.GetPath())
+
+		entries, err := treeEntries(revision, path, stdin, stdout)
+		if err != nil {
+			return err
+		}
+
+		if offset := int(in.GetOffset()); offset < len(entries) {
+			entries = entries[offset:]
+		} else {
+			entries = nil
+		}
+		if limit := int(in.GetLimit()); limit > 0 && len(entries) > limit {
+			entries = entries[:limit]
+		}
+
+		if len(entries) == 0 {
+			return nil
+		}
+
+		commitIDs, err := lastCommitsForEntries(ctx, in.Repository, revision, path, entries)
+		if err != nil {
+			return err
+		}
+
+		commitCache := make(map[string]*pb.GitCommit)
+		var commits []*pb.ListLastCommitsForTreeResponse_CommitForTree
+
+		sendResponse := func() error {
+			if len(commits) == 0 {
+				return nil
+			}
+			if err := stream.Send(&pb.ListLastCommitsForTreeResponse{Commits: commits}); err != nil {
+				return status.Errorf(codes.Unavailable, "ListLastCommitsForTree: send: %v", err)
+			}
+			commits = nil
+			return nil
+		}
+
+		for i, entry := range entries {
+			commitID := commitIDs[i]
+			if commitID == "" {
+				continue
+			}
+
+			commit, ok := commitCache[commitID]
+			if !ok {
+				commit, err = gitlog.GetCommitCatfile(ctx, in.Repository, commitID)
+				if err != nil {
+					return status.Errorf(codes.Internal, "ListLastCommitsForTree: commit: %v", err)
+				}
+				commitCache[commitID] = commit
+			}
+
+			commits = append(commits, &pb.ListLastCommitsForTreeResponse_CommitForTree{
+				Commit: commit,
+				Path:   entry.Path,
+			})
+
+			if len(commits) == commitsForTreePerMessage {
+				if err := sendResponse(); err != nil {
+					return err
+				}
+			}
+		}
+
+		return sendResponse()
+	}
+}
+
+// lastCommitsForEntries walks the history once and returns, for each entry,
+// the id of the most recent commit touching it. The returned slice is
+// parallel to entries; an empty string means no commit was found.
+func lastCommitsForEntries(ctx context.Context, repo *pb.Repository, revision, path string, entries []*pb.TreeEntry) ([]string, error) {
+	cmdArgs := []string{"log", "--format=%x01%H", "--raw", "-z", revision}
+	if path != "" && path != "." {
+		cmdArgs = append(cmdArgs, "--", path)
+	}
+
+	cmd, err := git.Command(ctx, repo, cmdArgs...)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "ListLastCommitsForTree: cmd: %v", err)
+	}
+
+	commitIDs := make([]string, len(entries))
+	unassigned := len(entries)
+
+	scanner := bufio.NewScanner(cmd)
+	scanner.Buffer(make([]byte, 4096), 16*1024*1024)
+	scanner.Split(scanNullTerminated)
+
+	var currentCommit string
+	for unassigned > 0 && scanner.Scan() {
+		token := scanner.Bytes()
+
+		// Tokens are either a commit header (\x01<hash>), a raw diff line
+		// (:<old mode> <new mode> ...), or a path belonging to the last raw
+		// diff line.
+		if len(token) > 0 && token[0] == '\x01' {
+			currentCommit = string(token[1:])
+			continue
+		}
+
+		token = bytes.TrimPrefix(token, []byte("\n"))
+		if len(token) > 0 && token[0] == ':' {
+			continue
+		}
+
+		for i, entry := range entries {
+			if commitIDs[i] != "" {
+				continue
+			}
+
+			if entryMatchesFile(entry, token) {
+				commitIDs[i] = currentCommit
+				unassigned--
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "ListLastCommitsForTree: scan: %v", err)
+	}
+
+	if unassigned == 0 {
+		// We stopped reading before the command was done; a Wait error is
+		// expected and harmless.
+		cmd.Wait()
+	} else if err := cmd.Wait(); err != nil {
+		return nil, status.Errorf(codes.Internal, "ListLastCommitsForTree: cmd wait: %v", err)
+	}
+
+	return commitIDs, nil
+}
+
+func entryMatchesFile(entry *pb.TreeEntry, file []byte) bool {
+	if entry.Type == pb.TreeEntry_TREE {
+		return strings.HasPrefix(string(file), string(entry.Path)+"/")
+	}
+
+	return bytes.Equal(entry.Path, file)
+}
+
+func scanNullTerminated(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexByte(data, '\x00'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
@@ -0,0 +1,155 @@
+package commit
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulListLastCommitsForTree(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepoWithWorktree(t)
+	defer cleanupFn()
+
+	commitChanges := func(message string, files map[string]string) string {
+		for name, content := range files {
+			require.NoError(t, os.MkdirAll(path.Join(testRepoPath, path.Dir(name)), 0755))
+			require.NoError(t, ioutil.WriteFile(path.Join(testRepoPath, name), []byte(content), 0644))
+		}
+		testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "add", ".")
+		testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath,
+			"-c", "user.name=Scrooge McDuck", "-c", "user.email=scrooge@mcduck.com",
+			"commit", "-m", message)
+
+		out := testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "rev-parse", "HEAD")
+		return string(out[:len(out)-1])
+	}
+
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "checkout", "-b", "last-commits-for-tree")
+
+	commit1 := commitChanges("add dir1 and top", map[string]string{"dir1/file1": "one\n", "top.txt": "top\n"})
+	commit2 := commitChanges("change dir1/file1", map[string]string{"dir1/file1": "two\n"})
+	commit3 := commitChanges("add dir2/file2", map[string]string{"dir2/file2": "three\n"})
+
+	fetchCommitsForTree := func(request *pb.ListLastCommitsForTreeRequest) []*pb.ListLastCommitsForTreeResponse_CommitForTree {
+		ctx, cancel := testhelper.Context()
+		defer cancel()
+
+		c, err := client.ListLastCommitsForTree(ctx, request)
+		require.NoError(t, err)
+
+		var commits []*pb.ListLastCommitsForTreeResponse_CommitForTree
+		for {
+			resp, err := c.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			commits = append(commits, resp.GetCommits()...)
+		}
+		return commits
+	}
+
+	t.Run("root tree", func(t *testing.T) {
+		commits := fetchCommitsForTree(&pb.ListLastCommitsForTreeRequest{
+			Repository: testRepo,
+			Revision:   []byte(commit3),
+		})
+
+		expected := map[string]string{
+			"dir1":    commit2,
+			"dir2":    commit3,
+			"top.txt": commit1,
+		}
+
+		require.Len(t, commits, len(expected))
+		for _, commitForTree := range commits {
+			expectedID, ok := expected[string(commitForTree.Path)]
+			require.True(t, ok, "unexpected path %q", commitForTree.Path)
+			require.Equal(t, expectedID, commitForTree.Commit.Id, "commit for %q", commitForTree.Path)
+		}
+	})
+
+	t.Run("sub tree", func(t *testing.T) {
+		commits := fetchCommitsForTree(&pb.ListLastCommitsForTreeRequest{
+			Repository: testRepo,
+			Revision:   []byte(commit3),
+			Path:       []byte("dir1"),
+		})
+
+		require.Len(t, commits, 1)
+		require.Equal(t, []byte("dir1/file1"), commits[0].Path)
+		require.Equal(t, commit2, commits[0].Commit.Id)
+	})
+
+	t.Run("offset and limit", func(t *testing.T) {
+		commits := fetchCommitsForTree(&pb.ListLastCommitsForTreeRequest{
+			Repository: testRepo,
+			Revision:   []byte(commit3),
+			Offset:     1,
+			Limit:      1,
+		})
+
+		require.Len(t, commits, 1)
+		require.Equal(t, []byte("dir2"), commits[0].Path)
+		require.Equal(t, commit3, commits[0].Commit.Id)
+	})
+}
+
+func TestFailedListLastCommitsForTreeRequestDueToValidations(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	testCases := []struct {
+		desc string
+		req  *pb.ListLastCommitsForTreeRequest
+	}{
+		{
+			desc: "empty revision",
+			req:  &pb.ListLastCommitsForTreeRequest{Repository: testRepo},
+		},
+		{
+			desc: "negative limit",
+			req:  &pb.ListLastCommitsForTreeRequest{Repository: testRepo, Revision: []byte("master"), Limit: -1},
+		},
+		{
+			desc: "negative offset",
+			req:  &pb.ListLastCommitsForTreeRequest{Repository: testRepo, Revision: []byte("master"), Offset: -1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			c, err := client.ListLastCommitsForTree(ctx, tc.req)
+			require.NoError(t, err)
+
+			var recvErr error
+			for recvErr == nil {
+				_, recvErr = c.Recv()
+			}
+			testhelper.AssertGrpcError(t, recvErr, codes.InvalidArgument, "")
+		})
+	}
+}
@@ -3,6 +3,7 @@ package commit
 import (
 	"fmt"
 	"io"
+	"regexp"
 
 	"gitlab.com/gitlab-org/gitaly/internal/git"
 	"gitlab.com/gitlab-org/gitaly/streamio"
@@ -22,8 +23,15 @@ func (s *server) RawBlame(in *pb.RawBlameRequest, stream pb.CommitService_RawBla
 	ctx := stream.Context()
 	revision := string(in.GetRevision())
 	path := string(in.GetPath())
+	blameRange := string(in.GetRange())
 
-	cmd, err := git.Command(ctx, in.Repository, "blame", "-p", revision, "--", path)
+	cmdArgs := []string{"blame", "-p"}
+	if blameRange != "" {
+		cmdArgs = append(cmdArgs, "-L", blameRange)
+	}
+	cmdArgs = append(cmdArgs, revision, "--", path)
+
+	cmd, err := git.Command(ctx, in.Repository, cmdArgs...)
 	if err != nil {
 		if _, ok := status.FromError(err); ok {
 			return err
@@ -47,6 +55,8 @@ func (s *server) RawBlame(in *pb.RawBlameRequest, stream pb.CommitService_RawBla
 	return nil
 }
 
+var validBlameRange = regexp.MustCompile(`\A\d+,\d+\z`)
+
 func validateRawBlameRequest(in *pb.RawBlameRequest) error {
 	if len(in.GetRevision()) == 0 {
 		return fmt.Errorf("empty Revision")
@@ -56,5 +66,9 @@ func validateRawBlameRequest(in *pb.RawBlameRequest) error {
 		return fmt.Errorf("empty Path")
 	}
 
+	if blameRange := in.GetRange(); len(blameRange) > 0 && !validBlameRange.Match(blameRange) {
+		return fmt.Errorf("invalid Range")
+	}
+
 	return nil
 }
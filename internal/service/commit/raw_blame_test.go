@@ -3,6 +3,8 @@ package commit
 import (
 	"fmt"
 	"io/ioutil"
+	"regexp"
+	"strconv"
 	"testing"
 
 	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
@@ -76,6 +78,48 @@ func TestSuccessfulRawBlameRequest(t *testing.T) {
 	}
 }
 
+func TestSuccessfulRawBlameRequestWithRange(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	request := &pb.RawBlameRequest{
+		Repository: testRepo,
+		Revision:   []byte("e63f41fe459e62e1228fcef60d7189127aeba95a"),
+		Path:       []byte("files/ruby/popen.rb"),
+		Range:      []byte("1,5"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := client.RawBlame(ctx, request)
+	require.NoError(t, err)
+
+	sr := streamio.NewReader(func() ([]byte, error) {
+		response, err := c.Recv()
+		return response.GetData(), err
+	})
+
+	blame, err := ioutil.ReadAll(sr)
+	require.NoError(t, err)
+
+	// In porcelain format each blamed line is preceded by a header line
+	// '<sha> <orig-lineno> <final-lineno> ...'.
+	headerLine := regexp.MustCompile(`(?m)^[0-9a-f]{40} \d+ (\d+)`)
+	matches := headerLine.FindAllSubmatch(blame, -1)
+	require.Len(t, matches, 5, "expected exactly 5 blamed lines")
+	for _, match := range matches {
+		lineno, err := strconv.Atoi(string(match[1]))
+		require.NoError(t, err)
+		require.True(t, lineno >= 1 && lineno <= 5, "blamed line %d outside requested range", lineno)
+	}
+}
+
 func TestFailedRawBlameRequest(t *testing.T) {
 	server, serverSocketPath := startTestServices(t)
 	defer server.Stop()
@@ -89,10 +133,10 @@ func TestFailedRawBlameRequest(t *testing.T) {
 	invalidRepo := &pb.Repository{StorageName: "fake", RelativePath: "path"}
 
 	testCases := []struct {
-		description    string
-		repo           *pb.Repository
-		revision, path []byte
-		code           codes.Code
+		description                string
+		repo                       *pb.Repository
+		revision, path, blameRange []byte
+		code                       codes.Code
 	}{
 		{
 			description: "Invalid repo",
@@ -115,6 +159,14 @@ func TestFailedRawBlameRequest(t *testing.T) {
 			path:        []byte(""),
 			code:        codes.InvalidArgument,
 		},
+		{
+			description: "Invalid range",
+			repo:        testRepo,
+			revision:    []byte("abcdef"),
+			path:        []byte("a/b/c"),
+			blameRange:  []byte("foo"),
+			code:        codes.InvalidArgument,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -124,6 +176,7 @@ func TestFailedRawBlameRequest(t *testing.T) {
 				Repository: testCase.repo,
 				Revision:   testCase.revision,
 				Path:       testCase.path,
+				Range:      testCase.blameRange,
 			}
 
 			ctx, cancel := context.WithCancel(context.Background())
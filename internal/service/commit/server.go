@@ -12,7 +12,7 @@ type server struct {
 }
 
 var (
-	defaultBranchName = ref.DefaultBranchName
+	defaultBranchName = ref.CachedDefaultBranchName
 	maxMsgSize        = 1024 * 128 // 128 KiB
 )
 
@@ -1,28 +1,82 @@
 package commit
 
 import (
+	"bufio"
+	"bytes"
+	"strconv"
+
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/git/log"
 	"gitlab.com/gitlab-org/gitaly/internal/helper"
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
 )
 
 func (s *server) CommitStats(ctx context.Context, in *pb.CommitStatsRequest) (*pb.CommitStatsResponse, error) {
-	client, err := s.CommitServiceClient(ctx)
-	if err != nil {
-		return nil, err
-	}
-
 	repo := in.GetRepository()
 	if _, err := helper.GetRepoPath(repo); err != nil {
 		return nil, err
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, repo)
+	commit, err := log.GetCommit(ctx, repo, string(in.GetRevision()), "")
 	if err != nil {
-		return nil, err
+		return nil, status.Errorf(codes.Internal, "CommitStats: commit: %v", err)
+	}
+	if commit == nil {
+		return nil, status.Errorf(codes.Internal, "CommitStats: commit not found for revision %q", in.GetRevision())
+	}
+
+	cmd, err := git.Command(ctx, repo, "diff-tree", "--numstat", "--no-renames", "--root", commit.Id)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "CommitStats: cmd: %v", err)
+	}
+
+	response := &pb.CommitStatsResponse{Oid: commit.Id}
+
+	scanner := bufio.NewScanner(cmd)
+	for scanner.Scan() {
+		// diff-tree echoes the commit id before the numstat lines
+		if bytes.Equal(scanner.Bytes(), []byte(commit.Id)) {
+			continue
+		}
+
+		elements := bytes.SplitN(scanner.Bytes(), []byte("\t"), 3)
+		if len(elements) != 3 {
+			return nil, status.Errorf(codes.Internal, "CommitStats: parse numstat line: %q", scanner.Text())
+		}
+
+		response.Files++
+
+		// Binary files are counted but report no line changes ("-\t-\tpath")
+		if bytes.Equal(elements[0], []byte("-")) {
+			continue
+		}
+
+		additions, err := strconv.ParseInt(string(elements[0]), 10, 32)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "CommitStats: parse additions: %v", err)
+		}
+		deletions, err := strconv.ParseInt(string(elements[1]), 10, 32)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "CommitStats: parse deletions: %v", err)
+		}
+
+		response.Additions += int32(additions)
+		response.Deletions += int32(deletions)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "CommitStats: scan: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, status.Errorf(codes.Internal, "CommitStats: cmd wait: %v", err)
 	}
 
-	return client.CommitStats(clientCtx, in)
+	return response, nil
 }
@@ -2,9 +2,12 @@ package commit
 
 import (
 	"context"
+	"io/ioutil"
+	"path"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"google.golang.org/grpc/codes"
 
@@ -99,3 +102,31 @@ func TestCommitStatsFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestCommitStatsFileCount(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepoWithWorktree(t)
+	defer cleanupFn()
+
+	require.NoError(t, ioutil.WriteFile(path.Join(testRepoPath, "ducks.txt"), []byte("quack\n"), 0644))
+	require.NoError(t, ioutil.WriteFile(path.Join(testRepoPath, "geese.txt"), []byte("honk\nhonk\n"), 0644))
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "add", ".")
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath,
+		"-c", "user.name=Scrooge McDuck", "-c", "user.email=scrooge@mcduck.com",
+		"commit", "-m", "Add birds")
+
+	resp, err := client.CommitStats(ctx, &pb.CommitStatsRequest{Repository: testRepo, Revision: []byte("HEAD")})
+	require.NoError(t, err)
+
+	require.Equal(t, int32(3), resp.GetAdditions())
+	require.Equal(t, int32(0), resp.GetDeletions())
+	require.Equal(t, int32(2), resp.GetFiles())
+}
@@ -53,15 +53,85 @@ func populateFlatPath(entries []*pb.TreeEntry, stdin io.Writer, stdout *bufio.Re
 	return nil
 }
 
-func getTreeEntriesHandler(stream pb.CommitService_GetTreeEntriesServer, revision, path string) catfile.Handler {
+func recursiveTreeEntries(revision, path string, stdin io.Writer, stdout *bufio.Reader) ([]*pb.TreeEntry, error) {
+	entries, err := treeEntries(revision, path, stdin, stdout)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*pb.TreeEntry
+	for _, entry := range entries {
+		result = append(result, entry)
+
+		if entry.Type == pb.TreeEntry_TREE {
+			subEntries, err := recursiveTreeEntries(revision, string(entry.Path), stdin, stdout)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, subEntries...)
+		}
+	}
+
+	return result, nil
+}
+
+// sortTreesFirst moves tree (and submodule) entries in front of blobs without
+// changing the relative order within either group.
+func sortTreesFirst(entries []*pb.TreeEntry) []*pb.TreeEntry {
+	var trees, blobs []*pb.TreeEntry
+
+	for _, entry := range entries {
+		if entry.Type == pb.TreeEntry_BLOB {
+			blobs = append(blobs, entry)
+		} else {
+			trees = append(trees, entry)
+		}
+	}
+
+	return append(trees, blobs...)
+}
+
+func getTreeEntriesHandler(stream pb.CommitService_GetTreeEntriesServer, in *pb.GetTreeEntriesRequest, revision, path string) catfile.Handler {
 	return func(stdin io.Writer, stdout *bufio.Reader) error {
-		entries, err := treeEntries(revision, path, stdin, stdout)
+		var entries []*pb.TreeEntry
+		var err error
+
+		if in.GetRecursive() {
+			entries, err = recursiveTreeEntries(revision, path, stdin, stdout)
+		} else {
+			entries, err = treeEntries(revision, path, stdin, stdout)
+		}
 		if err != nil {
 			return err
 		}
 
-		if err := populateFlatPath(entries, stdin, stdout); err != nil {
-			return err
+		// Flat paths only make sense when showing a single tree level.
+		if !in.GetRecursive() {
+			if err := populateFlatPath(entries, stdin, stdout); err != nil {
+				return err
+			}
+		}
+
+		if in.GetSortBy() == pb.GetTreeEntriesRequest_TREES_FIRST {
+			entries = sortTreesFirst(entries)
+		}
+
+		if pageToken := in.GetPageToken(); pageToken != "" {
+			index := -1
+			for i, entry := range entries {
+				if string(entry.Path) == pageToken {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				return status.Errorf(codes.InvalidArgument, "GetTreeEntries: could not find page token %q", pageToken)
+			}
+			entries = entries[index+1:]
+		}
+
+		if limit := int(in.GetLimit()); limit > 0 && len(entries) > limit {
+			entries = entries[:limit]
 		}
 
 		for len(entries) > maxTreeEntries {
@@ -94,7 +164,7 @@ func (s *server) GetTreeEntries(in *pb.GetTreeEntriesRequest, stream pb.CommitSe
 
 	revision := string(in.GetRevision())
 	path := string(in.GetPath())
-	handler := getTreeEntriesHandler(stream, revision, path)
+	handler := getTreeEntriesHandler(stream, in, revision, path)
 
 	return catfile.CatFile(stream.Context(), in.Repository, handler)
 }
@@ -9,9 +9,15 @@ import (
 	"google.golang.org/grpc/status"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/cache"
 	"gitlab.com/gitlab-org/gitaly/internal/git/catfile"
 )
 
+// treeCache memoizes parsed tree listings by tree OID. Trees are
+// immutable, so identical directories across branches hit the same
+// slot.
+var treeCache = cache.NewTreeCache()
+
 func getTreeInfo(revision, path string, stdin io.Writer, stdout *bufio.Reader) (*catfile.ObjectInfo, error) {
 	if _, err := fmt.Fprintf(stdin, "%s^{tree}:%s\n", revision, path); err != nil {
 		return nil, status.Errorf(codes.Internal, "TreeEntry: stdin write: %v", err)
@@ -25,16 +31,37 @@ func getTreeInfo(revision, path string, stdin io.Writer, stdout *bufio.Reader) (
 }
 
 func extractEntryInfoFromTreeData(stdout *bufio.Reader, commitOid, rootOid, rootPath string, treeInfo *catfile.ObjectInfo) ([]*pb.TreeEntry, error) {
-	var entries []*pb.TreeEntry
-	var modeBytes, filename []byte
-	var err error
-
 	// Non-existing tree, return empty entry list
 	if len(treeInfo.Oid) == 0 {
-		return entries, nil
+		return nil, nil
+	}
+
+	if cached, ok := treeCache.Get(treeInfo.Oid); ok {
+		// The object data is already in flight from cat-file; skip past
+		// it, including the trailing linefeed.
+		if _, err := stdout.Discard(int(treeInfo.Size + 1)); err != nil {
+			return nil, fmt.Errorf("stdout discard: %v", err)
+		}
+
+		return buildTreeEntries(cached, commitOid, rootOid, rootPath)
 	}
 
-	oidBytes := make([]byte, 20)
+	rawEntries, err := readTreeData(stdout, treeInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	treeCache.Put(treeInfo.Oid, rawEntries)
+
+	return buildTreeEntries(rawEntries, commitOid, rootOid, rootPath)
+}
+
+// readTreeData parses the raw tree object into its entries.
+func readTreeData(stdout *bufio.Reader, treeInfo *catfile.ObjectInfo) ([]cache.TreeEntryData, error) {
+	var entries []cache.TreeEntryData
+	var modeBytes, filename []byte
+	var err error
+
 	bytesLeft := treeInfo.Size
 
 	for bytesLeft > 0 {
@@ -52,6 +79,8 @@ func extractEntryInfoFromTreeData(stdout *bufio.Reader, commitOid, rootOid, root
 		bytesLeft -= int64(len(filename))
 		filename = filename[:len(filename)-1]
 
+		oidBytes := make([]byte, 20)
+
 		// bufio.Reader.Read isn't guaranteed to read len(p) since bytes
 		// are taken from at most one Read on the underlying Reader.
 		// We call Peek to make sure we have enough bytes buffered to read into oidBytes.
@@ -64,12 +93,11 @@ func extractEntryInfoFromTreeData(stdout *bufio.Reader, commitOid, rootOid, root
 
 		bytesLeft -= int64(len(oidBytes))
 
-		treeEntry, err := newTreeEntry(commitOid, rootOid, rootPath, filename, oidBytes, modeBytes)
-		if err != nil {
-			return nil, fmt.Errorf("new entry info: %v", err)
-		}
-
-		entries = append(entries, treeEntry)
+		entries = append(entries, cache.TreeEntryData{
+			Mode:     append([]byte{}, modeBytes...),
+			Oid:      oidBytes,
+			Filename: append([]byte{}, filename...),
+		})
 	}
 
 	// Extra byte for a linefeed at the end
@@ -80,6 +108,23 @@ func extractEntryInfoFromTreeData(stdout *bufio.Reader, commitOid, rootOid, root
 	return entries, nil
 }
 
+// buildTreeEntries materializes response entries for the requesting
+// revision and root from the revision-independent raw entries.
+func buildTreeEntries(rawEntries []cache.TreeEntryData, commitOid, rootOid, rootPath string) ([]*pb.TreeEntry, error) {
+	var entries []*pb.TreeEntry
+
+	for _, raw := range rawEntries {
+		treeEntry, err := newTreeEntry(commitOid, rootOid, rootPath, raw.Filename, raw.Oid, raw.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("new entry info: %v", err)
+		}
+
+		entries = append(entries, treeEntry)
+	}
+
+	return entries, nil
+}
+
 func treeEntries(revision, path string, stdin io.Writer, stdout *bufio.Reader) ([]*pb.TreeEntry, error) {
 	if path == "." {
 		path = ""
@@ -299,6 +299,121 @@ func getTreeEntriesFromTreeEntryClient(t *testing.T, client pb.CommitService_Get
 	return entries
 }
 
+func TestSuccessfulGetTreeEntriesRecursive(t *testing.T) {
+	commitID := "ce369011c189f62c815f5971d096b26759bab0d1"
+
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	fetchEntries := func(request *pb.GetTreeEntriesRequest) []*pb.TreeEntry {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		c, err := client.GetTreeEntries(ctx, request)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return getTreeEntriesFromTreeEntryClient(t, c)
+	}
+
+	allEntries := fetchEntries(&pb.GetTreeEntriesRequest{
+		Repository: testRepo,
+		Revision:   []byte(commitID),
+		Path:       []byte("files"),
+		Recursive:  true,
+	})
+
+	entryPaths := make(map[string]pb.TreeEntry_EntryType)
+	for _, entry := range allEntries {
+		entryPaths[string(entry.Path)] = entry.Type
+	}
+
+	// Direct children and a nested blob must all be present
+	if entryType, ok := entryPaths["files/html"]; !ok || entryType != pb.TreeEntry_TREE {
+		t.Fatalf("expected files/html tree in recursive entries, got %v", entryPaths)
+	}
+	if entryType, ok := entryPaths["files/html/500.html"]; !ok || entryType != pb.TreeEntry_BLOB {
+		t.Fatalf("expected files/html/500.html blob in recursive entries, got %v", entryPaths)
+	}
+
+	t.Run("trees first sorting", func(t *testing.T) {
+		sortedEntries := fetchEntries(&pb.GetTreeEntriesRequest{
+			Repository: testRepo,
+			Revision:   []byte(commitID),
+			Path:       []byte("files"),
+			Recursive:  true,
+			SortBy:     pb.GetTreeEntriesRequest_TREES_FIRST,
+		})
+
+		if len(sortedEntries) != len(allEntries) {
+			t.Fatalf("expected %d entries, got %d", len(allEntries), len(sortedEntries))
+		}
+
+		seenBlob := false
+		for _, entry := range sortedEntries {
+			if entry.Type == pb.TreeEntry_BLOB {
+				seenBlob = true
+			} else if seenBlob {
+				t.Fatalf("found %q (type %s) after a blob", entry.Path, entry.Type)
+			}
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		var paginatedEntries []*pb.TreeEntry
+		pageToken := ""
+
+		for {
+			page := fetchEntries(&pb.GetTreeEntriesRequest{
+				Repository: testRepo,
+				Revision:   []byte(commitID),
+				Path:       []byte("files"),
+				Recursive:  true,
+				Limit:      3,
+				PageToken:  pageToken,
+			})
+			if len(page) == 0 {
+				break
+			}
+
+			paginatedEntries = append(paginatedEntries, page...)
+			pageToken = string(page[len(page)-1].Path)
+		}
+
+		assertPaginatedEqual := len(paginatedEntries) == len(allEntries)
+		for i := 0; assertPaginatedEqual && i < len(allEntries); i++ {
+			assertPaginatedEqual = treeEntriesEqual(paginatedEntries[i], allEntries[i])
+		}
+		if !assertPaginatedEqual {
+			t.Fatalf("paginated entries do not match full listing: %d vs %d entries", len(paginatedEntries), len(allEntries))
+		}
+	})
+
+	t.Run("invalid page token", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		c, err := client.GetTreeEntries(ctx, &pb.GetTreeEntriesRequest{
+			Repository: testRepo,
+			Revision:   []byte(commitID),
+			Path:       []byte("files"),
+			Recursive:  true,
+			PageToken:  "files/does-not-exist",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = drainTreeEntriesResponse(c)
+		testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "could not find page token")
+	})
+}
+
 func TestFailedGetTreeEntriesRequestDueToValidationError(t *testing.T) {
 	server, serverSocketPath := startTestServices(t)
 	defer server.Stop()
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"path"
 	"strings"
 
@@ -17,7 +18,7 @@ import (
 	"google.golang.org/grpc/status"
 )
 
-func treeEntryHandler(stream pb.Commit_TreeEntryServer, revision, path, baseName string, limit int64) catfile.Handler {
+func treeEntryHandler(stream pb.Commit_TreeEntryServer, revision, path, baseName string, maxSize, offset, limit int64) catfile.Handler {
 	return func(stdin io.Writer, stdout *bufio.Reader) error {
 		var treeEntry *pb.TreeEntry
 
@@ -75,7 +76,20 @@ func treeEntryHandler(stream pb.Commit_TreeEntryServer, revision, path, baseName
 			return helper.DecorateError(codes.Unavailable, stream.Send(response))
 		}
 
-		dataLength := objectInfo.Size
+		if maxSize > 0 && objectInfo.Size > maxSize {
+			return status.Errorf(codes.FailedPrecondition, "TreeEntry: object size (%d) is bigger than the maximum allowed size (%d)", objectInfo.Size, maxSize)
+		}
+
+		if offset > objectInfo.Size {
+			offset = objectInfo.Size
+		}
+		if offset > 0 {
+			if _, err := io.CopyN(ioutil.Discard, stdout, offset); err != nil {
+				return status.Errorf(codes.Internal, "TreeEntry: skip offset: %v", err)
+			}
+		}
+
+		dataLength := objectInfo.Size - offset
 		if limit > 0 && dataLength > limit {
 			dataLength = limit
 		}
@@ -124,7 +138,7 @@ func (s *server) TreeEntry(in *pb.TreeEntryRequest, stream pb.CommitService_Tree
 		requestPath = strings.TrimRight(requestPath, "/")
 	}
 
-	handler := treeEntryHandler(stream, string(in.GetRevision()), path.Dir(requestPath), requestPath, in.GetLimit())
+	handler := treeEntryHandler(stream, string(in.GetRevision()), path.Dir(requestPath), requestPath, in.GetMaxSize(), in.GetOffset(), in.GetLimit())
 	return catfile.CatFile(stream.Context(), in.Repository, handler)
 }
 
@@ -137,5 +151,13 @@ func validateRequest(in *pb.TreeEntryRequest) error {
 		return fmt.Errorf("empty Path")
 	}
 
+	if in.GetMaxSize() < 0 {
+		return fmt.Errorf("negative MaxSize")
+	}
+
+	if in.GetOffset() < 0 {
+		return fmt.Errorf("negative Offset")
+	}
+
 	return nil
 }
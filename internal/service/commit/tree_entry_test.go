@@ -36,8 +36,34 @@ func TestSuccessfulTreeEntry(t *testing.T) {
 		revision          []byte
 		path              []byte
 		limit             int64
+		maxSize           int64
+		offset            int64
 		expectedTreeEntry treeEntry
 	}{
+		{
+			revision: []byte("913c66a37b4a45b9769037c55c2d238bd0942d2e"),
+			path:     []byte("MAINTENANCE.md"),
+			offset:   2,
+			limit:    10,
+			expectedTreeEntry: treeEntry{
+				objectType: pb.TreeEntryResponse_BLOB,
+				oid:        "95d9f0a5e7bb054e9dd3975589b8dfc689e20e88",
+				size:       1367,
+				mode:       0100644,
+				data:       testhelper.MustReadFile(t, "testdata/maintenance-md-blob.txt")[2:12],
+			},
+		},
+		{
+			revision: []byte("913c66a37b4a45b9769037c55c2d238bd0942d2e"),
+			path:     []byte("MAINTENANCE.md"),
+			offset:   4000,
+			expectedTreeEntry: treeEntry{
+				objectType: pb.TreeEntryResponse_BLOB,
+				oid:        "95d9f0a5e7bb054e9dd3975589b8dfc689e20e88",
+				size:       1367,
+				mode:       0100644,
+			},
+		},
 		{
 			revision: []byte("913c66a37b4a45b9769037c55c2d238bd0942d2e"),
 			path:     []byte("MAINTENANCE.md"),
@@ -143,6 +169,8 @@ func TestSuccessfulTreeEntry(t *testing.T) {
 				Revision:   testCase.revision,
 				Path:       testCase.path,
 				Limit:      testCase.limit,
+				MaxSize:    testCase.maxSize,
+				Offset:     testCase.offset,
 			}
 
 			ctx, cancel := context.WithCancel(context.Background())
@@ -157,6 +185,34 @@ func TestSuccessfulTreeEntry(t *testing.T) {
 	}
 }
 
+func TestFailedTreeEntryRequestDueToMaxSize(t *testing.T) {
+	server, serverSocketPath := startTestServices(t)
+	defer server.Stop()
+
+	client, conn := newCommitServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	request := &pb.TreeEntryRequest{
+		Repository: testRepo,
+		Revision:   []byte("913c66a37b4a45b9769037c55c2d238bd0942d2e"),
+		Path:       []byte("MAINTENANCE.md"),
+		MaxSize:    100,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := client.TreeEntry(ctx, request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = drainTreeEntryResponse(c)
+	testhelper.AssertGrpcError(t, err, codes.FailedPrecondition, "bigger than the maximum allowed size")
+}
+
 func TestFailedTreeEntryRequestDueToValidationError(t *testing.T) {
 	server, serverSocketPath := startTestServices(t)
 	defer server.Stop()
@@ -175,6 +231,8 @@ func TestFailedTreeEntryRequestDueToValidationError(t *testing.T) {
 		{Repository: nil, Revision: revision, Path: path},                                                       // Repository is nil
 		{Repository: testRepo, Revision: nil, Path: path},                                                       // Revision is empty
 		{Repository: testRepo, Revision: revision},                                                              // Path is empty
+		{Repository: testRepo, Revision: revision, Path: path, MaxSize: -1},                                     // MaxSize is negative
+		{Repository: testRepo, Revision: revision, Path: path, Offset: -1},                                      // Offset is negative
 	}
 
 	for _, rpcRequest := range rpcRequests {
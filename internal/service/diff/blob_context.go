@@ -0,0 +1,106 @@
+package diff
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+
+	"gitlab.com/gitlab-org/gitaly/internal/git/catfile"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetBlobContext returns a line range of a blob so "expand diff
+// context" actions don't have to fetch and slice the whole file on the
+// client.
+func (s *server) GetBlobContext(ctx context.Context, in *pb.GetBlobContextRequest) (*pb.GetBlobContextResponse, error) {
+	if err := validateGetBlobContextRequest(in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "GetBlobContext: %v", err)
+	}
+
+	var lines [][]byte
+
+	err := catfile.CatFile(ctx, in.GetRepository(), func(stdin io.Writer, stdout *bufio.Reader) error {
+		if _, err := fmt.Fprintln(stdin, in.GetBlobOid()); err != nil {
+			return err
+		}
+
+		objectInfo, err := catfile.ParseObjectInfo(stdout)
+		if err != nil {
+			return err
+		}
+		if objectInfo.Oid == "" {
+			return status.Errorf(codes.NotFound, "GetBlobContext: blob %s not found", in.GetBlobOid())
+		}
+		if objectInfo.Type != "blob" {
+			return status.Errorf(codes.InvalidArgument, "GetBlobContext: object %s is a %s, not a blob", in.GetBlobOid(), objectInfo.Type)
+		}
+
+		data := make([]byte, objectInfo.Size)
+		if _, err := io.ReadFull(stdout, data); err != nil {
+			return err
+		}
+
+		lines = sliceBlobLines(data, int(in.GetStartLine()), int(in.GetEndLine()))
+		return nil
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "GetBlobContext: %v", err)
+	}
+
+	return &pb.GetBlobContextResponse{Lines: lines}, nil
+}
+
+func validateGetBlobContextRequest(in *pb.GetBlobContextRequest) error {
+	if in.GetRepository() == nil {
+		return fmt.Errorf("empty Repository")
+	}
+
+	if in.GetBlobOid() == "" {
+		return fmt.Errorf("empty BlobOid")
+	}
+
+	if in.GetStartLine() < 1 {
+		return fmt.Errorf("StartLine must be at least 1")
+	}
+
+	if in.GetEndLine() < in.GetStartLine() {
+		return fmt.Errorf("EndLine precedes StartLine")
+	}
+
+	return nil
+}
+
+// sliceBlobLines returns the 1-based, inclusive line range of data
+// without trailing newlines. A range past the end of the blob yields
+// the lines that do exist.
+func sliceBlobLines(data []byte, startLine, endLine int) [][]byte {
+	var lines [][]byte
+
+	currentLine := 0
+	for len(data) > 0 && currentLine < endLine {
+		currentLine++
+
+		line := data
+		if newline := bytes.IndexByte(data, '\n'); newline >= 0 {
+			line = data[:newline]
+			data = data[newline+1:]
+		} else {
+			data = nil
+		}
+
+		if currentLine >= startLine {
+			lines = append(lines, append([]byte(nil), line...))
+		}
+	}
+
+	return lines
+}
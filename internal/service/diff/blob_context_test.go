@@ -0,0 +1,166 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulGetBlobContextRequest(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	content := "line 1\nline 2\nline 3\nline 4\nline 5"
+	blobOid := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader(content),
+		"git", "-C", testRepoPath, "hash-object", "-w", "--stdin")))
+
+	testCases := []struct {
+		desc      string
+		startLine int32
+		endLine   int32
+		expected  [][]byte
+	}{
+		{
+			desc:      "middle of the blob",
+			startLine: 2,
+			endLine:   4,
+			expected:  [][]byte{[]byte("line 2"), []byte("line 3"), []byte("line 4")},
+		},
+		{
+			desc:      "single line",
+			startLine: 1,
+			endLine:   1,
+			expected:  [][]byte{[]byte("line 1")},
+		},
+		{
+			desc:      "range past the end is clamped",
+			startLine: 4,
+			endLine:   100,
+			expected:  [][]byte{[]byte("line 4"), []byte("line 5")},
+		},
+		{
+			desc:      "range entirely past the end",
+			startLine: 10,
+			endLine:   20,
+			expected:  nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			response, err := client.GetBlobContext(ctx, &pb.GetBlobContextRequest{
+				Repository: testRepo,
+				BlobOid:    blobOid,
+				StartLine:  tc.startLine,
+				EndLine:    tc.endLine,
+			})
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, response.GetLines())
+		})
+	}
+}
+
+func TestFailedGetBlobContextRequest(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	blobOid := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader("some content\n"),
+		"git", "-C", testRepoPath, "hash-object", "-w", "--stdin")))
+	treeOid := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader("100644 blob "+blobOid+"\tfile.txt\n"),
+		"git", "-C", testRepoPath, "mktree")))
+
+	testCases := []struct {
+		desc    string
+		request *pb.GetBlobContextRequest
+		code    codes.Code
+	}{
+		{
+			desc: "empty Repository",
+			request: &pb.GetBlobContextRequest{
+				BlobOid:   blobOid,
+				StartLine: 1,
+				EndLine:   1,
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "empty BlobOid",
+			request: &pb.GetBlobContextRequest{
+				Repository: testRepo,
+				StartLine:  1,
+				EndLine:    1,
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "StartLine of zero",
+			request: &pb.GetBlobContextRequest{
+				Repository: testRepo,
+				BlobOid:    blobOid,
+				StartLine:  0,
+				EndLine:    1,
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "EndLine preceding StartLine",
+			request: &pb.GetBlobContextRequest{
+				Repository: testRepo,
+				BlobOid:    blobOid,
+				StartLine:  5,
+				EndLine:    2,
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "unknown blob",
+			request: &pb.GetBlobContextRequest{
+				Repository: testRepo,
+				BlobOid:    "1234567890123456789012345678901234567890",
+				StartLine:  1,
+				EndLine:    1,
+			},
+			code: codes.NotFound,
+		},
+		{
+			desc: "non-blob object",
+			request: &pb.GetBlobContextRequest{
+				Repository: testRepo,
+				BlobOid:    treeOid,
+				StartLine:  1,
+				EndLine:    1,
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			_, err := client.GetBlobContext(ctx, tc.request)
+			testhelper.AssertGrpcError(t, err, tc.code, "")
+		})
+	}
+}
@@ -43,10 +43,20 @@ func (s *server) CommitDiff(in *pb.CommitDiffRequest, stream pb.DiffService_Comm
 		"--full-index",
 		"--find-renames",
 	}
+	cmdArgs = append(cmdArgs, diffDriverArgs(in.GetTextconv(), in.GetExtDiff())...)
 	if ignoreWhitespaceChange {
 		cmdArgs = append(cmdArgs, "--ignore-space-change")
 	}
-	cmdArgs = append(cmdArgs, leftSha, rightSha)
+	if in.GetWordDiff() {
+		cmdArgs = append(cmdArgs, "--word-diff=porcelain")
+	}
+	if in.GetBaseWithMergeBase() {
+		// The three-dot notation makes git compute the merge base itself,
+		// so left..right and the merge base cannot race with ref updates.
+		cmdArgs = append(cmdArgs, leftSha+"..."+rightSha)
+	} else {
+		cmdArgs = append(cmdArgs, leftSha, rightSha)
+	}
 	if len(paths) > 0 {
 		cmdArgs = append(cmdArgs, "--")
 		for _, path := range paths {
@@ -65,6 +75,7 @@ func (s *server) CommitDiff(in *pb.CommitDiffRequest, stream pb.DiffService_Comm
 	limits.SafeMaxFiles = int(in.SafeMaxFiles)
 	limits.SafeMaxLines = int(in.SafeMaxLines)
 	limits.SafeMaxBytes = int(in.SafeMaxBytes)
+	limits.MaxPatchBytes = int(in.MaxPatchBytes)
 
 	return eachDiff(stream.Context(), "CommitDiff", in.Repository, cmdArgs, limits, func(diff *diff.Diff) error {
 		response := &pb.CommitDiffResponse{
@@ -77,6 +88,7 @@ func (s *server) CommitDiff(in *pb.CommitDiffRequest, stream pb.DiffService_Comm
 			Binary:         diff.Binary,
 			OverflowMarker: diff.OverflowMarker,
 			Collapsed:      diff.Collapsed,
+			TooLarge:       diff.TooLarge,
 		}
 
 		if len(diff.Patch) <= s.MsgSizeThreshold {
@@ -190,6 +202,23 @@ func (s *server) CommitDelta(in *pb.CommitDeltaRequest, stream pb.DiffService_Co
 	return flushFunc()
 }
 
+// diffDriverArgs returns flags controlling whether textconv filters and
+// external diff drivers from gitattributes are applied. Both are disabled
+// explicitly unless the request opts in, so repository-supplied
+// info/attributes cannot change diff output behind the client's back.
+func diffDriverArgs(textconv, extDiff bool) []string {
+	args := []string{"--no-textconv", "--no-ext-diff"}
+
+	if textconv {
+		args[0] = "--textconv"
+	}
+	if extDiff {
+		args[1] = "--ext-diff"
+	}
+
+	return args
+}
+
 func validateRequest(in requestWithLeftRightCommitIds) error {
 	if in.GetLeftCommitId() == "" {
 		return fmt.Errorf("empty LeftCommitId")
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"path"
+	"strings"
 	"testing"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
@@ -308,6 +311,97 @@ func TestSuccessfulCommitDiffRequestWithTypeChangeDiff(t *testing.T) {
 	assertExactReceivedDiffs(t, c, expectedDiffs)
 }
 
+func TestSuccessfulCommitDiffRequestWithWordDiff(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	rightCommit := "e4003da16c1c2c3fc4567700121b17bf8e591c6c"
+	leftCommit := "8a0f2ee90d940bfb0ba1e14e8214b0649056e4ab"
+	rpcRequest := &pb.CommitDiffRequest{
+		Repository:    testRepo,
+		RightCommitId: rightCommit,
+		LeftCommitId:  leftCommit,
+		WordDiff:      true,
+		Paths:         [][]byte{[]byte("README.md")},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := client.CommitDiff(ctx, rpcRequest)
+	require.NoError(t, err)
+
+	diffs := getDiffsFromCommitDiffClient(t, c)
+	require.Len(t, diffs, 1)
+	require.Equal(t, []byte("README.md"), diffs[0].FromPath)
+
+	sawSeparator := false
+	for _, line := range bytes.SplitAfter(diffs[0].Patch, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		// In porcelain format each line is one removed, added or context
+		// token, a "~" line separator, or a chunk header.
+		switch line[0] {
+		case '-', '+', ' ', '~', '\\', '@':
+		default:
+			t.Fatalf("unexpected line in word-diff porcelain output: %q", line)
+		}
+
+		if line[0] == '~' {
+			sawSeparator = true
+		}
+	}
+	require.True(t, sawSeparator, "porcelain output must contain '~' line separators")
+}
+
+func TestSuccessfulCommitDiffRequestWithBaseWithMergeBase(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	leftCommit := "e4003da16c1c2c3fc4567700121b17bf8e591c6c"  // master
+	rightCommit := "0b4bc9a49b562e85de7cc9e834518ea6828729b9" // feature
+
+	mergeBase := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "merge-base", leftCommit, rightCommit)))
+	require.NotEqual(t, leftCommit, mergeBase, "left commit must not itself be the merge base")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := client.CommitDiff(ctx, &pb.CommitDiffRequest{
+		Repository:        testRepo,
+		LeftCommitId:      leftCommit,
+		RightCommitId:     rightCommit,
+		BaseWithMergeBase: true,
+	})
+	require.NoError(t, err)
+	mergeBaseDiffs := getDiffsFromCommitDiffClient(t, c)
+
+	// The result must match an explicit diff against the merge base.
+	c, err = client.CommitDiff(ctx, &pb.CommitDiffRequest{
+		Repository:    testRepo,
+		LeftCommitId:  mergeBase,
+		RightCommitId: rightCommit,
+	})
+	require.NoError(t, err)
+	explicitDiffs := getDiffsFromCommitDiffClient(t, c)
+
+	require.NotEmpty(t, mergeBaseDiffs)
+	require.Equal(t, explicitDiffs, mergeBaseDiffs)
+}
+
 func TestSuccessfulCommitDiffRequestWithIgnoreWhitespaceChange(t *testing.T) {
 	server, serverSocketPath := runDiffServer(t)
 	defer server.Stop()
@@ -619,6 +713,61 @@ func TestSuccessfulCommitDiffRequestWithLimits(t *testing.T) {
 	}
 }
 
+func TestSuccessfulCommitDiffRequestWithMaxPatchBytes(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepoWithWorktree(t)
+	defer cleanupFn()
+
+	commitFiles := func(files map[string]string) string {
+		for name, content := range files {
+			require.NoError(t, ioutil.WriteFile(path.Join(testRepoPath, name), []byte(content), 0644))
+		}
+		testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "add", ".")
+		testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath,
+			"-c", "user.name=Scrooge McDuck", "-c", "user.email=scrooge@mcduck.com",
+			"commit", "-m", "change files")
+
+		out := testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "rev-parse", "HEAD")
+		return string(out[:len(out)-1])
+	}
+
+	leftCommit := commitFiles(map[string]string{"big.txt": "", "small.txt": ""})
+	rightCommit := commitFiles(map[string]string{
+		"big.txt":   strings.Repeat("x\n", 200),
+		"small.txt": "tiny\n",
+	})
+
+	rpcRequest := &pb.CommitDiffRequest{
+		Repository:    testRepo,
+		LeftCommitId:  leftCommit,
+		RightCommitId: rightCommit,
+		MaxPatchBytes: 100,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := client.CommitDiff(ctx, rpcRequest)
+	require.NoError(t, err)
+
+	receivedDiffs := getDiffsFromCommitDiffClient(t, c)
+	require.Len(t, receivedDiffs, 2)
+
+	bigDiff := receivedDiffs[0]
+	require.Equal(t, "big.txt", string(bigDiff.FromPath))
+	require.True(t, bigDiff.TooLarge, "big.txt should be flagged as too large")
+	require.Empty(t, bigDiff.Patch, "big.txt patch should be pruned")
+
+	smallDiff := receivedDiffs[1]
+	require.Equal(t, "small.txt", string(smallDiff.FromPath))
+	require.False(t, smallDiff.TooLarge, "small.txt should not be flagged as too large")
+	require.Contains(t, string(smallDiff.Patch), "+tiny")
+}
+
 func TestFailedCommitDiffRequestDueToValidationError(t *testing.T) {
 	server, serverSocketPath := runDiffServer(t)
 	defer server.Stop()
@@ -974,6 +1123,7 @@ func getDiffsFromCommitDiffClient(t *testing.T, client pb.DiffService_CommitDiff
 				Binary:         fetchedDiff.Binary,
 				Collapsed:      fetchedDiff.Collapsed,
 				OverflowMarker: fetchedDiff.OverflowMarker,
+				TooLarge:       fetchedDiff.TooLarge,
 				Patch:          fetchedDiff.RawPatchData,
 			}
 		} else {
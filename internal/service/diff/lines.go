@@ -0,0 +1,153 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/diff"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) CommitDiffLines(in *pb.CommitDiffLinesRequest, stream pb.DiffService_CommitDiffLinesServer) error {
+	if err := validateRequest(in); err != nil {
+		return status.Errorf(codes.InvalidArgument, "CommitDiffLines: %v", err)
+	}
+
+	cmdArgs := []string{
+		"diff",
+		"--patch",
+		"--raw",
+		"--abbrev=40",
+		"--full-index",
+		"--find-renames",
+		in.LeftCommitId,
+		in.RightCommitId,
+	}
+	if paths := in.GetPaths(); len(paths) > 0 {
+		cmdArgs = append(cmdArgs, "--")
+		for _, path := range paths {
+			cmdArgs = append(cmdArgs, string(path))
+		}
+	}
+
+	return eachDiff(stream.Context(), "CommitDiffLines", in.Repository, cmdArgs, diff.Limits{}, func(diff *diff.Diff) error {
+		response := &pb.CommitDiffLinesResponse{
+			FromPath: diff.FromPath,
+			ToPath:   diff.ToPath,
+			Binary:   diff.Binary,
+		}
+
+		lines, err := parseDiffLines(diff.Patch)
+		if err != nil {
+			return status.Errorf(codes.Internal, "CommitDiffLines: %v", err)
+		}
+
+		var batchSize int
+		for _, line := range lines {
+			response.Lines = append(response.Lines, line)
+			batchSize += len(line.Content) + 16
+
+			if batchSize > s.MsgSizeThreshold {
+				if err := stream.Send(response); err != nil {
+					return status.Errorf(codes.Unavailable, "CommitDiffLines: send: %v", err)
+				}
+
+				// Use a new response so we don't send other fields (FromPath, ...) over and over
+				response = &pb.CommitDiffLinesResponse{}
+				batchSize = 0
+			}
+		}
+
+		response.EndOfFile = true
+		if err := stream.Send(response); err != nil {
+			return status.Errorf(codes.Unavailable, "CommitDiffLines: send: %v", err)
+		}
+
+		return nil
+	})
+}
+
+// parseDiffLines turns a unified patch into line records with old/new line
+// numbers, so clients don't have to parse hunk headers themselves.
+func parseDiffLines(patch []byte) ([]*pb.CommitDiffLinesResponse_Line, error) {
+	var lines []*pb.CommitDiffLinesResponse_Line
+	var oldLine, newLine int32
+
+	for _, rawLine := range bytes.Split(patch, []byte("\n")) {
+		if len(rawLine) == 0 {
+			continue
+		}
+
+		line := &pb.CommitDiffLinesResponse_Line{Content: rawLine}
+
+		switch rawLine[0] {
+		case '@':
+			var err error
+			if oldLine, newLine, err = parseHunkHeader(rawLine); err != nil {
+				return nil, err
+			}
+			line.Type = pb.CommitDiffLinesResponse_Line_HUNK
+			line.OldLine = oldLine
+			line.NewLine = newLine
+		case '+':
+			line.Type = pb.CommitDiffLinesResponse_Line_ADDED
+			line.NewLine = newLine
+			newLine++
+		case '-':
+			line.Type = pb.CommitDiffLinesResponse_Line_REMOVED
+			line.OldLine = oldLine
+			oldLine++
+		case '\\':
+			line.Type = pb.CommitDiffLinesResponse_Line_NO_NEWLINE
+		default:
+			line.Type = pb.CommitDiffLinesResponse_Line_CONTEXT
+			line.OldLine = oldLine
+			line.NewLine = newLine
+			oldLine++
+			newLine++
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+// parseHunkHeader extracts the old and new start line from a header like
+// `@@ -24,3 +24,5 @@ func main() {`.
+func parseHunkHeader(header []byte) (oldLine int32, newLine int32, err error) {
+	fields := bytes.SplitN(header, []byte(" "), 4)
+	if len(fields) < 3 {
+		return 0, 0, fmt.Errorf("malformed hunk header: %q", header)
+	}
+
+	oldLine, err = parseHunkRange(fields[1])
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk header: %q", header)
+	}
+
+	newLine, err = parseHunkRange(fields[2])
+	if err != nil {
+		return 0, 0, fmt.Errorf("malformed hunk header: %q", header)
+	}
+
+	return oldLine, newLine, nil
+}
+
+func parseHunkRange(hunkRange []byte) (int32, error) {
+	if len(hunkRange) < 2 {
+		return 0, fmt.Errorf("invalid range %q", hunkRange)
+	}
+
+	start := bytes.SplitN(hunkRange[1:], []byte(","), 2)[0]
+	n, err := strconv.ParseInt(string(start), 10, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(n), nil
+}
@@ -0,0 +1,142 @@
+package diff
+
+import (
+	"io"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulCommitDiffLinesRequest(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepoWithWorktree(t)
+	defer cleanupFn()
+
+	commitFile := func(content string) string {
+		require.NoError(t, ioutil.WriteFile(path.Join(testRepoPath, "lines.txt"), []byte(content), 0644))
+		testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "add", "lines.txt")
+		testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath,
+			"-c", "user.name=Scrooge McDuck", "-c", "user.email=scrooge@mcduck.com",
+			"commit", "-m", "change lines.txt")
+
+		out := testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "rev-parse", "HEAD")
+		return string(out[:len(out)-1])
+	}
+
+	leftCommit := commitFile("a\nb\nc\n")
+	rightCommit := commitFile("a\nB\nc\nd\n")
+
+	rpcRequest := &pb.CommitDiffLinesRequest{
+		Repository:    testRepo,
+		LeftCommitId:  leftCommit,
+		RightCommitId: rightCommit,
+	}
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	c, err := client.CommitDiffLines(ctx, rpcRequest)
+	require.NoError(t, err)
+
+	var lines []*pb.CommitDiffLinesResponse_Line
+	var fromPath, toPath []byte
+
+	for {
+		resp, err := c.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		if resp.GetFromPath() != nil {
+			fromPath = resp.GetFromPath()
+			toPath = resp.GetToPath()
+		}
+		lines = append(lines, resp.GetLines()...)
+	}
+
+	require.Equal(t, []byte("lines.txt"), fromPath)
+	require.Equal(t, []byte("lines.txt"), toPath)
+
+	expectedLines := []*pb.CommitDiffLinesResponse_Line{
+		{
+			Type:    pb.CommitDiffLinesResponse_Line_HUNK,
+			OldLine: 1,
+			NewLine: 1,
+			Content: []byte("@@ -1,3 +1,4 @@"),
+		},
+		{
+			Type:    pb.CommitDiffLinesResponse_Line_CONTEXT,
+			OldLine: 1,
+			NewLine: 1,
+			Content: []byte(" a"),
+		},
+		{
+			Type:    pb.CommitDiffLinesResponse_Line_REMOVED,
+			OldLine: 2,
+			Content: []byte("-b"),
+		},
+		{
+			Type:    pb.CommitDiffLinesResponse_Line_ADDED,
+			NewLine: 2,
+			Content: []byte("+B"),
+		},
+		{
+			Type:    pb.CommitDiffLinesResponse_Line_CONTEXT,
+			OldLine: 3,
+			NewLine: 3,
+			Content: []byte(" c"),
+		},
+		{
+			Type:    pb.CommitDiffLinesResponse_Line_ADDED,
+			NewLine: 4,
+			Content: []byte("+d"),
+		},
+	}
+
+	require.Equal(t, expectedLines, lines)
+}
+
+func TestFailedCommitDiffLinesRequestDueToValidations(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	rpcRequest := &pb.CommitDiffLinesRequest{
+		Repository:    testRepo,
+		RightCommitId: "e395f646b1499e8e0279445fc99a0596a65fab7e",
+	}
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	c, err := client.CommitDiffLines(ctx, rpcRequest)
+	require.NoError(t, err)
+
+	err = drainCommitDiffLinesResponse(c)
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+}
+
+func drainCommitDiffLinesResponse(c pb.DiffService_CommitDiffLinesClient) error {
+	var err error
+	for err == nil {
+		_, err = c.Recv()
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package diff
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// numStatsPerMessage is the number of stat entries in a single stream
+// response message.
+const numStatsPerMessage = 1000
+
+func (s *server) DiffStats(in *pb.DiffStatsRequest, stream pb.DiffService_DiffStatsServer) error {
+	if err := validateRequest(in); err != nil {
+		return status.Errorf(codes.InvalidArgument, "DiffStats: %v", err)
+	}
+
+	cmdArgs := []string{"diff", "--numstat", "-z", in.LeftCommitId, in.RightCommitId}
+
+	cmd, err := git.Command(stream.Context(), in.Repository, cmdArgs...)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Errorf(codes.Internal, "DiffStats: cmd: %v", err)
+	}
+
+	var batch []*pb.DiffStats
+
+	flushFunc := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := stream.Send(&pb.DiffStatsResponse{Stats: batch}); err != nil {
+			return status.Errorf(codes.Unavailable, "DiffStats: send: %v", err)
+		}
+
+		batch = nil
+		return nil
+	}
+
+	reader := bufio.NewReader(cmd)
+	for {
+		stat, err := parseNumStat(reader)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return status.Errorf(codes.Internal, "DiffStats: parse: %v", err)
+		}
+
+		batch = append(batch, stat)
+
+		if len(batch) == numStatsPerMessage {
+			if err := flushFunc(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return status.Errorf(codes.Unavailable, "DiffStats: %v", err)
+	}
+
+	return flushFunc()
+}
+
+// parseNumStat reads one `git diff --numstat -z` record. A record is
+// "<additions>\t<deletions>\t<path>\0", except for renames where the
+// path field is empty and the old and new path follow as two separate
+// NUL-terminated fields.
+func parseNumStat(reader *bufio.Reader) (*pb.DiffStats, error) {
+	record, err := readNullTerminated(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	split := strings.SplitN(record, "\t", 3)
+	if len(split) != 3 {
+		return nil, status.Errorf(codes.Internal, "unexpected numstat line %q", record)
+	}
+
+	path := split[2]
+	if path == "" {
+		// Rename: skip the old path, use the new one
+		if _, err := readNullTerminated(reader); err != nil {
+			return nil, err
+		}
+		if path, err = readNullTerminated(reader); err != nil {
+			return nil, err
+		}
+	}
+
+	stat := &pb.DiffStats{Path: []byte(path)}
+
+	// Binary files are reported as "-\t-"; their counts stay zero.
+	if split[0] != "-" {
+		additions, err := strconv.ParseInt(split[0], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		deletions, err := strconv.ParseInt(split[1], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+
+		stat.Additions = int32(additions)
+		stat.Deletions = int32(deletions)
+	}
+
+	return stat, nil
+}
+
+func readNullTerminated(reader *bufio.Reader) (string, error) {
+	field, err := reader.ReadString('\x00')
+	if err == io.EOF && field != "" {
+		return field, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(field, "\x00"), nil
+}
@@ -0,0 +1,125 @@
+package diff
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulDiffStatsRequest(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	rightCommit := "e4003da16c1c2c3fc4567700121b17bf8e591c6c"
+	leftCommit := "8a0f2ee90d940bfb0ba1e14e8214b0649056e4ab"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := client.DiffStats(ctx, &pb.DiffStatsRequest{
+		Repository:    testRepo,
+		LeftCommitId:  leftCommit,
+		RightCommitId: rightCommit,
+	})
+	require.NoError(t, err)
+
+	var stats []*pb.DiffStats
+	for {
+		r, err := c.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		stats = append(stats, r.GetStats()...)
+	}
+
+	expectedStats := expectedNumStats(t, testRepoPath, leftCommit, rightCommit)
+	require.Equal(t, expectedStats, stats)
+}
+
+// expectedNumStats derives the expectation from git itself so the test
+// does not depend on the exact contents of the seed repository.
+func expectedNumStats(t *testing.T, repoPath, leftCommit, rightCommit string) []*pb.DiffStats {
+	output := testhelper.MustRunCommand(t, nil, "git", "-C", repoPath, "diff", "--numstat", leftCommit, rightCommit)
+
+	var stats []*pb.DiffStats
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		split := strings.SplitN(line, "\t", 3)
+		require.Len(t, split, 3, "numstat line %q", line)
+
+		stat := &pb.DiffStats{Path: []byte(split[2])}
+		if split[0] != "-" {
+			additions, err := strconv.Atoi(split[0])
+			require.NoError(t, err)
+			deletions, err := strconv.Atoi(split[1])
+			require.NoError(t, err)
+
+			stat.Additions = int32(additions)
+			stat.Deletions = int32(deletions)
+		}
+
+		stats = append(stats, stat)
+	}
+
+	return stats
+}
+
+func TestFailedDiffStatsRequestDueToValidationError(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	testCases := []struct {
+		description string
+		request     *pb.DiffStatsRequest
+	}{
+		{
+			description: "empty LeftCommitId",
+			request: &pb.DiffStatsRequest{
+				Repository:    testRepo,
+				RightCommitId: "e4003da16c1c2c3fc4567700121b17bf8e591c6c",
+			},
+		},
+		{
+			description: "empty RightCommitId",
+			request: &pb.DiffStatsRequest{
+				Repository:   testRepo,
+				LeftCommitId: "8a0f2ee90d940bfb0ba1e14e8214b0649056e4ab",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			c, err := client.DiffStats(ctx, tc.request)
+			require.NoError(t, err)
+
+			var recvErr error
+			for recvErr == nil {
+				_, recvErr = c.Recv()
+			}
+			testhelper.AssertGrpcError(t, recvErr, codes.InvalidArgument, "")
+		})
+	}
+}
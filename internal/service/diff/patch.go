@@ -1,36 +1,27 @@
 package diff
 
 import (
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/streamio"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// CommitPatch produces a format-patch style email patch for a single
+// commit, including the diffstat and signature line, as served by the
+// ".patch" download endpoint.
 func (s *server) CommitPatch(in *pb.CommitPatchRequest, stream pb.DiffService_CommitPatchServer) error {
-	ctx := stream.Context()
-
-	client, err := s.DiffServiceClient(ctx)
-	if err != nil {
-		return err
-	}
-
-	clientCtx, err := rubyserver.SetHeaders(ctx, in.GetRepository())
-	if err != nil {
-		return err
+	if len(in.GetRevision()) == 0 {
+		return status.Errorf(codes.InvalidArgument, "CommitPatch: empty Revision")
 	}
 
-	rubyStream, err := client.CommitPatch(clientCtx, in)
-	if err != nil {
-		return err
-	}
+	cmdArgs := []string{"format-patch", "-1", "--stdout", "--root", string(in.GetRevision())}
 
-	return rubyserver.Proxy(func() error {
-		resp, err := rubyStream.Recv()
-		if err != nil {
-			md := rubyStream.Trailer()
-			stream.SetTrailer(md)
-			return err
-		}
-		return stream.Send(resp)
+	sw := streamio.NewWriter(func(p []byte) error {
+		return stream.Send(&pb.CommitPatchResponse{Data: p})
 	})
+
+	return sendRawOutput(stream.Context(), "CommitPatch", in.Repository, sw, cmdArgs)
 }
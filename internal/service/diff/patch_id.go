@@ -0,0 +1,70 @@
+package diff
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strings"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetPatchID returns the stable patch-id of the diff between two
+// revisions. Identical changes on different commits yield the same
+// patch-id, which lets callers detect already-merged changes without
+// downloading the diff itself.
+func (s *server) GetPatchID(ctx context.Context, in *pb.GetPatchIDRequest) (*pb.GetPatchIDResponse, error) {
+	if err := validateGetPatchIDRequest(in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "GetPatchID: %v", err)
+	}
+
+	diffCmd, err := git.Command(ctx, in.GetRepository(), "diff", string(in.GetOldRevision()), string(in.GetNewRevision()))
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "GetPatchID: diff cmd: %v", err)
+	}
+
+	patchIDCmd, err := command.New(ctx, exec.Command(command.GitPath(), "patch-id", "--stable"), diffCmd, nil, nil)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "GetPatchID: patch-id cmd: %v", err)
+	}
+
+	output, err := ioutil.ReadAll(patchIDCmd)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "GetPatchID: read: %v", err)
+	}
+
+	if err := diffCmd.Wait(); err != nil {
+		return nil, status.Errorf(codes.Internal, "GetPatchID: diff: %v", err)
+	}
+	if err := patchIDCmd.Wait(); err != nil {
+		return nil, status.Errorf(codes.Internal, "GetPatchID: patch-id: %v", err)
+	}
+
+	// Output is "<patch-id> <commit-id>\n"; an empty diff yields no output.
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return nil, status.Errorf(codes.FailedPrecondition, "GetPatchID: no difference between revisions")
+	}
+
+	return &pb.GetPatchIDResponse{PatchId: fields[0]}, nil
+}
+
+func validateGetPatchIDRequest(in *pb.GetPatchIDRequest) error {
+	if err := git.ValidateRevision(in.GetOldRevision()); err != nil {
+		return fmt.Errorf("OldRevision: %v", err)
+	}
+	if err := git.ValidateRevision(in.GetNewRevision()); err != nil {
+		return fmt.Errorf("NewRevision: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package diff
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulGetPatchIDRequest(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	oldRevision := "8a0f2ee90d940bfb0ba1e14e8214b0649056e4ab"
+	newRevision := "e4003da16c1c2c3fc4567700121b17bf8e591c6c"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	response, err := client.GetPatchID(ctx, &pb.GetPatchIDRequest{
+		Repository:  testRepo,
+		OldRevision: []byte(oldRevision),
+		NewRevision: []byte(newRevision),
+	})
+	require.NoError(t, err)
+
+	// Derive the expectation from git itself
+	diffOutput := testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "diff", oldRevision, newRevision)
+	patchIDOutput := testhelper.MustRunCommand(t, bytes.NewReader(diffOutput), "git", "patch-id", "--stable")
+	expectedPatchID := strings.Fields(string(patchIDOutput))[0]
+
+	require.Equal(t, expectedPatchID, response.PatchId)
+}
+
+func TestFailedGetPatchIDRequest(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	revision := "e4003da16c1c2c3fc4567700121b17bf8e591c6c"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testCases := []struct {
+		desc    string
+		request *pb.GetPatchIDRequest
+		code    codes.Code
+	}{
+		{
+			desc: "empty OldRevision",
+			request: &pb.GetPatchIDRequest{
+				Repository:  testRepo,
+				NewRevision: []byte(revision),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "empty NewRevision",
+			request: &pb.GetPatchIDRequest{
+				Repository:  testRepo,
+				OldRevision: []byte(revision),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "OldRevision looks like an option",
+			request: &pb.GetPatchIDRequest{
+				Repository:  testRepo,
+				OldRevision: []byte("--output=/tmp/arbitrary-path"),
+				NewRevision: []byte(revision),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "NewRevision looks like an option",
+			request: &pb.GetPatchIDRequest{
+				Repository:  testRepo,
+				OldRevision: []byte(revision),
+				NewRevision: []byte("--output=/tmp/arbitrary-path"),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "identical revisions have no patch-id",
+			request: &pb.GetPatchIDRequest{
+				Repository:  testRepo,
+				OldRevision: []byte(revision),
+				NewRevision: []byte(revision),
+			},
+			code: codes.FailedPrecondition,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := client.GetPatchID(ctx, tc.request)
+			testhelper.AssertGrpcError(t, err, tc.code, "")
+		})
+	}
+}
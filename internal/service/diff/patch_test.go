@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
@@ -17,23 +20,20 @@ func TestSuccessfulCommitPatchRequest(t *testing.T) {
 	client, conn := newDiffClient(t, serverSocketPath)
 	defer conn.Close()
 
-	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
 	defer cleanupFn()
 
 	testCases := []struct {
 		desc     string
-		revision []byte
-		diff     []byte
+		revision string
 	}{
 		{
 			desc:     "With a commit id",
-			revision: []byte("2f63565e7aac07bcdadb654e253078b727143ec4"),
-			diff:     testhelper.MustReadFile(t, "testdata/binary-changes-patch.txt"),
+			revision: "2f63565e7aac07bcdadb654e253078b727143ec4",
 		},
 		{
 			desc:     "With a root commit id",
-			revision: []byte("1a0b36b3cdad1d2ee32457c102a8c0b7056fa863"),
-			diff:     testhelper.MustReadFile(t, "testdata/initial-commit-patch.txt"),
+			revision: "1a0b36b3cdad1d2ee32457c102a8c0b7056fa863",
 		},
 	}
 
@@ -42,9 +42,12 @@ func TestSuccessfulCommitPatchRequest(t *testing.T) {
 			ctx, cancel := testhelper.Context()
 			defer cancel()
 
+			expectedPatch := testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath,
+				"format-patch", "-1", "--stdout", "--root", testCase.revision)
+
 			request := &pb.CommitPatchRequest{
 				Repository: testRepo,
-				Revision:   testCase.revision,
+				Revision:   []byte(testCase.revision),
 			}
 
 			c, err := client.CommitPatch(ctx, request)
@@ -64,7 +67,35 @@ func TestSuccessfulCommitPatchRequest(t *testing.T) {
 				data = append(data, r.GetData()...)
 			}
 
-			assert.Equal(t, testCase.diff, data)
+			assert.Equal(t, expectedPatch, data)
 		})
 	}
 }
+
+func TestFailedCommitPatchRequestDueToValidations(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	c, err := client.CommitPatch(ctx, &pb.CommitPatchRequest{Repository: testRepo})
+	require.NoError(t, err)
+
+	err = drainCommitPatchResponse(c)
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "empty Revision")
+}
+
+func drainCommitPatchResponse(c pb.DiffService_CommitPatchClient) error {
+	var err error
+	for err == nil {
+		_, err = c.Recv()
+	}
+	return err
+}
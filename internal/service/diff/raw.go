@@ -18,7 +18,9 @@ func (s *server) RawDiff(in *pb.RawDiffRequest, stream pb.DiffService_RawDiffSer
 		return status.Errorf(codes.InvalidArgument, "RawDiff: %v", err)
 	}
 
-	cmdArgs := []string{"diff", "--full-index", in.LeftCommitId, in.RightCommitId}
+	cmdArgs := []string{"diff", "--full-index"}
+	cmdArgs = append(cmdArgs, diffDriverArgs(in.GetTextconv(), in.GetExtDiff())...)
+	cmdArgs = append(cmdArgs, in.LeftCommitId, in.RightCommitId)
 
 	sw := streamio.NewWriter(func(p []byte) error {
 		return stream.Send(&pb.RawDiffResponse{Data: p})
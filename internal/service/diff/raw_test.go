@@ -1,7 +1,11 @@
 package diff
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
 	"testing"
 
 	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
@@ -219,3 +223,71 @@ func drainRawPatchResponse(c pb.DiffService_RawPatchClient) error {
 	}
 	return err
 }
+
+func TestRawDiffTextconvRequest(t *testing.T) {
+	server, serverSocketPath := runDiffServer(t)
+	defer server.Stop()
+
+	client, conn := newDiffClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepoWithWorktree(t)
+	defer cleanupFn()
+
+	// Configure a textconv filter for the file we are about to change
+	attributesPath := path.Join(testRepoPath, ".git", "info", "attributes")
+	require.NoError(t, os.MkdirAll(path.Dir(attributesPath), 0755))
+	require.NoError(t, ioutil.WriteFile(attributesPath, []byte("numbers.txt diff=upcase\n"), 0644))
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "config", "diff.upcase.textconv", "awk '{print toupper($0)}'")
+
+	require.NoError(t, ioutil.WriteFile(path.Join(testRepoPath, "numbers.txt"), []byte("one\n"), 0644))
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "add", "numbers.txt")
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath,
+		"-c", "user.name=Scrooge McDuck", "-c", "user.email=scrooge@mcduck.com",
+		"commit", "-m", "Add numbers")
+
+	require.NoError(t, ioutil.WriteFile(path.Join(testRepoPath, "numbers.txt"), []byte("one\ntwo\n"), 0644))
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "add", "numbers.txt")
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath,
+		"-c", "user.name=Scrooge McDuck", "-c", "user.email=scrooge@mcduck.com",
+		"commit", "-m", "Add more numbers")
+
+	leftCommit := string(bytes.TrimSpace(testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "rev-parse", "HEAD~1")))
+	rightCommit := string(bytes.TrimSpace(testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "rev-parse", "HEAD")))
+
+	testCases := []struct {
+		desc     string
+		textconv bool
+		addition string
+	}{
+		{desc: "without textconv", textconv: false, addition: "+two"},
+		{desc: "with textconv", textconv: true, addition: "+TWO"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			rpcRequest := &pb.RawDiffRequest{
+				Repository:    testRepo,
+				LeftCommitId:  leftCommit,
+				RightCommitId: rightCommit,
+				Textconv:      tc.textconv,
+			}
+
+			c, err := client.RawDiff(ctx, rpcRequest)
+			require.NoError(t, err)
+
+			reader := streamio.NewReader(func() ([]byte, error) {
+				response, err := c.Recv()
+				return response.GetData(), err
+			})
+
+			rawDiff, err := ioutil.ReadAll(reader)
+			require.NoError(t, err)
+
+			require.Contains(t, string(rawDiff), tc.addition)
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package objectpool
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CreateObjectPool creates a bare pool repository primed with the
+// objects of the origin repository. The clone is local so objects are
+// hardlinked, not copied.
+func (s *server) CreateObjectPool(ctx context.Context, req *pb.CreateObjectPoolRequest) (*pb.CreateObjectPoolResponse, error) {
+	poolRepo, err := validatePoolRequest(req.GetObjectPool(), req.GetOrigin())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "CreateObjectPool: %v", err)
+	}
+
+	originPath, err := helper.GetRepoPath(req.GetOrigin())
+	if err != nil {
+		return nil, err
+	}
+
+	poolPath, err := helper.GetPath(poolRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(poolPath); err == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "CreateObjectPool: pool already exists")
+	} else if !os.IsNotExist(err) {
+		return nil, status.Errorf(codes.Internal, "CreateObjectPool: stat pool: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(poolPath), 0770); err != nil {
+		return nil, status.Errorf(codes.Internal, "CreateObjectPool: mkdir: %v", err)
+	}
+
+	args := []string{"clone", "--quiet", "--bare", "--local", "--", originPath, poolPath}
+	cmd, err := command.New(ctx, exec.Command(command.GitPath(), args...), nil, nil, nil)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "CreateObjectPool: clone cmd start: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		os.RemoveAll(poolPath)
+		return nil, status.Errorf(codes.Internal, "CreateObjectPool: clone cmd wait: %v", err)
+	}
+
+	return &pb.CreateObjectPoolResponse{}, nil
+}
+
+// validatePoolRequest checks the pool and member/origin repositories of
+// a request. Pools only make sense on the same storage as their
+// members: alternates are relative paths on one filesystem.
+func validatePoolRequest(pool *pb.ObjectPool, repo *pb.Repository) (*pb.Repository, error) {
+	poolRepo := pool.GetRepository()
+	if poolRepo == nil {
+		return nil, fmt.Errorf("empty ObjectPool")
+	}
+
+	if repo == nil {
+		return nil, fmt.Errorf("empty Repository")
+	}
+
+	if poolRepo.GetStorageName() != repo.GetStorageName() {
+		return nil, fmt.Errorf("pool and repository are on different storages")
+	}
+
+	return poolRepo, nil
+}
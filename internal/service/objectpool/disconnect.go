@@ -0,0 +1,158 @@
+package objectpool
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DisconnectGitAlternates severs the link between a repository and its
+// pool. Borrowed objects are hardlinked (or copied) into the repository
+// first, and the alternates file is only moved aside after a
+// connectivity check passes, so the repository never loses objects.
+func (s *server) DisconnectGitAlternates(ctx context.Context, req *pb.DisconnectGitAlternatesRequest) (*pb.DisconnectGitAlternatesResponse, error) {
+	if req.GetRepository() == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "DisconnectGitAlternates: empty Repository")
+	}
+
+	repoPath, err := helper.GetRepoPath(req.GetRepository())
+	if err != nil {
+		return nil, err
+	}
+
+	objectsDir := filepath.Join(repoPath, "objects")
+	altPath := filepath.Join(objectsDir, "info", "alternates")
+
+	altContent, err := ioutil.ReadFile(altPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// Not linked to a pool; nothing to do.
+			return &pb.DisconnectGitAlternatesResponse{}, nil
+		}
+		return nil, status.Errorf(codes.Internal, "DisconnectGitAlternates: read alternates: %v", err)
+	}
+
+	altObjectsDir := strings.TrimSpace(string(altContent))
+	if altObjectsDir == "" || strings.Contains(altObjectsDir, "\n") {
+		return nil, status.Errorf(codes.FailedPrecondition, "DisconnectGitAlternates: unexpected alternates content")
+	}
+	if !filepath.IsAbs(altObjectsDir) {
+		altObjectsDir = filepath.Join(objectsDir, altObjectsDir)
+	}
+
+	if info, err := os.Stat(altObjectsDir); err != nil || !info.IsDir() {
+		return nil, status.Errorf(codes.FailedPrecondition, "DisconnectGitAlternates: invalid alternate object directory %q", altObjectsDir)
+	}
+
+	if err := copyObjects(altObjectsDir, objectsDir); err != nil {
+		return nil, status.Errorf(codes.Internal, "DisconnectGitAlternates: copy objects: %v", err)
+	}
+
+	backupPath := fmt.Sprintf("%s.%d", altPath, time.Now().Unix())
+	if err := os.Rename(altPath, backupPath); err != nil {
+		return nil, status.Errorf(codes.Internal, "DisconnectGitAlternates: disable alternates: %v", err)
+	}
+
+	if err := connectivityCheck(ctx, req.GetRepository()); err != nil {
+		// Put the alternates file back: with it the repository is known
+		// to be complete.
+		if renameErr := os.Rename(backupPath, altPath); renameErr != nil {
+			return nil, status.Errorf(codes.Internal, "DisconnectGitAlternates: restore alternates: %v", renameErr)
+		}
+		return nil, status.Errorf(codes.Internal, "DisconnectGitAlternates: connectivity check: %v", err)
+	}
+
+	return &pb.DisconnectGitAlternatesResponse{}, nil
+}
+
+// copyObjects hardlinks every object file from srcDir into destDir,
+// falling back to a file copy across filesystems. Files that already
+// exist in destDir are left alone, and the info directory is skipped so
+// an alternates file of the pool itself is never inherited.
+func copyObjects(srcDir, destDir string) error {
+	return filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if rel == "info" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		dest := filepath.Join(destDir, rel)
+		if _, err := os.Stat(dest); err == nil {
+			return nil
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+
+		if err := os.Link(path, dest); err == nil {
+			return nil
+		}
+
+		return copyFile(path, dest, info.Mode())
+	})
+}
+
+func copyFile(src, dest string, mode os.FileMode) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	destFile, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(destFile, srcFile); err != nil {
+		destFile.Close()
+		os.Remove(dest)
+		return err
+	}
+
+	return destFile.Close()
+}
+
+func connectivityCheck(ctx context.Context, repo *pb.Repository) error {
+	repoPath, err := helper.GetRepoPath(repo)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"--git-dir", repoPath, "fsck", "--connectivity-only", "--no-progress"}
+	cmd, err := command.New(ctx, exec.Command(command.GitPath(), args...), nil, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	return cmd.Wait()
+}
@@ -0,0 +1,44 @@
+package objectpool
+
+import (
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FetchIntoObjectPool pulls the current objects and refs of the origin
+// repository into the pool. Origin refs are kept under
+// refs/remotes/origin so the pool retains objects that members still
+// borrow even after the origin rewrites history.
+func (s *server) FetchIntoObjectPool(ctx context.Context, req *pb.FetchIntoObjectPoolRequest) (*pb.FetchIntoObjectPoolResponse, error) {
+	poolRepo, err := validatePoolRequest(req.GetObjectPool(), req.GetOrigin())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "FetchIntoObjectPool: %v", err)
+	}
+
+	originPath, err := helper.GetRepoPath(req.GetOrigin())
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"fetch", "--quiet", "--prune", "--", originPath, "+refs/*:refs/remotes/origin/*"}
+
+	cmd, err := git.Command(ctx, poolRepo, args...)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "FetchIntoObjectPool: cmd: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, status.Errorf(codes.Internal, "FetchIntoObjectPool: cmd wait: %v", err)
+	}
+
+	return &pb.FetchIntoObjectPoolResponse{}, nil
+}
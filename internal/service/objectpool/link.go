@@ -0,0 +1,75 @@
+package objectpool
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LinkRepositoryToObjectPool writes objects/info/alternates in the
+// member repository so it borrows its objects from the pool.
+func (s *server) LinkRepositoryToObjectPool(ctx context.Context, req *pb.LinkRepositoryToObjectPoolRequest) (*pb.LinkRepositoryToObjectPoolResponse, error) {
+	poolRepo, err := validatePoolRequest(req.GetObjectPool(), req.GetRepository())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "LinkRepositoryToObjectPool: %v", err)
+	}
+
+	repoPath, err := helper.GetRepoPath(req.GetRepository())
+	if err != nil {
+		return nil, err
+	}
+
+	poolPath, err := helper.GetRepoPath(poolRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	altContent, err := filepath.Rel(filepath.Join(repoPath, "objects"), filepath.Join(poolPath, "objects"))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "LinkRepositoryToObjectPool: alternates path: %v", err)
+	}
+	altContent += "\n"
+
+	altPath := filepath.Join(repoPath, "objects", "info", "alternates")
+	if existing, err := ioutil.ReadFile(altPath); err == nil {
+		if bytes.Equal(existing, []byte(altContent)) {
+			// Already linked to this pool; nothing to do.
+			return &pb.LinkRepositoryToObjectPoolResponse{}, nil
+		}
+		return nil, status.Errorf(codes.FailedPrecondition, "LinkRepositoryToObjectPool: repository has alternates")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(altPath), 0755); err != nil {
+		return nil, status.Errorf(codes.Internal, "LinkRepositoryToObjectPool: mkdir: %v", err)
+	}
+
+	// Write-then-rename so a concurrent git process never sees a partial
+	// alternates file.
+	tmp, err := ioutil.TempFile(filepath.Dir(altPath), "alternates")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "LinkRepositoryToObjectPool: create: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write([]byte(altContent)); err != nil {
+		return nil, status.Errorf(codes.Internal, "LinkRepositoryToObjectPool: write: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, status.Errorf(codes.Internal, "LinkRepositoryToObjectPool: close: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), altPath); err != nil {
+		return nil, status.Errorf(codes.Internal, "LinkRepositoryToObjectPool: rename: %v", err)
+	}
+
+	return &pb.LinkRepositoryToObjectPoolResponse{}, nil
+}
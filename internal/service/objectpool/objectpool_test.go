@@ -0,0 +1,169 @@
+package objectpool
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestObjectPoolLifecycle(t *testing.T) {
+	server, serverSocketPath := runObjectPoolServer(t)
+	defer server.Stop()
+
+	client, conn := newObjectPoolClient(t, serverSocketPath)
+	defer conn.Close()
+
+	origin, originPath, cleanupOrigin := testhelper.InitBareRepo(t)
+	defer cleanupOrigin()
+
+	member, memberPath, cleanupMember := testhelper.InitBareRepo(t)
+	defer cleanupMember()
+
+	commitID := writePoolTestCommit(t, originPath, "first commit")
+
+	pool := &pb.ObjectPool{
+		Repository: &pb.Repository{
+			StorageName:  origin.StorageName,
+			RelativePath: "@pools/" + filepath.Base(originPath),
+		},
+	}
+	poolPath := filepath.Join(filepath.Dir(originPath), pool.Repository.RelativePath)
+	defer os.RemoveAll(poolPath)
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	_, err := client.CreateObjectPool(ctx, &pb.CreateObjectPoolRequest{
+		ObjectPool: pool,
+		Origin:     origin,
+	})
+	require.NoError(t, err)
+
+	// The pool was primed with the objects of the origin
+	require.Equal(t, "commit", objectType(t, poolPath, commitID))
+
+	// Creating the same pool twice must fail
+	_, err = client.CreateObjectPool(ctx, &pb.CreateObjectPoolRequest{
+		ObjectPool: pool,
+		Origin:     origin,
+	})
+	testhelper.AssertGrpcError(t, err, codes.FailedPrecondition, "")
+
+	_, err = client.LinkRepositoryToObjectPool(ctx, &pb.LinkRepositoryToObjectPoolRequest{
+		ObjectPool: pool,
+		Repository: member,
+	})
+	require.NoError(t, err)
+
+	// The member sees pool objects through the alternates link
+	altPath := filepath.Join(memberPath, "objects", "info", "alternates")
+	require.True(t, fileExists(altPath))
+	require.Equal(t, "commit", objectType(t, memberPath, commitID))
+
+	// Linking again is idempotent
+	_, err = client.LinkRepositoryToObjectPool(ctx, &pb.LinkRepositoryToObjectPoolRequest{
+		ObjectPool: pool,
+		Repository: member,
+	})
+	require.NoError(t, err)
+
+	// New objects in the origin reach the pool via FetchIntoObjectPool
+	newCommitID := writePoolTestCommit(t, originPath, "second commit")
+	require.NotEqual(t, "commit", objectType(t, poolPath, newCommitID))
+
+	_, err = client.FetchIntoObjectPool(ctx, &pb.FetchIntoObjectPoolRequest{
+		Origin:     origin,
+		ObjectPool: pool,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "commit", objectType(t, poolPath, newCommitID))
+
+	// Disconnecting keeps the member complete without the pool
+	testhelper.MustRunCommand(t, nil, "git", "-C", memberPath, "update-ref", "refs/heads/master", commitID)
+
+	_, err = client.DisconnectGitAlternates(ctx, &pb.DisconnectGitAlternatesRequest{
+		Repository: member,
+	})
+	require.NoError(t, err)
+
+	require.False(t, fileExists(altPath))
+	require.Equal(t, "commit", objectType(t, memberPath, commitID))
+
+	// Disconnecting an unlinked repository is a no-op
+	_, err = client.DisconnectGitAlternates(ctx, &pb.DisconnectGitAlternatesRequest{
+		Repository: member,
+	})
+	require.NoError(t, err)
+}
+
+func TestObjectPoolRequestValidation(t *testing.T) {
+	server, serverSocketPath := runObjectPoolServer(t)
+	defer server.Stop()
+
+	client, conn := newObjectPoolClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	pool := &pb.ObjectPool{
+		Repository: &pb.Repository{
+			StorageName:  "other-storage",
+			RelativePath: "@pools/pool.git",
+		},
+	}
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	_, err := client.CreateObjectPool(ctx, &pb.CreateObjectPoolRequest{Origin: testRepo})
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "empty ObjectPool")
+
+	_, err = client.CreateObjectPool(ctx, &pb.CreateObjectPoolRequest{ObjectPool: pool})
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "empty Repository")
+
+	// Pool and repository on different storages
+	_, err = client.CreateObjectPool(ctx, &pb.CreateObjectPoolRequest{ObjectPool: pool, Origin: testRepo})
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "different storages")
+
+	_, err = client.LinkRepositoryToObjectPool(ctx, &pb.LinkRepositoryToObjectPoolRequest{ObjectPool: pool, Repository: testRepo})
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "different storages")
+
+	_, err = client.DisconnectGitAlternates(ctx, &pb.DisconnectGitAlternatesRequest{})
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "empty Repository")
+}
+
+func writePoolTestCommit(t *testing.T, repoPath, message string) string {
+	blob := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader(message),
+		"git", "-C", repoPath, "hash-object", "-w", "--stdin")))
+
+	tree := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader("100644 blob "+blob+"\tfile.txt\n"),
+		"git", "-C", repoPath, "mktree")))
+
+	commitID := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil,
+		"git", "-C", repoPath, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit-tree", "-m", message, tree)))
+	testhelper.MustRunCommand(t, nil, "git", "-C", repoPath, "update-ref", "refs/heads/master", commitID)
+
+	return commitID
+}
+
+// objectType returns the type of an object, or the git error message
+// if it cannot be resolved in the repository.
+func objectType(t *testing.T, repoPath, oid string) string {
+	output, _ := exec.Command("git", "-C", repoPath, "cat-file", "-t", oid).CombinedOutput()
+	return strings.TrimSpace(string(output))
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,14 @@
+// Package objectpool manages pool repositories that hold the shared
+// objects of a fork network. Member repositories borrow objects from
+// their pool through objects/info/alternates, so forks no longer keep a
+// full copy of the objects of the source repository.
+package objectpool
+
+import pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+type server struct{}
+
+// NewServer creates a new instance of a grpc ObjectPoolServer
+func NewServer() pb.ObjectPoolServiceServer {
+	return &server{}
+}
@@ -1,7 +1,8 @@
 package operations
 
 import (
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+	"gitlab.com/gitlab-org/gitaly/internal/git/log"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -11,17 +12,50 @@ import (
 )
 
 func (s *server) UserCreateBranch(ctx context.Context, req *pb.UserCreateBranchRequest) (*pb.UserCreateBranchResponse, error) {
-	client, err := s.OperationServiceClient(ctx)
-	if err != nil {
-		return nil, err
+	if len(req.BranchName) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Bad Request (empty branch name)")
+	}
+
+	if req.User == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Bad Request (empty user)")
+	}
+
+	if len(req.StartPoint) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "Bad Request (empty start point)")
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, req.GetRepository())
+	repo, err := localrepo.New(req.Repository)
 	if err != nil {
 		return nil, err
 	}
 
-	return client.UserCreateBranch(clientCtx, req)
+	startPointCommit, err := log.GetCommit(ctx, req.Repository, string(req.StartPoint), "")
+	if err != nil || startPointCommit == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "Invalid start_point %s", req.StartPoint)
+	}
+
+	reference := "refs/heads/" + string(req.BranchName)
+
+	// An old value of all zeroes makes update-ref fail unless the branch
+	// is new, so a concurrently created branch is never overwritten.
+	if err := updateRefWithHooks(ctx, repo, req.User, reference, startPointCommit.Id, zeroOID); err != nil {
+		if hookErr, ok := err.(hookError); ok {
+			return &pb.UserCreateBranchResponse{PreReceiveError: hookErr.output}, nil
+		}
+
+		if _, ok := err.(updateRefError); ok {
+			return nil, status.Errorf(codes.FailedPrecondition, "Could not update %s. Please refresh and try again.", reference)
+		}
+
+		return nil, status.Errorf(codes.Internal, "UserCreateBranch: %v", err)
+	}
+
+	return &pb.UserCreateBranchResponse{
+		Branch: &pb.Branch{
+			Name:         req.BranchName,
+			TargetCommit: startPointCommit,
+		},
+	}, nil
 }
 
 func (s *server) UserDeleteBranch(ctx context.Context, req *pb.UserDeleteBranchRequest) (*pb.UserDeleteBranchResponse, error) {
@@ -33,15 +67,29 @@ func (s *server) UserDeleteBranch(ctx context.Context, req *pb.UserDeleteBranchR
 		return nil, status.Errorf(codes.InvalidArgument, "Bad Request (empty user)")
 	}
 
-	client, err := s.OperationServiceClient(ctx)
+	repo, err := localrepo.New(req.Repository)
 	if err != nil {
 		return nil, err
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, req.GetRepository())
-	if err != nil {
-		return nil, err
+	reference := "refs/heads/" + string(req.BranchName)
+
+	branchCommit, err := log.GetCommit(ctx, req.Repository, reference, "")
+	if err != nil || branchCommit == nil {
+		return nil, status.Errorf(codes.Unknown, "branch not found: %s", req.BranchName)
+	}
+
+	if err := updateRefWithHooks(ctx, repo, req.User, reference, zeroOID, branchCommit.Id); err != nil {
+		if hookErr, ok := err.(hookError); ok {
+			return &pb.UserDeleteBranchResponse{PreReceiveError: hookErr.output}, nil
+		}
+
+		if _, ok := err.(updateRefError); ok {
+			return nil, status.Errorf(codes.FailedPrecondition, "Could not update %s. Please refresh and try again.", reference)
+		}
+
+		return nil, status.Errorf(codes.Internal, "UserDeleteBranch: %v", err)
 	}
 
-	return client.UserDeleteBranch(clientCtx, req)
+	return &pb.UserDeleteBranchResponse{}, nil
 }
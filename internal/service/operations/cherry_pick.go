@@ -24,5 +24,23 @@ func (s *server) UserCherryPick(ctx context.Context, req *pb.UserCherryPickReque
 		return nil, err
 	}
 
-	return client.UserCherryPick(clientCtx, req)
+	response, err := client.UserCherryPick(clientCtx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.GetCreateTreeError() != "" {
+		// The sidecar only reports that building the tree failed. Recover
+		// the conflicting files so clients can show them. A cherry-pick
+		// merges the commit onto the branch with the commit's parent as
+		// merge base.
+		commitID := req.GetCommit().GetId()
+		branch := "refs/heads/" + string(req.GetBranchName())
+
+		if files, err := conflictFiles(ctx, req.GetRepository(), commitID+"^", branch, commitID); err == nil {
+			response.ConflictFiles = files
+		}
+	}
+
+	return response, nil
 }
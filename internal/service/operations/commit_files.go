@@ -2,11 +2,16 @@ package operations
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+	"gitlab.com/gitlab-org/gitaly/internal/git/treebuilder"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	"golang.org/x/net/context"
 )
 
 func (s *server) UserCommitFiles(stream pb.OperationService_UserCommitFilesServer) error {
@@ -25,43 +30,198 @@ func (s *server) UserCommitFiles(stream pb.OperationService_UserCommitFilesServe
 	}
 
 	ctx := stream.Context()
-	client, err := s.OperationServiceClient(ctx)
+
+	repo, err := localrepo.New(header.GetRepository())
 	if err != nil {
 		return err
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, header.GetRepository())
+	actions, err := receiveActions(stream)
 	if err != nil {
 		return err
 	}
 
-	rubyStream, err := client.UserCommitFiles(clientCtx)
+	response, err := commitFiles(ctx, repo, header, actions)
 	if err != nil {
 		return err
 	}
 
-	if err := rubyStream.Send(firstRequest); err != nil {
-		return err
-	}
+	return stream.SendAndClose(response)
+}
+
+// receiveActions reassembles the streamed actions: an action header
+// message opens an action, subsequent content messages append to it.
+func receiveActions(stream pb.OperationService_UserCommitFilesServer) ([]commitFilesAction, error) {
+	var actions []commitFilesAction
 
-	err = rubyserver.Proxy(func() error {
+	for {
 		request, err := stream.Recv()
+		if err == io.EOF {
+			return actions, nil
+		}
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		action := request.GetAction()
+		if action == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "UserCommitFiles: expected action")
+		}
+
+		if header := action.GetHeader(); header != nil {
+			actions = append(actions, commitFilesAction{header: header})
+			continue
+		}
+
+		if len(actions) == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "UserCommitFiles: content sent before action header")
+		}
+
+		current := &actions[len(actions)-1]
+		current.content = append(current.content, action.GetContent()...)
+	}
+}
+
+func commitFiles(ctx context.Context, repo *localrepo.Repo, header *pb.UserCommitFilesRequestHeader, actions []commitFilesAction) (*pb.UserCommitFilesResponse, error) {
+	reference := "refs/heads/" + string(header.GetBranchName())
+
+	repoEmpty, err := isRepositoryEmpty(ctx, repo)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "UserCommitFiles: %v", err)
+	}
+
+	branchOID, _ := revParse(ctx, repo, reference)
+
+	parentID := branchOID
+	if parentID == "" && len(header.GetStartBranchName()) > 0 {
+		if parentID, err = resolveStartBranch(ctx, repo, header); err != nil {
+			return nil, err
 		}
-		return rubyStream.Send(request)
-	})
+	}
 
+	tb, cleanup, err := treebuilder.New(repo)
 	if err != nil {
-		return err
+		return nil, status.Errorf(codes.Internal, "UserCommitFiles: %v", err)
+	}
+	defer cleanup()
+
+	if err := tb.Init(ctx, parentID); err != nil {
+		return nil, status.Errorf(codes.Internal, "UserCommitFiles: read-tree: %v", err)
 	}
 
-	response, err := rubyStream.CloseAndRecv()
+	for _, action := range actions {
+		if err := applyAction(ctx, tb, action); err != nil {
+			if idxErr, ok := err.(indexError); ok {
+				return &pb.UserCommitFilesResponse{IndexError: string(idxErr)}, nil
+			}
+			return nil, status.Errorf(codes.Internal, "UserCommitFiles: %v", err)
+		}
+	}
+
+	treeID, err := tb.WriteTree(ctx)
 	if err != nil {
-		return err
+		return nil, status.Errorf(codes.Internal, "UserCommitFiles: write-tree: %v", err)
 	}
 
-	return stream.SendAndClose(response)
+	commitID, err := commitTree(ctx, repo, header, treeID, parentID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "UserCommitFiles: commit-tree: %v", err)
+	}
+
+	oldValue := branchOID
+	if oldValue == "" {
+		oldValue = zeroOID
+	}
+
+	if err := updateRefWithHooks(ctx, repo, header.GetUser(), reference, commitID, oldValue); err != nil {
+		if hookErr, ok := err.(hookError); ok {
+			return &pb.UserCommitFilesResponse{PreReceiveError: hookErr.output}, nil
+		}
+
+		if _, ok := err.(updateRefError); ok {
+			return nil, status.Errorf(codes.FailedPrecondition, "Could not update %s. Please refresh and try again.", reference)
+		}
+
+		return nil, status.Errorf(codes.Internal, "UserCommitFiles: %v", err)
+	}
+
+	return &pb.UserCommitFilesResponse{
+		BranchUpdate: &pb.OperationBranchUpdate{
+			CommitId:      commitID,
+			RepoCreated:   repoEmpty,
+			BranchCreated: branchOID == "",
+		},
+	}, nil
+}
+
+func revParse(ctx context.Context, repo *localrepo.Repo, revision string) (string, error) {
+	return repo.Run(ctx, nil, nil, "rev-parse", "--verify", "--quiet", revision)
+}
+
+func isRepositoryEmpty(ctx context.Context, repo *localrepo.Repo) (bool, error) {
+	out, err := repo.Run(ctx, nil, nil, "for-each-ref", "--count=1")
+	return out == "", err
+}
+
+// resolveStartBranch finds the parent commit for a new branch. When the
+// start repository differs from the target the start branch is fetched
+// first so its objects are available locally.
+func resolveStartBranch(ctx context.Context, repo *localrepo.Repo, header *pb.UserCommitFilesRequestHeader) (string, error) {
+	startBranch := string(header.GetStartBranchName())
+	if !strings.HasPrefix(startBranch, "refs/") {
+		startBranch = "refs/heads/" + startBranch
+	}
+
+	if startRepo := header.GetStartRepository(); startRepo != nil &&
+		(startRepo.GetStorageName() != repo.GetStorageName() || startRepo.GetRelativePath() != repo.GetRelativePath()) {
+		startRepoLocal, err := localrepo.New(startRepo)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := repo.Run(ctx, nil, nil, "fetch", "--no-tags", startRepoLocal.Path(), startBranch); err != nil {
+			return "", status.Errorf(codes.FailedPrecondition, "UserCommitFiles: fetch start branch: %v", err)
+		}
+
+		return revParse(ctx, repo, "FETCH_HEAD")
+	}
+
+	startOID, err := revParse(ctx, repo, startBranch)
+	if err != nil || startOID == "" {
+		return "", status.Errorf(codes.FailedPrecondition, "UserCommitFiles: start branch %q not found", startBranch)
+	}
+
+	return startOID, nil
+}
+
+// commitTree creates the commit object. The author defaults to the
+// committing user when the request carries no explicit author.
+func commitTree(ctx context.Context, repo *localrepo.Repo, header *pb.UserCommitFilesRequestHeader, treeID, parentID string) (string, error) {
+	user := header.GetUser()
+
+	authorName := header.GetCommitAuthorName()
+	if len(authorName) == 0 {
+		authorName = user.GetName()
+	}
+
+	authorEmail := header.GetCommitAuthorEmail()
+	if len(authorEmail) == 0 {
+		authorEmail = user.GetEmail()
+	}
+
+	env := []string{
+		"GIT_AUTHOR_NAME=" + string(authorName),
+		"GIT_AUTHOR_EMAIL=" + string(authorEmail),
+		"GIT_COMMITTER_NAME=" + string(user.GetName()),
+		"GIT_COMMITTER_EMAIL=" + string(user.GetEmail()),
+	}
+
+	args := []string{"commit-tree", treeID}
+	if parentID != "" {
+		args = append(args, "-p", parentID)
+	}
+
+	return repo.Run(ctx, env, strings.NewReader(string(header.GetCommitMessage())), args...)
 }
 
 func validateUserCommitFilesHeader(header *pb.UserCommitFilesRequestHeader) error {
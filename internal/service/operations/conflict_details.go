@@ -0,0 +1,97 @@
+package operations
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"golang.org/x/net/context"
+)
+
+// mergeTreeEntryRegexp matches the per-stage info lines of 'git merge-tree'
+// output, e.g. "  our    100644 <blob oid> some/path".
+var mergeTreeEntryRegexp = regexp.MustCompile(`^  (base|our|their) +\d+ ([0-9a-f]{40}) (.*)$`)
+
+// conflictFiles merges theirs into ours with mergeBase as common ancestor
+// and returns the files that would conflict, with the blob OIDs of their
+// merge stages. It is used to enrich the generic conflict errors reported
+// by the Ruby sidecar for cherry-pick and revert.
+func conflictFiles(ctx context.Context, repo *pb.Repository, mergeBase, ours, theirs string) ([]*pb.OperationConflictFile, error) {
+	cmd, err := git.Command(ctx, repo, "merge-tree", mergeBase, ours, theirs)
+	if err != nil {
+		return nil, err
+	}
+
+	files := parseMergeTreeConflicts(cmd)
+
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// parseMergeTreeConflicts extracts conflicting files from 'git merge-tree'
+// output. An entry that is changed or added on both sides only conflicts
+// when its merged content contains conflict markers; two-sided changes
+// that merge cleanly are skipped.
+func parseMergeTreeConflicts(r io.Reader) []*pb.OperationConflictFile {
+	var files []*pb.OperationConflictFile
+	var pending *pb.OperationConflictFile
+	conflicted := false
+
+	commit := func() {
+		if pending != nil && conflicted {
+			files = append(files, pending)
+		}
+		pending = nil
+		conflicted = false
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "changed in both" || line == "added in both" {
+			commit()
+			pending = &pb.OperationConflictFile{}
+			continue
+		}
+
+		// Any other unindented line starts a block that cannot conflict,
+		// e.g. "merged" or "added in remote".
+		if len(line) > 0 && !strings.ContainsAny(line[:1], " +-@\\") {
+			commit()
+			continue
+		}
+
+		if pending == nil {
+			continue
+		}
+
+		if match := mergeTreeEntryRegexp.FindStringSubmatch(line); match != nil {
+			switch match[1] {
+			case "base":
+				pending.AncestorOid = match[2]
+			case "our":
+				pending.OurOid = match[2]
+			case "their":
+				pending.TheirOid = match[2]
+			}
+
+			pending.Path = []byte(match[3])
+			continue
+		}
+
+		if strings.HasPrefix(line, "+<<<<<<<") {
+			conflicted = true
+		}
+	}
+
+	commit()
+
+	return files
+}
@@ -0,0 +1,49 @@
+package operations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+)
+
+func TestParseMergeTreeConflicts(t *testing.T) {
+	mergeTreeOutput := strings.Join([]string{
+		"added in remote",
+		"  their  100644 2ef267e25bd6c6a300bb473e604b3b54b97f4d3d new-file",
+		"@@ -0,0 +1 @@",
+		"+new content",
+		"merged",
+		"  result 100644 6907208d755b60ebeacb2e9dfea74c92c3449a1f merged-file",
+		"  our    100644 c74175afd117781cbc983664339a0f599b5bb34e merged-file",
+		"changed in both",
+		"  base   100644 2f63563e1f6a6cb5e8b9d00b1bed29e4f5162e55 cleanly-merged",
+		"  our    100644 1269b97dbf51e480bbb1b52bfc28e1e1ba0b4a9b cleanly-merged",
+		"  their  100644 f3581c23c6eb8b9a18e8270feeb7542e97e0542b cleanly-merged",
+		"@@ -1,2 +1,2 @@",
+		" context",
+		"+merged line",
+		"changed in both",
+		"  base   100644 e69de29bb2d1d6434b8b29ae775ad8c2e48c5391 conflicting-file",
+		"  our    100644 257cc5642cb1a054f08cc83f2d943e56fd3ebe99 conflicting-file",
+		"  their  100644 5716ca5987cbf97d6bb54920bea6adde242d87e6 conflicting-file",
+		"@@ -1 +1,5 @@",
+		"+<<<<<<< .our",
+		" foo",
+		"+=======",
+		"+bar",
+		"+>>>>>>> .their",
+	}, "\n")
+
+	files := parseMergeTreeConflicts(strings.NewReader(mergeTreeOutput))
+
+	require.Equal(t, []*pb.OperationConflictFile{
+		{
+			Path:        []byte("conflicting-file"),
+			AncestorOid: "e69de29bb2d1d6434b8b29ae775ad8c2e48c5391",
+			OurOid:      "257cc5642cb1a054f08cc83f2d943e56fd3ebe99",
+			TheirOid:    "5716ca5987cbf97d6bb54920bea6adde242d87e6",
+		},
+	}, files)
+}
@@ -0,0 +1,173 @@
+package operations
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/events"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+	"gitlab.com/gitlab-org/gitaly/internal/hooks"
+	"gitlab.com/gitlab-org/gitaly/internal/housekeeping"
+	"gitlab.com/gitlab-org/gitaly/internal/service/ref"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+)
+
+const zeroOID = "0000000000000000000000000000000000000000"
+
+var hookDurations = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "gitaly_hook_duration_seconds",
+		Help: "Latency of git hook execution, including custom hooks, per hook type",
+	},
+	[]string{"hook"},
+)
+
+func init() {
+	prometheus.MustRegister(hookDurations)
+}
+
+// hookError is returned when a pre-receive or update hook rejects a ref
+// update. Its output is reported to the client as PreReceiveError rather
+// than as an RPC error.
+type hookError struct {
+	hook   string
+	output string
+}
+
+func (e hookError) Error() string {
+	return fmt.Sprintf("%s hook declined: %s", e.hook, e.output)
+}
+
+// updateRefError is returned when `git update-ref` itself fails, for
+// instance because the ref already exists or was changed concurrently.
+type updateRefError struct {
+	reference string
+}
+
+func (e updateRefError) Error() string {
+	return fmt.Sprintf("could not update %s", e.reference)
+}
+
+// hookEnv builds the GL_* environment gitlab-shell hooks expect, plus the
+// object directory variables of the repository.
+func hookEnv(repo *localrepo.Repo, user *pb.User) []string {
+	env := []string{
+		"GL_ID=" + user.GetGlId(),
+		"GL_USERNAME=" + user.GetGlUsername(),
+		"GL_REPOSITORY=" + repo.GetGlRepository(),
+		"GL_PROJECT_PATH=" + repo.GetGlProjectPath(),
+		"GL_PROTOCOL=web",
+	}
+
+	return append(env, repo.Env()...)
+}
+
+// runHook executes one of the gitlab-shell hooks installed in the
+// repository, followed by any custom hooks, with the repository as
+// working directory. A missing hook is not an error. A hook exiting
+// non-zero yields a hookError carrying the combined output.
+func runHook(ctx context.Context, repo *localrepo.Repo, name string, env []string, stdin []byte, args ...string) error {
+	defer func(start time.Time) {
+		hookDurations.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	var output bytes.Buffer
+
+	hookPath := filepath.Join(repo.Path(), "hooks", name)
+	if _, err := os.Stat(hookPath); err == nil {
+		hookCtx := ctx
+		timeout := hooks.Timeout(name)
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			hookCtx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		osCmd := exec.Command(hookPath, args...)
+		osCmd.Dir = repo.Path()
+
+		cmd, err := command.New(hookCtx, osCmd, bytes.NewReader(stdin), &output, &output, env...)
+		if err != nil {
+			return err
+		}
+
+		if err := cmd.Wait(); err != nil {
+			if hookCtx.Err() == context.DeadlineExceeded {
+				return hookError{hook: name, output: fmt.Sprintf("%s hook timed out after %v", name, timeout)}
+			}
+			if _, ok := command.ExitStatus(err); ok {
+				return hookError{hook: name, output: output.String()}
+			}
+			return err
+		}
+	}
+
+	if err := hooks.RunCustom(ctx, repo.Path(), name, env, stdin, &output, &output, args...); err != nil {
+		if _, ok := err.(hooks.Error); ok {
+			return hookError{hook: name, output: output.String()}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// updateRefWithHooks performs a ref update the way `git receive-pack`
+// would: pre-receive and update hooks run before the update and can
+// reject it, post-receive runs after it and cannot. oldValue guards
+// against concurrent updates; a newValue of zeroOID deletes the ref.
+func updateRefWithHooks(ctx context.Context, repo *localrepo.Repo, user *pb.User, reference, newValue, oldValue string) error {
+	changes := []byte(fmt.Sprintf("%s %s %s\n", oldValue, newValue, reference))
+	env := hookEnv(repo, user)
+
+	if err := runHook(ctx, repo, "pre-receive", env, changes); err != nil {
+		return err
+	}
+
+	if err := runHook(ctx, repo, "update", env, nil, reference, oldValue, newValue); err != nil {
+		return err
+	}
+
+	args := []string{"update-ref", reference, newValue, oldValue}
+	if newValue == zeroOID {
+		args = []string{"update-ref", "-d", reference, oldValue}
+	}
+
+	cmd, err := repo.Exec(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return updateRefError{reference: reference}
+	}
+
+	// The ref update is final at this point: a failing post-receive hook
+	// does not roll it back.
+	runHook(ctx, repo, "post-receive", env, changes)
+
+	ref.InvalidateDefaultBranchCache(repo.Repository)
+
+	housekeeping.MaybeOptimize(repo.Repository)
+
+	events.PublishRefUpdate(events.RefUpdate{
+		StorageName:  repo.GetStorageName(),
+		RelativePath: repo.GetRelativePath(),
+		Ref:          reference,
+		OldOid:       oldValue,
+		NewOid:       newValue,
+		Actor:        user.GetGlId(),
+		Transport:    "web",
+	})
+
+	return nil
+}
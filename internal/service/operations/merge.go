@@ -1,61 +1,177 @@
 package operations
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
 	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+func validateMergeBranchRequest(request *pb.UserMergeBranchRequest) error {
+	if request.GetRepository() == nil {
+		return fmt.Errorf("empty repository")
+	}
+
+	if request.User == nil {
+		return fmt.Errorf("empty user")
+	}
+
+	if len(request.Branch) == 0 {
+		return fmt.Errorf("empty branch name")
+	}
+
+	if request.CommitId == "" {
+		return fmt.Errorf("empty commit id")
+	}
+
+	return nil
+}
+
+// UserMergeBranch is a two-phase merge: the merge commit is computed
+// and written to the object database first, its OID is returned, and
+// only after the client confirms with an apply message is the branch
+// updated — with hooks — to point at it. This gives GitLab a window to
+// run approval checks against the actual merge result.
 func (s *server) UserMergeBranch(bidi pb.OperationService_UserMergeBranchServer) error {
+	ctx := bidi.Context()
+
 	firstRequest, err := bidi.Recv()
 	if err != nil {
 		return err
 	}
 
-	ctx := bidi.Context()
-	client, err := s.OperationServiceClient(ctx)
+	if err := validateMergeBranchRequest(firstRequest); err != nil {
+		return status.Errorf(codes.InvalidArgument, "UserMergeBranch: %v", err)
+	}
+
+	repo, err := localrepo.New(firstRequest.GetRepository())
 	if err != nil {
 		return err
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, firstRequest.GetRepository())
+	branch := string(firstRequest.GetBranch())
+	reference := "refs/heads/" + branch
+
+	branchHead, err := revParse(ctx, repo, reference)
+	if err != nil || branchHead == "" {
+		return status.Errorf(codes.FailedPrecondition, "UserMergeBranch: branch %q not found", branch)
+	}
+
+	mergeOID, err := computeMergeCommit(ctx, repo, firstRequest, branchHead)
 	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, "UserMergeBranch: %v", err)
+	}
+
+	if err := bidi.Send(&pb.UserMergeBranchResponse{CommitId: mergeOID}); err != nil {
 		return err
 	}
 
-	rubyBidi, err := client.UserMergeBranch(clientCtx)
+	secondRequest, err := bidi.Recv()
 	if err != nil {
-		return err
+		return status.Errorf(codes.FailedPrecondition, "UserMergeBranch: merge aborted: %v", err)
 	}
 
-	if err := rubyBidi.Send(firstRequest); err != nil {
-		return err
+	if !secondRequest.GetApply() {
+		return status.Errorf(codes.FailedPrecondition, "UserMergeBranch: merge not applied")
+	}
+
+	if err := updateRefWithHooks(ctx, repo, firstRequest.GetUser(), reference, mergeOID, branchHead); err != nil {
+		switch err.(type) {
+		case hookError, updateRefError:
+			// The branch was not updated: either a hook declined or the
+			// branch moved concurrently. Tell the client by sending an
+			// empty final response.
+			return bidi.Send(&pb.UserMergeBranchResponse{})
+		}
+
+		return status.Errorf(codes.Internal, "UserMergeBranch: %v", err)
+	}
+
+	return bidi.Send(&pb.UserMergeBranchResponse{
+		BranchUpdate: &pb.OperationBranchUpdate{CommitId: mergeOID},
+	})
+}
+
+// computeMergeCommit merges the source commit into the branch head in a
+// shared throwaway clone and fetches the resulting merge commit back
+// into the repository, without touching any refs.
+func computeMergeCommit(ctx context.Context, repo *localrepo.Repo, request *pb.UserMergeBranchRequest, branchHead string) (string, error) {
+	if _, err := revParse(ctx, repo, request.GetCommitId()+"^{commit}"); err != nil {
+		return "", fmt.Errorf("invalid merge source %q", request.GetCommitId())
+	}
+
+	workDir, err := ioutil.TempDir("", "gitaly-merge-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(workDir)
+
+	clonePath := filepath.Join(workDir, "repo")
+	if _, err := runGitDir(ctx, "", nil, nil, "clone", "--quiet", "--shared", "--no-checkout", repo.Path(), clonePath); err != nil {
+		return "", fmt.Errorf("create working clone: %v", err)
+	}
+
+	if _, err := runGitDir(ctx, clonePath, nil, nil, "checkout", "--quiet", "--detach", branchHead); err != nil {
+		return "", fmt.Errorf("checkout branch head: %v", err)
+	}
+
+	user := request.GetUser()
+	env := []string{
+		"GIT_AUTHOR_NAME=" + string(user.GetName()),
+		"GIT_AUTHOR_EMAIL=" + string(user.GetEmail()),
+		"GIT_COMMITTER_NAME=" + string(user.GetName()),
+		"GIT_COMMITTER_EMAIL=" + string(user.GetEmail()),
+	}
+
+	if _, err := runGitDir(ctx, clonePath, env, nil, "merge", "--quiet", "--no-ff", "--no-edit", "-m", string(request.GetMessage()), request.GetCommitId()); err != nil {
+		return "", fmt.Errorf("merge failed: %v", err)
+	}
+
+	mergeOID, err := runGitDir(ctx, clonePath, nil, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	// Copy the merge commit and its trees and blobs into the repository
+	// proper before the clone is removed.
+	if _, err := repo.Run(ctx, nil, nil, "fetch", "--quiet", "--no-tags", clonePath, "HEAD"); err != nil {
+		return "", fmt.Errorf("fetch merge commit: %v", err)
+	}
+
+	return mergeOID, nil
+}
+
+// runGitDir runs a git command in the given working directory (or the
+// process working directory when dir is empty) and returns its trimmed
+// stdout.
+func runGitDir(ctx context.Context, dir string, env []string, stdin io.Reader, args ...string) (string, error) {
+	osCmd := exec.Command(command.GitPath(), args...)
+	osCmd.Dir = dir
+
+	var stdout bytes.Buffer
+	cmd, err := command.New(ctx, osCmd, stdin, &stdout, nil, env...)
+	if err != nil {
+		return "", err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return "", err
 	}
 
-	return rubyserver.ProxyBidi(
-		func() error {
-			request, err := bidi.Recv()
-			if err != nil {
-				return err
-			}
-
-			return rubyBidi.Send(request)
-		},
-		rubyBidi,
-		func() error {
-			response, err := rubyBidi.Recv()
-			if err != nil {
-				return err
-			}
-
-			return bidi.Send(response)
-		},
-	)
+	return strings.TrimSpace(stdout.String()), nil
 }
 
 func validateFFRequest(in *pb.UserFFBranchRequest) error {
@@ -0,0 +1,81 @@
+package operations
+
+import (
+	"fmt"
+	"strings"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func validateGetRebaseTodoRequest(req *pb.GetRebaseTodoRequest) error {
+	if req.GetRepository() == nil {
+		return fmt.Errorf("empty Repository")
+	}
+	if len(req.GetBranch()) == 0 {
+		return fmt.Errorf("empty Branch")
+	}
+	if req.GetTargetRevision() == "" {
+		return fmt.Errorf("empty TargetRevision")
+	}
+	return nil
+}
+
+// GetRebaseTodo lists the commits an interactive rebase of Branch onto
+// TargetRevision would replay, oldest first, like the pick lines of a
+// `git rebase -i` todo list. No rebase is performed; the client shows
+// the list, lets the user reorder it, and requests the rebase
+// separately.
+func (s *server) GetRebaseTodo(ctx context.Context, req *pb.GetRebaseTodoRequest) (*pb.GetRebaseTodoResponse, error) {
+	if err := validateGetRebaseTodoRequest(req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "GetRebaseTodo: %v", err)
+	}
+
+	repo, err := localrepo.New(req.GetRepository())
+	if err != nil {
+		return nil, err
+	}
+
+	branchRef := "refs/heads/" + string(req.GetBranch())
+	if _, err := revParse(ctx, repo, branchRef); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "GetRebaseTodo: branch %q not found", req.GetBranch())
+	}
+
+	if _, err := revParse(ctx, repo, req.GetTargetRevision()+"^{commit}"); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "GetRebaseTodo: invalid target revision %q", req.GetTargetRevision())
+	}
+
+	// The same commit range a rebase would replay: commits on the branch
+	// that are not reachable from the target, without merge commits,
+	// oldest first.
+	out, err := repo.Run(ctx, nil, nil,
+		"log", "--reverse", "--no-merges", "--format=%H%x00%s", req.GetTargetRevision()+".."+branchRef)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "GetRebaseTodo: %v", err)
+	}
+
+	response := &pb.GetRebaseTodoResponse{}
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, "\x00", 2)
+		if len(parts) != 2 {
+			return nil, status.Errorf(codes.Internal, "GetRebaseTodo: unexpected log line %q", line)
+		}
+
+		response.Entries = append(response.Entries, &pb.RebaseTodoEntry{
+			Action:   "pick",
+			CommitId: parts[0],
+			Subject:  []byte(parts[1]),
+		})
+	}
+
+	return response, nil
+}
@@ -0,0 +1,110 @@
+package operations
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+	"google.golang.org/grpc/codes"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+)
+
+func TestSuccessfulGetRebaseTodoRequest(t *testing.T) {
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	server, serverSocketPath := runOperationServiceServer(t)
+	defer server.Stop()
+
+	client, conn := newOperationClient(t, serverSocketPath)
+	defer conn.Close()
+
+	branch := "few-commits"
+	target := "master"
+
+	request := &pb.GetRebaseTodoRequest{
+		Repository:     testRepo,
+		Branch:         []byte(branch),
+		TargetRevision: target,
+	}
+
+	response, err := client.GetRebaseTodo(ctx, request)
+	require.NoError(t, err)
+
+	logOut := testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath,
+		"log", "--reverse", "--no-merges", "--format=%H %s", target+"..refs/heads/"+branch)
+	logLines := strings.Split(strings.TrimSpace(string(logOut)), "\n")
+
+	require.Len(t, response.GetEntries(), len(logLines))
+	for i, entry := range response.GetEntries() {
+		require.Equal(t, "pick", entry.GetAction())
+		require.Equal(t, logLines[i], entry.GetCommitId()+" "+string(entry.GetSubject()))
+	}
+}
+
+func TestFailedGetRebaseTodoRequestDueToValidations(t *testing.T) {
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	server, serverSocketPath := runOperationServiceServer(t)
+	defer server.Stop()
+
+	client, conn := newOperationClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testCases := []struct {
+		desc    string
+		request *pb.GetRebaseTodoRequest
+		code    codes.Code
+	}{
+		{
+			desc: "empty branch",
+			request: &pb.GetRebaseTodoRequest{
+				Repository:     testRepo,
+				TargetRevision: "master",
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "empty target revision",
+			request: &pb.GetRebaseTodoRequest{
+				Repository: testRepo,
+				Branch:     []byte("few-commits"),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "unknown branch",
+			request: &pb.GetRebaseTodoRequest{
+				Repository:     testRepo,
+				Branch:         []byte("does-not-exist"),
+				TargetRevision: "master",
+			},
+			code: codes.FailedPrecondition,
+		},
+		{
+			desc: "unknown target revision",
+			request: &pb.GetRebaseTodoRequest{
+				Repository:     testRepo,
+				Branch:         []byte("few-commits"),
+				TargetRevision: "1234567890123456789012345678901234567890",
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.desc, func(t *testing.T) {
+			_, err := client.GetRebaseTodo(ctx, testCase.request)
+			testhelper.AssertGrpcError(t, err, testCase.code, "")
+		})
+	}
+}
@@ -24,5 +24,23 @@ func (s *server) UserRevert(ctx context.Context, req *pb.UserRevertRequest) (*pb
 		return nil, err
 	}
 
-	return client.UserRevert(clientCtx, req)
+	response, err := client.UserRevert(clientCtx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.GetCreateTreeError() != "" {
+		// The sidecar only reports that building the tree failed. Recover
+		// the conflicting files so clients can show them. A revert applies
+		// the inverse of the commit: its parent is merged onto the branch
+		// with the commit itself as merge base.
+		commitID := req.GetCommit().GetId()
+		branch := "refs/heads/" + string(req.GetBranchName())
+
+		if files, err := conflictFiles(ctx, req.GetRepository(), commitID, branch, commitID+"^"); err == nil {
+			response.ConflictFiles = files
+		}
+	}
+
+	return response, nil
 }
@@ -1,11 +1,177 @@
 package operations
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
-	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+	"gitlab.com/gitlab-org/gitaly/internal/helper/worktree"
+
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-func (*server) UserSquash(context.Context, *pb.UserSquashRequest) (*pb.UserSquashResponse, error) {
-	return nil, helper.Unimplemented
+func validateUserSquashRequest(req *pb.UserSquashRequest) error {
+	if req.GetRepository() == nil {
+		return fmt.Errorf("empty Repository")
+	}
+	if req.GetUser() == nil {
+		return fmt.Errorf("empty User")
+	}
+	if req.GetSquashId() == "" {
+		return fmt.Errorf("empty SquashId")
+	}
+	if strings.Contains(req.GetSquashId(), "/") {
+		return fmt.Errorf("SquashId contains '/'")
+	}
+	if req.GetStartSha() == "" {
+		return fmt.Errorf("empty StartSha")
+	}
+	if req.GetEndSha() == "" {
+		return fmt.Errorf("empty EndSha")
+	}
+	if req.GetAuthor() == nil {
+		return fmt.Errorf("empty Author")
+	}
+	if len(req.GetCommitMessage()) == 0 {
+		return fmt.Errorf("empty CommitMessage")
+	}
+	return nil
+}
+
+// UserSquash replaces the commits between StartSha and EndSha with a
+// single commit carrying the end-state tree. The work happens in a
+// detached throwaway worktree; no refs are touched, the caller updates
+// the branch with the returned SquashSha afterwards. Conflicts while
+// replaying the changes are reported in GitError rather than as an RPC
+// error.
+func (s *server) UserSquash(ctx context.Context, req *pb.UserSquashRequest) (*pb.UserSquashResponse, error) {
+	if err := validateUserSquashRequest(req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "UserSquash: %v", err)
+	}
+
+	repo, err := localrepo.New(req.GetRepository())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sha := range []string{req.GetStartSha(), req.GetEndSha()} {
+		if _, err := revParse(ctx, repo, sha+"^{commit}"); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "UserSquash: invalid sha %q", sha)
+		}
+	}
+
+	squashSha, gitErr, err := squash(ctx, repo, req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "UserSquash: %v", err)
+	}
+	if gitErr != "" {
+		return &pb.UserSquashResponse{GitError: gitErr}, nil
+	}
+
+	return &pb.UserSquashResponse{SquashSha: squashSha}, nil
+}
+
+func squash(ctx context.Context, repo *localrepo.Repo, req *pb.UserSquashRequest) (string, string, error) {
+	// The conventional location lets IsSquashInProgress find the
+	// worktree of a crashed squash.
+	worktreePath := worktree.Path(repo.Path(), worktree.SquashPrefix, req.GetSquashId())
+
+	// Cleanup must also run when ctx was cancelled mid-squash, so it
+	// uses its own context.
+	defer func() {
+		cleanupCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		os.RemoveAll(worktreePath)
+		if cmd, err := repo.Exec(cleanupCtx, "worktree", "prune"); err == nil {
+			cmd.Wait()
+		}
+	}()
+
+	// Only the paths the squash touches need to exist on disk.
+	sparsePaths, err := changedPaths(ctx, repo, req.GetStartSha(), req.GetEndSha())
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := addWorktree(ctx, repo, worktreePath, req.GetStartSha(), sparsePaths); err != nil {
+		return "", "", err
+	}
+
+	// Stage the changes between start and end on top of the start
+	// commit. A three-way apply surfaces content conflicts.
+	diffCmd, err := repo.Exec(ctx, "diff", "--binary", req.GetStartSha()+"..."+req.GetEndSha())
+	if err != nil {
+		return "", "", fmt.Errorf("diff: %v", err)
+	}
+
+	applyStderr, applyErr := applySquashDiff(ctx, worktreePath, diffCmd)
+	if err := diffCmd.Wait(); err != nil {
+		return "", "", fmt.Errorf("diff: %v", err)
+	}
+	if applyErr != nil {
+		return "", fmt.Sprintf("squash: applying diff %s...%s failed: %s", req.GetStartSha(), req.GetEndSha(), applyStderr), nil
+	}
+
+	treeID, err := runGitDir(ctx, worktreePath, nil, nil, "write-tree")
+	if err != nil {
+		return "", "", fmt.Errorf("write-tree: %v", err)
+	}
+
+	author := req.GetAuthor()
+	committer := req.GetUser()
+	env := []string{
+		"GIT_AUTHOR_NAME=" + string(author.GetName()),
+		"GIT_AUTHOR_EMAIL=" + string(author.GetEmail()),
+		"GIT_COMMITTER_NAME=" + string(committer.GetName()),
+		"GIT_COMMITTER_EMAIL=" + string(committer.GetEmail()),
+	}
+
+	squashSha, err := runGitDir(ctx, worktreePath, env, strings.NewReader(string(req.GetCommitMessage())),
+		"commit-tree", treeID, "-p", req.GetStartSha())
+	if err != nil {
+		return "", "", fmt.Errorf("commit-tree: %v", err)
+	}
+
+	return squashSha, "", nil
+}
+
+// changedPaths lists the paths the diff from start to end touches,
+// including both sides of renames.
+func changedPaths(ctx context.Context, repo *localrepo.Repo, startSha, endSha string) ([]string, error) {
+	out, err := repo.Run(ctx, nil, nil, "diff", "--name-only", "-z", startSha+"..."+endSha)
+	if err != nil {
+		return nil, fmt.Errorf("diff --name-only: %v", err)
+	}
+
+	var paths []string
+	for _, path := range strings.Split(out, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
+
+// applySquashDiff runs `git apply` in the worktree with the diff on
+// stdin and returns captured stderr for conflict reporting.
+func applySquashDiff(ctx context.Context, worktreePath string, diff *command.Command) (string, error) {
+	osCmd := exec.Command(command.GitPath(), "apply", "--index", "--3way")
+	osCmd.Dir = worktreePath
+
+	var stderr bytes.Buffer
+	cmd, err := command.New(ctx, osCmd, diff, nil, &stderr)
+	if err != nil {
+		return "", err
+	}
+
+	err = cmd.Wait()
+	return strings.TrimSpace(stderr.String()), err
 }
@@ -0,0 +1,142 @@
+package operations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gitlab.com/gitlab-org/gitaly/internal/git/log"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+	"google.golang.org/grpc/codes"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+)
+
+var (
+	squashAuthor = &pb.User{
+		Name:  []byte("John Doe"),
+		Email: []byte("johndoe@gitlab.com"),
+	}
+	squashUser = &pb.User{
+		Name:  []byte("Jane Doe"),
+		Email: []byte("janedoe@gitlab.com"),
+		GlId:  "user-1",
+	}
+	squashStartSha = "b83d6e391c22777fca1ed3012fce84f633d7fed0"
+	squashEndSha   = "54cec5282aa9f21856362fe321c800c236a61615"
+)
+
+func TestSuccessfulUserSquashRequest(t *testing.T) {
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	server, serverSocketPath := runOperationServiceServer(t)
+	defer server.Stop()
+
+	client, conn := newOperationClient(t, serverSocketPath)
+	defer conn.Close()
+
+	request := &pb.UserSquashRequest{
+		Repository:    testRepo,
+		User:          squashUser,
+		SquashId:      "1",
+		Branch:        []byte("some-branch"),
+		StartSha:      squashStartSha,
+		EndSha:        squashEndSha,
+		Author:        squashAuthor,
+		CommitMessage: []byte("Squashed commit"),
+	}
+
+	response, err := client.UserSquash(ctx, request)
+	require.NoError(t, err)
+	require.Empty(t, response.GetGitError())
+	require.NotEmpty(t, response.GetSquashSha())
+
+	commit, err := log.GetCommit(ctx, testRepo, response.GetSquashSha(), "")
+	require.NoError(t, err)
+	require.Equal(t, []string{squashStartSha}, commit.ParentIds)
+	require.Equal(t, squashAuthor.Name, commit.Author.Name)
+	require.Equal(t, squashAuthor.Email, commit.Author.Email)
+	require.Equal(t, squashUser.Name, commit.Committer.Name)
+	require.Equal(t, squashUser.Email, commit.Committer.Email)
+	require.Equal(t, []byte("Squashed commit"), commit.Subject)
+}
+
+func TestFailedUserSquashRequestDueToValidations(t *testing.T) {
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	server, serverSocketPath := runOperationServiceServer(t)
+	defer server.Stop()
+
+	client, conn := newOperationClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testCases := []struct {
+		desc    string
+		request *pb.UserSquashRequest
+		code    codes.Code
+	}{
+		{
+			desc: "empty user",
+			request: &pb.UserSquashRequest{
+				Repository:    testRepo,
+				SquashId:      "1",
+				StartSha:      squashStartSha,
+				EndSha:        squashEndSha,
+				Author:        squashAuthor,
+				CommitMessage: []byte("Squashed commit"),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "empty squash id",
+			request: &pb.UserSquashRequest{
+				Repository:    testRepo,
+				User:          squashUser,
+				StartSha:      squashStartSha,
+				EndSha:        squashEndSha,
+				Author:        squashAuthor,
+				CommitMessage: []byte("Squashed commit"),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "empty start sha",
+			request: &pb.UserSquashRequest{
+				Repository:    testRepo,
+				User:          squashUser,
+				SquashId:      "1",
+				EndSha:        squashEndSha,
+				Author:        squashAuthor,
+				CommitMessage: []byte("Squashed commit"),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "unknown start sha",
+			request: &pb.UserSquashRequest{
+				Repository:    testRepo,
+				User:          squashUser,
+				SquashId:      "1",
+				StartSha:      "1234567890123456789012345678901234567890",
+				EndSha:        squashEndSha,
+				Author:        squashAuthor,
+				CommitMessage: []byte("Squashed commit"),
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.desc, func(t *testing.T) {
+			_, err := client.UserSquash(ctx, testCase.request)
+			testhelper.AssertGrpcError(t, err, testCase.code, "")
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package operations
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"gitlab.com/gitlab-org/gitaly/internal/git/treebuilder"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+)
+
+// indexError is a user error from applying commit actions, reported to
+// the client in the IndexError response field instead of as an RPC
+// error.
+type indexError string
+
+func (e indexError) Error() string { return string(e) }
+
+// commitFilesAction is one fully received action with its content
+// chunks reassembled.
+type commitFilesAction struct {
+	header  *pb.UserCommitFilesActionHeader
+	content []byte
+}
+
+func applyAction(ctx context.Context, tb *treebuilder.Builder, action commitFilesAction) error {
+	header := action.header
+	filePath := string(header.GetFilePath())
+
+	if err := validateFilePath(filePath); err != nil {
+		return err
+	}
+
+	content := action.content
+	if header.GetBase64Content() {
+		decoded, err := base64.StdEncoding.DecodeString(string(content))
+		if err != nil {
+			return indexError("Failed to decode base64 content")
+		}
+		content = decoded
+	}
+
+	switch header.GetAction() {
+	case pb.UserCommitFilesActionHeader_CREATE:
+		if mode, _, err := tb.FileEntry(ctx, filePath); err != nil {
+			return err
+		} else if mode != "" {
+			return indexError("A file with this name already exists")
+		}
+
+		if exists, err := tb.DirExists(ctx, filePath); err != nil {
+			return err
+		} else if exists {
+			return indexError("A directory with this name already exists")
+		}
+
+		oid, err := tb.HashObject(ctx, content)
+		if err != nil {
+			return err
+		}
+
+		return tb.AddEntry(ctx, treebuilder.ModeFile, oid, filePath)
+
+	case pb.UserCommitFilesActionHeader_CREATE_DIR:
+		if mode, _, err := tb.FileEntry(ctx, filePath); err != nil {
+			return err
+		} else if mode != "" {
+			return indexError("A file with this name already exists")
+		}
+
+		if exists, err := tb.DirExists(ctx, filePath); err != nil {
+			return err
+		} else if exists {
+			return indexError("A directory with this name already exists")
+		}
+
+		oid, err := tb.HashObject(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		return tb.AddEntry(ctx, treebuilder.ModeFile, oid, filePath+"/.gitkeep")
+
+	case pb.UserCommitFilesActionHeader_UPDATE:
+		mode, _, err := tb.FileEntry(ctx, filePath)
+		if err != nil {
+			return err
+		}
+		if mode == "" {
+			return indexError("A file with this name doesn't exist")
+		}
+
+		oid, err := tb.HashObject(ctx, content)
+		if err != nil {
+			return err
+		}
+
+		return tb.AddEntry(ctx, mode, oid, filePath)
+
+	case pb.UserCommitFilesActionHeader_MOVE:
+		previousPath := string(header.GetPreviousPath())
+		if err := validateFilePath(previousPath); err != nil {
+			return err
+		}
+
+		mode, oid, err := tb.FileEntry(ctx, previousPath)
+		if err != nil {
+			return err
+		}
+		if mode == "" {
+			return indexError("A file with this name doesn't exist")
+		}
+
+		if newMode, _, err := tb.FileEntry(ctx, filePath); err != nil {
+			return err
+		} else if newMode != "" {
+			return indexError("A file with this name already exists")
+		}
+
+		if len(action.content) > 0 {
+			if oid, err = tb.HashObject(ctx, content); err != nil {
+				return err
+			}
+		}
+
+		if err := tb.RemoveEntry(ctx, previousPath); err != nil {
+			return err
+		}
+
+		return tb.AddEntry(ctx, mode, oid, filePath)
+
+	case pb.UserCommitFilesActionHeader_DELETE:
+		mode, _, err := tb.FileEntry(ctx, filePath)
+		if err != nil {
+			return err
+		}
+		if mode == "" {
+			return indexError("A file with this name doesn't exist")
+		}
+
+		return tb.RemoveEntry(ctx, filePath)
+
+	case pb.UserCommitFilesActionHeader_CHMOD:
+		mode, oid, err := tb.FileEntry(ctx, filePath)
+		if err != nil {
+			return err
+		}
+		if mode == "" {
+			return indexError("A file with this name doesn't exist")
+		}
+
+		newMode := treebuilder.ModeFile
+		if header.GetExecuteFilemode() {
+			newMode = treebuilder.ModeExecutable
+		}
+
+		return tb.AddEntry(ctx, newMode, oid, filePath)
+	}
+
+	return fmt.Errorf("unknown action %v", header.GetAction())
+}
+
+// validateFilePath rejects empty paths and traversal outside the
+// repository root.
+func validateFilePath(path string) error {
+	if path == "" {
+		return indexError("You must provide a file path")
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return indexError(fmt.Sprintf("Invalid path: '%s'", path))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package operations
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+
+	"golang.org/x/net/context"
+)
+
+// addWorktree creates a detached throwaway worktree at worktreePath,
+// checked out at committish. When sparsePaths is non-empty, only those
+// paths are materialized on disk via sparse checkout; the index still
+// covers the full tree, so `git write-tree` in the worktree produces a
+// complete tree. Limiting the checkout to the paths an operation will
+// touch avoids writing out a whole monorepo for a cherry-pick or squash
+// that changes a handful of files.
+func addWorktree(ctx context.Context, repo *localrepo.Repo, worktreePath, committish string, sparsePaths []string) error {
+	if len(sparsePaths) == 0 {
+		if _, err := repo.Run(ctx, nil, nil, "worktree", "add", "--detach", worktreePath, committish); err != nil {
+			return fmt.Errorf("create worktree: %v", err)
+		}
+		return nil
+	}
+
+	if _, err := repo.Run(ctx, nil, nil, "worktree", "add", "--no-checkout", "--detach", worktreePath, committish); err != nil {
+		return fmt.Errorf("create worktree: %v", err)
+	}
+
+	// The sparse-checkout file of a linked worktree lives in its private
+	// git dir under the main repository.
+	worktreeGitDir, err := runGitDir(ctx, worktreePath, nil, nil, "rev-parse", "--git-dir")
+	if err != nil {
+		return fmt.Errorf("resolve worktree git dir: %v", err)
+	}
+
+	infoDir := filepath.Join(worktreeGitDir, "info")
+	if err := os.MkdirAll(infoDir, 0755); err != nil {
+		return fmt.Errorf("create worktree info dir: %v", err)
+	}
+
+	var sparseFile []byte
+	for _, path := range sparsePaths {
+		sparseFile = append(sparseFile, path...)
+		sparseFile = append(sparseFile, '\n')
+	}
+	if err := ioutil.WriteFile(filepath.Join(infoDir, "sparse-checkout"), sparseFile, 0644); err != nil {
+		return fmt.Errorf("write sparse-checkout: %v", err)
+	}
+
+	// core.sparseCheckout is passed per invocation so the shared
+	// repository config stays untouched.
+	if _, err := runGitDir(ctx, worktreePath, nil, nil, "-c", "core.sparseCheckout=true", "read-tree", "-mu", committish); err != nil {
+		return fmt.Errorf("sparse checkout: %v", err)
+	}
+
+	return nil
+}
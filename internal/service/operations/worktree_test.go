@@ -0,0 +1,65 @@
+package operations
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddWorktreeSparseCheckout(t *testing.T) {
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	repo, err := localrepo.New(testRepo)
+	require.NoError(t, err)
+
+	tmpDir, err := ioutil.TempDir("", "gitaly-worktree-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	worktreePath := filepath.Join(tmpDir, "worktree")
+
+	require.NoError(t, addWorktree(ctx, repo, worktreePath, "master", []string{"README.md"}))
+
+	_, err = os.Stat(filepath.Join(worktreePath, "README.md"))
+	require.NoError(t, err, "sparse path should be checked out")
+
+	_, err = os.Stat(filepath.Join(worktreePath, "files"))
+	require.True(t, os.IsNotExist(err), "paths outside the sparse set should not be checked out")
+
+	// The index still covers the full tree.
+	tree, err := runGitDir(ctx, worktreePath, nil, nil, "write-tree")
+	require.NoError(t, err)
+	expectedTree, err := repo.Run(ctx, nil, nil, "rev-parse", "master^{tree}")
+	require.NoError(t, err)
+	require.Equal(t, expectedTree, tree)
+}
+
+func TestAddWorktreeFullCheckout(t *testing.T) {
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	repo, err := localrepo.New(testRepo)
+	require.NoError(t, err)
+
+	tmpDir, err := ioutil.TempDir("", "gitaly-worktree-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	worktreePath := filepath.Join(tmpDir, "worktree")
+
+	require.NoError(t, addWorktree(ctx, repo, worktreePath, "master", nil))
+
+	_, err = os.Stat(filepath.Join(worktreePath, "files"))
+	require.NoError(t, err, "full checkout should contain all paths")
+}
@@ -0,0 +1,56 @@
+package ref
+
+import (
+	"sync"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"golang.org/x/net/context"
+)
+
+// The default branch is read on nearly every page render but only changes
+// when refs or HEAD move. We memoize the resolved name per repository and
+// rely on the write RPCs (WriteRef, receive-pack, DeleteRefs) to invalidate.
+var defaultBranchCache = struct {
+	sync.RWMutex
+	entries map[string][]byte
+}{entries: make(map[string][]byte)}
+
+func defaultBranchCacheKey(repo *pb.Repository) string {
+	return repo.GetStorageName() + ":" + repo.GetRelativePath()
+}
+
+// CachedDefaultBranchName behaves like DefaultBranchName but serves repeated
+// lookups for the same repository from an in-memory cache.
+func CachedDefaultBranchName(ctx context.Context, repo *pb.Repository) ([]byte, error) {
+	key := defaultBranchCacheKey(repo)
+
+	defaultBranchCache.RLock()
+	name, ok := defaultBranchCache.entries[key]
+	defaultBranchCache.RUnlock()
+	if ok {
+		return name, nil
+	}
+
+	name, err := DefaultBranchName(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// An empty name means the repository has no branches; don't cache that,
+	// the first push would serve stale emptiness.
+	if len(name) > 0 {
+		defaultBranchCache.Lock()
+		defaultBranchCache.entries[key] = name
+		defaultBranchCache.Unlock()
+	}
+
+	return name, nil
+}
+
+// InvalidateDefaultBranchCache drops the cached default branch of repo. Any
+// RPC that can move refs or HEAD must call this.
+func InvalidateDefaultBranchCache(repo *pb.Repository) {
+	defaultBranchCache.Lock()
+	delete(defaultBranchCache.entries, defaultBranchCacheKey(repo))
+	defaultBranchCache.Unlock()
+}
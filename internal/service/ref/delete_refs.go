@@ -29,5 +29,7 @@ func (s *server) DeleteRefs(ctx context.Context, in *pb.DeleteRefsRequest) (*pb.
 		return nil, err
 	}
 
+	InvalidateDefaultBranchCache(in.GetRepository())
+
 	return client.DeleteRefs(clientCtx, in)
 }
@@ -0,0 +1,60 @@
+package ref
+
+import (
+	"bufio"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"golang.org/x/net/context"
+)
+
+// FindRefsByOID returns the names of all refs pointing at the given OID,
+// or, with Containing set, all refs whose history contains the OID.
+func (s *server) FindRefsByOID(ctx context.Context, in *pb.FindRefsByOIDRequest) (*pb.FindRefsByOIDResponse, error) {
+	if in.GetOid() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "FindRefsByOID: empty Oid")
+	}
+
+	args := []string{"for-each-ref", "--format=%(refname)"}
+
+	if in.GetLimit() > 0 {
+		args = append(args, fmt.Sprintf("--count=%d", in.GetLimit()))
+	}
+
+	if in.GetContaining() {
+		args = append(args, "--contains="+in.GetOid())
+	} else {
+		args = append(args, "--points-at="+in.GetOid())
+	}
+
+	args = append(args, in.GetRefPatterns()...)
+
+	cmd, err := git.Command(ctx, in.GetRepository(), args...)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "FindRefsByOID: cmd: %v", err)
+	}
+
+	var refs []string
+	scanner := bufio.NewScanner(cmd)
+	for scanner.Scan() {
+		refs = append(refs, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "FindRefsByOID: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		// for-each-ref fails when the OID does not resolve to an object.
+		// An unknown OID simply has no refs, so report an empty list.
+		return &pb.FindRefsByOIDResponse{}, nil
+	}
+
+	return &pb.FindRefsByOIDResponse{Refs: refs}, nil
+}
@@ -0,0 +1,114 @@
+package ref
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+)
+
+func TestFindRefsByOID(t *testing.T) {
+	server, serverSocketPath := runRefServiceServer(t)
+	defer server.Stop()
+
+	client, conn := newRefServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	// Point a branch and a tag at the same commit so we can check that refs
+	// of both types are returned.
+	oid := "1e292f8fedd741b75372e19097c76d327140c312"
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "update-ref", "refs/heads/find-refs-branch", oid)
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "tag", "find-refs-tag", oid)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testCases := []struct {
+		desc    string
+		request *pb.FindRefsByOIDRequest
+		refs    []string
+	}{
+		{
+			desc:    "all refs pointing at the OID",
+			request: &pb.FindRefsByOIDRequest{Repository: testRepo, Oid: oid},
+			refs:    []string{"refs/heads/find-refs-branch", "refs/tags/find-refs-tag"},
+		},
+		{
+			desc: "limited to branches",
+			request: &pb.FindRefsByOIDRequest{
+				Repository:  testRepo,
+				Oid:         oid,
+				RefPatterns: []string{"refs/heads/"},
+			},
+			refs: []string{"refs/heads/find-refs-branch"},
+		},
+		{
+			desc:    "limit caps the number of results",
+			request: &pb.FindRefsByOIDRequest{Repository: testRepo, Oid: oid, Limit: 1},
+			refs:    []string{"refs/heads/find-refs-branch"},
+		},
+		{
+			desc:    "unknown OID has no refs",
+			request: &pb.FindRefsByOIDRequest{Repository: testRepo, Oid: "1234123412341234123412341234123412341234"},
+			refs:    nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			response, err := client.FindRefsByOID(ctx, tc.request)
+			require.NoError(t, err)
+			require.Equal(t, tc.refs, response.GetRefs())
+		})
+	}
+}
+
+func TestFindRefsByOIDContaining(t *testing.T) {
+	server, serverSocketPath := runRefServiceServer(t)
+	defer server.Stop()
+
+	client, conn := newRefServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// An ancestor of master: no ref points directly at it but master
+	// contains it.
+	response, err := client.FindRefsByOID(ctx, &pb.FindRefsByOIDRequest{
+		Repository:  testRepo,
+		Oid:         "6907208d755b60ebeacb2e9dfea74c92c3449a1f",
+		RefPatterns: []string{"refs/heads/master"},
+		Containing:  true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"refs/heads/master"}, response.GetRefs())
+}
+
+func TestFindRefsByOIDEmptyOID(t *testing.T) {
+	server, serverSocketPath := runRefServiceServer(t)
+	defer server.Stop()
+
+	client, conn := newRefServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := client.FindRefsByOID(ctx, &pb.FindRefsByOIDRequest{Repository: testRepo})
+	require.Equal(t, codes.InvalidArgument, helper.GrpcCode(err))
+}
@@ -0,0 +1,126 @@
+package ref
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// tagsPerMessage is the number of tags in a single stream response message
+	tagsPerMessage = 20
+
+	tagFieldDelimiter  = "\x1f"
+	tagRecordDelimiter = "\x00"
+)
+
+// ListTags streams the tags of a repository sorted by name or semantic
+// version. A page token (the name of the last tag of the previous page)
+// combined with a limit allows deep pagination without re-sending earlier
+// pages.
+func (s *server) ListTags(in *pb.ListTagsRequest, stream pb.RefService_ListTagsServer) error {
+	ctx := stream.Context()
+
+	sortKey := "refname"
+	if in.GetSortBy() == pb.ListTagsRequest_VERSION_REFNAME {
+		sortKey = "version:refname"
+	}
+
+	cmdArgs := []string{
+		"for-each-ref",
+		"--format=" + strings.Join(tagFormatFields, tagFieldDelimiter) + tagRecordDelimiter,
+		"--sort=" + sortKey,
+		"refs/tags",
+	}
+
+	cmd, err := git.Command(ctx, in.GetRepository(), cmdArgs...)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Errorf(codes.Internal, "ListTags: cmd: %v", err)
+	}
+
+	pageToken := string(in.GetPageToken())
+	pastToken := len(pageToken) == 0
+
+	var tags []*pb.Tag
+	sent := int32(0)
+
+	scanner := bufio.NewScanner(cmd)
+	scanner.Split(splitTagRecords)
+
+	for scanner.Scan() {
+		elements, err := parseTag(scanner.Bytes())
+		if err != nil {
+			return err
+		}
+
+		tagName := string(elements[0])
+
+		if !pastToken {
+			if tagName == pageToken {
+				pastToken = true
+			}
+			continue
+		}
+
+		if limit := in.GetLimit(); limit > 0 && sent == limit {
+			break
+		}
+
+		tag, err := buildTag(elements)
+		if err != nil {
+			return err
+		}
+
+		tags = append(tags, tag)
+		sent++
+
+		if len(tags) == tagsPerMessage {
+			if err := stream.Send(&pb.ListTagsResponse{Tags: tags}); err != nil {
+				return err
+			}
+			tags = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return status.Errorf(codes.Internal, "ListTags: scan: %v", err)
+	}
+
+	if !pastToken {
+		return status.Errorf(codes.InvalidArgument, "ListTags: could not find page token %q", pageToken)
+	}
+
+	if len(tags) > 0 {
+		if err := stream.Send(&pb.ListTagsResponse{Tags: tags}); err != nil {
+			return err
+		}
+	}
+
+	return cmd.Wait()
+}
+
+// splitTagRecords is a bufio.SplitFunc for for-each-ref output where each
+// record ends with tagRecordDelimiter followed by the newline added by git.
+func splitTagRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.Index(data, []byte(tagRecordDelimiter+"\n")); i >= 0 {
+		return i + 2, data[:i], nil
+	}
+
+	if atEOF {
+		return len(data), bytes.TrimSuffix(data, []byte(tagRecordDelimiter)), nil
+	}
+
+	return 0, nil, nil
+}
@@ -0,0 +1,168 @@
+package ref
+
+import (
+	"io"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulListTagsRequest(t *testing.T) {
+	server, serverSocketPath := runRefServiceServer(t)
+	defer server.Stop()
+
+	client, conn := newRefServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	// Lightweight tags whose lexicographic and version order differ
+	for _, tag := range []string{"v1.2.0", "v1.3.5", "v1.10.0"} {
+		testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "tag", tag, "1b12f15a11fc6e62177bef08f47bc7b5ce50b141")
+	}
+
+	testCases := []struct {
+		desc    string
+		request *pb.ListTagsRequest
+		names   []string
+	}{
+		{
+			desc:    "lexicographic sort",
+			request: &pb.ListTagsRequest{Repository: testRepo},
+			names:   []string{"v1.0.0", "v1.1.0", "v1.10.0", "v1.2.0", "v1.3.5"},
+		},
+		{
+			desc: "semantic version sort",
+			request: &pb.ListTagsRequest{
+				Repository: testRepo,
+				SortBy:     pb.ListTagsRequest_VERSION_REFNAME,
+			},
+			names: []string{"v1.0.0", "v1.1.0", "v1.2.0", "v1.3.5", "v1.10.0"},
+		},
+		{
+			desc: "first page",
+			request: &pb.ListTagsRequest{
+				Repository: testRepo,
+				SortBy:     pb.ListTagsRequest_VERSION_REFNAME,
+				Limit:      2,
+			},
+			names: []string{"v1.0.0", "v1.1.0"},
+		},
+		{
+			desc: "page after token",
+			request: &pb.ListTagsRequest{
+				Repository: testRepo,
+				SortBy:     pb.ListTagsRequest_VERSION_REFNAME,
+				Limit:      2,
+				PageToken:  []byte("v1.1.0"),
+			},
+			names: []string{"v1.2.0", "v1.3.5"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			c, err := client.ListTags(ctx, tc.request)
+			require.NoError(t, err)
+
+			var tags []*pb.Tag
+			for {
+				r, err := c.Recv()
+				if err == io.EOF {
+					break
+				}
+				require.NoError(t, err)
+
+				tags = append(tags, r.GetTags()...)
+			}
+
+			var names []string
+			for _, tag := range tags {
+				names = append(names, string(tag.GetName()))
+				require.NotEmpty(t, tag.GetId(), "tag %q has no object id", tag.GetName())
+				require.NotNil(t, tag.GetTargetCommit(), "tag %q has no target commit", tag.GetName())
+			}
+
+			require.Equal(t, tc.names, names)
+		})
+	}
+}
+
+func TestListTagsInvalidPageToken(t *testing.T) {
+	server, serverSocketPath := runRefServiceServer(t)
+	defer server.Stop()
+
+	client, conn := newRefServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	c, err := client.ListTags(ctx, &pb.ListTagsRequest{
+		Repository: testRepo,
+		PageToken:  []byte("v0.0.0-does-not-exist"),
+	})
+	require.NoError(t, err)
+
+	var recvErr error
+	for recvErr == nil {
+		_, recvErr = c.Recv()
+	}
+	testhelper.AssertGrpcError(t, recvErr, codes.InvalidArgument, "could not find page token")
+}
+
+func TestListTagsAnnotatedTagMessage(t *testing.T) {
+	server, serverSocketPath := runRefServiceServer(t)
+	defer server.Stop()
+
+	client, conn := newRefServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath,
+		"-c", "user.name=Scrooge McDuck",
+		"-c", "user.email=scrooge@mcduck.com",
+		"tag", "-a", "v1.4.0", "-m", "Version 1.4.0", "1b12f15a11fc6e62177bef08f47bc7b5ce50b141")
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	c, err := client.ListTags(ctx, &pb.ListTagsRequest{
+		Repository: testRepo,
+		Limit:      1,
+		PageToken:  []byte("v1.1.0"),
+	})
+	require.NoError(t, err)
+
+	var tags []*pb.Tag
+	for {
+		r, err := c.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		tags = append(tags, r.GetTags()...)
+	}
+
+	require.Len(t, tags, 1)
+	tag := tags[0]
+
+	require.Equal(t, []byte("v1.4.0"), tag.GetName())
+	require.Equal(t, []byte("Version 1.4.0"), tag.GetMessage())
+	require.Equal(t, "1b12f15a11fc6e62177bef08f47bc7b5ce50b141", tag.GetTargetCommit().GetId())
+	require.NotEqual(t, "1b12f15a11fc6e62177bef08f47bc7b5ce50b141", tag.GetId(), "annotated tag should have its own object id")
+}
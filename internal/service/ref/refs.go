@@ -196,7 +196,7 @@ func DefaultBranchName(ctx context.Context, repo *pb.Repository) ([]byte, error)
 func (s *server) FindDefaultBranchName(ctx context.Context, in *pb.FindDefaultBranchNameRequest) (*pb.FindDefaultBranchNameResponse, error) {
 	grpc_logrus.Extract(ctx).Debug("FindDefaultBranchName")
 
-	defaultBranchName, err := DefaultBranchName(ctx, in.Repository)
+	defaultBranchName, err := CachedDefaultBranchName(ctx, in.Repository)
 	if err != nil {
 		if _, ok := status.FromError(err); ok {
 			return nil, err
@@ -235,6 +235,10 @@ func (s *server) FindLocalBranches(in *pb.FindLocalBranchesRequest, stream pb.Re
 }
 
 func (s *server) FindAllBranches(in *pb.FindAllBranchesRequest, stream pb.RefService_FindAllBranchesServer) error {
+	if in.GetLimit() < 0 {
+		return status.Errorf(codes.InvalidArgument, "FindAllBranches: negative Limit")
+	}
+
 	args := []string{
 		// %00 inserts the null character into the output (see for-each-ref docs)
 		"--format=" + strings.Join(localBranchFormatFields, "%00"),
@@ -243,7 +247,7 @@ func (s *server) FindAllBranches(in *pb.FindAllBranchesRequest, stream pb.RefSer
 	patterns := []string{"refs/heads", "refs/remotes"}
 
 	if in.MergedOnly {
-		defaultBranchName, err := DefaultBranchName(stream.Context(), in.Repository)
+		defaultBranchName, err := CachedDefaultBranchName(stream.Context(), in.Repository)
 		if err != nil {
 			if _, ok := status.FromError(err); ok {
 				return err
@@ -265,9 +269,18 @@ func (s *server) FindAllBranches(in *pb.FindAllBranchesRequest, stream pb.RefSer
 	opts := &findRefsOpts{
 		cmdArgs: args,
 	}
-	writer := newFindAllBranchesWriter(stream)
+	page := newBranchesPage(in.GetPageToken(), int(in.GetLimit()))
+	writer := newFindAllBranchesWriter(stream, page, in.GetOmitTipCommits())
+
+	if err := findRefs(stream.Context(), writer, in.Repository, patterns, opts); err != nil {
+		return err
+	}
+
+	if !page.started {
+		return status.Errorf(codes.InvalidArgument, "FindAllBranches: could not find page token %q", in.GetPageToken())
+	}
 
-	return findRefs(stream.Context(), writer, in.Repository, patterns, opts)
+	return nil
 }
 
 func (*server) ListBranchNamesContainingCommit(context.Context, *pb.ListBranchNamesContainingCommitRequest) (*pb.ListBranchNamesContainingCommitResponse, error) {
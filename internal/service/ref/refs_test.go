@@ -314,6 +314,50 @@ func TestDefaultBranchName(t *testing.T) {
 	}
 }
 
+func TestCachedDefaultBranchName(t *testing.T) {
+	// We are going to override these functions during this test. Restore them after we're done
+	defer func() {
+		FindBranchNames = _findBranchNames
+		headReference = _headReference
+	}()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+	defer InvalidateDefaultBranchCache(testRepo)
+
+	lookups := 0
+	FindBranchNames = func(context.Context, *pb.Repository) ([][]byte, error) {
+		lookups++
+		return [][]byte{[]byte("refs/heads/master")}, nil
+	}
+	headReference = func(context.Context, *pb.Repository) ([]byte, error) { return nil, nil }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		defaultBranch, err := CachedDefaultBranchName(ctx, testRepo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(defaultBranch, []byte("refs/heads/master")) {
+			t.Fatalf("expected refs/heads/master, got %s", defaultBranch)
+		}
+	}
+	if lookups != 1 {
+		t.Fatalf("expected 1 branch lookup, got %d", lookups)
+	}
+
+	InvalidateDefaultBranchCache(testRepo)
+
+	if _, err := CachedDefaultBranchName(ctx, testRepo); err != nil {
+		t.Fatal(err)
+	}
+	if lookups != 2 {
+		t.Fatalf("expected a fresh lookup after invalidation, got %d lookups", lookups)
+	}
+}
+
 func TestSuccessfulFindDefaultBranchName(t *testing.T) {
 	server, serverSocketPath := runRefServiceServer(t)
 	defer server.Stop()
@@ -784,6 +828,74 @@ func TestSuccessfulFindAllBranchesRequest(t *testing.T) {
 	assertContainsBranch(t, branches, remoteBranch)
 }
 
+func TestFindAllBranchesPaginationAndOmitTipCommits(t *testing.T) {
+	server, serverSocketPath := runRefServiceServer(t)
+	defer server.Stop()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	client, conn := newRefServiceClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	fetchBranches := func(request *pb.FindAllBranchesRequest) []*pb.FindAllBranchesResponse_Branch {
+		request.Repository = testRepo
+		c, err := client.FindAllBranches(ctx, request)
+		require.NoError(t, err)
+		return readFindAllBranchesResponsesFromClient(t, c)
+	}
+
+	allBranches := fetchBranches(&pb.FindAllBranchesRequest{OmitTipCommits: true})
+	require.True(t, len(allBranches) > 3, "expected more than 3 branches in the test repository")
+
+	t.Run("omitted tip commits", func(t *testing.T) {
+		for _, branch := range allBranches {
+			require.Len(t, branch.Target.Id, 40, "target id of %q", branch.Name)
+			require.Empty(t, branch.Target.Subject, "target subject of %q", branch.Name)
+			require.Nil(t, branch.Target.Author, "target author of %q", branch.Name)
+		}
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		var paginated []*pb.FindAllBranchesResponse_Branch
+		pageToken := ""
+
+		for {
+			page := fetchBranches(&pb.FindAllBranchesRequest{
+				OmitTipCommits: true,
+				Limit:          3,
+				PageToken:      pageToken,
+			})
+			if len(page) == 0 {
+				break
+			}
+
+			require.True(t, len(page) <= 3, "page exceeds limit")
+			paginated = append(paginated, page...)
+			pageToken = string(page[len(page)-1].Name)
+		}
+
+		require.Equal(t, allBranches, paginated)
+	})
+
+	t.Run("invalid page token", func(t *testing.T) {
+		c, err := client.FindAllBranches(ctx, &pb.FindAllBranchesRequest{
+			Repository: testRepo,
+			PageToken:  "refs/heads/does-not-exist",
+		})
+		require.NoError(t, err)
+
+		var recvErr error
+		for recvErr == nil {
+			_, recvErr = c.Recv()
+		}
+		testhelper.AssertGrpcError(t, recvErr, codes.InvalidArgument, "could not find page token")
+	})
+}
+
 func TestSuccessfulFindAllBranchesRequestWithMergedBranches(t *testing.T) {
 	server, serverSocketPath := runRefServiceServer(t)
 	defer server.Stop()
@@ -16,6 +16,20 @@ var localBranchFormatFields = []string{
 	"%(committeremail)", "%(committerdate:iso-strict)",
 }
 
+// tagFormatFields contains the tag itself, its message and two sets of
+// commit metadata: the starred fields peel annotated tags to the commit
+// they point to, the plain fields describe the commit a lightweight tag
+// points to directly.
+var tagFormatFields = []string{
+	"%(refname:strip=2)", "%(objectname)", "%(objecttype)", "%(contents)",
+	"%(*objectname)", "%(*contents:subject)", "%(*authorname)",
+	"%(*authoremail)", "%(*authordate:iso-strict)", "%(*committername)",
+	"%(*committeremail)", "%(*committerdate:iso-strict)",
+	"%(contents:subject)", "%(authorname)", "%(authoremail)",
+	"%(authordate:iso-strict)", "%(committername)", "%(committeremail)",
+	"%(committerdate:iso-strict)",
+}
+
 func parseRef(ref []byte) ([][]byte, error) {
 	elements := bytes.Split(ref, []byte("\x00"))
 	if len(elements) != 9 {
@@ -97,7 +111,75 @@ func newFindLocalBranchesWriter(stream pb.Ref_FindLocalBranchesServer) lines.Sen
 	}
 }
 
-func newFindAllBranchesWriter(stream pb.RefService_FindAllBranchesServer) lines.Sender {
+// branchesPage tracks which branches fall inside the requested page. A
+// zero pageToken means the page starts at the first branch.
+type branchesPage struct {
+	pageToken string
+	limit     int
+	started   bool
+	sent      int
+}
+
+func newBranchesPage(pageToken string, limit int) *branchesPage {
+	return &branchesPage{pageToken: pageToken, limit: limit, started: pageToken == ""}
+}
+
+func (page *branchesPage) include(name []byte) bool {
+	if !page.started {
+		if string(name) == page.pageToken {
+			// The token is the last entry of the previous page; the page
+			// starts at the entry after it.
+			page.started = true
+		}
+		return false
+	}
+
+	if page.limit > 0 && page.sent >= page.limit {
+		return false
+	}
+
+	page.sent++
+	return true
+}
+
+func parseTag(ref []byte) ([][]byte, error) {
+	elements := bytes.Split(ref, []byte("\x1f"))
+	if len(elements) != len(tagFormatFields) {
+		return nil, status.Errorf(codes.Internal, "error parsing tag %q", ref)
+	}
+	return elements, nil
+}
+
+func buildTag(elements [][]byte) (*pb.Tag, error) {
+	tag := &pb.Tag{
+		Name: elements[0],
+		Id:   string(elements[1]),
+	}
+
+	var commitElements [][]byte
+	switch string(elements[2]) {
+	case "tag":
+		tag.Message = bytes.TrimRight(elements[3], "\n")
+		if len(elements[4]) > 0 {
+			// The annotated tag points to a commit
+			commitElements = elements[4:12]
+		}
+	case "commit":
+		commitElements = append([][]byte{elements[1]}, elements[12:]...)
+	}
+
+	if commitElements != nil {
+		target, err := buildCommitFromBranchInfo(commitElements)
+		if err != nil {
+			return nil, err
+		}
+		tag.TargetCommit = target
+	}
+
+	return tag, nil
+}
+
+func newFindAllBranchesWriter(stream pb.RefService_FindAllBranchesServer, page *branchesPage, omitTipCommits bool) lines.Sender {
 	return func(refs [][]byte) error {
 		var branches []*pb.FindAllBranchesResponse_Branch
 
@@ -106,12 +188,29 @@ func newFindAllBranchesWriter(stream pb.RefService_FindAllBranchesServer) lines.
 			if err != nil {
 				return err
 			}
-			branch, err := buildBranch(elements)
-			if err != nil {
-				return err
+
+			if !page.include(elements[0]) {
+				continue
+			}
+
+			var branch *pb.FindAllBranchesResponse_Branch
+			if omitTipCommits {
+				branch = &pb.FindAllBranchesResponse_Branch{
+					Name:   elements[0],
+					Target: &pb.GitCommit{Id: string(elements[1])},
+				}
+			} else {
+				branch, err = buildBranch(elements)
+				if err != nil {
+					return err
+				}
 			}
 			branches = append(branches, branch)
 		}
+
+		if len(branches) == 0 {
+			return nil
+		}
 		return stream.Send(&pb.FindAllBranchesResponse{Branches: branches})
 	}
 }
@@ -9,10 +9,12 @@ import (
 	"gitlab.com/gitlab-org/gitaly/internal/service/diff"
 	"gitlab.com/gitlab-org/gitaly/internal/service/namespace"
 	"gitlab.com/gitlab-org/gitaly/internal/service/notifications"
+	"gitlab.com/gitlab-org/gitaly/internal/service/objectpool"
 	"gitlab.com/gitlab-org/gitaly/internal/service/operations"
 	"gitlab.com/gitlab-org/gitaly/internal/service/ref"
 	"gitlab.com/gitlab-org/gitaly/internal/service/remote"
 	"gitlab.com/gitlab-org/gitaly/internal/service/repository"
+	"gitlab.com/gitlab-org/gitaly/internal/service/server"
 	"gitlab.com/gitlab-org/gitaly/internal/service/smarthttp"
 	"gitlab.com/gitlab-org/gitaly/internal/service/ssh"
 	"gitlab.com/gitlab-org/gitaly/internal/service/wiki"
@@ -30,6 +32,7 @@ func RegisterAll(grpcServer *grpc.Server, rubyServer *rubyserver.Server) {
 	pb.RegisterDiffServiceServer(grpcServer, diff.NewServer(rubyServer))
 	pb.RegisterNamespaceServiceServer(grpcServer, namespace.NewServer())
 	pb.RegisterNotificationServiceServer(grpcServer, notifications.NewServer())
+	pb.RegisterObjectPoolServiceServer(grpcServer, objectpool.NewServer())
 	pb.RegisterOperationServiceServer(grpcServer, operations.NewServer(rubyServer))
 	pb.RegisterRefServiceServer(grpcServer, ref.NewServer(rubyServer))
 	pb.RegisterRepositoryServiceServer(grpcServer, repository.NewServer(rubyServer))
@@ -38,6 +41,7 @@ func RegisterAll(grpcServer *grpc.Server, rubyServer *rubyserver.Server) {
 	pb.RegisterWikiServiceServer(grpcServer, wiki.NewServer(rubyServer))
 	pb.RegisterConflictsServiceServer(grpcServer, conflicts.NewServer(rubyServer))
 	pb.RegisterRemoteServiceServer(grpcServer, remote.NewServer(rubyServer))
+	pb.RegisterServerServiceServer(grpcServer, server.NewServer())
 
 	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
 }
@@ -0,0 +1,72 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"time"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetDefaultBranchOfRemote discovers the default branch of a remote
+// repository by asking it for its symbolic HEAD. The remote URL may
+// carry credentials; it is never logged.
+func (s *server) GetDefaultBranchOfRemote(ctx context.Context, in *pb.GetDefaultBranchOfRemoteRequest) (*pb.GetDefaultBranchOfRemoteResponse, error) {
+	if err := validateGetDefaultBranchOfRemoteRequest(in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "GetDefaultBranchOfRemote: %v", err)
+	}
+
+	if timeout := in.GetTimeoutSeconds(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+
+	cmd, err := git.Command(ctx, in.GetRepository(), "ls-remote", "--symref", in.GetRemoteUrl(), "HEAD")
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "GetDefaultBranchOfRemote: cmd: %v", err)
+	}
+
+	// The symref line looks like "ref: refs/heads/master\tHEAD". Remotes
+	// running older servers may not advertise it at all.
+	var name []byte
+	scanner := bufio.NewScanner(cmd)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if !bytes.HasPrefix(line, []byte("ref: ")) || !bytes.HasSuffix(line, []byte("\tHEAD")) {
+			continue
+		}
+
+		name = append([]byte(nil), bytes.TrimSuffix(bytes.TrimPrefix(line, []byte("ref: ")), []byte("\tHEAD"))...)
+		break
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "GetDefaultBranchOfRemote: scan: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, status.Errorf(codes.Internal, "GetDefaultBranchOfRemote: ls-remote: %v", err)
+	}
+
+	return &pb.GetDefaultBranchOfRemoteResponse{Name: name}, nil
+}
+
+func validateGetDefaultBranchOfRemoteRequest(in *pb.GetDefaultBranchOfRemoteRequest) error {
+	if in.GetRemoteUrl() == "" {
+		return fmt.Errorf("empty remote url")
+	}
+	if in.GetTimeoutSeconds() < 0 {
+		return fmt.Errorf("negative timeout")
+	}
+
+	return nil
+}
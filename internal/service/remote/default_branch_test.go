@@ -0,0 +1,88 @@
+package remote
+
+import (
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulGetDefaultBranchOfRemote(t *testing.T) {
+	server, serverSocketPath := runRemoteServiceServer(t)
+	defer server.Stop()
+
+	client, conn := NewRemoteClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	// A second copy of the test repository stands in for the remote; for
+	// ls-remote a plain path works like any other URL.
+	_, remoteRepoPath, remoteCleanupFn := testhelper.NewTestRepo(t)
+	defer remoteCleanupFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	response, err := client.GetDefaultBranchOfRemote(ctx, &pb.GetDefaultBranchOfRemoteRequest{
+		Repository: testRepo,
+		RemoteUrl:  remoteRepoPath,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []byte("refs/heads/master"), response.Name)
+}
+
+func TestFailedGetDefaultBranchOfRemote(t *testing.T) {
+	server, serverSocketPath := runRemoteServiceServer(t)
+	defer server.Stop()
+
+	client, conn := NewRemoteClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testCases := []struct {
+		description string
+		request     *pb.GetDefaultBranchOfRemoteRequest
+		code        codes.Code
+	}{
+		{
+			description: "empty remote url",
+			request:     &pb.GetDefaultBranchOfRemoteRequest{Repository: testRepo},
+			code:        codes.InvalidArgument,
+		},
+		{
+			description: "negative timeout",
+			request: &pb.GetDefaultBranchOfRemoteRequest{
+				Repository:     testRepo,
+				RemoteUrl:      "http://my-repo.git",
+				TimeoutSeconds: -1,
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			description: "unreachable remote",
+			request: &pb.GetDefaultBranchOfRemoteRequest{
+				Repository: testRepo,
+				RemoteUrl:  "/does/not/exist.git",
+			},
+			code: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := client.GetDefaultBranchOfRemote(ctx, tc.request)
+			testhelper.AssertGrpcError(t, err, tc.code, "")
+		})
+	}
+}
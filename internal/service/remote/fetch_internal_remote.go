@@ -2,32 +2,82 @@ package remote
 
 import (
 	"fmt"
+	"path"
 
+	"github.com/golang/protobuf/jsonpb"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
 )
 
+const gitalyInternalURL = "ssh://gitaly/internal.git"
+
 // FetchInternalRemote fetches another Gitaly repository set as a remote
 func (s *server) FetchInternalRemote(ctx context.Context, req *pb.FetchInternalRemoteRequest) (*pb.FetchInternalRemoteResponse, error) {
 	if err := validateFetchInternalRemoteRequest(req); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "FetchInternalRemote: %v", err)
 	}
 
-	client, err := s.RemoteServiceClient(ctx)
+	env, err := gitalySSHEnv(ctx, req.GetRemoteRepository())
 	if err != nil {
 		return nil, err
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, req.GetRepository())
+	remoteURL := fmt.Sprintf("%s:%s", gitalyInternalURL, req.GetRemoteRepository().GetRelativePath())
+
+	cmd, err := git.CommandWithEnv(ctx, req.GetRepository(), env,
+		"fetch", "--prune", remoteURL, knownRefmaps["all_refs"])
 	if err != nil {
-		return nil, err
+		return nil, status.Errorf(codes.Internal, "FetchInternalRemote: fetch cmd start: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if _, ok := command.ExitStatus(err); ok {
+			return &pb.FetchInternalRemoteResponse{Result: false}, nil
+		}
+
+		return nil, status.Errorf(codes.Internal, "FetchInternalRemote: fetch cmd wait: %v", err)
+	}
+
+	return &pb.FetchInternalRemoteResponse{Result: true}, nil
+}
+
+// gitalySSHEnv returns an environment that routes `git fetch` to the
+// Gitaly node holding remoteRepository through the gitaly-ssh transport,
+// using the `gitaly-servers` metadata of the current request.
+func gitalySSHEnv(ctx context.Context, remoteRepository *pb.Repository) ([]string, error) {
+	gitalyServersInfo, err := helper.ExtractGitalyServers(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "FetchInternalRemote: extracting Gitaly servers: %v", err)
+	}
+
+	storageInfo, ok := gitalyServersInfo[remoteRepository.StorageName]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "FetchInternalRemote: no storage info for %s", remoteRepository.StorageName)
+	}
+
+	if storageInfo["address"] == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "FetchInternalRemote: empty gitaly address")
+	}
+
+	pbMarshaler := &jsonpb.Marshaler{}
+	payload, err := pbMarshaler.MarshalToString(&pb.SSHUploadPackRequest{Repository: remoteRepository})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "FetchInternalRemote: marshalling payload failed: %v", err)
 	}
 
-	return client.FetchInternalRemote(clientCtx, req)
+	return []string{
+		fmt.Sprintf("GITALY_ADDRESS=%s", storageInfo["address"]),
+		fmt.Sprintf("GITALY_PAYLOAD=%s", payload),
+		fmt.Sprintf("GITALY_TOKEN=%s", storageInfo["token"]),
+		fmt.Sprintf("GIT_SSH_COMMAND=%s upload-pack", path.Join(config.Config.BinDir, "gitaly-ssh")),
+	}, nil
 }
 
 func validateFetchInternalRemoteRequest(req *pb.FetchInternalRemoteRequest) error {
@@ -0,0 +1,130 @@
+package remote
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PushRemote pushes the given refspecs to a remote and reports the
+// outcome per ref. A partially rejected push is not an RPC error; the
+// rejected refs are flagged in the response instead.
+func (s *server) PushRemote(ctx context.Context, in *pb.PushRemoteRequest) (*pb.PushRemoteResponse, error) {
+	if err := validatePushRemoteRequest(in); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "PushRemote: %v", err)
+	}
+
+	var env []string
+	if header := in.GetHttpAuthorizationHeader(); header != "" {
+		// The header is a credential. Passing it as `-c` would leak it via
+		// /proc/<pid>/cmdline and the args in command logs, so hand it to
+		// git through the environment instead; SanitizeEnv redacts
+		// GIT_CONFIG_PARAMETERS wholesale.
+		env = append(env, "GIT_CONFIG_PARAMETERS="+sqQuote("http.extraHeader=Authorization: "+header))
+	}
+
+	args := []string{"push", "--porcelain"}
+	if in.GetForce() {
+		args = append(args, "--force")
+	}
+	if in.GetPrune() {
+		args = append(args, "--prune")
+	}
+	args = append(args, in.GetRemote())
+	args = append(args, in.GetRefspecs()...)
+
+	cmd, err := git.CommandWithEnv(ctx, in.GetRepository(), env, args...)
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return nil, err
+		}
+		return nil, status.Errorf(codes.Internal, "PushRemote: cmd: %v", err)
+	}
+
+	var results []*pb.PushRemoteResponse_RefResult
+
+	scanner := bufio.NewScanner(cmd)
+	for scanner.Scan() {
+		if result := parsePushResult(scanner.Text()); result != nil {
+			results = append(results, result)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "PushRemote: scan: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil && len(results) == 0 {
+		// With --porcelain a non-zero exit only reflects rejected refs,
+		// which the results cover. Without results the push itself failed.
+		return nil, status.Errorf(codes.Internal, "PushRemote: push: %v", err)
+	}
+
+	return &pb.PushRemoteResponse{Results: results}, nil
+}
+
+// sqQuote quotes s the way git's sq_dequote in GIT_CONFIG_PARAMETERS
+// expects: wrapped in single quotes, with embedded single quotes
+// sq-escaped.
+func sqQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// parsePushResult parses one `git push --porcelain` line. Ref status
+// lines look like "<flag>\t<from>:<to>\t<summary>"; the "To <url>" and
+// "Done" framing lines yield nil.
+func parsePushResult(line string) *pb.PushRemoteResponse_RefResult {
+	elements := strings.SplitN(line, "\t", 3)
+	if len(elements) != 3 || len(elements[0]) != 1 {
+		return nil
+	}
+
+	refspec := elements[1]
+	refName := refspec[strings.LastIndex(refspec, ":")+1:]
+
+	result := &pb.PushRemoteResponse_RefResult{RefName: []byte(refName)}
+
+	switch elements[0][0] {
+	case '!':
+		result.Status = pb.PushRemoteResponse_RefResult_REJECTED
+		summary := elements[2]
+		if open := strings.Index(summary, "("); open >= 0 && strings.HasSuffix(summary, ")") {
+			result.Reason = summary[open+1 : len(summary)-1]
+		}
+	case '=':
+		result.Status = pb.PushRemoteResponse_RefResult_UP_TO_DATE
+	default:
+		// ' ' (fast-forward), '+' (forced), '-' (deleted) and '*' (new)
+		// all mean the remote ref was changed.
+		result.Status = pb.PushRemoteResponse_RefResult_UPDATED
+	}
+
+	return result
+}
+
+func validatePushRemoteRequest(in *pb.PushRemoteRequest) error {
+	if in.GetRemote() == "" {
+		return fmt.Errorf("empty remote")
+	}
+	// Remote and refspecs end up in argv; a leading dash would let them be
+	// parsed as options, e.g. --receive-pack.
+	if strings.HasPrefix(in.GetRemote(), "-") {
+		return fmt.Errorf("remote can't start with '-'")
+	}
+	if len(in.GetRefspecs()) == 0 {
+		return fmt.Errorf("empty refspecs")
+	}
+	for _, refspec := range in.GetRefspecs() {
+		if strings.HasPrefix(refspec, "-") {
+			return fmt.Errorf("refspec can't start with '-'")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package remote
+
+import (
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulPushRemote(t *testing.T) {
+	server, serverSocketPath := runRemoteServiceServer(t)
+	defer server.Stop()
+
+	client, conn := NewRemoteClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	// A local bare repository stands in for the remote.
+	_, remoteRepoPath, remoteCleanupFn := testhelper.InitBareRepo(t)
+	defer remoteCleanupFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	request := &pb.PushRemoteRequest{
+		Repository: testRepo,
+		Remote:     remoteRepoPath,
+		Refspecs:   []string{"refs/heads/master:refs/heads/master"},
+	}
+
+	response, err := client.PushRemote(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, response.Results, 1)
+	require.Equal(t, []byte("refs/heads/master"), response.Results[0].RefName)
+	require.Equal(t, pb.PushRemoteResponse_RefResult_UPDATED, response.Results[0].Status)
+
+	// Pushing the same ref again is a no-op
+	response, err = client.PushRemote(ctx, request)
+	require.NoError(t, err)
+	require.Len(t, response.Results, 1)
+	require.Equal(t, pb.PushRemoteResponse_RefResult_UP_TO_DATE, response.Results[0].Status)
+
+	// A non-fast-forward push without force is rejected per ref, not as
+	// an RPC error.
+	response, err = client.PushRemote(ctx, &pb.PushRemoteRequest{
+		Repository: testRepo,
+		Remote:     remoteRepoPath,
+		Refspecs:   []string{"refs/heads/branch-merged:refs/heads/master"},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 1)
+	require.Equal(t, pb.PushRemoteResponse_RefResult_REJECTED, response.Results[0].Status)
+	require.NotEmpty(t, response.Results[0].Reason)
+
+	// With force the same push goes through
+	response, err = client.PushRemote(ctx, &pb.PushRemoteRequest{
+		Repository: testRepo,
+		Remote:     remoteRepoPath,
+		Refspecs:   []string{"refs/heads/branch-merged:refs/heads/master"},
+		Force:      true,
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 1)
+	require.Equal(t, pb.PushRemoteResponse_RefResult_UPDATED, response.Results[0].Status)
+}
+
+func TestFailedPushRemote(t *testing.T) {
+	server, serverSocketPath := runRemoteServiceServer(t)
+	defer server.Stop()
+
+	client, conn := NewRemoteClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testCases := []struct {
+		description string
+		request     *pb.PushRemoteRequest
+		code        codes.Code
+	}{
+		{
+			description: "empty remote",
+			request: &pb.PushRemoteRequest{
+				Repository: testRepo,
+				Refspecs:   []string{"refs/heads/master:refs/heads/master"},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			description: "empty refspecs",
+			request: &pb.PushRemoteRequest{
+				Repository: testRepo,
+				Remote:     "/does/not/matter.git",
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			description: "remote looks like an option",
+			request: &pb.PushRemoteRequest{
+				Repository: testRepo,
+				Remote:     "--receive-pack=/bin/false",
+				Refspecs:   []string{"refs/heads/master:refs/heads/master"},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			description: "refspec looks like an option",
+			request: &pb.PushRemoteRequest{
+				Repository: testRepo,
+				Remote:     "/does/not/matter.git",
+				Refspecs:   []string{"--receive-pack=/bin/false"},
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			description: "unreachable remote",
+			request: &pb.PushRemoteRequest{
+				Repository: testRepo,
+				Remote:     "/does/not/exist.git",
+				Refspecs:   []string{"refs/heads/master:refs/heads/master"},
+			},
+			code: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := client.PushRemote(ctx, tc.request)
+			testhelper.AssertGrpcError(t, err, tc.code, "")
+		})
+	}
+}
@@ -10,26 +10,45 @@ import (
 	"golang.org/x/net/context"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
 )
 
+// knownRefmaps maps the symbolic names accepted in
+// AddRemoteRequest.MirrorRefmaps to full fetch refspecs. Any other
+// non-blank value is used as a refspec as-is.
+var knownRefmaps = map[string]string{
+	// With `all_refs`, the remote is equivalent to the result of `git clone --mirror`
+	"all_refs": "+refs/*:refs/*",
+	"heads":    "+refs/heads/*:refs/heads/*",
+	"tags":     "+refs/tags/*:refs/tags/*",
+}
+
 // AddRemote adds a remote to the repository
 func (s *server) AddRemote(ctx context.Context, req *pb.AddRemoteRequest) (*pb.AddRemoteResponse, error) {
 	if err := validateAddRemoteRequest(req); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "AddRemote: %v", err)
 	}
 
-	client, err := s.RemoteServiceClient(ctx)
-	if err != nil {
-		return nil, err
+	// `git remote add` fails when the remote already exists; point the
+	// existing remote at the new URL in that case.
+	if err := runCmd(ctx, req.GetRepository(), "remote", "add", req.GetName(), req.GetUrl()); err != nil {
+		if _, ok := command.ExitStatus(err); !ok {
+			return nil, status.Errorf(codes.Internal, "AddRemote: %v", err)
+		}
+
+		if err := runCmd(ctx, req.GetRepository(), "remote", "set-url", req.GetName(), req.GetUrl()); err != nil {
+			return nil, status.Errorf(codes.Internal, "AddRemote: %v", err)
+		}
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, req.GetRepository())
-	if err != nil {
-		return nil, err
+	if refspecs := resolveRefmaps(req.GetMirrorRefmaps()); len(refspecs) > 0 {
+		if err := setRemoteAsMirror(ctx, req.GetRepository(), req.GetName(), refspecs); err != nil {
+			return nil, status.Errorf(codes.Internal, "AddRemote: set mirror: %v", err)
+		}
 	}
 
-	return client.AddRemote(clientCtx, req)
+	return &pb.AddRemoteResponse{}, nil
 }
 
 func validateAddRemoteRequest(req *pb.AddRemoteRequest) error {
@@ -46,20 +65,27 @@ func validateAddRemoteRequest(req *pb.AddRemoteRequest) error {
 // RemoveRemote removes the given remote
 func (s *server) RemoveRemote(ctx context.Context, req *pb.RemoveRemoteRequest) (*pb.RemoveRemoteResponse, error) {
 	if err := validateRemoveRemoteRequest(req); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "AddRemote: %v", err)
+		return nil, status.Errorf(codes.InvalidArgument, "RemoveRemote: %v", err)
 	}
 
-	client, err := s.RemoteServiceClient(ctx)
-	if err != nil {
-		return nil, err
+	// Removing a remote deletes its remote refs too, but mirror remotes
+	// map their refs into the top level namespace. Drop the mapping first
+	// so those refs survive the removal.
+	if runCmd(ctx, req.GetRepository(), "config", "--get", fmt.Sprintf("remote.%s.mirror", req.GetName())) == nil {
+		if err := runCmd(ctx, req.GetRepository(), "config", "--unset-all", fmt.Sprintf("remote.%s.fetch", req.GetName())); err != nil {
+			return nil, status.Errorf(codes.Internal, "RemoveRemote: unset fetch: %v", err)
+		}
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, req.GetRepository())
-	if err != nil {
-		return nil, err
+	if err := runCmd(ctx, req.GetRepository(), "remote", "remove", req.GetName()); err != nil {
+		if _, ok := command.ExitStatus(err); ok {
+			return &pb.RemoveRemoteResponse{Result: false}, nil
+		}
+
+		return nil, status.Errorf(codes.Internal, "RemoveRemote: %v", err)
 	}
 
-	return client.RemoveRemote(clientCtx, req)
+	return &pb.RemoveRemoteResponse{Result: true}, nil
 }
 
 func validateRemoveRemoteRequest(req *pb.RemoveRemoteRequest) error {
@@ -69,3 +95,53 @@ func validateRemoveRemoteRequest(req *pb.RemoveRemoteRequest) error {
 
 	return nil
 }
+
+// resolveRefmaps expands symbolic refmap names and drops blank entries.
+func resolveRefmaps(refmaps []string) []string {
+	var refspecs []string
+
+	for _, refmap := range refmaps {
+		if strings.TrimSpace(refmap) == "" {
+			continue
+		}
+
+		if refspec, ok := knownRefmaps[refmap]; ok {
+			refmap = refspec
+		}
+
+		refspecs = append(refspecs, refmap)
+	}
+
+	return refspecs
+}
+
+func setRemoteAsMirror(ctx context.Context, repo *pb.Repository, name string, refspecs []string) error {
+	fetchKey := fmt.Sprintf("remote.%s.fetch", name)
+
+	// The first refspec replaces whatever `git remote add` configured,
+	// the remaining ones are added alongside it.
+	if err := runCmd(ctx, repo, "config", "--replace-all", fetchKey, refspecs[0]); err != nil {
+		return err
+	}
+
+	for _, refspec := range refspecs[1:] {
+		if err := runCmd(ctx, repo, "config", "--add", fetchKey, refspec); err != nil {
+			return err
+		}
+	}
+
+	if err := runCmd(ctx, repo, "config", fmt.Sprintf("remote.%s.mirror", name), "true"); err != nil {
+		return err
+	}
+
+	return runCmd(ctx, repo, "config", fmt.Sprintf("remote.%s.prune", name), "true")
+}
+
+func runCmd(ctx context.Context, repo *pb.Repository, args ...string) error {
+	cmd, err := git.Command(ctx, repo, args...)
+	if err != nil {
+		return err
+	}
+
+	return cmd.Wait()
+}
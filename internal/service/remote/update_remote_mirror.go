@@ -1,62 +1,260 @@
 package remote
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
 
-	pb "gitlab.com/gitlab-org/gitaly-proto/go"
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	"golang.org/x/net/context"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
 )
 
+// pushBatchSize bounds the number of refspecs passed to a single
+// `git push` invocation so the command line stays well below OS limits.
+const pushBatchSize = 100
+
 func (s *server) UpdateRemoteMirror(stream pb.RemoteService_UpdateRemoteMirrorServer) error {
 	firstRequest, err := stream.Recv()
 	if err != nil {
 		return err
 	}
 
-	if err = validateUpdateRemoteMirrorRequest(firstRequest); err != nil {
+	if err := validateUpdateRemoteMirrorRequest(firstRequest); err != nil {
 		return status.Errorf(codes.InvalidArgument, "UpdateRemoteMirror: %v", err)
 	}
 
-	ctx := stream.Context()
-	client, err := s.RemoteServiceClient(ctx)
+	onlyBranchesMatching := firstRequest.GetOnlyBranchesMatching()
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		onlyBranchesMatching = append(onlyBranchesMatching, request.GetOnlyBranchesMatching()...)
+	}
+
+	divergentRefs, err := updateRemoteMirror(stream.Context(), firstRequest, onlyBranchesMatching)
 	if err != nil {
-		return err
+		return status.Errorf(codes.Internal, "UpdateRemoteMirror: %v", err)
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, firstRequest.GetRepository())
+	return stream.SendAndClose(&pb.UpdateRemoteMirrorResponse{DivergentRefs: divergentRefs})
+}
+
+// updateRemoteMirror computes the delta between the local refs and the
+// refs the mirror remote currently advertises, then reconciles the
+// mirror with batched force-pushes. Branches are filtered by
+// onlyBranchesMatching; tags are always mirrored. Mirror refs without a
+// local counterpart are deleted only when they are merged into the local
+// default branch, and divergent refs are kept and reported when
+// KeepDivergentRefs is set.
+func updateRemoteMirror(ctx context.Context, req *pb.UpdateRemoteMirrorRequest, onlyBranchesMatching [][]byte) ([][]byte, error) {
+	repo := req.GetRepository()
+	remoteName := req.GetRefName()
+
+	localRefs, err := mirrorLocalRefs(ctx, repo)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("local refs: %v", err)
 	}
 
-	rubyStream, err := client.UpdateRemoteMirror(clientCtx)
+	remoteRefs, err := mirrorRemoteRefs(ctx, repo, remoteName)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("remote refs: %v", err)
 	}
 
-	if err := rubyStream.Send(firstRequest); err != nil {
-		return err
+	defaultBranch, _ := revParse(ctx, repo, "symbolic-ref", "HEAD")
+	defaultBranchSHA := localRefs[defaultBranch]
+
+	var pushRefspecs []string
+	var divergentRefs [][]byte
+
+	for _, refName := range sortedRefNames(localRefs) {
+		if !refMatchesPatterns(refName, onlyBranchesMatching) {
+			continue
+		}
+
+		localSHA := localRefs[refName]
+		remoteSHA, existsOnRemote := remoteRefs[refName]
+		if existsOnRemote && remoteSHA == localSHA {
+			continue
+		}
+
+		// Divergence is only defined for branches: annotated tag objects
+		// have no ancestry to compare.
+		if existsOnRemote && req.GetKeepDivergentRefs() &&
+			strings.HasPrefix(refName, "refs/heads/") && !isAncestor(ctx, repo, remoteSHA, localSHA) {
+			divergentRefs = append(divergentRefs, []byte(refName))
+			continue
+		}
+
+		pushRefspecs = append(pushRefspecs, "+"+refName+":"+refName)
 	}
 
-	err = rubyserver.Proxy(func() error {
-		request, err := stream.Recv()
-		if err != nil {
-			return err
+	for _, refName := range sortedRefNames(remoteRefs) {
+		if _, existsLocally := localRefs[refName]; existsLocally {
+			continue
+		}
+
+		if !refMatchesPatterns(refName, onlyBranchesMatching) {
+			continue
+		}
+
+		// Only delete mirror refs that are merged into the local default
+		// branch; anything else may hold work the mirror should keep.
+		if defaultBranchSHA == "" || !isAncestor(ctx, repo, remoteRefs[refName], defaultBranchSHA) {
+			continue
+		}
+
+		pushRefspecs = append(pushRefspecs, ":"+refName)
+	}
+
+	// Push the default branch first so mirroring into an empty remote
+	// starts out with the right HEAD.
+	for i, refspec := range pushRefspecs {
+		if defaultBranch != "" && refspec == "+"+defaultBranch+":"+defaultBranch {
+			pushRefspecs[0], pushRefspecs[i] = pushRefspecs[i], pushRefspecs[0]
+			break
 		}
-		return rubyStream.Send(request)
-	})
+	}
+
+	for len(pushRefspecs) > 0 {
+		batch := pushRefspecs
+		if len(batch) > pushBatchSize {
+			batch = batch[:pushBatchSize]
+		}
+		pushRefspecs = pushRefspecs[len(batch):]
+
+		if err := runCmd(ctx, repo, append([]string{"push", remoteName}, batch...)...); err != nil {
+			return nil, fmt.Errorf("push to %q: %v", remoteName, err)
+		}
+	}
 
+	return divergentRefs, nil
+}
+
+// refMatchesPatterns reports whether a branch matches the
+// only_branches_matching patterns. Patterns are matched against the
+// branch name and may contain `*` wildcards; an empty pattern list
+// matches everything, and tags are never filtered.
+func refMatchesPatterns(refName string, patterns [][]byte) bool {
+	if len(patterns) == 0 || !strings.HasPrefix(refName, "refs/heads/") {
+		return true
+	}
+
+	branchName := strings.TrimPrefix(refName, "refs/heads/")
+
+	for _, pattern := range patterns {
+		if string(pattern) == branchName {
+			return true
+		}
+
+		if matched, err := path.Match(string(pattern), branchName); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mirrorLocalRefs returns the SHA of each local branch and tag, keyed by
+// full ref name.
+func mirrorLocalRefs(ctx context.Context, repo *pb.Repository) (map[string]string, error) {
+	cmd, err := git.Command(ctx, repo, "for-each-ref", "--format=%(objectname) %(refname)", "refs/heads", "refs/tags")
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	refs := make(map[string]string)
+
+	scanner := bufio.NewScanner(cmd)
+	for scanner.Scan() {
+		split := strings.SplitN(scanner.Text(), " ", 2)
+		if len(split) != 2 {
+			continue
+		}
+		refs[split[1]] = split[0]
 	}
 
-	response, err := rubyStream.CloseAndRecv()
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+// mirrorRemoteRefs returns the branches and tags the remote currently
+// advertises, keyed by full ref name.
+func mirrorRemoteRefs(ctx context.Context, repo *pb.Repository, remoteName string) (map[string]string, error) {
+	cmd, err := git.Command(ctx, repo, "ls-remote", remoteName, "refs/heads/*", "refs/tags/*")
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	refs := make(map[string]string)
+
+	scanner := bufio.NewScanner(cmd)
+	for scanner.Scan() {
+		split := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(split) != 2 || strings.HasSuffix(split[1], "^{}") {
+			continue
+		}
+		refs[split[1]] = split[0]
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, err
+	}
+
+	return refs, nil
+}
+
+func sortedRefNames(refs map[string]string) []string {
+	refNames := make([]string, 0, len(refs))
+	for refName := range refs {
+		refNames = append(refNames, refName)
+	}
+	sort.Strings(refNames)
+
+	return refNames
+}
+
+// isAncestor returns whether ancestor is reachable from descendant. Any
+// failure, e.g. because one of the SHAs is not a commit, counts as "not
+// an ancestor".
+func isAncestor(ctx context.Context, repo *pb.Repository, ancestor, descendant string) bool {
+	return runCmd(ctx, repo, "merge-base", "--is-ancestor", ancestor, descendant) == nil
+}
+
+// revParse returns the chomped output of a git command that prints a
+// single ref or SHA.
+func revParse(ctx context.Context, repo *pb.Repository, args ...string) (string, error) {
+	cmd, err := git.Command(ctx, repo, args...)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := ioutil.ReadAll(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return "", err
 	}
 
-	return stream.SendAndClose(response)
+	return strings.TrimSpace(string(output)), nil
 }
 
 func validateUpdateRemoteMirrorRequest(req *pb.UpdateRemoteMirrorRequest) error {
@@ -123,3 +123,76 @@ func TestFailedUpdateRemoteMirrorRequestDueToValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateRemoteMirrorKeepDivergentRefs(t *testing.T) {
+	server, serverSocketPath := runRemoteServiceServer(t)
+	defer server.Stop()
+
+	client, conn := NewRemoteClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	_, mirrorPath, mirrorCleanupFn := testhelper.InitBareRepo(t)
+	defer mirrorCleanupFn()
+
+	remoteName := "remote_mirror_1"
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "remote", "add", remoteName, mirrorPath)
+
+	base := writeTestCommit(t, testRepoPath, "", "base")
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "update-ref", "refs/heads/master", base)
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "update-ref", "refs/heads/diverged", base)
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "push", remoteName, "+refs/*:refs/*")
+
+	// Advance the branch on both sides so the mirror diverges.
+	mirrorCommit := writeTestCommit(t, mirrorPath, base, "mirror work")
+	testhelper.MustRunCommand(t, nil, "git", "-C", mirrorPath, "update-ref", "refs/heads/diverged", mirrorCommit)
+	localCommit := writeTestCommit(t, testRepoPath, base, "local work")
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "update-ref", "refs/heads/diverged", localCommit)
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	stream, err := client.UpdateRemoteMirror(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.UpdateRemoteMirrorRequest{
+		Repository:        testRepo,
+		RefName:           remoteName,
+		KeepDivergentRefs: true,
+	}))
+
+	response, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{[]byte("refs/heads/diverged")}, response.DivergentRefs)
+
+	divergedSHA := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil, "git", "-C", mirrorPath, "rev-parse", "refs/heads/diverged")))
+	require.Equal(t, mirrorCommit, divergedSHA, "divergent mirror ref must be kept")
+
+	// Without KeepDivergentRefs the local state wins.
+	stream, err = client.UpdateRemoteMirror(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&pb.UpdateRemoteMirrorRequest{
+		Repository: testRepo,
+		RefName:    remoteName,
+	}))
+
+	response, err = stream.CloseAndRecv()
+	require.NoError(t, err)
+	require.Empty(t, response.DivergentRefs)
+
+	divergedSHA = strings.TrimSpace(string(testhelper.MustRunCommand(t, nil, "git", "-C", mirrorPath, "rev-parse", "refs/heads/diverged")))
+	require.Equal(t, localCommit, divergedSHA)
+}
+
+// writeTestCommit creates a commit of the empty tree via plumbing so it
+// works in bare repositories.
+func writeTestCommit(t *testing.T, repoPath, parent, message string) string {
+	args := []string{"-c", "user.name=a", "-c", "user.email=a@example.com", "-C", repoPath, "commit-tree"}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	args = append(args, "-m", message, "4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+
+	return strings.TrimSpace(string(testhelper.MustRunCommand(t, nil, "git", args...)))
+}
@@ -48,7 +48,7 @@ func applyGitattributesHandler(ctx context.Context, repoPath string, revision []
 			// Remove info/attributes file if there's no .gitattributes file
 			err := os.Remove(attributesPath)
 
-			// Ignore error if atttributes file doesn't exist
+			// Ignore error if attributes file doesn't exist
 			if err != nil && !os.IsNotExist(err) {
 				return err
 			}
@@ -82,6 +82,12 @@ func applyGitattributesHandler(ctx context.Context, repoPath string, revision []
 			return err
 		}
 
+		// TempFile creates the file 0600; open up the result so other
+		// system users (e.g. a shared git user) can read the attributes
+		if err := os.Chmod(tempFile.Name(), 0644); err != nil {
+			return err
+		}
+
 		// Rename temp file and return the result
 		return os.Rename(tempFile.Name(), attributesPath)
 	}
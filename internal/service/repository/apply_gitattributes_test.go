@@ -146,5 +146,10 @@ func assertGitattributesApplied(t *testing.T, client pb.RepositoryServiceClient,
 		}
 
 		assert.Equal(t, expectedContents, contents)
+
+		info, err := os.Stat(attributesPath)
+		if assert.NoError(t, err) {
+			assert.Equal(t, os.FileMode(0644), info.Mode().Perm(), "attributes file must be world readable")
+		}
 	}
 }
@@ -2,13 +2,16 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"os/exec"
+	"strings"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/config"
 	"gitlab.com/gitlab-org/gitaly/streamio"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
@@ -20,9 +23,9 @@ func parseArchiveFormat(format pb.GetArchiveRequest_Format) (*exec.Cmd, string)
 	case pb.GetArchiveRequest_TAR:
 		return nil, "tar"
 	case pb.GetArchiveRequest_TAR_GZ:
-		return exec.Command("gzip", "-c", "-n"), "tar"
+		return compressCommand(config.Config.Archive.GzipCommand, "gzip", "-c", "-n"), "tar"
 	case pb.GetArchiveRequest_TAR_BZ2:
-		return exec.Command("bzip2", "-c"), "tar"
+		return compressCommand(config.Config.Archive.Bzip2Command, "bzip2", "-c"), "tar"
 	case pb.GetArchiveRequest_ZIP:
 		return nil, "zip"
 	}
@@ -30,15 +33,57 @@ func parseArchiveFormat(format pb.GetArchiveRequest_Format) (*exec.Cmd, string)
 	return nil, ""
 }
 
-func handleArchive(ctx context.Context, writer io.Writer, repo *pb.Repository,
-	format pb.GetArchiveRequest_Format, prefix, commitID string) error {
-	compressCmd, formatArg := parseArchiveFormat(format)
+// compressCommand builds the compression command for an archive format,
+// preferring the whitespace-separated override from config over the
+// built-in default.
+func compressCommand(override string, defaultArgs ...string) *exec.Cmd {
+	args := defaultArgs
+	if override != "" {
+		args = strings.Fields(override)
+	}
+
+	return exec.Command(args[0], args[1:]...)
+}
+
+func handleArchive(ctx context.Context, writer io.Writer, in *pb.GetArchiveRequest) error {
+	compressCmd, formatArg := parseArchiveFormat(in.GetFormat())
 	if len(formatArg) == 0 {
 		return status.Errorf(codes.InvalidArgument, "invalid format")
 	}
 
-	archiveCommand, err := git.Command(ctx, repo, "archive",
-		"--format="+formatArg, "--prefix="+prefix+"/", commitID)
+	args := []string{"archive", "--format=" + formatArg, "--prefix=" + in.GetPrefix() + "/", in.GetCommitId()}
+	var env []string
+
+	if path := string(in.GetPath()); path != "" {
+		if err := validateArchivePath(path); err != nil {
+			return status.Errorf(codes.InvalidArgument, "GetArchive: %v", err)
+		}
+
+		args = append(args, "--", path)
+	}
+
+	if in.GetIncludeLfsBlobs() {
+		smudgeBin := config.Config.Lfs.SmudgeBinPath
+		if smudgeBin == "" {
+			return status.Errorf(codes.FailedPrecondition, "GetArchive: lfs.smudge_bin_path is not configured")
+		}
+
+		// git-archive checks blobs out through the filter machinery, so
+		// overriding the LFS smudge filter replaces pointer files with the
+		// real contents. The helper reads the internal API credentials from
+		// the gitlab-shell directory.
+		args = append([]string{
+			"-c", "filter.lfs.smudge=" + smudgeBin,
+			"-c", "filter.lfs.required=false",
+		}, args...)
+		env = []string{
+			"GL_REPOSITORY=" + in.GetRepository().GetGlRepository(),
+			"GL_PROJECT_PATH=" + in.GetRepository().GetGlProjectPath(),
+			"GITALY_GITLAB_SHELL_DIR=" + config.Config.GitlabShell.Dir,
+		}
+	}
+
+	archiveCommand, err := git.CommandWithEnv(ctx, in.GetRepository(), env, args...)
 	if err != nil {
 		return err
 	}
@@ -59,6 +104,17 @@ func handleArchive(ctx context.Context, writer io.Writer, repo *pb.Repository,
 	return archiveCommand.Wait()
 }
 
+// validateArchivePath rejects paths that escape the repository root.
+func validateArchivePath(path string) error {
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid path: %q", path)
+		}
+	}
+
+	return nil
+}
+
 func (s *server) GetArchive(in *pb.GetArchiveRequest, stream pb.RepositoryService_GetArchiveServer) error {
 	if err := git.ValidateRevision([]byte(in.CommitId)); err != nil {
 		return status.Errorf(codes.InvalidArgument, "invalid commitId: %v", err)
@@ -68,5 +124,5 @@ func (s *server) GetArchive(in *pb.GetArchiveRequest, stream pb.RepositoryServic
 		return stream.Send(&pb.GetArchiveResponse{Data: p})
 	})
 
-	return handleArchive(stream.Context(), writer, in.Repository, in.Format, in.Prefix, in.CommitId)
+	return handleArchive(stream.Context(), writer, in)
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"google.golang.org/grpc/codes"
@@ -11,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/config"
 	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
 	"gitlab.com/gitlab-org/gitaly/streamio"
 )
@@ -160,6 +163,114 @@ func TestGetArchiveFailure(t *testing.T) {
 	}
 }
 
+func TestGetArchivePath(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	commitID := writeArchiveTestCommit(t, testRepoPath)
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	stream, err := client.GetArchive(ctx, &pb.GetArchiveRequest{
+		Repository: testRepo,
+		CommitId:   commitID,
+		Format:     pb.GetArchiveRequest_TAR,
+		Path:       []byte("sub"),
+	})
+	require.NoError(t, err)
+
+	data, err := consumeArchive(stream)
+	require.NoError(t, err)
+
+	contents := string(archiveListing(t, data))
+	require.Contains(t, contents, "sub/file.txt")
+	require.NotContains(t, contents, "README.md")
+
+	// A path escaping the repository root must be rejected
+	stream, err = client.GetArchive(ctx, &pb.GetArchiveRequest{
+		Repository: testRepo,
+		CommitId:   commitID,
+		Format:     pb.GetArchiveRequest_TAR,
+		Path:       []byte("../escape"),
+	})
+	require.NoError(t, err)
+
+	_, err = consumeArchive(stream)
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+}
+
+func TestGetArchiveCompressCommand(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	commitID := writeArchiveTestCommit(t, testRepoPath)
+
+	// With cat standing in for gzip the "tar.gz" response is a plain
+	// tar stream, which proves the configured command was used.
+	defer func(old config.Archive) { config.Config.Archive = old }(config.Config.Archive)
+	config.Config.Archive.GzipCommand = "cat"
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	stream, err := client.GetArchive(ctx, &pb.GetArchiveRequest{
+		Repository: testRepo,
+		CommitId:   commitID,
+		Format:     pb.GetArchiveRequest_TAR_GZ,
+	})
+	require.NoError(t, err)
+
+	data, err := consumeArchive(stream)
+	require.NoError(t, err)
+
+	require.Contains(t, string(archiveListing(t, data)), "README.md")
+}
+
+// archiveListing returns the plain `tar tf` listing of an uncompressed
+// tar stream.
+func archiveListing(t *testing.T, data []byte) []byte {
+	archiveFile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(archiveFile.Name())
+
+	_, err = archiveFile.Write(data)
+	require.NoError(t, err)
+
+	return testhelper.MustRunCommand(t, nil, "tar", "tf", archiveFile.Name())
+}
+
+// writeArchiveTestCommit commits a README.md at the root and a
+// sub/file.txt below it.
+func writeArchiveTestCommit(t *testing.T, repoPath string) string {
+	blob := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader("file contents\n"),
+		"git", "-C", repoPath, "hash-object", "-w", "--stdin")))
+
+	subTree := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader("100644 blob "+blob+"\tfile.txt\n"),
+		"git", "-C", repoPath, "mktree")))
+	treeSpec := fmt.Sprintf("100644 blob %s\tREADME.md\n040000 tree %s\tsub\n", blob, subTree)
+	tree := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader(treeSpec), "git", "-C", repoPath, "mktree")))
+
+	commitID := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil,
+		"git", "-C", repoPath, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit-tree", "-m", "add archive test files", tree)))
+	testhelper.MustRunCommand(t, nil, "git", "-C", repoPath, "update-ref", "refs/heads/master", commitID)
+
+	return commitID
+}
+
 func compressedFileContents(t *testing.T, format pb.GetArchiveRequest_Format, name string) []byte {
 	switch format {
 	case pb.GetArchiveRequest_TAR:
@@ -183,3 +294,122 @@ func consumeArchive(stream pb.RepositoryService_GetArchiveClient) ([]byte, error
 
 	return ioutil.ReadAll(reader)
 }
+
+func TestGetArchiveIncludeLfsBlobs(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	commitID := writeLfsPointerCommit(t, testRepoPath)
+
+	tmpDir, err := ioutil.TempDir("", "gitaly-lfs-smudge")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	// Stand-in for gitaly-lfs-smudge: replaces whatever comes in on stdin
+	// with a known payload.
+	smudgeBin := filepath.Join(tmpDir, "lfs-smudge")
+	require.NoError(t, ioutil.WriteFile(smudgeBin, []byte("#!/bin/sh\ncat > /dev/null\necho smudged contents\n"), 0755))
+
+	defer func(old string) { config.Config.Lfs.SmudgeBinPath = old }(config.Config.Lfs.SmudgeBinPath)
+	config.Config.Lfs.SmudgeBinPath = smudgeBin
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	stream, err := client.GetArchive(ctx, &pb.GetArchiveRequest{
+		Repository:      testRepo,
+		CommitId:        commitID,
+		Prefix:          "lfs",
+		Format:          pb.GetArchiveRequest_TAR,
+		IncludeLfsBlobs: true,
+	})
+	require.NoError(t, err)
+
+	extracted := extractArchiveFile(t, stream, "lfs/data.bin")
+	require.Equal(t, "smudged contents\n", extracted)
+
+	// Without the option the archive must contain the raw pointer file
+	stream, err = client.GetArchive(ctx, &pb.GetArchiveRequest{
+		Repository: testRepo,
+		CommitId:   commitID,
+		Prefix:     "lfs",
+		Format:     pb.GetArchiveRequest_TAR,
+	})
+	require.NoError(t, err)
+
+	require.Contains(t, extractArchiveFile(t, stream, "lfs/data.bin"), "git-lfs.github.com")
+}
+
+func TestGetArchiveIncludeLfsBlobsWithoutHelper(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	commitID := writeLfsPointerCommit(t, testRepoPath)
+
+	defer func(old string) { config.Config.Lfs.SmudgeBinPath = old }(config.Config.Lfs.SmudgeBinPath)
+	config.Config.Lfs.SmudgeBinPath = ""
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	stream, err := client.GetArchive(ctx, &pb.GetArchiveRequest{
+		Repository:      testRepo,
+		CommitId:        commitID,
+		Format:          pb.GetArchiveRequest_TAR,
+		IncludeLfsBlobs: true,
+	})
+	require.NoError(t, err)
+
+	_, err = consumeArchive(stream)
+	testhelper.AssertGrpcError(t, err, codes.FailedPrecondition, "")
+}
+
+// extractArchiveFile consumes a TAR archive stream and returns the
+// contents of one file in it.
+func extractArchiveFile(t *testing.T, stream pb.RepositoryService_GetArchiveClient, path string) string {
+	data, err := consumeArchive(stream)
+	require.NoError(t, err)
+
+	archiveFile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer os.Remove(archiveFile.Name())
+
+	_, err = archiveFile.Write(data)
+	require.NoError(t, err)
+
+	return string(testhelper.MustRunCommand(t, nil, "tar", "-xOf", archiveFile.Name(), path))
+}
+
+// writeLfsPointerCommit commits an LFS pointer file `data.bin` together
+// with a .gitattributes entry marking it as LFS-filtered.
+func writeLfsPointerCommit(t *testing.T, repoPath string) string {
+	attributes := "*.bin filter=lfs diff=lfs merge=lfs -text\n"
+	pointer := "version https://git-lfs.github.com/spec/v1\noid sha256:91eff75a492a3ed0dfcb544d7f31326bc4014c8551849c192fd1e48d4dd2c897\nsize 1575078\n"
+
+	attrBlob := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader(attributes),
+		"git", "-C", repoPath, "hash-object", "-w", "--stdin")))
+	pointerBlob := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader(pointer),
+		"git", "-C", repoPath, "hash-object", "-w", "--stdin")))
+
+	treeSpec := fmt.Sprintf("100644 blob %s\t.gitattributes\n100644 blob %s\tdata.bin\n", attrBlob, pointerBlob)
+	tree := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader(treeSpec), "git", "-C", repoPath, "mktree")))
+
+	commitID := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil,
+		"git", "-C", repoPath, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit-tree", "-m", "add lfs pointer", tree)))
+	testhelper.MustRunCommand(t, nil, "git", "-C", repoPath, "update-ref", "refs/heads/master", commitID)
+
+	return commitID
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CalculateChecksum computes a checksum over all refs and their targets.
+// The checksum is the XOR of the SHA1 of each `<target> <refname>` line,
+// so it is independent of ref ordering and two repositories with the
+// same refs always produce the same value — enough to verify a mirror or
+// a moved repository against its source without fetching it.
+func (s *server) CalculateChecksum(ctx context.Context, req *pb.CalculateChecksumRequest) (*pb.CalculateChecksumResponse, error) {
+	repo := req.GetRepository()
+	if repo == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "CalculateChecksum: empty Repository")
+	}
+
+	if _, err := helper.GetRepoPath(repo); err != nil {
+		return nil, err
+	}
+
+	cmd, err := git.Command(ctx, repo, "show-ref", "--head")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "CalculateChecksum: cmd start failed: %v", err)
+	}
+
+	var checksum [sha1.Size]byte
+
+	scanner := bufio.NewScanner(cmd)
+	for scanner.Scan() {
+		lineSum := sha1.Sum(scanner.Bytes())
+		for i := range checksum {
+			checksum[i] ^= lineSum[i]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "CalculateChecksum: read refs: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		// show-ref exits 1 when the repository has no refs at all; the
+		// checksum of an empty repository is all zeroes.
+		if exitStatus, ok := command.ExitStatus(err); !ok || exitStatus != 1 {
+			return nil, status.Errorf(codes.Internal, "CalculateChecksum: %v", err)
+		}
+	}
+
+	return &pb.CalculateChecksumResponse{Checksum: hex.EncodeToString(checksum[:])}, nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulCalculateChecksumRequest(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	response, err := client.CalculateChecksum(ctx, &pb.CalculateChecksumRequest{Repository: testRepo})
+	require.NoError(t, err)
+
+	// Recompute the checksum from git itself: XOR of the SHA1 of each
+	// show-ref line.
+	refsOut := testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "show-ref", "--head")
+
+	var expected [sha1.Size]byte
+	for _, line := range strings.Split(strings.TrimSuffix(string(refsOut), "\n"), "\n") {
+		lineSum := sha1.Sum([]byte(line))
+		for i := range expected {
+			expected[i] ^= lineSum[i]
+		}
+	}
+
+	require.Equal(t, hex.EncodeToString(expected[:]), response.GetChecksum())
+
+	// The checksum must be stable across invocations.
+	secondResponse, err := client.CalculateChecksum(ctx, &pb.CalculateChecksumRequest{Repository: testRepo})
+	require.NoError(t, err)
+	require.Equal(t, response.GetChecksum(), secondResponse.GetChecksum())
+}
+
+func TestEmptyRepositoryCalculateChecksumRequest(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	emptyRepo, _, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	response, err := client.CalculateChecksum(ctx, &pb.CalculateChecksumRequest{Repository: emptyRepo})
+	require.NoError(t, err)
+	require.Equal(t, strings.Repeat("0", 40), response.GetChecksum())
+}
+
+func TestFailedCalculateChecksumRequestDueToValidations(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	testCases := []struct {
+		desc    string
+		request *pb.CalculateChecksumRequest
+		code    codes.Code
+	}{
+		{
+			desc:    "empty repository",
+			request: &pb.CalculateChecksumRequest{},
+			code:    codes.InvalidArgument,
+		},
+		{
+			desc: "non-existing repository",
+			request: &pb.CalculateChecksumRequest{
+				Repository: &pb.Repository{StorageName: "default", RelativePath: "not-a-repo.git"},
+			},
+			code: codes.NotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.desc, func(t *testing.T) {
+			_, err := client.CalculateChecksum(ctx, testCase.request)
+			testhelper.AssertGrpcError(t, err, testCase.code, "")
+		})
+	}
+}
@@ -1,13 +1,27 @@
 package repository
 
 import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
 	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
 
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (s *server) CreateRepository(ctx context.Context, req *pb.CreateRepositoryRequest) (*pb.CreateRepositoryResponse, error) {
+	if err := validateCreateRepositoryRequest(req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "CreateRepository: %v", err)
+	}
+
 	client, err := s.RepositoryServiceClient(ctx)
 	if err != nil {
 		return nil, err
@@ -18,5 +32,133 @@ func (s *server) CreateRepository(ctx context.Context, req *pb.CreateRepositoryR
 		return nil, err
 	}
 
-	return client.CreateRepository(clientCtx, req)
+	response, err := client.CreateRepository(clientCtx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyRepositoryDefaults(ctx, req.GetRepository()); err != nil {
+		return nil, err
+	}
+
+	if len(req.GetDefaultBranch()) > 0 || req.GetInitialCommitAuthor() != nil {
+		if err := initializeRepository(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
+
+func validateCreateRepositoryRequest(req *pb.CreateRepositoryRequest) error {
+	if branch := req.GetDefaultBranch(); len(branch) > 0 {
+		if err := git.ValidateRevision(branch); err != nil {
+			return fmt.Errorf("Validate DefaultBranch: %v", err)
+		}
+	}
+
+	if author := req.GetInitialCommitAuthor(); author != nil {
+		if len(author.GetName()) == 0 || len(author.GetEmail()) == 0 {
+			return fmt.Errorf("InitialCommitAuthor must have name and email")
+		}
+	}
+
+	return nil
+}
+
+// applyRepositoryDefaults sets the instance-wide default git config keys
+// and description file content on a freshly created repository.
+func applyRepositoryDefaults(ctx context.Context, repository *pb.Repository) error {
+	defaults := config.Config.RepositoryDefaults
+
+	if len(defaults.GitConfig) == 0 && defaults.Description == "" {
+		return nil
+	}
+
+	repo, err := localrepo.New(repository)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range defaults.GitConfig {
+		if _, err := runGitAndCollect(ctx, repo, "config", entry.Key, entry.Value); err != nil {
+			return status.Errorf(codes.Internal, "CreateRepository: config %s: %v", entry.Key, err)
+		}
+	}
+
+	if defaults.Description != "" {
+		descPath := filepath.Join(repo.Path(), "description")
+		if err := ioutil.WriteFile(descPath, []byte(defaults.Description+"\n"), 0644); err != nil {
+			return status.Errorf(codes.Internal, "CreateRepository: write description: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// initializeRepository points HEAD at the requested default branch and,
+// if an author was given, creates an empty initial commit on it. The
+// commit timestamp is the current server time.
+func initializeRepository(ctx context.Context, req *pb.CreateRepositoryRequest) error {
+	repo, err := localrepo.New(req.GetRepository())
+	if err != nil {
+		return err
+	}
+
+	headRef := "refs/heads/master"
+	if branch := string(req.GetDefaultBranch()); branch != "" {
+		if !strings.HasPrefix(branch, "refs/") {
+			branch = "refs/heads/" + branch
+		}
+		headRef = branch
+
+		if _, err := runGitAndCollect(ctx, repo, "symbolic-ref", "HEAD", headRef); err != nil {
+			return status.Errorf(codes.Internal, "CreateRepository: symbolic-ref: %v", err)
+		}
+	}
+
+	author := req.GetInitialCommitAuthor()
+	if author == nil {
+		return nil
+	}
+
+	treeID, err := runGitAndCollect(ctx, repo, "mktree")
+	if err != nil {
+		return status.Errorf(codes.Internal, "CreateRepository: mktree: %v", err)
+	}
+
+	commitID, err := runGitAndCollect(ctx, repo,
+		"-c", "user.name="+string(author.GetName()),
+		"-c", "user.email="+string(author.GetEmail()),
+		"commit-tree", treeID, "-m", "Initial commit",
+	)
+	if err != nil {
+		return status.Errorf(codes.Internal, "CreateRepository: commit-tree: %v", err)
+	}
+
+	if _, err := runGitAndCollect(ctx, repo, "update-ref", headRef, commitID); err != nil {
+		return status.Errorf(codes.Internal, "CreateRepository: update-ref: %v", err)
+	}
+
+	return nil
+}
+
+// runGitAndCollect runs a git command against repo and returns its
+// trimmed stdout.
+func runGitAndCollect(ctx context.Context, repo *localrepo.Repo, args ...string) (string, error) {
+	cmd, err := repo.ExecWithStdin(ctx, strings.NewReader(""), args...)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := ioutil.ReadAll(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(output)), nil
 }
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"io"
+	"strings"
 
 	"gitlab.com/gitlab-org/gitaly/internal/git"
 	"gitlab.com/gitlab-org/gitaly/streamio"
@@ -20,7 +21,17 @@ func (s *server) CreateBundle(req *pb.CreateBundleRequest, stream pb.RepositoryS
 
 	ctx := stream.Context()
 
-	cmd, err := git.Command(ctx, repo, "bundle", "create", "-", "--all")
+	args := []string{"bundle", "create", "-", "--all"}
+	// Excluding refs the receiving side already has yields an incremental
+	// bundle; `git bundle` accepts rev-list negations.
+	for _, ref := range req.GetExcludeRefs() {
+		if strings.HasPrefix(ref, "-") {
+			return status.Errorf(codes.InvalidArgument, "CreateBundle: invalid exclude ref %q", ref)
+		}
+		args = append(args, "^"+ref)
+	}
+
+	cmd, err := git.Command(ctx, repo, args...)
 	if err != nil {
 		return status.Errorf(codes.Internal, "CreateBundle: cmd start failed: %v", err)
 	}
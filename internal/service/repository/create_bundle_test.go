@@ -54,6 +54,48 @@ func TestSuccessfulCreateBundleRequest(t *testing.T) {
 	require.Contains(t, string(output), "The bundle records a complete history")
 }
 
+func TestSuccessfulCreateBundleRequestWithExcludeRefs(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	request := &pb.CreateBundleRequest{
+		Repository:  testRepo,
+		ExcludeRefs: []string{"refs/heads/master"},
+	}
+
+	c, err := client.CreateBundle(ctx, request)
+	require.NoError(t, err)
+
+	reader := streamio.NewReader(func() ([]byte, error) {
+		response, err := c.Recv()
+		return response.GetData(), err
+	})
+
+	dstDir, err := tempdir.New(ctx, testRepo)
+	require.NoError(t, err)
+	dstFile, err := ioutil.TempFile(dstDir, "")
+	require.NoError(t, err)
+	defer dstFile.Close()
+	defer os.RemoveAll(dstFile.Name())
+
+	_, err = io.Copy(dstFile, reader)
+	require.NoError(t, err)
+
+	// An incremental bundle lists the excluded revisions as prerequisites
+	// and does not record complete history.
+	output := testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "bundle", "verify", dstFile.Name())
+	require.NotContains(t, string(output), "The bundle records a complete history")
+}
+
 func TestFailedCreateBundleRequestDueToValidations(t *testing.T) {
 	server, serverSocketPath := runRepoServer(t)
 	defer server.Stop()
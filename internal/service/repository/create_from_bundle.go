@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/tempdir"
+	"gitlab.com/gitlab-org/gitaly/streamio"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) CreateRepositoryFromBundle(stream pb.RepositoryService_CreateRepositoryFromBundleServer) error {
+	firstRequest, err := stream.Recv()
+	if err != nil {
+		return status.Errorf(codes.Internal, "CreateRepositoryFromBundle: first request failed: %v", err)
+	}
+
+	repository := firstRequest.GetRepository()
+	if repository == nil {
+		return status.Errorf(codes.InvalidArgument, "CreateRepositoryFromBundle: empty Repository")
+	}
+
+	repositoryFullPath, err := helper.GetPath(repository)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(repositoryFullPath); !os.IsNotExist(err) {
+		return status.Errorf(codes.InvalidArgument, "CreateRepositoryFromBundle: dest dir exists")
+	}
+
+	tmpDir, err := tempdir.New(stream.Context(), repository)
+	if err != nil {
+		return status.Errorf(codes.Internal, "CreateRepositoryFromBundle: tmp dir failed: %v", err)
+	}
+
+	bundleFile, err := ioutil.TempFile(tmpDir, "")
+	if err != nil {
+		return status.Errorf(codes.Internal, "CreateRepositoryFromBundle: tmp file failed: %v", err)
+	}
+	defer os.Remove(bundleFile.Name())
+
+	firstRead := false
+	reader := streamio.NewReader(func() ([]byte, error) {
+		if !firstRead {
+			firstRead = true
+			return firstRequest.GetData(), nil
+		}
+
+		request, err := stream.Recv()
+		return request.GetData(), err
+	})
+
+	receiveProgress := &receiveProgressSender{stream: stream}
+	if _, err := io.Copy(io.MultiWriter(bundleFile, receiveProgress), reader); err != nil {
+		return status.Errorf(codes.Internal, "CreateRepositoryFromBundle: receive bundle failed: %v", err)
+	}
+	if err := bundleFile.Close(); err != nil {
+		return status.Errorf(codes.Internal, "CreateRepositoryFromBundle: flush bundle failed: %v", err)
+	}
+
+	stderr := &progressWriter{send: func(line []byte) error {
+		return stream.Send(&pb.CreateRepositoryFromBundleResponse{Phase: "clone", Progress: line})
+	}}
+
+	args := []string{
+		"clone",
+		"--bare",
+		"--progress",
+		"--",
+		bundleFile.Name(),
+		repositoryFullPath,
+	}
+	cmd, err := command.New(stream.Context(), exec.Command(command.GitPath(), args...), nil, nil, stderr)
+	if err != nil {
+		return status.Errorf(codes.Internal, "CreateRepositoryFromBundle: clone cmd start: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		os.RemoveAll(repositoryFullPath)
+		return status.Errorf(codes.Internal, "CreateRepositoryFromBundle: clone cmd wait: %v", err)
+	}
+
+	// A clone from a bundle has no working origin; the remote and the
+	// fetch refspec it leaves behind must not survive.
+	if err := removeOriginInRepo(stream.Context(), repository); err != nil {
+		return status.Errorf(codes.Internal, "CreateRepositoryFromBundle: %v", err)
+	}
+
+	// CreateRepository is harmless on existing repositories with the side effect that it creates the hook symlink.
+	if _, err := s.CreateRepository(stream.Context(), &pb.CreateRepositoryRequest{Repository: repository}); err != nil {
+		return status.Errorf(codes.Internal, "CreateRepositoryFromBundle: create hooks failed: %v", err)
+	}
+
+	return stream.Send(&pb.CreateRepositoryFromBundleResponse{})
+}
+
+// Progress during the receive phase is reported at this granularity.
+const receiveProgressInterval = 1 << 22 // 4 MiB
+
+// receiveProgressSender reports how much of the bundle arrived so far.
+type receiveProgressSender struct {
+	stream       pb.RepositoryService_CreateRepositoryFromBundleServer
+	bytesWritten uint64
+	lastReported uint64
+}
+
+func (rp *receiveProgressSender) Write(p []byte) (int, error) {
+	rp.bytesWritten += uint64(len(p))
+
+	if rp.bytesWritten-rp.lastReported >= receiveProgressInterval {
+		rp.lastReported = rp.bytesWritten
+
+		if err := rp.stream.Send(&pb.CreateRepositoryFromBundleResponse{
+			Phase:        "receive",
+			BytesWritten: rp.bytesWritten,
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulCreateRepositoryFromBundleRequest(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	_, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	tmpDir, err := ioutil.TempDir("", "gitaly-bundle")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	bundlePath := filepath.Join(tmpDir, "original_repository.bundle")
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "bundle", "create", bundlePath, "--all")
+
+	importedRepo := &pb.Repository{
+		StorageName:  testhelper.TestRepository().GetStorageName(),
+		RelativePath: "a-repo-from-bundle",
+	}
+	importedRepoPath, err := helper.GetPath(importedRepo)
+	require.NoError(t, err)
+	defer os.RemoveAll(importedRepoPath)
+
+	stream, err := client.CreateRepositoryFromBundle(ctx)
+	require.NoError(t, err)
+
+	request := &pb.CreateRepositoryFromBundleRequest{Repository: importedRepo}
+	bundle, err := os.Open(bundlePath)
+	require.NoError(t, err)
+	defer bundle.Close()
+
+	for {
+		buf := make([]byte, 16*1024)
+		bytesRead, err := bundle.Read(buf)
+		if bytesRead > 0 {
+			request.Data = buf[:bytesRead]
+			require.NoError(t, stream.Send(request))
+			request = &pb.CreateRepositoryFromBundleRequest{}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	require.NoError(t, stream.CloseSend())
+
+	for {
+		if _, err := stream.Recv(); err != nil {
+			require.Equal(t, io.EOF, err)
+			break
+		}
+	}
+
+	testhelper.MustRunCommand(t, nil, "git", "-C", importedRepoPath, "fsck")
+
+	_, err = os.Lstat(filepath.Join(importedRepoPath, "hooks"))
+	require.NoError(t, err, "hooks symlink should have been created")
+
+	remotes := testhelper.MustRunCommand(t, nil, "git", "-C", importedRepoPath, "remote")
+	require.NotContains(t, string(remotes), "origin")
+}
+
+func TestFailedCreateRepositoryFromBundleRequestDueToValidations(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	stream, err := client.CreateRepositoryFromBundle(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&pb.CreateRepositoryFromBundleRequest{}))
+	require.NoError(t, stream.CloseSend())
+
+	_, err = stream.Recv()
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+}
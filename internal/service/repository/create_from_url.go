@@ -10,53 +10,61 @@ import (
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 
-	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
-func (s *server) CreateRepositoryFromURL(ctx context.Context, req *pb.CreateRepositoryFromURLRequest) (*pb.CreateRepositoryFromURLResponse, error) {
+// CreateRepositoryFromURL clones a bare repository from url and streams
+// the clone progress git reports, so clients can show real progress for
+// imports that take minutes. The final message has an empty phase.
+func (s *server) CreateRepositoryFromURL(req *pb.CreateRepositoryFromURLRequest, stream pb.RepositoryService_CreateRepositoryFromURLServer) error {
 	if err := validateCreateRepositoryFromURLRequest(req); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "CreateRepositoryFromURL: %v", err)
+		return status.Errorf(codes.InvalidArgument, "CreateRepositoryFromURL: %v", err)
 	}
 
+	ctx := stream.Context()
 	repository := req.Repository
 
 	repositoryFullPath, err := helper.GetPath(repository)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	if _, err := os.Stat(repositoryFullPath); !os.IsNotExist(err) {
-		return nil, status.Errorf(codes.InvalidArgument, "CreateRepositoryFromURL: dest dir exists")
+		return status.Errorf(codes.InvalidArgument, "CreateRepositoryFromURL: dest dir exists")
 	}
 
+	stderr := &progressWriter{send: func(line []byte) error {
+		return stream.Send(&pb.CreateRepositoryFromURLResponse{Phase: "clone", Progress: line})
+	}}
+
 	args := []string{
 		"clone",
 		"--bare",
+		"--progress",
 		"--",
 		req.Url,
 		repositoryFullPath,
 	}
-	cmd, err := command.New(ctx, exec.Command(command.GitPath(), args...), nil, nil, nil)
+	cmd, err := command.New(ctx, exec.Command(command.GitPath(), args...), nil, nil, stderr)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "CreateRepositoryFromURL: clone cmd start: %v", err)
+		return status.Errorf(codes.Internal, "CreateRepositoryFromURL: clone cmd start: %v", err)
 	}
 	if err := cmd.Wait(); err != nil {
 		os.RemoveAll(repositoryFullPath)
-		return nil, status.Errorf(codes.Internal, "CreateRepositoryFromURL: clone cmd wait: %v", err)
+		return status.Errorf(codes.Internal, "CreateRepositoryFromURL: clone cmd wait: %v", err)
 	}
 
 	// CreateRepository is harmless on existing repositories with the side effect that it creates the hook symlink.
 	if _, err := s.CreateRepository(ctx, &pb.CreateRepositoryRequest{Repository: repository}); err != nil {
-		return nil, status.Errorf(codes.Internal, "CreateRepositoryFromURL: create hooks failed: %v", err)
+		return status.Errorf(codes.Internal, "CreateRepositoryFromURL: create hooks failed: %v", err)
 	}
 
 	if err := removeOriginInRepo(ctx, repository); err != nil {
-		return nil, status.Errorf(codes.Internal, "CreateRepositoryFromURL: %v", err)
+		return status.Errorf(codes.Internal, "CreateRepositoryFromURL: %v", err)
 	}
 
-	return &pb.CreateRepositoryFromURLResponse{}, nil
+	return stream.Send(&pb.CreateRepositoryFromURLResponse{})
 }
 
 func validateCreateRepositoryFromURLRequest(req *pb.CreateRepositoryFromURLRequest) error {
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"io"
 	"io/ioutil"
 	"os"
 	"path"
@@ -17,6 +18,17 @@ import (
 
 const defaultStorageName = "default"
 
+func drainCreateFromURLResponses(stream pb.RepositoryService_CreateRepositoryFromURLClient) error {
+	for {
+		if _, err := stream.Recv(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
 func TestSuccessfulCreateRepositoryFromURLRequest(t *testing.T) {
 	server, serverSocketPath := runRepoServer(t)
 	defer server.Stop()
@@ -37,9 +49,11 @@ func TestSuccessfulCreateRepositoryFromURLRequest(t *testing.T) {
 		Url:        "https://gitlab.com/gitlab-org/gitlab-test.git",
 	}
 
-	_, err := client.CreateRepositoryFromURL(ctx, req)
+	stream, err := client.CreateRepositoryFromURL(ctx, req)
 	require.NoError(t, err)
 
+	require.NoError(t, drainCreateFromURLResponses(stream))
+
 	importedRepoPath, err := helper.GetRepoPath(importedRepo)
 	require.NoError(t, err)
 	defer os.RemoveAll(importedRepoPath)
@@ -103,7 +117,10 @@ func TestFailedCreateRepositoryFromURLRequestDueToExistingTarget(t *testing.T) {
 				Url:        "https://gitlab.com/gitlab-org/gitlab-test.git",
 			}
 
-			_, err = client.CreateRepositoryFromURL(ctx, req)
+			stream, err := client.CreateRepositoryFromURL(ctx, req)
+			require.NoError(t, err)
+
+			err = drainCreateFromURLResponses(stream)
 			testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
 		})
 	}
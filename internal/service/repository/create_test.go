@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
@@ -53,6 +54,41 @@ func TestCreateRepositorySuccess(t *testing.T) {
 	require.Equal(t, path.Join(config.Config.GitlabShell.Dir, "hooks"), hooksTarget)
 }
 
+func TestCreateRepositoryWithDefaultBranchAndInitialCommit(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	storageDir, err := helper.GetStorageByName("default")
+	require.NoError(t, err)
+	relativePath := "create-repository-initialized-test.git"
+	repoDir := path.Join(storageDir, relativePath)
+	require.NoError(t, os.RemoveAll(repoDir))
+	defer os.RemoveAll(repoDir)
+
+	repo := &pb.Repository{StorageName: "default", RelativePath: relativePath}
+	_, err = client.CreateRepository(ctx, &pb.CreateRepositoryRequest{
+		Repository:    repo,
+		DefaultBranch: []byte("main"),
+		InitialCommitAuthor: &pb.CommitAuthor{
+			Name:  []byte("Ahmad Sherif"),
+			Email: []byte("ahmad@gitlab.com"),
+		},
+	})
+	require.NoError(t, err)
+
+	headRef := testhelper.MustRunCommand(t, nil, "git", "-C", repoDir, "symbolic-ref", "HEAD")
+	require.Equal(t, "refs/heads/main", strings.TrimSpace(string(headRef)))
+
+	logOutput := testhelper.MustRunCommand(t, nil, "git", "-C", repoDir, "log", "--format=%an <%ae> %s", "main")
+	require.Equal(t, "Ahmad Sherif <ahmad@gitlab.com> Initial commit", strings.TrimSpace(string(logOutput)))
+}
+
 func TestCreateRepositoryFailure(t *testing.T) {
 	server, serverSocketPath := runRepoServer(t)
 	defer server.Stop()
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/golang/protobuf/jsonpb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/service/ref"
+)
+
+// FetchRef fetches a single ref into the repository without configuring
+// a persistent remote, e.g. to pull the upstream default branch into a
+// fork before merging.
+func (s *server) FetchRef(ctx context.Context, req *pb.FetchRefRequest) (*pb.FetchRefResponse, error) {
+	if err := validateFetchRefRequest(req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "FetchRef: %v", err)
+	}
+
+	remote := req.GetRemoteUrl()
+	var env []string
+
+	if source := req.GetSourceRepository(); source != nil {
+		var err error
+		if env, err = gitalySSHUploadPackEnv(ctx, source); err != nil {
+			return nil, status.Errorf(codes.Internal, "FetchRef: %v", err)
+		}
+
+		remote = fmt.Sprintf("%s:%s", gitalyInternalURL, source.RelativePath)
+	}
+
+	args := []string{"fetch", "--quiet", "--no-tags"}
+	if depth := req.GetDepth(); depth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", depth))
+	}
+	args = append(args, remote, fmt.Sprintf("+%s:%s", req.GetSourceRef(), req.GetTargetRef()))
+
+	cmd, err := git.CommandWithEnv(ctx, req.GetRepository(), env, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if _, ok := command.ExitStatus(err); ok {
+			return nil, status.Errorf(codes.Unknown, "FetchRef: fetching %q failed", string(req.GetSourceRef()))
+		}
+		return nil, status.Errorf(codes.Internal, "FetchRef: %v", err)
+	}
+
+	ref.InvalidateDefaultBranchCache(req.GetRepository())
+
+	return &pb.FetchRefResponse{}, nil
+}
+
+func validateFetchRefRequest(req *pb.FetchRefRequest) error {
+	if req.GetRepository() == nil {
+		return fmt.Errorf("empty Repository")
+	}
+
+	if (req.GetRemoteUrl() == "") == (req.GetSourceRepository() == nil) {
+		return fmt.Errorf("exactly one of RemoteUrl and SourceRepository must be set")
+	}
+
+	if err := git.ValidateRevision(req.GetSourceRef()); err != nil {
+		return fmt.Errorf("invalid SourceRef: %v", err)
+	}
+
+	if err := git.ValidateRevision(req.GetTargetRef()); err != nil {
+		return fmt.Errorf("invalid TargetRef: %v", err)
+	}
+
+	return nil
+}
+
+// gitalySSHUploadPackEnv builds the environment that routes a git fetch
+// from sourceRepository through gitaly-ssh, using the addresses and
+// tokens from the request metadata.
+func gitalySSHUploadPackEnv(ctx context.Context, sourceRepository *pb.Repository) ([]string, error) {
+	gitalyServersInfo, err := helper.ExtractGitalyServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("extracting Gitaly servers: %v", err)
+	}
+
+	storageInfo, ok := gitalyServersInfo[sourceRepository.StorageName]
+	if !ok {
+		return nil, fmt.Errorf("no storage info for %s", sourceRepository.StorageName)
+	}
+
+	if storageInfo["address"] == "" {
+		return nil, fmt.Errorf("empty gitaly address")
+	}
+
+	payload, err := (&jsonpb.Marshaler{}).MarshalToString(&pb.SSHUploadPackRequest{Repository: sourceRepository})
+	if err != nil {
+		return nil, fmt.Errorf("marshalling payload failed: %v", err)
+	}
+
+	return []string{
+		fmt.Sprintf("GITALY_ADDRESS=%s", storageInfo["address"]),
+		fmt.Sprintf("GITALY_PAYLOAD=%s", payload),
+		fmt.Sprintf("GITALY_TOKEN=%s", storageInfo["token"]),
+		fmt.Sprintf("GIT_SSH_COMMAND=%s upload-pack", path.Join(config.Config.BinDir, "gitaly-ssh")),
+	}, nil
+}
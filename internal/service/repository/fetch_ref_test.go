@@ -0,0 +1,191 @@
+package repository_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/service/repository"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// writeEmptyTreeCommit commits the empty tree, optionally on top of a
+// parent, and points the branch at it.
+func writeEmptyTreeCommit(t *testing.T, repoPath, branch, parent string) string {
+	args := []string{"-C", repoPath, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit-tree", "-m", "message"}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	args = append(args, "4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+
+	commitID := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil, "git", args...)))
+	testhelper.MustRunCommand(t, nil, "git", "-C", repoPath, "update-ref", "refs/heads/"+branch, commitID)
+
+	return commitID
+}
+
+func TestFetchRefFromRemoteUrl(t *testing.T) {
+	server, serverSocketPath := runFullServer(t)
+	defer server.Stop()
+
+	client, conn := repository.NewRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	targetRepo, targetRepoPath, targetCleanupFn := testhelper.InitBareRepo(t)
+	defer targetCleanupFn()
+
+	_, sourceRepoPath, sourceCleanupFn := testhelper.InitBareRepo(t)
+	defer sourceCleanupFn()
+
+	first := writeEmptyTreeCommit(t, sourceRepoPath, "master", "")
+	second := writeEmptyTreeCommit(t, sourceRepoPath, "master", first)
+
+	targetRef := "refs/tmp/fetch-ref-test"
+	_, err := client.FetchRef(ctx, &pb.FetchRefRequest{
+		Repository: targetRepo,
+		RemoteUrl:  sourceRepoPath,
+		SourceRef:  []byte("refs/heads/master"),
+		TargetRef:  []byte(targetRef),
+	})
+	require.NoError(t, err)
+
+	fetched := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil, "git", "-C", targetRepoPath, "rev-parse", targetRef)))
+	require.Equal(t, second, fetched)
+}
+
+func TestFetchRefWithDepth(t *testing.T) {
+	server, serverSocketPath := runFullServer(t)
+	defer server.Stop()
+
+	client, conn := repository.NewRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	targetRepo, targetRepoPath, targetCleanupFn := testhelper.InitBareRepo(t)
+	defer targetCleanupFn()
+
+	_, sourceRepoPath, sourceCleanupFn := testhelper.InitBareRepo(t)
+	defer sourceCleanupFn()
+
+	first := writeEmptyTreeCommit(t, sourceRepoPath, "master", "")
+	writeEmptyTreeCommit(t, sourceRepoPath, "master", first)
+
+	_, err := client.FetchRef(ctx, &pb.FetchRefRequest{
+		Repository: targetRepo,
+		RemoteUrl:  fmt.Sprintf("file://%s", sourceRepoPath),
+		SourceRef:  []byte("refs/heads/master"),
+		TargetRef:  []byte("refs/tmp/shallow"),
+		Depth:      1,
+	})
+	require.NoError(t, err)
+
+	commitCount := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil,
+		"git", "-C", targetRepoPath, "rev-list", "--count", "refs/tmp/shallow")))
+	require.Equal(t, "1", commitCount, "only the tip commit must be fetched")
+}
+
+func TestFetchRefFromSourceRepository(t *testing.T) {
+	server, serverSocketPath := runFullServer(t)
+	defer server.Stop()
+
+	client, conn := repository.NewRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctxOuter, cancel := testhelper.Context()
+	defer cancel()
+
+	md := testhelper.GitalyServersMetadata(t, serverSocketPath)
+	ctx := metadata.NewOutgoingContext(ctxOuter, md)
+
+	targetRepo, targetRepoPath, targetCleanupFn := testhelper.InitBareRepo(t)
+	defer targetCleanupFn()
+
+	sourceRepo, sourceRepoPath, sourceCleanupFn := testhelper.InitBareRepo(t)
+	defer sourceCleanupFn()
+
+	commitID := writeEmptyTreeCommit(t, sourceRepoPath, "master", "")
+
+	targetRef := "refs/tmp/fetch-ref-internal"
+	_, err := client.FetchRef(ctx, &pb.FetchRefRequest{
+		Repository:       targetRepo,
+		SourceRepository: sourceRepo,
+		SourceRef:        []byte("refs/heads/master"),
+		TargetRef:        []byte(targetRef),
+	})
+	require.NoError(t, err)
+
+	fetched := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil, "git", "-C", targetRepoPath, "rev-parse", targetRef)))
+	require.Equal(t, commitID, fetched)
+}
+
+func TestFetchRefFailure(t *testing.T) {
+	server, serverSocketPath := runFullServer(t)
+	defer server.Stop()
+
+	client, conn := repository.NewRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	targetRepo, _, targetCleanupFn := testhelper.InitBareRepo(t)
+	defer targetCleanupFn()
+
+	sourceRepo, sourceRepoPath, sourceCleanupFn := testhelper.InitBareRepo(t)
+	defer sourceCleanupFn()
+
+	testCases := []struct {
+		desc string
+		req  *pb.FetchRefRequest
+		code codes.Code
+	}{
+		{
+			desc: "no repository",
+			req:  &pb.FetchRefRequest{RemoteUrl: sourceRepoPath, SourceRef: []byte("master"), TargetRef: []byte("refs/tmp/x")},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "neither remote url nor source repository",
+			req:  &pb.FetchRefRequest{Repository: targetRepo, SourceRef: []byte("master"), TargetRef: []byte("refs/tmp/x")},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "both remote url and source repository",
+			req:  &pb.FetchRefRequest{Repository: targetRepo, RemoteUrl: sourceRepoPath, SourceRepository: sourceRepo, SourceRef: []byte("master"), TargetRef: []byte("refs/tmp/x")},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "empty source ref",
+			req:  &pb.FetchRefRequest{Repository: targetRepo, RemoteUrl: sourceRepoPath, TargetRef: []byte("refs/tmp/x")},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "dangerous target ref",
+			req:  &pb.FetchRefRequest{Repository: targetRepo, RemoteUrl: sourceRepoPath, SourceRef: []byte("master"), TargetRef: []byte("-refs/tmp/x")},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "missing source ref",
+			req:  &pb.FetchRefRequest{Repository: targetRepo, RemoteUrl: sourceRepoPath, SourceRef: []byte("refs/heads/does-not-exist"), TargetRef: []byte("refs/tmp/x")},
+			code: codes.Unknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := client.FetchRef(ctx, tc.req)
+			testhelper.AssertGrpcError(t, err, tc.code, "")
+		})
+	}
+}
@@ -1,12 +1,21 @@
 package repository
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
 )
 
 func (s *server) FetchRemote(ctx context.Context, in *pb.FetchRemoteRequest) (*pb.FetchRemoteResponse, error) {
@@ -15,19 +24,88 @@ func (s *server) FetchRemote(ctx context.Context, in *pb.FetchRemoteRequest) (*p
 		"Force":      in.GetForce(),
 		"NoTags":     in.GetNoTags(),
 		"Timeout":    in.GetTimeout(),
-		"SSHKey":     in.GetSshKey(),
-		"KnownHosts": in.GetKnownHosts(),
+		"SSHKey":     in.GetSshKey() != "",
+		"KnownHosts": in.GetKnownHosts() != "",
 	}).Debug("FetchRemote")
 
-	client, err := s.RepositoryServiceClient(ctx)
+	if in.GetRemote() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "FetchRemote: empty remote")
+	}
+
+	env, cleanup, err := sshFetchEnv(in.GetSshKey(), in.GetKnownHosts())
 	if err != nil {
-		return nil, err
+		return nil, status.Errorf(codes.Internal, "FetchRemote: ssh auth setup: %v", err)
 	}
+	defer cleanup()
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, in.GetRepository())
+	if timeout := in.GetTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+
+	args := []string{"fetch", in.GetRemote(), "--prune", "--quiet"}
+	if in.GetForce() {
+		args = append(args, "--force")
+	}
+	if in.GetNoTags() {
+		args = append(args, "--no-tags")
+	} else {
+		args = append(args, "--tags")
+	}
+
+	cmd, err := git.CommandWithEnv(ctx, in.GetRepository(), env, args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return client.FetchRemote(clientCtx, in)
+	if err := cmd.Wait(); err != nil {
+		if _, ok := command.ExitStatus(err); ok {
+			return nil, status.Errorf(codes.Unknown, "FetchRemote: fetching remote %q failed", in.GetRemote())
+		}
+		return nil, status.Errorf(codes.Internal, "FetchRemote: %v", err)
+	}
+
+	return &pb.FetchRemoteResponse{}, nil
+}
+
+// sshFetchEnv materializes the SSH credentials from the request as
+// ephemeral files and returns a GIT_SSH_COMMAND environment pointing at
+// them, so pull mirrors no longer depend on on-disk gitlab-shell keys.
+// The cleanup function removes the files; SSH reads them at connection
+// time so they must outlive the whole fetch.
+func sshFetchEnv(sshKey, knownHosts string) ([]string, func(), error) {
+	if sshKey == "" && knownHosts == "" {
+		return nil, func() {}, nil
+	}
+
+	authDir, err := ioutil.TempDir("", "gitaly-fetch-remote-auth")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(authDir) }
+
+	sshCommand := "ssh"
+
+	if sshKey != "" {
+		keyPath := filepath.Join(authDir, "key")
+		if err := ioutil.WriteFile(keyPath, []byte(sshKey), 0600); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+
+		sshCommand += fmt.Sprintf(" -oIdentitiesOnly=yes -oIdentityFile=%s", keyPath)
+	}
+
+	if knownHosts != "" {
+		hostsPath := filepath.Join(authDir, "known_hosts")
+		if err := ioutil.WriteFile(hostsPath, []byte(knownHosts), 0600); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+
+		sshCommand += fmt.Sprintf(" -oStrictHostKeyChecking=yes -oUserKnownHostsFile=%s", hostsPath)
+	}
+
+	return []string{"GIT_SSH_COMMAND=" + sshCommand}, cleanup, nil
 }
@@ -1,8 +1,10 @@
 package repository
 
 import (
+	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	"google.golang.org/grpc/codes"
@@ -94,3 +96,109 @@ func TestFetchRemoteFailure(t *testing.T) {
 		})
 	}
 }
+
+func TestFetchRemotePruneAndTags(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, _ := newRepositoryClient(t, serverSocketPath)
+
+	sourceRepo, sourceRepoPath, sourceCleanupFn := testhelper.InitBareRepo(t)
+	defer sourceCleanupFn()
+
+	commitID := writeLfsPointerCommit(t, sourceRepoPath)
+	testhelper.MustRunCommand(t, nil, "git", "-C", sourceRepoPath, "update-ref", "refs/heads/to-be-pruned", commitID)
+	testhelper.MustRunCommand(t, nil, "git", "-C", sourceRepoPath, "tag", "v1.0.0", commitID)
+
+	mirrorRepo := copyRepoWithNewRemote(t, sourceRepo, "my-remote")
+	defer func(r *pb.Repository) {
+		path, err := helper.GetRepoPath(r)
+		require.NoError(t, err)
+		os.RemoveAll(path)
+	}(mirrorRepo)
+	mirrorRepoPath, err := helper.GetRepoPath(mirrorRepo)
+	require.NoError(t, err)
+
+	testhelper.MustRunCommand(t, nil, "git", "-C", sourceRepoPath, "update-ref", "-d", "refs/heads/to-be-pruned")
+	testhelper.MustRunCommand(t, nil, "git", "-C", sourceRepoPath, "tag", "v2.0.0", commitID)
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	_, err = client.FetchRemote(ctx, &pb.FetchRemoteRequest{
+		Repository: mirrorRepo,
+		Remote:     "my-remote",
+		NoTags:     true,
+	})
+	require.NoError(t, err)
+
+	refs := string(testhelper.MustRunCommand(t, nil, "git", "-C", mirrorRepoPath, "for-each-ref"))
+	require.NotContains(t, refs, "refs/remotes/my-remote/to-be-pruned", "stale remote-tracking branch must be pruned")
+	require.NotContains(t, refs, "refs/tags/v2.0.0", "no tags must be fetched with NoTags")
+
+	_, err = client.FetchRemote(ctx, &pb.FetchRemoteRequest{
+		Repository: mirrorRepo,
+		Remote:     "my-remote",
+	})
+	require.NoError(t, err)
+
+	refs = string(testhelper.MustRunCommand(t, nil, "git", "-C", mirrorRepoPath, "for-each-ref"))
+	require.Contains(t, refs, "refs/tags/v2.0.0")
+}
+
+func TestFetchRemoteMissingRemote(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, _ := newRepositoryClient(t, serverSocketPath)
+
+	testRepo, _, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	_, err := client.FetchRemote(ctx, &pb.FetchRemoteRequest{Repository: testRepo})
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "empty remote")
+
+	_, err = client.FetchRemote(ctx, &pb.FetchRemoteRequest{Repository: testRepo, Remote: "no-such-remote"})
+	testhelper.AssertGrpcError(t, err, codes.Unknown, "")
+}
+
+func TestSSHFetchEnv(t *testing.T) {
+	env, cleanup, err := sshFetchEnv("", "")
+	require.NoError(t, err)
+	require.Empty(t, env)
+	cleanup()
+
+	env, cleanup, err = sshFetchEnv("PRIVATE KEY", "example.com ssh-rsa AAAA")
+	require.NoError(t, err)
+	require.Len(t, env, 1)
+
+	sshCommand := strings.TrimPrefix(env[0], "GIT_SSH_COMMAND=ssh")
+	require.NotEqual(t, env[0], sshCommand, "env must set GIT_SSH_COMMAND")
+	require.Contains(t, sshCommand, "-oIdentitiesOnly=yes")
+	require.Contains(t, sshCommand, "-oStrictHostKeyChecking=yes")
+
+	var keyPath, hostsPath string
+	for _, option := range strings.Fields(sshCommand) {
+		if strings.HasPrefix(option, "-oIdentityFile=") {
+			keyPath = strings.TrimPrefix(option, "-oIdentityFile=")
+		}
+		if strings.HasPrefix(option, "-oUserKnownHostsFile=") {
+			hostsPath = strings.TrimPrefix(option, "-oUserKnownHostsFile=")
+		}
+	}
+
+	key, err := ioutil.ReadFile(keyPath)
+	require.NoError(t, err)
+	require.Equal(t, "PRIVATE KEY", string(key))
+
+	hosts, err := ioutil.ReadFile(hostsPath)
+	require.NoError(t, err)
+	require.Equal(t, "example.com ssh-rsa AAAA", string(hosts))
+
+	cleanup()
+	_, err = os.Stat(keyPath)
+	require.True(t, os.IsNotExist(err), "cleanup must remove the ephemeral key file")
+}
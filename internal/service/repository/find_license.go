@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"bufio"
+	"io/ioutil"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/license"
+)
+
+// FindLicense inspects the root tree of HEAD for a license file and
+// classifies it, so project pages get a license badge without a Ruby
+// licensee round-trip.
+func (s *server) FindLicense(ctx context.Context, req *pb.FindLicenseRequest) (*pb.FindLicenseResponse, error) {
+	licenseFile, err := findLicenseFile(ctx, req.GetRepository())
+	if err != nil {
+		return nil, err
+	}
+	if licenseFile == "" {
+		return &pb.FindLicenseResponse{}, nil
+	}
+
+	cmd, err := git.Command(ctx, req.GetRepository(), "cat-file", "blob", "HEAD:"+licenseFile)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := ioutil.ReadAll(cmd)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "FindLicense: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, status.Errorf(codes.Internal, "FindLicense: %v", err)
+	}
+
+	return &pb.FindLicenseResponse{LicenseShortName: license.Identify(text)}, nil
+}
+
+// findLicenseFile returns the name of the license file in the root tree
+// of HEAD, or the empty string when the repository has none. A missing
+// or unborn HEAD is treated as "no license".
+func findLicenseFile(ctx context.Context, repo *pb.Repository) (string, error) {
+	cmd, err := git.Command(ctx, repo, "ls-tree", "--name-only", "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	licenseFile := ""
+	scanner := bufio.NewScanner(cmd)
+	for scanner.Scan() {
+		if licenseFile == "" && license.IsLicenseFile(scanner.Text()) {
+			licenseFile = scanner.Text()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", status.Errorf(codes.Internal, "FindLicense: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		// A git exit status means HEAD could not be listed, e.g. an
+		// unborn branch: no license. Anything else is an infrastructure
+		// failure the caller must hear about.
+		if _, ok := command.ExitStatus(err); ok {
+			return "", nil
+		}
+		return "", status.Errorf(codes.Internal, "FindLicense: %v", err)
+	}
+
+	return licenseFile, nil
+}
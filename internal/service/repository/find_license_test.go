@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"google.golang.org/grpc/codes"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+)
+
+func TestFindLicense(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	// An empty repository has no license
+	resp, err := client.FindLicense(ctx, &pb.FindLicenseRequest{Repository: testRepo})
+	require.NoError(t, err)
+	require.Empty(t, resp.GetLicenseShortName())
+
+	// A commit without a license file
+	writeLicenseTestCommit(t, testRepoPath, "", "")
+	resp, err = client.FindLicense(ctx, &pb.FindLicenseRequest{Repository: testRepo})
+	require.NoError(t, err)
+	require.Empty(t, resp.GetLicenseShortName())
+
+	mitText := "MIT License\n\nPermission is hereby granted, free of charge, to any person obtaining a copy of this software.\n"
+	writeLicenseTestCommit(t, testRepoPath, "LICENSE", mitText)
+	resp, err = client.FindLicense(ctx, &pb.FindLicenseRequest{Repository: testRepo})
+	require.NoError(t, err)
+	require.Equal(t, "mit", resp.GetLicenseShortName())
+}
+
+func TestFindLicenseValidation(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	_, err := client.FindLicense(ctx, &pb.FindLicenseRequest{
+		Repository: &pb.Repository{StorageName: "fake", RelativePath: "repo.git"},
+	})
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+}
+
+// writeLicenseTestCommit commits a README.md plus, when licenseName is
+// not empty, a license file with the given contents.
+func writeLicenseTestCommit(t *testing.T, repoPath, licenseName, licenseText string) {
+	readmeBlob := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader("file contents\n"),
+		"git", "-C", repoPath, "hash-object", "-w", "--stdin")))
+
+	treeSpec := fmt.Sprintf("100644 blob %s\tREADME.md\n", readmeBlob)
+	if licenseName != "" {
+		licenseBlob := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader(licenseText),
+			"git", "-C", repoPath, "hash-object", "-w", "--stdin")))
+		treeSpec += fmt.Sprintf("100644 blob %s\t%s\n", licenseBlob, licenseName)
+	}
+	tree := strings.TrimSpace(string(testhelper.MustRunCommand(t, strings.NewReader(treeSpec), "git", "-C", repoPath, "mktree")))
+
+	commitID := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil,
+		"git", "-C", repoPath, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"commit-tree", "-m", "add license test files", tree)))
+	testhelper.MustRunCommand(t, nil, "git", "-C", repoPath, "update-ref", "refs/heads/master", commitID)
+}
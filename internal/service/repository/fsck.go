@@ -1,23 +1,62 @@
 package repository
 
 import (
+	"bytes"
+	"fmt"
+	"os/exec"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 
 	"golang.org/x/net/context"
-
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// Fsck checks the integrity of a repository. By default only the
+// connectivity of the object graph is verified; with Full set, object
+// contents are checked as well. Problems found by git are reported in
+// the response Error field, not as an RPC error, so the caller can
+// schedule checks across a fleet and collect the findings.
 func (s *server) Fsck(ctx context.Context, req *pb.FsckRequest) (*pb.FsckResponse, error) {
-	client, err := s.RepositoryServiceClient(ctx)
+	repo := req.GetRepository()
+	if repo == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Fsck: empty Repository")
+	}
+
+	repoPath, err := helper.GetPath(repo)
 	if err != nil {
 		return nil, err
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, req.GetRepository())
+	if !helper.IsGitDirectory(repoPath) {
+		return &pb.FsckResponse{
+			Error: []byte(fmt.Sprintf("could not find repository at '%s'", repoPath)),
+		}, nil
+	}
+
+	args := []string{"--git-dir", repoPath, "fsck"}
+	if !req.GetFull() {
+		args = append(args, "--connectivity-only")
+	}
+
+	var output bytes.Buffer
+	cmd, err := command.New(ctx, exec.Command(command.GitPath(), args...), nil, &output, &output)
 	if err != nil {
-		return nil, err
+		return nil, status.Errorf(codes.Internal, "Fsck: cmd start failed: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if _, ok := command.ExitStatus(err); !ok {
+			return nil, status.Errorf(codes.Internal, "Fsck: cmd wait failed: %v", err)
+		}
+
+		return &pb.FsckResponse{
+			Error: []byte(fmt.Sprintf("Could not fsck repository:\n%s", output.String())),
+		}, nil
 	}
 
-	return client.Fsck(clientCtx, req)
+	return &pb.FsckResponse{}, nil
 }
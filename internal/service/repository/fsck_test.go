@@ -79,3 +79,22 @@ func TestFsckFailureSlightlyBrokenRepo(t *testing.T) {
 	assert.NotEmpty(t, string(c.GetError()))
 	assert.Contains(t, string(c.GetError()), "Could not fsck repository")
 }
+
+func TestFsckFullMode(t *testing.T) {
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	c, err := client.Fsck(ctx, &pb.FsckRequest{Repository: testRepo, Full: true})
+	assert.NoError(t, err)
+	assert.NotNil(t, c)
+	assert.Empty(t, c.GetError())
+}
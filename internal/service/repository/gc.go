@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	log "github.com/sirupsen/logrus"
 	"gitlab.com/gitlab-org/gitaly/internal/helper/housekeeping"
@@ -9,8 +11,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
-	"gitlab.com/gitlab-org/gitaly/internal/git"
-	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
 )
 
 func (server) GarbageCollect(ctx context.Context, in *pb.GarbageCollectRequest) (*pb.GarbageCollectResponse, error) {
@@ -26,7 +27,23 @@ func (server) GarbageCollect(ctx context.Context, in *pb.GarbageCollectRequest)
 		args = append(args, "repack.writeBitmaps=false")
 	}
 	args = append(args, "gc")
-	cmd, err := git.Command(ctx, in.GetRepository(), args...)
+
+	repo, err := localrepo.New(in.GetRepository())
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(start time.Time) {
+		housekeepingDurations.WithLabelValues("gc").Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	looseObjectsHistogram.Observe(float64(countLooseObjects(repo.Path())))
+
+	if err := housekeeping.RemoveStaleLocks(repo.Path()); err != nil {
+		ctxlogger.WithError(err).Warn("GarbageCollect: stale lock cleanup failed")
+	}
+
+	cmd, err := repo.Exec(ctx, args...)
 	if err != nil {
 		if _, ok := status.FromError(err); ok {
 			return nil, err
@@ -39,12 +56,7 @@ func (server) GarbageCollect(ctx context.Context, in *pb.GarbageCollectRequest)
 	}
 
 	// Perform housekeeping post GC
-	repoPath, err := helper.GetRepoPath(in.GetRepository())
-	if err != nil {
-		return nil, err
-	}
-
-	err = housekeeping.Perform(ctx, repoPath)
+	err = housekeeping.Perform(ctx, repo.Path())
 	if err != nil {
 		ctxlogger.WithError(err).Warn("Post gc housekeeping failed")
 	}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	housekeepingDurations = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "gitaly_housekeeping_task_duration_seconds",
+			Help:    "Latency of repository housekeeping tasks",
+			Buckets: prometheus.ExponentialBuckets(0.1, 3, 8),
+		},
+		[]string{"task"},
+	)
+
+	looseObjectsHistogram = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "gitaly_housekeeping_loose_objects",
+			Help:    "Number of loose objects found in a repository before housekeeping",
+			Buckets: prometheus.ExponentialBuckets(1, 10, 7),
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(housekeepingDurations, looseObjectsHistogram)
+}
+
+// countLooseObjects counts the files in the two-character fan-out
+// directories under objects/. Errors are ignored: the count feeds a
+// metric and must not fail the housekeeping run.
+func countLooseObjects(repoPath string) int {
+	fanOutDirs, err := filepath.Glob(filepath.Join(repoPath, "objects", "??"))
+	if err != nil {
+		return 0
+	}
+
+	count := 0
+	for _, dir := range fanOutDirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if entry.Mode().IsRegular() {
+				count++
+			}
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,38 @@
+package repository
+
+// progressWriter splits git's sideband progress output into lines and
+// forwards them through send. git redraws in-place counters with '\r',
+// so both '\r' and '\n' terminate a line. Send failures are remembered
+// rather than returned: a vanished client cancels the RPC context,
+// which kills the git process anyway, and aborting the stderr copy
+// early would lose the real clone error.
+type progressWriter struct {
+	send    func(line []byte) error
+	buf     []byte
+	sendErr error
+}
+
+const maxProgressLineLen = 4096
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	start := 0
+	for i, b := range w.buf {
+		if b != '\r' && b != '\n' {
+			continue
+		}
+
+		if line := w.buf[start:i]; len(line) > 0 && w.sendErr == nil {
+			w.sendErr = w.send(line)
+		}
+		start = i + 1
+	}
+
+	w.buf = w.buf[:copy(w.buf, w.buf[start:])]
+	if len(w.buf) > maxProgressLineLen {
+		w.buf = w.buf[:0]
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressWriterSplitsLines(t *testing.T) {
+	var lines []string
+	w := &progressWriter{send: func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	}}
+
+	// git redraws counters with \r and ends phases with \n; chunks can
+	// split lines at any point.
+	_, err := w.Write([]byte("Receiving objects:  10% (1/10)\rReceiving obj"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("ects: 100% (10/10), done.\nResolving deltas: 100% (3/3), done.\n"))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"Receiving objects:  10% (1/10)",
+		"Receiving objects: 100% (10/10), done.",
+		"Resolving deltas: 100% (3/3), done.",
+	}, lines)
+}
+
+func TestProgressWriterDropsOverlongLine(t *testing.T) {
+	var lines []string
+	w := &progressWriter{send: func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	}}
+
+	_, err := w.Write(make([]byte, maxProgressLineLen+1))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("done.\n"))
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"done."}, lines)
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/git/catfile"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	nullOID = "0000000000000000000000000000000000000000"
+
+	// rawChangesPerMessage keeps individual response messages well below
+	// the gRPC message size limit even for pathological path lengths.
+	rawChangesPerMessage = 100
+)
+
+var rawChangeOperations = map[byte]pb.GetRawChangesResponse_RawChange_Operation{
+	'A': pb.GetRawChangesResponse_RawChange_ADDED,
+	'C': pb.GetRawChangesResponse_RawChange_COPIED,
+	'D': pb.GetRawChangesResponse_RawChange_DELETED,
+	'M': pb.GetRawChangesResponse_RawChange_MODIFIED,
+	'R': pb.GetRawChangesResponse_RawChange_RENAMED,
+	'T': pb.GetRawChangesResponse_RawChange_TYPE_CHANGED,
+}
+
+// GetRawChanges returns the files changed between two commits as
+// structured records instead of patches.
+func (s *server) GetRawChanges(req *pb.GetRawChangesRequest, stream pb.RepositoryService_GetRawChangesServer) error {
+	ctx := stream.Context()
+	repo := req.GetRepository()
+
+	if repo == nil {
+		return status.Errorf(codes.InvalidArgument, "GetRawChanges: empty Repository")
+	}
+
+	for _, revision := range []string{req.GetFromRevision(), req.GetToRevision()} {
+		if err := validateRawChangesRevision(ctx, repo, revision); err != nil {
+			return err
+		}
+	}
+
+	changes, err := rawChanges(ctx, repo, req.GetFromRevision(), req.GetToRevision())
+	if err != nil {
+		return status.Errorf(codes.Internal, "GetRawChanges: %v", err)
+	}
+
+	for len(changes) > 0 {
+		n := rawChangesPerMessage
+		if n > len(changes) {
+			n = len(changes)
+		}
+
+		if err := stream.Send(&pb.GetRawChangesResponse{RawChanges: changes[:n]}); err != nil {
+			return err
+		}
+		changes = changes[n:]
+	}
+
+	return nil
+}
+
+func validateRawChangesRevision(ctx context.Context, repo *pb.Repository, revision string) error {
+	if revision == "" {
+		return status.Errorf(codes.InvalidArgument, "GetRawChanges: empty revision")
+	}
+
+	cmd, err := git.Command(ctx, repo, "rev-parse", "--verify", "--quiet", revision+"^{commit}")
+	if err != nil {
+		return status.Errorf(codes.Internal, "GetRawChanges: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return status.Errorf(codes.InvalidArgument, "GetRawChanges: invalid revision %q", revision)
+	}
+
+	return nil
+}
+
+func rawChanges(ctx context.Context, repo *pb.Repository, from, to string) ([]*pb.GetRawChangesResponse_RawChange, error) {
+	cmd, err := git.Command(ctx, repo, "diff-tree", "-z", "-r", "-M", from, to)
+	if err != nil {
+		return nil, fmt.Errorf("diff-tree: %v", err)
+	}
+
+	out, err := ioutil.ReadAll(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("diff-tree output: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("diff-tree: %v", err)
+	}
+
+	changes, err := parseRawChanges(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := setBlobSizes(ctx, repo, changes); err != nil {
+		return nil, fmt.Errorf("blob sizes: %v", err)
+	}
+
+	return changes, nil
+}
+
+// parseRawChanges parses NUL-terminated `git diff-tree -z --raw` output:
+// a header ":oldmode newmode oldsha newsha status" followed by one path,
+// or two paths for renames and copies.
+func parseRawChanges(out []byte) ([]*pb.GetRawChangesResponse_RawChange, error) {
+	var changes []*pb.GetRawChangesResponse_RawChange
+
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	for i := 0; i < len(fields); {
+		header := fields[i]
+		if header == "" {
+			i++
+			continue
+		}
+
+		headerFields := strings.Fields(strings.TrimPrefix(header, ":"))
+		if !strings.HasPrefix(header, ":") || len(headerFields) != 5 || i+1 >= len(fields) {
+			return nil, fmt.Errorf("unexpected diff-tree header %q", header)
+		}
+
+		oldMode, err := strconv.ParseInt(headerFields[0], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parse old mode: %v", err)
+		}
+		newMode, err := strconv.ParseInt(headerFields[1], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("parse new mode: %v", err)
+		}
+
+		rawOperation := headerFields[4]
+		change := &pb.GetRawChangesResponse_RawChange{
+			Operation:    rawChangeOperations[rawOperation[0]],
+			RawOperation: rawOperation,
+			OldMode:      int32(oldMode),
+			NewMode:      int32(newMode),
+		}
+
+		if newSha := headerFields[3]; newSha != nullOID {
+			change.BlobId = newSha
+		}
+
+		switch change.Operation {
+		case pb.GetRawChangesResponse_RawChange_RENAMED, pb.GetRawChangesResponse_RawChange_COPIED:
+			if i+2 >= len(fields) {
+				return nil, fmt.Errorf("missing destination path after %q", header)
+			}
+			change.OldPath = fields[i+1]
+			change.NewPath = fields[i+2]
+			i += 3
+		case pb.GetRawChangesResponse_RawChange_DELETED:
+			change.OldPath = fields[i+1]
+			i += 2
+		case pb.GetRawChangesResponse_RawChange_ADDED:
+			change.NewPath = fields[i+1]
+			i += 2
+		default:
+			change.OldPath = fields[i+1]
+			change.NewPath = fields[i+1]
+			i += 2
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+// setBlobSizes looks up the sizes of all new blobs in one `git cat-file
+// --batch-check` invocation, in record order.
+func setBlobSizes(ctx context.Context, repo *pb.Repository, changes []*pb.GetRawChangesResponse_RawChange) error {
+	var lookup []*pb.GetRawChangesResponse_RawChange
+	var stdin bytes.Buffer
+
+	for _, change := range changes {
+		if change.BlobId == "" {
+			continue
+		}
+
+		lookup = append(lookup, change)
+		fmt.Fprintln(&stdin, change.BlobId)
+	}
+
+	if len(lookup) == 0 {
+		return nil
+	}
+
+	repoPath, err := helper.GetRepoPath(repo)
+	if err != nil {
+		return err
+	}
+
+	osCmd := exec.Command(command.GitPath(), "--git-dir", repoPath, "cat-file", "--batch-check")
+	cmd, err := command.New(ctx, osCmd, &stdin, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	stdout := bufio.NewReader(cmd)
+	for _, change := range lookup {
+		info, err := catfile.ParseObjectInfo(stdout)
+		if err != nil {
+			return err
+		}
+
+		// Submodule entries point at commits in another repository; their
+		// lookup comes back missing and the size stays zero.
+		if info.Type == "blob" {
+			change.Size_ = info.Size
+		}
+	}
+
+	return cmd.Wait()
+}
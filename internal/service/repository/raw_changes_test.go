@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulGetRawChangesRequest(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	fromRevision := "1a0b36b3cdad1d2ee32457c102a8c0b7056fa863"
+	toRevision := "54cec5282aa9f21856362fe321c800c236a61615"
+
+	request := &pb.GetRawChangesRequest{
+		Repository:   testRepo,
+		FromRevision: fromRevision,
+		ToRevision:   toRevision,
+	}
+
+	stream, err := client.GetRawChanges(ctx, request)
+	require.NoError(t, err)
+
+	var changes []*pb.GetRawChangesResponse_RawChange
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		changes = append(changes, resp.GetRawChanges()...)
+	}
+
+	// Build the expected paths from git itself so the test does not
+	// hard-code the fixture contents.
+	rawOut := testhelper.MustRunCommand(t, nil,
+		"git", "-C", testRepoPath, "diff-tree", "-z", "-r", "-M", fromRevision, toRevision)
+
+	var expectedPaths []string
+	for _, field := range strings.Split(strings.TrimSuffix(string(rawOut), "\x00"), "\x00") {
+		if field != "" && !strings.HasPrefix(field, ":") {
+			expectedPaths = append(expectedPaths, field)
+		}
+	}
+
+	var receivedPaths []string
+	for _, change := range changes {
+		require.NotEqual(t, pb.GetRawChangesResponse_RawChange_UNKNOWN, change.GetOperation())
+		require.NotEmpty(t, change.GetRawOperation())
+
+		if change.GetOperation() == pb.GetRawChangesResponse_RawChange_DELETED {
+			require.Empty(t, change.GetBlobId())
+		} else {
+			require.NotEmpty(t, change.GetBlobId())
+		}
+
+		for _, path := range []string{change.GetOldPath(), change.GetNewPath()} {
+			if path != "" {
+				receivedPaths = append(receivedPaths, path)
+			}
+		}
+	}
+
+	require.NotEmpty(t, changes)
+	for _, path := range expectedPaths {
+		require.Contains(t, receivedPaths, path)
+	}
+}
+
+func TestFailedGetRawChangesRequestDueToValidations(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	testCases := []struct {
+		desc    string
+		request *pb.GetRawChangesRequest
+		code    codes.Code
+	}{
+		{
+			desc: "empty repository",
+			request: &pb.GetRawChangesRequest{
+				FromRevision: "1a0b36b3cdad1d2ee32457c102a8c0b7056fa863",
+				ToRevision:   "54cec5282aa9f21856362fe321c800c236a61615",
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "empty from revision",
+			request: &pb.GetRawChangesRequest{
+				Repository: testRepo,
+				ToRevision: "54cec5282aa9f21856362fe321c800c236a61615",
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "unknown to revision",
+			request: &pb.GetRawChangesRequest{
+				Repository:   testRepo,
+				FromRevision: "1a0b36b3cdad1d2ee32457c102a8c0b7056fa863",
+				ToRevision:   "1234567890123456789012345678901234567890",
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.desc, func(t *testing.T) {
+			stream, err := client.GetRawChanges(ctx, testCase.request)
+			require.NoError(t, err)
+
+			_, err = stream.Recv()
+			testhelper.AssertGrpcError(t, err, testCase.code, "")
+		})
+	}
+}
@@ -2,32 +2,33 @@ package repository
 
 import (
 	"fmt"
+	"strings"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
-
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/helper/worktree"
 
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// IsRebaseInProgress reports whether the worktree a UserRebase with the
+// given ID would use exists and is fresh, so the client can recover the
+// state of a crashed rebase without filesystem access.
 func (s *server) IsRebaseInProgress(ctx context.Context, req *pb.IsRebaseInProgressRequest) (*pb.IsRebaseInProgressResponse, error) {
 	if err := validateIsRebaseInProgressRequest(req); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "IsRebaseInProgress: %v", err)
 	}
 
-	client, err := s.RepositoryServiceClient(ctx)
+	repoPath, err := helper.GetRepoPath(req.GetRepository())
 	if err != nil {
 		return nil, err
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, req.GetRepository())
-	if err != nil {
-		return nil, err
-	}
+	inProgress := worktree.InProgress(worktree.Path(repoPath, worktree.RebasePrefix, req.GetRebaseId()))
 
-	return client.IsRebaseInProgress(clientCtx, req)
+	return &pb.IsRebaseInProgressResponse{InProgress: inProgress}, nil
 }
 
 func validateIsRebaseInProgressRequest(req *pb.IsRebaseInProgressRequest) error {
@@ -39,5 +40,9 @@ func validateIsRebaseInProgressRequest(req *pb.IsRebaseInProgressRequest) error
 		return fmt.Errorf("empty RebaseId")
 	}
 
+	if strings.Contains(req.GetRebaseId(), "/") {
+		return fmt.Errorf("RebaseId contains '/'")
+	}
+
 	return nil
 }
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
@@ -9,27 +11,44 @@ import (
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/helper/housekeeping"
 )
 
 func (server) RepackFull(ctx context.Context, in *pb.RepackFullRequest) (*pb.RepackFullResponse, error) {
-	if err := repackCommand(ctx, "RepackFull", in.GetRepository(), in.GetCreateBitmap(), "-A", "--pack-kept-objects"); err != nil {
+	if err := repackCommand(ctx, "RepackFull", "repack_full", in.GetRepository(), in.GetCreateBitmap(), "-A", "--pack-kept-objects"); err != nil {
 		return nil, err
 	}
 	return &pb.RepackFullResponse{}, nil
 }
 
 func (server) RepackIncremental(ctx context.Context, in *pb.RepackIncrementalRequest) (*pb.RepackIncrementalResponse, error) {
-	if err := repackCommand(ctx, "RepackIncremental", in.GetRepository(), false); err != nil {
+	if err := repackCommand(ctx, "RepackIncremental", "repack_incremental", in.GetRepository(), in.GetCreateBitmap()); err != nil {
 		return nil, err
 	}
 	return &pb.RepackIncrementalResponse{}, nil
 }
 
-func repackCommand(ctx context.Context, rpcName string, repo *pb.Repository, bitmap bool, args ...string) error {
+func repackCommand(ctx context.Context, rpcName, task string, repo *pb.Repository, bitmap bool, args ...string) error {
 	grpc_logrus.Extract(ctx).WithFields(log.Fields{
 		"WriteBitmaps": bitmap,
 	}).Debug(rpcName)
 
+	repoPath, err := helper.GetRepoPath(repo)
+	if err != nil {
+		return err
+	}
+
+	defer func(start time.Time) {
+		housekeepingDurations.WithLabelValues(task).Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	looseObjectsHistogram.Observe(float64(countLooseObjects(repoPath)))
+
+	if err := housekeeping.RemoveStaleLocks(repoPath); err != nil {
+		grpc_logrus.Extract(ctx).WithError(err).Warnf("%s: stale lock cleanup failed", rpcName)
+	}
+
 	var cmdArgs []string
 	if bitmap {
 		cmdArgs = []string{"-c", "repack.writeBitmaps=true", "repack", "-d"}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/helper/lines"
+)
+
+const (
+	// surroundingLines is the number of context lines sent with each
+	// content match, like the pre-indexing code search in gitlab-ce
+	surroundingLines = 2
+
+	// Hard caps so a query matching most of the repository cannot
+	// stream unbounded output. Responses are truncated, not failed.
+	maxContentMatches  = 1000
+	maxFilenameMatches = 10000
+)
+
+// contentMatchSeparator is what `git grep` prints between match groups
+// when context lines are requested.
+var contentMatchSeparator = []byte("--\n")
+
+// SearchFilesByContent searches file content at a ref with `git grep`
+// and streams the matches, each with surrounding context lines.
+func (s *server) SearchFilesByContent(req *pb.SearchFilesByContentRequest, stream pb.RepositoryService_SearchFilesByContentServer) error {
+	if err := validateSearchFilesRequest(req.GetQuery(), req.GetRef()); err != nil {
+		return status.Errorf(codes.InvalidArgument, "SearchFilesByContent: %v", err)
+	}
+
+	ctx := stream.Context()
+	cmd, err := git.Command(ctx, req.GetRepository(), "grep",
+		"--ignore-case", "-I", "--line-number", "--extended-regexp",
+		fmt.Sprintf("--before-context=%d", surroundingLines),
+		fmt.Sprintf("--after-context=%d", surroundingLines),
+		"-e", req.GetQuery(), string(req.GetRef()), "--")
+	if err != nil {
+		return err
+	}
+
+	sender := &searchFilesSender{send: func(matches [][]byte) error {
+		return stream.Send(&pb.SearchFilesByContentResponse{Matches: matches})
+	}}
+
+	matchCount, err := sendMatchGroups(cmd, sender)
+	if err != nil {
+		return status.Errorf(codes.Internal, "SearchFilesByContent: %v", err)
+	}
+
+	if matchCount >= maxContentMatches {
+		// Truncated: leave the command to be reaped when the RPC ends
+		return sender.flush()
+	}
+
+	if err := cmd.Wait(); err != nil {
+		// Exit status 1 is how grep reports "no matches"
+		if code, ok := command.ExitStatus(err); ok && code == 1 {
+			return sender.flush()
+		}
+		return status.Errorf(codes.Internal, "SearchFilesByContent: %v", err)
+	}
+
+	return sender.flush()
+}
+
+// SearchFilesByName lists the files at a ref whose path matches a
+// regular expression.
+func (s *server) SearchFilesByName(req *pb.SearchFilesByNameRequest, stream pb.RepositoryService_SearchFilesByNameServer) error {
+	if err := validateSearchFilesRequest(req.GetQuery(), req.GetRef()); err != nil {
+		return status.Errorf(codes.InvalidArgument, "SearchFilesByName: %v", err)
+	}
+
+	filter, err := regexp.Compile(req.GetQuery())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "SearchFilesByName: invalid query: %v", err)
+	}
+
+	ctx := stream.Context()
+	cmd, err := git.Command(ctx, req.GetRepository(), "ls-tree", "--full-tree", "--name-only", "-r", "-z", string(req.GetRef()), "--")
+	if err != nil {
+		return err
+	}
+
+	sender := &searchFilesSender{send: func(files [][]byte) error {
+		return stream.Send(&pb.SearchFilesByNameResponse{Files: files})
+	}}
+
+	matchCount := 0
+	scanner := bufio.NewScanner(cmd)
+	scanner.Split(scanNullDelimited)
+	for scanner.Scan() && matchCount < maxFilenameMatches {
+		if !filter.Match(scanner.Bytes()) {
+			continue
+		}
+
+		matchCount++
+		if err := sender.add(scanner.Bytes()); err != nil {
+			return status.Errorf(codes.Internal, "SearchFilesByName: %v", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return status.Errorf(codes.Internal, "SearchFilesByName: %v", err)
+	}
+
+	if matchCount >= maxFilenameMatches {
+		return sender.flush()
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return status.Errorf(codes.Internal, "SearchFilesByName: %v", err)
+	}
+
+	return sender.flush()
+}
+
+func validateSearchFilesRequest(query string, ref []byte) error {
+	if query == "" {
+		return fmt.Errorf("empty Query")
+	}
+	if err := git.ValidateRevision(ref); err != nil {
+		return fmt.Errorf("Ref: %v", err)
+	}
+
+	return nil
+}
+
+// searchFilesSender batches matches until they reach the message size
+// limit, so small matches share a response message.
+type searchFilesSender struct {
+	send  func([][]byte) error
+	batch [][]byte
+	size  int
+}
+
+func (s *searchFilesSender) add(match []byte) error {
+	s.batch, _ = lines.CopyAndAppend(s.batch, match)
+	s.size += len(match)
+
+	if s.size > lines.MaxMsgSize {
+		return s.flush()
+	}
+
+	return nil
+}
+
+func (s *searchFilesSender) flush() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+
+	err := s.send(s.batch)
+	s.batch = nil
+	s.size = 0
+
+	return err
+}
+
+// sendMatchGroups splits `git grep` output into match groups at the
+// "--" separator lines and feeds them to the sender, stopping at
+// maxContentMatches.
+func sendMatchGroups(r io.Reader, sender *searchFilesSender) (int, error) {
+	reader := bufio.NewReader(r)
+
+	matchCount := 0
+	var group []byte
+	for matchCount < maxContentMatches {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return matchCount, err
+		}
+
+		if bytes.Equal(line, contentMatchSeparator) || (err == io.EOF && len(line) == 0) {
+			if len(group) > 0 {
+				matchCount++
+				if addErr := sender.add(group); addErr != nil {
+					return matchCount, addErr
+				}
+				group = nil
+			}
+		} else {
+			group = append(group, line...)
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	if len(group) > 0 && matchCount < maxContentMatches {
+		matchCount++
+		if err := sender.add(group); err != nil {
+			return matchCount, err
+		}
+	}
+
+	return matchCount, nil
+}
+
+func scanNullDelimited(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"io"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/stretchr/testify/require"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+)
+
+func TestSearchFilesByContent(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	writeArchiveTestCommit(t, testRepoPath)
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	stream, err := client.SearchFilesByContent(ctx, &pb.SearchFilesByContentRequest{
+		Repository: testRepo,
+		Query:      "file contents",
+		Ref:        []byte("master"),
+	})
+	require.NoError(t, err)
+
+	matches, err := consumeContentMatches(stream)
+	require.NoError(t, err)
+
+	require.Len(t, matches, 2, "README.md and sub/file.txt both contain the query")
+	require.Contains(t, string(matches[0]), "master:README.md:1:file contents")
+	require.Contains(t, string(matches[1]), "master:sub/file.txt:1:file contents")
+
+	// A query without matches streams no messages and no error
+	stream, err = client.SearchFilesByContent(ctx, &pb.SearchFilesByContentRequest{
+		Repository: testRepo,
+		Query:      "no such needle",
+		Ref:        []byte("master"),
+	})
+	require.NoError(t, err)
+
+	matches, err = consumeContentMatches(stream)
+	require.NoError(t, err)
+	require.Empty(t, matches)
+}
+
+func TestSearchFilesByContentValidation(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	testCases := []struct {
+		desc  string
+		query string
+		ref   string
+	}{
+		{desc: "empty query", query: "", ref: "master"},
+		{desc: "empty ref", query: "needle", ref: ""},
+		{desc: "ref looks like an option", query: "needle", ref: "--open-files-in-pager=touch"},
+		{desc: "ref with whitespace", query: "needle", ref: "master branch"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			stream, err := client.SearchFilesByContent(ctx, &pb.SearchFilesByContentRequest{
+				Repository: testRepo,
+				Query:      tc.query,
+				Ref:        []byte(tc.ref),
+			})
+			require.NoError(t, err)
+
+			_, err = consumeContentMatches(stream)
+			testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+		})
+	}
+}
+
+func TestSearchFilesByName(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.InitBareRepo(t)
+	defer cleanupFn()
+
+	writeArchiveTestCommit(t, testRepoPath)
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	testCases := []struct {
+		desc  string
+		query string
+		files []string
+	}{
+		{desc: "by extension", query: `\.md$`, files: []string{"README.md"}},
+		{desc: "in subdirectory", query: "^sub/", files: []string{"sub/file.txt"}},
+		{desc: "no matches", query: "no-such-file", files: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			stream, err := client.SearchFilesByName(ctx, &pb.SearchFilesByNameRequest{
+				Repository: testRepo,
+				Query:      tc.query,
+				Ref:        []byte("master"),
+			})
+			require.NoError(t, err)
+
+			files, err := consumeFilenameMatches(stream)
+			require.NoError(t, err)
+
+			var fileStrings []string
+			for _, f := range files {
+				fileStrings = append(fileStrings, string(f))
+			}
+			require.Equal(t, tc.files, fileStrings)
+		})
+	}
+
+	t.Run("invalid regexp", func(t *testing.T) {
+		stream, err := client.SearchFilesByName(ctx, &pb.SearchFilesByNameRequest{
+			Repository: testRepo,
+			Query:      "*",
+			Ref:        []byte("master"),
+		})
+		require.NoError(t, err)
+
+		_, err = consumeFilenameMatches(stream)
+		testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+	})
+}
+
+func consumeContentMatches(stream pb.RepositoryService_SearchFilesByContentClient) ([][]byte, error) {
+	var matches [][]byte
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return matches, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, resp.GetMatches()...)
+	}
+}
+
+func consumeFilenameMatches(stream pb.RepositoryService_SearchFilesByNameClient) ([][]byte, error) {
+	var files [][]byte
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, resp.GetFiles()...)
+	}
+}
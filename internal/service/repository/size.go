@@ -2,9 +2,12 @@ package repository
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os/exec"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"gitlab.com/gitlab-org/gitaly/internal/command"
 	"gitlab.com/gitlab-org/gitaly/internal/helper"
@@ -13,6 +16,8 @@ import (
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (s *server) RepositorySize(ctx context.Context, in *pb.RepositorySizeRequest) (*pb.RepositorySizeResponse, error) {
@@ -21,38 +26,88 @@ func (s *server) RepositorySize(ctx context.Context, in *pb.RepositorySizeReques
 		return nil, err
 	}
 
-	cmd, err := command.New(ctx, exec.Command("du", "-sk", path), nil, nil, nil)
+	for _, exclude := range in.GetExcludePaths() {
+		if err := validateExcludePath(exclude); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "RepositorySize: %v", err)
+		}
+	}
+
+	return &pb.RepositorySizeResponse{Size: dirSizeKb(ctx, path, in.GetExcludePaths())}, nil
+}
+
+// GetObjectDirectorySize reports the size of the repository's object
+// directory only, honoring a quarantine GIT_OBJECT_DIRECTORY when one is
+// set on the repository.
+func (s *server) GetObjectDirectorySize(ctx context.Context, in *pb.GetObjectDirectorySizeRequest) (*pb.GetObjectDirectorySizeResponse, error) {
+	repoPath, err := helper.GetRepoPath(in.GetRepository())
+	if err != nil {
+		return nil, err
+	}
+
+	objectDir := in.GetRepository().GetGitObjectDirectory()
+	if objectDir == "" {
+		objectDir = "objects"
+	}
+	if err := validateExcludePath(objectDir); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "GetObjectDirectorySize: %v", err)
+	}
+
+	return &pb.GetObjectDirectorySizeResponse{Size: dirSizeKb(ctx, filepath.Join(repoPath, objectDir), nil)}, nil
+}
+
+func validateExcludePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("empty path")
+	}
+	if filepath.IsAbs(path) {
+		return fmt.Errorf("path %q must be relative", path)
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == ".." {
+			return fmt.Errorf("path %q must not escape the repository", path)
+		}
+	}
+	return nil
+}
+
+// dirSizeKb runs `du -sk` on path. Sizing is best effort: quota checks
+// prefer a zero size over a failed request, so errors are logged and
+// swallowed.
+func dirSizeKb(ctx context.Context, path string, excludes []string) int64 {
+	args := []string{"-sk"}
+	for _, exclude := range excludes {
+		args = append(args, "--exclude="+filepath.Join(path, exclude))
+	}
+	args = append(args, path)
+
+	cmd, err := command.New(ctx, exec.Command("du", args...), nil, nil, nil)
 	if err != nil {
 		grpc_logrus.Extract(ctx).WithError(err).Warn("ignoring du command error")
-		return repositorySizeResponse(0), nil
+		return 0
 	}
 
 	sizeLine, err := ioutil.ReadAll(cmd)
 	if err != nil {
 		grpc_logrus.Extract(ctx).WithError(err).Warn("ignoring command read error")
-		return repositorySizeResponse(0), nil
+		return 0
 	}
 
 	if err := cmd.Wait(); err != nil {
 		grpc_logrus.Extract(ctx).WithError(err).Warn("ignoring du wait error")
-		return repositorySizeResponse(0), nil
+		return 0
 	}
 
 	sizeParts := bytes.Split(sizeLine, []byte("\t"))
 	if len(sizeParts) != 2 {
-		grpc_logrus.Extract(ctx).Warn("ignoring du malformed output: %q", sizeLine)
-		return repositorySizeResponse(0), nil
+		grpc_logrus.Extract(ctx).Warnf("ignoring du malformed output: %q", sizeLine)
+		return 0
 	}
 
 	size, err := strconv.ParseInt(string(sizeParts[0]), 10, 0)
 	if err != nil {
 		grpc_logrus.Extract(ctx).WithError(err).Warn("ignoring parsing size error")
-		return repositorySizeResponse(0), nil
+		return 0
 	}
 
-	return repositorySizeResponse(size), nil
-}
-
-func repositorySizeResponse(size int64) *pb.RepositorySizeResponse {
-	return &pb.RepositorySizeResponse{Size: size}
+	return size
 }
@@ -81,3 +81,54 @@ func TestFailedRepositorySizeRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestRepositorySizeRequestWithExcludePaths(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fullResponse, err := client.RepositorySize(ctx, &pb.RepositorySizeRequest{Repository: testRepo})
+	require.NoError(t, err)
+
+	excludedResponse, err := client.RepositorySize(ctx, &pb.RepositorySizeRequest{
+		Repository:   testRepo,
+		ExcludePaths: []string{"objects"},
+	})
+	require.NoError(t, err)
+
+	require.True(t, excludedResponse.Size < fullResponse.Size,
+		"size of %q without objects (%d) must be smaller than the full size (%d)",
+		testRepoPath, excludedResponse.Size, fullResponse.Size)
+
+	_, err = client.RepositorySize(ctx, &pb.RepositorySizeRequest{
+		Repository:   testRepo,
+		ExcludePaths: []string{"../other-repo"},
+	})
+	testhelper.AssertGrpcError(t, err, codes.InvalidArgument, "")
+}
+
+func TestSuccessfulGetObjectDirectorySizeRequest(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	response, err := client.GetObjectDirectorySize(ctx, &pb.GetObjectDirectorySizeRequest{Repository: testRepo})
+	require.NoError(t, err)
+
+	require.True(t, response.Size > 0, "size must be greater than zero")
+}
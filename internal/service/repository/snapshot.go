@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/streamio"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// snapshotPaths are the parts of a bare repository included in a
+// snapshot. Everything else (hooks, config, worktrees) is host-specific
+// and must not travel to another server.
+var snapshotPaths = []string{"HEAD", "packed-refs", "refs", "objects"}
+
+// GetSnapshot streams a tar archive of the bare repository so that
+// storage moves and disaster recovery pulls can run over gRPC instead of
+// rsync over SSH. Temporary files and stale lock files are skipped: the
+// snapshot of a live repository is best-effort and a subsequent fetch
+// must reconcile refs that moved while the archive was being written.
+func (s *server) GetSnapshot(req *pb.GetSnapshotRequest, stream pb.RepositoryService_GetSnapshotServer) error {
+	repo := req.GetRepository()
+	if repo == nil {
+		return status.Errorf(codes.InvalidArgument, "GetSnapshot: empty Repository")
+	}
+
+	repoPath, err := helper.GetRepoPath(repo)
+	if err != nil {
+		return err
+	}
+
+	writer := streamio.NewWriter(func(p []byte) error {
+		return stream.Send(&pb.GetSnapshotResponse{Data: p})
+	})
+
+	tarWriter := tar.NewWriter(writer)
+
+	for _, path := range snapshotPaths {
+		if err := addToSnapshot(tarWriter, repoPath, path); err != nil {
+			return status.Errorf(codes.Internal, "GetSnapshot: building snapshot failed: %v", err)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return status.Errorf(codes.Internal, "GetSnapshot: flushing snapshot failed: %v", err)
+	}
+
+	return nil
+}
+
+// addToSnapshot archives path, relative to repoPath, recursing into
+// directories. A path that does not exist (e.g. packed-refs) is skipped.
+func addToSnapshot(tarWriter *tar.Writer, repoPath, path string) error {
+	return filepath.Walk(filepath.Join(repoPath, path), func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		relPath, err := filepath.Rel(repoPath, fullPath)
+		if err != nil {
+			return err
+		}
+
+		if skipInSnapshot(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Sockets, pipes and symlinks do not belong in a bare repository
+		// and are not archived.
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = relPath
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		file, err := os.Open(fullPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+}
+
+// skipInSnapshot reports whether an entry is a temporary file or a lock
+// left behind by git and must not be part of the snapshot.
+func skipInSnapshot(name string) bool {
+	return strings.HasSuffix(name, ".lock") || strings.HasPrefix(name, "tmp_")
+}
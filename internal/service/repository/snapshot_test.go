@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+	"gitlab.com/gitlab-org/gitaly/streamio"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulGetSnapshotRequest(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	// Temporary files and locks must not end up in the snapshot.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testRepoPath, "HEAD.lock"), nil, 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(testRepoPath, "objects", "tmp_object123"), nil, 0644))
+
+	c, err := client.GetSnapshot(ctx, &pb.GetSnapshotRequest{Repository: testRepo})
+	require.NoError(t, err)
+
+	reader := streamio.NewReader(func() ([]byte, error) {
+		response, err := c.Recv()
+		return response.GetData(), err
+	})
+
+	entries := make(map[string]bool)
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		entries[header.Name] = true
+
+		require.False(t, strings.HasSuffix(header.Name, ".lock"), "lock file %q in snapshot", header.Name)
+		require.NotContains(t, header.Name, "tmp_", "temporary file %q in snapshot", header.Name)
+		require.False(t, strings.HasPrefix(header.Name, "hooks/"), "hook %q in snapshot", header.Name)
+	}
+
+	require.True(t, entries["HEAD"], "snapshot must contain HEAD")
+	require.True(t, entries["refs/"] || entries["packed-refs"], "snapshot must contain refs")
+	require.True(t, entries["objects/"], "snapshot must contain objects")
+}
+
+func TestFailedGetSnapshotRequestDueToValidations(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testCases := []struct {
+		desc    string
+		request *pb.GetSnapshotRequest
+		code    codes.Code
+	}{
+		{
+			desc:    "empty repository",
+			request: &pb.GetSnapshotRequest{},
+			code:    codes.InvalidArgument,
+		},
+		{
+			desc: "non-existing repository",
+			request: &pb.GetSnapshotRequest{
+				Repository: &pb.Repository{StorageName: "default", RelativePath: "not-a-repo.git"},
+			},
+			code: codes.NotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			stream, err := client.GetSnapshot(ctx, testCase.request)
+			require.NoError(t, err)
+
+			_, err = stream.Recv()
+			require.NotEqual(t, io.EOF, err)
+			testhelper.AssertGrpcError(t, err, testCase.code, "")
+		})
+	}
+}
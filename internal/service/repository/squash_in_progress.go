@@ -1,11 +1,47 @@
 package repository
 
 import (
+	"fmt"
+	"strings"
+
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/helper/worktree"
+
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-func (*server) IsSquashInProgress(context.Context, *pb.IsSquashInProgressRequest) (*pb.IsSquashInProgressResponse, error) {
-	return nil, helper.Unimplemented
+// IsSquashInProgress reports whether the worktree a UserSquash with the
+// given ID would use exists and is fresh.
+func (s *server) IsSquashInProgress(ctx context.Context, req *pb.IsSquashInProgressRequest) (*pb.IsSquashInProgressResponse, error) {
+	if err := validateIsSquashInProgressRequest(req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "IsSquashInProgress: %v", err)
+	}
+
+	repoPath, err := helper.GetRepoPath(req.GetRepository())
+	if err != nil {
+		return nil, err
+	}
+
+	inProgress := worktree.InProgress(worktree.Path(repoPath, worktree.SquashPrefix, req.GetSquashId()))
+
+	return &pb.IsSquashInProgressResponse{InProgress: inProgress}, nil
+}
+
+func validateIsSquashInProgressRequest(req *pb.IsSquashInProgressRequest) error {
+	if req.GetRepository() == nil {
+		return fmt.Errorf("empty Repository")
+	}
+
+	if req.GetSquashId() == "" {
+		return fmt.Errorf("empty SquashId")
+	}
+
+	if strings.Contains(req.GetSquashId(), "/") {
+		return fmt.Errorf("SquashId contains '/'")
+	}
+
+	return nil
 }
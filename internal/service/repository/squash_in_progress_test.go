@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestSuccessfulIsSquashInProgressRequest(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo1, testRepo1Path, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepo1Path, "worktree", "add", "--detach", path.Join(testRepo1Path, "gitlab-worktree", "squash-1"), "master")
+
+	staleWorktreePath := path.Join(testRepo1Path, "gitlab-worktree", "squash-2")
+	testhelper.MustRunCommand(t, nil, "git", "-C", testRepo1Path, "worktree", "add", "--detach", staleWorktreePath, "master")
+	staleTime := time.Now().Add(-16 * time.Minute)
+	require.NoError(t, os.Chtimes(staleWorktreePath, staleTime, staleTime))
+
+	testRepo2, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	testCases := []struct {
+		desc       string
+		request    *pb.IsSquashInProgressRequest
+		inProgress bool
+	}{
+		{
+			desc: "squash in progress",
+			request: &pb.IsSquashInProgressRequest{
+				Repository: testRepo1,
+				SquashId:   "1",
+			},
+			inProgress: true,
+		},
+		{
+			desc: "stale squash worktree",
+			request: &pb.IsSquashInProgressRequest{
+				Repository: testRepo1,
+				SquashId:   "2",
+			},
+			inProgress: false,
+		},
+		{
+			desc: "no squash in progress",
+			request: &pb.IsSquashInProgressRequest{
+				Repository: testRepo2,
+				SquashId:   "2",
+			},
+			inProgress: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			response, err := client.IsSquashInProgress(ctx, testCase.request)
+			require.NoError(t, err)
+
+			require.Equal(t, testCase.inProgress, response.InProgress)
+		})
+	}
+}
+
+func TestFailedIsSquashInProgressRequestDueToValidations(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testCases := []struct {
+		desc    string
+		request *pb.IsSquashInProgressRequest
+		code    codes.Code
+	}{
+		{
+			desc:    "empty repository",
+			request: &pb.IsSquashInProgressRequest{SquashId: "1"},
+			code:    codes.InvalidArgument,
+		},
+		{
+			desc:    "empty squash id",
+			request: &pb.IsSquashInProgressRequest{Repository: &pb.Repository{}},
+			code:    codes.InvalidArgument,
+		},
+		{
+			desc: "squash id with slash",
+			request: &pb.IsSquashInProgressRequest{
+				Repository: &pb.Repository{},
+				SquashId:   "1/2",
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			_, err := client.IsSquashInProgress(ctx, testCase.request)
+			testhelper.AssertGrpcError(t, err, testCase.code, "")
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"golang.org/x/net/context"
+)
+
+func (s *server) WriteMergeRequestRefs(ctx context.Context, req *pb.WriteMergeRequestRefsRequest) (*pb.WriteMergeRequestRefsResponse, error) {
+	if err := validateWriteMergeRequestRefsRequest(req); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "WriteMergeRequestRefs: %v", err)
+	}
+
+	repo, err := localrepo.New(req.GetRepository())
+	if err != nil {
+		return nil, err
+	}
+
+	headCommitID, err := resolveMergeRequestSource(ctx, repo, req)
+	if err != nil {
+		return nil, err
+	}
+
+	refPrefix := fmt.Sprintf("refs/merge-requests/%d", req.MergeRequestIid)
+
+	// 'git update-ref --stdin' applies all updates in a single transaction, so
+	// the head and merge refs move together or not at all.
+	var stdin bytes.Buffer
+	fmt.Fprintf(&stdin, "update %s/head\x00%s\x00\x00", refPrefix, headCommitID)
+	if req.MergeCommitId != "" {
+		fmt.Fprintf(&stdin, "update %s/merge\x00%s\x00\x00", refPrefix, req.MergeCommitId)
+	}
+
+	cmd, err := repo.ExecWithStdin(ctx, &stdin, "update-ref", "-z", "--stdin")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "WriteMergeRequestRefs: cmd: %v", err)
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, status.Errorf(codes.Internal, "WriteMergeRequestRefs: update-ref: %v", err)
+	}
+
+	return &pb.WriteMergeRequestRefsResponse{HeadCommitId: headCommitID}, nil
+}
+
+// resolveMergeRequestSource returns the commit id of the source branch,
+// fetching it from the source repository first when the source branch lives in
+// another repository on the same Gitaly node.
+func resolveMergeRequestSource(ctx context.Context, repo *localrepo.Repo, req *pb.WriteMergeRequestRefsRequest) (string, error) {
+	sourceRef := "refs/heads/" + string(req.SourceBranch)
+
+	if sourceRepo := req.GetSourceRepository(); sourceRepo != nil {
+		source, err := localrepo.New(sourceRepo)
+		if err != nil {
+			return "", err
+		}
+
+		fetch, err := repo.Exec(ctx, "fetch", "--no-tags", source.Path(), sourceRef)
+		if err != nil {
+			return "", status.Errorf(codes.Internal, "WriteMergeRequestRefs: fetch cmd: %v", err)
+		}
+		if err := fetch.Wait(); err != nil {
+			return "", status.Errorf(codes.InvalidArgument, "WriteMergeRequestRefs: fetch source branch %q: %v", req.SourceBranch, err)
+		}
+
+		sourceRef = "FETCH_HEAD"
+	}
+
+	revParse, err := repo.Exec(ctx, "rev-parse", "--verify", sourceRef+"^{commit}")
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "WriteMergeRequestRefs: rev-parse cmd: %v", err)
+	}
+
+	commitID, err := ioutil.ReadAll(revParse)
+	if err != nil {
+		return "", status.Errorf(codes.Internal, "WriteMergeRequestRefs: read rev-parse: %v", err)
+	}
+
+	if err := revParse.Wait(); err != nil {
+		return "", status.Errorf(codes.InvalidArgument, "WriteMergeRequestRefs: source branch %q not found", req.SourceBranch)
+	}
+
+	return strings.TrimSpace(string(commitID)), nil
+}
+
+func validateWriteMergeRequestRefsRequest(req *pb.WriteMergeRequestRefsRequest) error {
+	if req.MergeRequestIid <= 0 {
+		return fmt.Errorf("invalid MergeRequestIid %d", req.MergeRequestIid)
+	}
+	if err := git.ValidateRevision(req.SourceBranch); err != nil {
+		return fmt.Errorf("Validate SourceBranch: %v", err)
+	}
+	if req.MergeCommitId != "" {
+		if err := git.ValidateRevision([]byte(req.MergeCommitId)); err != nil {
+			return fmt.Errorf("Validate MergeCommitId: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
+	"google.golang.org/grpc/codes"
+)
+
+func TestWriteMergeRequestRefsSuccessful(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	masterCommitID := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "rev-parse", "refs/heads/master")))
+
+	request := &pb.WriteMergeRequestRefsRequest{
+		Repository:      testRepo,
+		SourceBranch:    []byte("master"),
+		MergeRequestIid: 1,
+		MergeCommitId:   masterCommitID,
+	}
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	response, err := client.WriteMergeRequestRefs(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, masterCommitID, response.HeadCommitId)
+
+	headRef := testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "rev-parse", "refs/merge-requests/1/head")
+	require.Equal(t, masterCommitID, strings.TrimSpace(string(headRef)))
+
+	mergeRef := testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "rev-parse", "refs/merge-requests/1/merge")
+	require.Equal(t, masterCommitID, strings.TrimSpace(string(mergeRef)))
+}
+
+func TestWriteMergeRequestRefsFromSourceRepository(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	sourceRepo, sourceRepoPath, sourceCleanupFn := testhelper.NewTestRepoWithWorktree(t)
+	defer sourceCleanupFn()
+
+	testhelper.MustRunCommand(t, nil, "git", "-C", sourceRepoPath, "checkout", "-b", "mr-source")
+	testhelper.MustRunCommand(t, nil, "git", "-C", sourceRepoPath,
+		"-c", "user.name=Scrooge McDuck", "-c", "user.email=scrooge@mcduck.com",
+		"commit", "--allow-empty", "-m", "source branch commit")
+
+	sourceCommitID := strings.TrimSpace(string(testhelper.MustRunCommand(t, nil, "git", "-C", sourceRepoPath, "rev-parse", "refs/heads/mr-source")))
+
+	request := &pb.WriteMergeRequestRefsRequest{
+		Repository:       testRepo,
+		SourceRepository: sourceRepo,
+		SourceBranch:     []byte("mr-source"),
+		MergeRequestIid:  7,
+	}
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	response, err := client.WriteMergeRequestRefs(ctx, request)
+	require.NoError(t, err)
+	require.Equal(t, sourceCommitID, response.HeadCommitId)
+
+	headRef := testhelper.MustRunCommand(t, nil, "git", "-C", testRepoPath, "rev-parse", "refs/merge-requests/7/head")
+	require.Equal(t, sourceCommitID, strings.TrimSpace(string(headRef)))
+}
+
+func TestWriteMergeRequestRefsFailure(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	testCases := []struct {
+		desc string
+		req  *pb.WriteMergeRequestRefsRequest
+		code codes.Code
+	}{
+		{
+			desc: "zero merge request iid",
+			req: &pb.WriteMergeRequestRefsRequest{
+				Repository:   testRepo,
+				SourceBranch: []byte("master"),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "empty source branch",
+			req: &pb.WriteMergeRequestRefsRequest{
+				Repository:      testRepo,
+				MergeRequestIid: 1,
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			desc: "non-existent source branch",
+			req: &pb.WriteMergeRequestRefsRequest{
+				Repository:      testRepo,
+				SourceBranch:    []byte("does-not-exist"),
+				MergeRequestIid: 1,
+			},
+			code: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctx, cancel := testhelper.Context()
+			defer cancel()
+
+			_, err := client.WriteMergeRequestRefs(ctx, tc.req)
+			testhelper.AssertGrpcError(t, err, tc.code, "")
+		})
+	}
+}
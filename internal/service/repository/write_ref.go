@@ -3,32 +3,71 @@ package repository
 import (
 	"bytes"
 	"fmt"
+	"os/exec"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/command"
 	"gitlab.com/gitlab-org/gitaly/internal/git"
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/service/ref"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"golang.org/x/net/context"
 )
 
+// WriteRef updates a reference, or HEAD via symbolic-ref. When
+// OldRevision is given the update is a compare-and-swap: git only moves
+// the ref if it still points at that revision, so concurrent
+// default-branch changes cannot silently overwrite each other.
 func (s *server) WriteRef(ctx context.Context, req *pb.WriteRefRequest) (*pb.WriteRefResponse, error) {
 	if err := validateWriteRefRequest(req); err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "WriteRef: %v", err)
 	}
 
-	client, err := s.RepositoryServiceClient(ctx)
+	repoPath, err := helper.GetRepoPath(req.GetRepository())
 	if err != nil {
 		return nil, err
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, req.GetRepository())
+	resp, err := writeRef(ctx, repoPath, req)
 	if err != nil {
 		return nil, err
 	}
 
-	return client.WriteRef(clientCtx, req)
+	ref.InvalidateDefaultBranchCache(req.GetRepository())
+
+	return resp, nil
+}
+
+func writeRef(ctx context.Context, repoPath string, req *pb.WriteRefRequest) (*pb.WriteRefResponse, error) {
+	args := []string{"--git-dir", repoPath}
+	if bytes.Equal(req.Ref, []byte("HEAD")) {
+		args = append(args, "symbolic-ref", string(req.Ref), string(req.Revision))
+	} else {
+		args = append(args, "update-ref", string(req.Ref), string(req.Revision))
+		if len(req.OldRevision) > 0 {
+			args = append(args, string(req.OldRevision))
+		}
+	}
+
+	var stderr bytes.Buffer
+	cmd, err := command.New(ctx, exec.Command(command.GitPath(), args...), nil, nil, &stderr)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "WriteRef: cmd start failed: %v", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if _, ok := command.ExitStatus(err); !ok {
+			return nil, status.Errorf(codes.Internal, "WriteRef: cmd wait failed: %v", err)
+		}
+
+		return &pb.WriteRefResponse{
+			Error: []byte(fmt.Sprintf("Could not update %s: %s", req.Ref, stderr.String())),
+		}, nil
+	}
+
+	return &pb.WriteRefResponse{}, nil
 }
 
 func validateWriteRefRequest(req *pb.WriteRefRequest) error {
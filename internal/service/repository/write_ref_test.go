@@ -171,3 +171,31 @@ func TestWriteRefValidationError(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteRefOldRevisionMismatch(t *testing.T) {
+	server, serverSocketPath := runRepoServer(t)
+	defer server.Stop()
+
+	client, conn := newRepositoryClient(t, serverSocketPath)
+	defer conn.Close()
+
+	testRepo, testRepoPath, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	before := testhelper.MustRunCommand(t, nil, "git", "--git-dir", testRepoPath, "rev-parse", "refs/heads/master")
+
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+	resp, err := client.WriteRef(ctx, &pb.WriteRefRequest{
+		Repository:  testRepo,
+		Ref:         []byte("refs/heads/master"),
+		Revision:    []byte("498214de67004b1da3d820901307bed2a68a8ef6"),
+		OldRevision: []byte("1234123412341234123412341234123412341234"),
+	})
+
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.GetError(), "expected an error when the old revision does not match")
+
+	after := testhelper.MustRunCommand(t, nil, "git", "--git-dir", testRepoPath, "rev-parse", "refs/heads/master")
+	require.Equal(t, string(before), string(after), "ref must not move on a failed compare-and-swap")
+}
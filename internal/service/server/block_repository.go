@@ -0,0 +1,46 @@
+package server
+
+import (
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/repoblock"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BlockRepository makes all RPCs — or only mutators — against a
+// repository fail with Unavailable until UnblockRepository is called or
+// the server restarts.
+func (s *server) BlockRepository(ctx context.Context, in *pb.BlockRepositoryRequest) (*pb.BlockRepositoryResponse, error) {
+	repo := in.GetRepository()
+	if repo == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "BlockRepository: empty Repository")
+	}
+	if repo.GetStorageName() == "" || repo.GetRelativePath() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "BlockRepository: empty StorageName or RelativePath")
+	}
+
+	repoblock.SetBlock(repo.GetStorageName(), repo.GetRelativePath(), repoblock.Block{
+		Message:      in.GetMessage(),
+		MutatorsOnly: in.GetMutatorsOnly(),
+	})
+
+	return &pb.BlockRepositoryResponse{}, nil
+}
+
+// UnblockRepository lifts a block set by BlockRepository or config.
+// Unblocking a repository that is not blocked is not an error.
+func (s *server) UnblockRepository(ctx context.Context, in *pb.UnblockRepositoryRequest) (*pb.UnblockRepositoryResponse, error) {
+	repo := in.GetRepository()
+	if repo == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "UnblockRepository: empty Repository")
+	}
+	if repo.GetStorageName() == "" || repo.GetRelativePath() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "UnblockRepository: empty StorageName or RelativePath")
+	}
+
+	wasBlocked := repoblock.RemoveBlock(repo.GetStorageName(), repo.GetRelativePath())
+
+	return &pb.UnblockRepositoryResponse{WasBlocked: wasBlocked}, nil
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/repoblock"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBlockAndUnblockRepository(t *testing.T) {
+	server, serverSocketPath := runServer(t)
+	defer server.Stop()
+
+	client, conn := newServerClient(t, serverSocketPath)
+	defer conn.Close()
+
+	repo := &pb.Repository{StorageName: "default", RelativePath: "incident.git"}
+	defer repoblock.RemoveBlock(repo.StorageName, repo.RelativePath)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := client.BlockRepository(ctx, &pb.BlockRepositoryRequest{
+		Repository:   repo,
+		Message:      "being repaired",
+		MutatorsOnly: true,
+	})
+	require.NoError(t, err)
+
+	unblockResp, err := client.UnblockRepository(ctx, &pb.UnblockRepositoryRequest{Repository: repo})
+	require.NoError(t, err)
+	require.True(t, unblockResp.WasBlocked)
+
+	unblockResp, err = client.UnblockRepository(ctx, &pb.UnblockRepositoryRequest{Repository: repo})
+	require.NoError(t, err)
+	require.False(t, unblockResp.WasBlocked, "repository was already unblocked")
+}
+
+func TestFailedBlockRepositoryDueToValidation(t *testing.T) {
+	server, serverSocketPath := runServer(t)
+	defer server.Stop()
+
+	client, conn := newServerClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testCases := []struct {
+		description string
+		repo        *pb.Repository
+	}{
+		{description: "no repository"},
+		{description: "empty storage name", repo: &pb.Repository{RelativePath: "foo.git"}},
+		{description: "empty relative path", repo: &pb.Repository{StorageName: "default"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			_, err := client.BlockRepository(ctx, &pb.BlockRepositoryRequest{Repository: testCase.repo})
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+			_, err = client.UnblockRepository(ctx, &pb.UnblockRepositoryRequest{Repository: testCase.repo})
+			require.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
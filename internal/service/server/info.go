@@ -0,0 +1,28 @@
+package server
+
+import (
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
+	"gitlab.com/gitlab-org/gitaly/internal/version"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ServerInfo reports the version and build metadata of the running
+// server so fleet tooling can verify what is actually deployed.
+func (s *server) ServerInfo(ctx context.Context, in *pb.ServerInfoRequest) (*pb.ServerInfoResponse, error) {
+	gitVersion, err := git.Version()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "ServerInfo: git version: %v", err)
+	}
+
+	return &pb.ServerInfoResponse{
+		ServerVersion: version.GetVersion(),
+		GitVersion:    gitVersion,
+		BuildTime:     version.GetBuildTime(),
+		CommitId:      version.GetCommit(),
+		GoVersion:     version.GetGoVersion(),
+	}, nil
+}
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"runtime"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/version"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestGitalyServerInfo(t *testing.T) {
+	server, serverSocketPath := runServer(t)
+	defer server.Stop()
+
+	client, conn := newServerClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := client.ServerInfo(ctx, &pb.ServerInfoRequest{})
+	require.NoError(t, err)
+
+	require.Equal(t, version.GetVersion(), c.GetServerVersion())
+	require.Equal(t, version.GetBuildTime(), c.GetBuildTime())
+	require.Equal(t, version.GetCommit(), c.GetCommitId())
+	require.Equal(t, runtime.Version(), c.GetGoVersion())
+	require.NotEmpty(t, c.GetGitVersion())
+}
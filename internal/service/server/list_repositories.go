@@ -0,0 +1,114 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/helper"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// repositoriesPerMessage is the maximum number of repositories in a
+// single stream message.
+const repositoriesPerMessage = 100
+
+// errLimitReached aborts the storage walk once the requested page is
+// full; it never reaches the client.
+var errLimitReached = errors.New("limit reached")
+
+// ListRepositories walks a storage shard and streams the repositories
+// found on disk, in lexical order of their relative paths.
+func (s *server) ListRepositories(in *pb.ListRepositoriesRequest, stream pb.ServerService_ListRepositoriesServer) error {
+	storagePath, err := helper.GetStorageByName(in.GetStorageName())
+	if err != nil {
+		return err
+	}
+
+	var batch []*pb.ListRepositoriesResponse_Repository
+	var sent int32
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := stream.Send(&pb.ListRepositoriesResponse{Repositories: batch}); err != nil {
+			return status.Errorf(codes.Unavailable, "ListRepositories: send: %v", err)
+		}
+
+		batch = nil
+		return nil
+	}
+
+	walkErr := filepath.Walk(storagePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() || !helper.IsGitDirectory(path) {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(storagePath, path)
+		if err != nil {
+			return err
+		}
+
+		// Repositories cannot be nested; never descend into one.
+		if !strings.HasPrefix(relativePath, in.GetPrefix()) || relativePath <= in.GetPageToken() {
+			return filepath.SkipDir
+		}
+
+		repository := &pb.ListRepositoriesResponse_Repository{RelativePath: relativePath}
+		if in.GetIncludeStats() {
+			repository.SizeBytes = diskUsage(path)
+			repository.LastModified = &timestamp.Timestamp{Seconds: info.ModTime().Unix()}
+		}
+
+		batch = append(batch, repository)
+		if len(batch) == repositoriesPerMessage {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		sent++
+		if limit := in.GetLimit(); limit > 0 && sent >= limit {
+			return errLimitReached
+		}
+
+		return filepath.SkipDir
+	})
+
+	if walkErr != nil && walkErr != errLimitReached {
+		if _, ok := status.FromError(walkErr); ok {
+			return walkErr
+		}
+		return status.Errorf(codes.Internal, "ListRepositories: walk: %v", walkErr)
+	}
+
+	return flush()
+}
+
+// diskUsage sums the apparent size of all files under dir. Unreadable
+// entries count as zero bytes.
+func diskUsage(dir string) int64 {
+	var size int64
+
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return size
+}
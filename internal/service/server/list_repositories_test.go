@@ -0,0 +1,132 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestListRepositories(t *testing.T) {
+	storageDir, err := ioutil.TempDir("", "gitaly-list-repositories-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(storageDir)
+
+	oldStorages := config.Config.Storages
+	config.Config.Storages = append(config.Config.Storages, config.Storage{Name: "list-test", Path: storageDir})
+	defer func() { config.Config.Storages = oldStorages }()
+
+	for _, relativePath := range []string{"a.git", "group/b.git", "group/c.git"} {
+		createFakeRepo(t, filepath.Join(storageDir, relativePath))
+	}
+	// A plain directory must not show up as a repository
+	require.NoError(t, os.MkdirAll(filepath.Join(storageDir, "not-a-repo"), 0755))
+
+	server, serverSocketPath := runServer(t)
+	defer server.Stop()
+
+	client, conn := newServerClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	testCases := []struct {
+		desc     string
+		request  *pb.ListRepositoriesRequest
+		expected []string
+	}{
+		{
+			desc:     "all repositories",
+			request:  &pb.ListRepositoriesRequest{StorageName: "list-test"},
+			expected: []string{"a.git", "group/b.git", "group/c.git"},
+		},
+		{
+			desc:     "prefix filter",
+			request:  &pb.ListRepositoriesRequest{StorageName: "list-test", Prefix: "group/"},
+			expected: []string{"group/b.git", "group/c.git"},
+		},
+		{
+			desc:     "limit",
+			request:  &pb.ListRepositoriesRequest{StorageName: "list-test", Limit: 1},
+			expected: []string{"a.git"},
+		},
+		{
+			desc:     "page token",
+			request:  &pb.ListRepositoriesRequest{StorageName: "list-test", PageToken: "a.git"},
+			expected: []string{"group/b.git", "group/c.git"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c, err := client.ListRepositories(ctx, tc.request)
+			require.NoError(t, err)
+
+			var relativePaths []string
+			for _, repository := range readListRepositories(t, c) {
+				relativePaths = append(relativePaths, repository.GetRelativePath())
+			}
+			require.Equal(t, tc.expected, relativePaths)
+		})
+	}
+}
+
+func TestListRepositoriesWithStats(t *testing.T) {
+	storageDir, err := ioutil.TempDir("", "gitaly-list-repositories-stats-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(storageDir)
+
+	oldStorages := config.Config.Storages
+	config.Config.Storages = append(config.Config.Storages, config.Storage{Name: "list-stats-test", Path: storageDir})
+	defer func() { config.Config.Storages = oldStorages }()
+
+	createFakeRepo(t, filepath.Join(storageDir, "sized.git"))
+
+	server, serverSocketPath := runServer(t)
+	defer server.Stop()
+
+	client, conn := newServerClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, err := client.ListRepositories(ctx, &pb.ListRepositoriesRequest{StorageName: "list-stats-test", IncludeStats: true})
+	require.NoError(t, err)
+
+	repositories := readListRepositories(t, c)
+	require.Len(t, repositories, 1)
+	require.True(t, repositories[0].GetSizeBytes() > 0, "size must count the HEAD file")
+	require.NotNil(t, repositories[0].GetLastModified())
+	require.NotZero(t, repositories[0].GetLastModified().Seconds)
+}
+
+func createFakeRepo(t *testing.T, repoPath string) {
+	for _, dir := range []string{"objects", "refs"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(repoPath, dir), 0755))
+	}
+	require.NoError(t, ioutil.WriteFile(filepath.Join(repoPath, "HEAD"), []byte("ref: refs/heads/master\n"), 0644))
+}
+
+func readListRepositories(t *testing.T, c pb.ServerService_ListRepositoriesClient) []*pb.ListRepositoriesResponse_Repository {
+	var repositories []*pb.ListRepositoriesResponse_Repository
+	for {
+		resp, err := c.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		repositories = append(repositories, resp.GetRepositories()...)
+	}
+
+	return repositories
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"gitlab.com/gitlab-org/gitaly/internal/events"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+)
+
+// refEventsBuffer is the number of events that may queue up per
+// subscriber before events are dropped for that subscriber.
+const refEventsBuffer = 100
+
+// RefEvents streams ref updates applied through this Gitaly server as
+// they happen. Consumers such as cache invalidation or audit pipelines
+// can use it instead of polling. Delivery is best-effort: events
+// published while the client is too slow to keep up are dropped.
+func (s *server) RefEvents(req *pb.RefEventsRequest, stream pb.ServerService_RefEventsServer) error {
+	ch, unsubscribe := events.Subscribe(refEventsBuffer)
+	defer unsubscribe()
+
+	for {
+		select {
+		case event := <-ch:
+			if req.GetStorageName() != "" && event.StorageName != req.GetStorageName() {
+				continue
+			}
+
+			err := stream.Send(&pb.RefEventsResponse{
+				StorageName:  event.StorageName,
+				RelativePath: event.RelativePath,
+				Ref:          []byte(event.Ref),
+				OldOid:       event.OldOid,
+				NewOid:       event.NewOid,
+				Actor:        event.Actor,
+				Transport:    event.Transport,
+			})
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+	}
+}
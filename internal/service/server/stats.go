@@ -0,0 +1,31 @@
+package server
+
+import (
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/middleware/slostats"
+
+	"golang.org/x/net/context"
+)
+
+// ServerStats reports per-RPC success/error ratios and apdex latency
+// counters over the collector's rolling window, so routers can steer
+// traffic away from a degraded node.
+func (s *server) ServerStats(ctx context.Context, in *pb.ServerStatsRequest) (*pb.ServerStatsResponse, error) {
+	snapshot := slostats.Snapshot()
+
+	rpcs := make([]*pb.ServerStatsResponse_RpcStats, 0, len(snapshot))
+	for _, stats := range snapshot {
+		rpcs = append(rpcs, &pb.ServerStatsResponse_RpcStats{
+			GrpcMethod:      stats.Method,
+			TotalCount:      stats.Total,
+			ErrorCount:      stats.Errors,
+			SatisfiedCount:  stats.Satisfied,
+			ToleratingCount: stats.Tolerating,
+		})
+	}
+
+	return &pb.ServerStatsResponse{
+		WindowSeconds: uint32(slostats.WindowDuration.Seconds()),
+		Rpcs:          rpcs,
+	}, nil
+}
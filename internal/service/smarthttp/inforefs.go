@@ -1,6 +1,7 @@
 package smarthttp
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -8,6 +9,8 @@ import (
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	log "github.com/sirupsen/logrus"
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/bundleuri"
+	"gitlab.com/gitlab-org/gitaly/internal/cache"
 	"gitlab.com/gitlab-org/gitaly/internal/git"
 	"gitlab.com/gitlab-org/gitaly/internal/helper"
 	"gitlab.com/gitlab-org/gitaly/streamio"
@@ -30,6 +33,10 @@ func (s *server) InfoRefsReceivePack(in *pb.InfoRefsRequest, stream pb.SmartHTTP
 	return handleInfoRefs(stream.Context(), "receive-pack", in, w)
 }
 
+// infoRefsCache holds ref advertisements of repositories whose refs have
+// not changed since the advertisement was generated.
+var infoRefsCache = cache.NewInfoRefsCache()
+
 func handleInfoRefs(ctx context.Context, service string, req *pb.InfoRefsRequest, w io.Writer) error {
 	grpc_logrus.Extract(ctx).WithFields(log.Fields{
 		"service": service,
@@ -40,14 +47,47 @@ func handleInfoRefs(ctx context.Context, service string, req *pb.InfoRefsRequest
 		return err
 	}
 
+	var bundleArgs []string
+	if service == "upload-pack" {
+		bundleArgs = bundleuri.UploadPackArgs(req.Repository)
+	}
+
+	// Custom git configuration or a bundle advertisement can change the
+	// advertisement in ways the cache key does not capture, so such
+	// requests bypass the cache.
+	cacheable := len(req.GitConfigOptions) == 0 && len(bundleArgs) == 0
+	cacheKey := service + "\x00" + req.GetGitProtocol()
+
+	if cacheable {
+		if payload, ok := infoRefsCache.Get(repoPath, cacheKey); ok {
+			if _, err := w.Write(payload); err != nil {
+				return status.Errorf(codes.Internal, "GetInfoRefs: %v", err)
+			}
+			return nil
+		}
+	}
+
+	var buf bytes.Buffer
+	out := w
+	if cacheable {
+		out = io.MultiWriter(w, &buf)
+	}
+
 	args := []string{}
+	if service == "upload-pack" {
+		args = append(args, git.UploadPackFilterArgs()...)
+	}
+	args = append(args, bundleArgs...)
+
 	for _, params := range req.GitConfigOptions {
 		args = append(args, "-c", params)
 	}
 
 	args = append(args, service, "--stateless-rpc", "--advertise-refs", repoPath)
 
-	cmd, err := git.Command(ctx, req.Repository, args...)
+	env := git.AddGitProtocolEnv(req, nil)
+
+	cmd, err := git.CommandWithEnv(ctx, req.Repository, env, args...)
 	if err != nil {
 		if _, ok := status.FromError(err); ok {
 			return err
@@ -55,15 +95,15 @@ func handleInfoRefs(ctx context.Context, service string, req *pb.InfoRefsRequest
 		return status.Errorf(codes.Internal, "GetInfoRefs: cmd: %v", err)
 	}
 
-	if err := pktLine(w, fmt.Sprintf("# service=git-%s\n", service)); err != nil {
+	if err := pktLine(out, fmt.Sprintf("# service=git-%s\n", service)); err != nil {
 		return status.Errorf(codes.Internal, "GetInfoRefs: pktLine: %v", err)
 	}
 
-	if err := pktFlush(w); err != nil {
+	if err := pktFlush(out); err != nil {
 		return status.Errorf(codes.Internal, "GetInfoRefs: pktFlush: %v", err)
 	}
 
-	if _, err := io.Copy(w, cmd); err != nil {
+	if _, err := io.Copy(out, cmd); err != nil {
 		return status.Errorf(codes.Internal, "GetInfoRefs: %v", err)
 	}
 
@@ -71,6 +111,10 @@ func handleInfoRefs(ctx context.Context, service string, req *pb.InfoRefsRequest
 		return status.Errorf(codes.Internal, "GetInfoRefs: %v", err)
 	}
 
+	if cacheable {
+		infoRefsCache.Put(repoPath, cacheKey, buf.Bytes())
+	}
+
 	return nil
 }
 
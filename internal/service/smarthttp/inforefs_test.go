@@ -5,6 +5,7 @@ import (
 	"strings"
 	"testing"
 
+	"gitlab.com/gitlab-org/gitaly/internal/git"
 	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
 	"gitlab.com/gitlab-org/gitaly/streamio"
 
@@ -51,6 +52,27 @@ func TestSuccessfulInfoRefsUploadPackWithGitConfigOptions(t *testing.T) {
 	assertGitRefAdvertisement(t, "InfoRefsUploadPack", string(response), "001e# service=git-upload-pack", "0000", []string{})
 }
 
+func TestInfoRefsUploadPackGitProtocolV2(t *testing.T) {
+	server, serverSocketPath := runSmartHTTPServer(t)
+	defer server.Stop()
+
+	testRepo, _, cleanupFn := testhelper.NewTestRepo(t)
+	defer cleanupFn()
+
+	rpcRequest := &pb.InfoRefsRequest{
+		Repository:  testRepo,
+		GitProtocol: git.ProtocolV2,
+	}
+
+	response, err := makeInfoRefsUploadPackRequest(t, serverSocketPath, rpcRequest)
+	require.NoError(t, err)
+
+	// With protocol v2 there is no up-front ref advertisement; the server
+	// announces the protocol version and its capabilities instead.
+	require.Contains(t, string(response), "version 2")
+	require.Contains(t, string(response), "ls-refs")
+}
+
 func makeInfoRefsUploadPackRequest(t *testing.T, serverSocketPath string, rpcRequest *pb.InfoRefsRequest) ([]byte, error) {
 	client, conn := newSmartHTTPClient(t, serverSocketPath)
 	defer conn.Close()
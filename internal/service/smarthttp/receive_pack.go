@@ -1,6 +1,7 @@
 package smarthttp
 
 import (
+	"bytes"
 	"fmt"
 	"os/exec"
 
@@ -8,6 +9,9 @@ import (
 	log "github.com/sirupsen/logrus"
 	"gitlab.com/gitlab-org/gitaly/internal/command"
 	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/housekeeping"
+	"gitlab.com/gitlab-org/gitaly/internal/service/ref"
+	"gitlab.com/gitlab-org/gitaly/internal/transfer"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 	"gitlab.com/gitlab-org/gitaly/streamio"
@@ -31,11 +35,16 @@ func (s *server) PostReceivePack(stream pb.SmartHTTPService_PostReceivePackServe
 		return err
 	}
 
+	ctx, cancel, touch := transfer.SessionContext(stream.Context(), transfer.Timeouts(transfer.ReceivePack))
+	defer cancel()
+
 	stdin := streamio.NewReader(func() ([]byte, error) {
 		resp, err := stream.Recv()
+		touch()
 		return resp.GetData(), err
 	})
 	stdout := streamio.NewWriter(func(p []byte) error {
+		touch()
 		return stream.Send(&pb.PostReceivePackResponse{Data: p})
 	})
 	env := []string{
@@ -48,22 +57,45 @@ func (s *server) PostReceivePack(stream pb.SmartHTTPService_PostReceivePackServe
 	if req.GlUsername != "" {
 		env = append(env, fmt.Sprintf("GL_USERNAME=%s", req.GlUsername))
 	}
+	if projectPath := req.GetRepository().GetGlProjectPath(); projectPath != "" {
+		env = append(env, fmt.Sprintf("GL_PROJECT_PATH=%s", projectPath))
+	}
 	repoPath, err := helper.GetRepoPath(req.Repository)
 	if err != nil {
 		return err
 	}
 
-	osCommand := exec.Command(command.GitPath(), "receive-pack", "--stateless-rpc", repoPath)
-	cmd, err := command.New(stream.Context(), osCommand, stdin, stdout, nil, env...)
+	args := []string{}
+	if req.GetFsckObjects() {
+		args = append(args, "-c", "receive.fsckObjects=true", "-c", "transfer.fsckObjects=true")
+	}
+	args = append(args, "receive-pack", "--stateless-rpc", repoPath)
+
+	// receive-pack writes object validation failures to stderr; keep them
+	// so the push error is not an opaque exit status.
+	var stderr bytes.Buffer
+
+	osCommand := exec.Command(command.GitPath(), args...)
+	cmd, err := command.New(ctx, osCommand, stdin, stdout, &stderr, env...)
 
 	if err != nil {
 		return status.Errorf(codes.Unavailable, "PostReceivePack: %v", err)
 	}
 
 	if err := cmd.Wait(); err != nil {
+		if timeoutErr := transfer.TimeoutError("PostReceivePack", ctx, stream.Context()); timeoutErr != nil {
+			return timeoutErr
+		}
+		if stderr.Len() > 0 {
+			return status.Errorf(codes.Unavailable, "PostReceivePack: %v, stderr: %q", err, stderr.String())
+		}
 		return status.Errorf(codes.Unavailable, "PostReceivePack: %v", err)
 	}
 
+	ref.InvalidateDefaultBranchCache(req.Repository)
+	infoRefsCache.Invalidate(repoPath)
+	housekeeping.MaybeOptimize(req.Repository)
+
 	return nil
 }
 
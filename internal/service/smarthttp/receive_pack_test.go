@@ -106,6 +106,73 @@ func TestSuccessfulReceivePackRequest(t *testing.T) {
 	testhelper.MustRunCommand(t, nil, "git", "-C", remoteRepoPath, "show", string(newHead))
 }
 
+func TestSuccessfulReceivePackRequestWithFsckObjects(t *testing.T) {
+	server, serverSocketPath := runSmartHTTPServer(t)
+	defer server.Stop()
+
+	testRepo := testhelper.TestRepository()
+	storagePath := testhelper.GitlabTestStoragePath()
+	remoteRepoRelativePath := "gitlab-test-remote-fsck"
+	localRepoRelativePath := "gitlab-test-local-fsck"
+	testRepoPath := path.Join(storagePath, testRepo.RelativePath)
+	remoteRepoPath := path.Join(storagePath, remoteRepoRelativePath)
+	localRepoPath := path.Join(storagePath, localRepoRelativePath)
+	testhelper.MustRunCommand(t, nil, "git", "clone", testRepoPath, localRepoPath)
+	testhelper.MustRunCommand(t, nil, "git", "clone", "--bare", testRepoPath, remoteRepoPath)
+	defer os.RemoveAll(remoteRepoPath)
+	defer os.RemoveAll(localRepoPath)
+
+	oldHead := bytes.TrimSpace(testhelper.MustRunCommand(t, nil, "git", "-C", localRepoPath, "rev-parse", "master"))
+
+	testhelper.MustRunCommand(t, nil, "git", "-C", localRepoPath,
+		"-c", "user.name=Scrooge McDuck",
+		"-c", "user.email=scrooge@mcduck.com",
+		"commit", "--allow-empty", "-m", "Healthy commit for fsck")
+
+	newHead := bytes.TrimSpace(testhelper.MustRunCommand(t, nil, "git", "-C", localRepoPath, "rev-parse", "master"))
+
+	pkt := fmt.Sprintf("%s %s refs/heads/master\x00 report-status side-band-64k agent=git/2.12.0", oldHead, newHead)
+	stdin := bytes.NewBufferString(fmt.Sprintf("^%s\n%s\n", oldHead, newHead))
+	pack := testhelper.MustRunCommand(t, stdin, "git", "-C", localRepoPath, "pack-objects", "--stdout", "--revs", "--thin", "--delta-base-offset", "-q")
+
+	requestBuffer := &bytes.Buffer{}
+	fmt.Fprintf(requestBuffer, "%04x%s%s", len(pkt)+4, pkt, pktFlushStr)
+	requestBuffer.Write(pack)
+
+	client, conn := newSmartHTTPClient(t, serverSocketPath)
+	defer conn.Close()
+	repo := &pb.Repository{StorageName: "default", RelativePath: remoteRepoRelativePath}
+	rpcRequest := &pb.PostReceivePackRequest{Repository: repo, GlId: "user-123", GlRepository: "project-123", FsckObjects: true}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.PostReceivePack(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(rpcRequest))
+
+	sw := streamio.NewWriter(func(p []byte) error {
+		return stream.Send(&pb.PostReceivePackRequest{Data: p})
+	})
+	_, err = io.Copy(sw, requestBuffer)
+	require.NoError(t, err)
+
+	stream.CloseSend()
+
+	responseBuffer := bytes.Buffer{}
+	rr := streamio.NewReader(func() ([]byte, error) {
+		resp, err := stream.Recv()
+		return resp.GetData(), err
+	})
+	_, err = io.Copy(&responseBuffer, rr)
+	require.NoError(t, err)
+
+	// Valid objects must still be accepted with fsck enabled
+	expectedResponse := "0030\x01000eunpack ok\n0019ok refs/heads/master\n00000000"
+	require.Equal(t, expectedResponse, responseBuffer.String())
+
+	testhelper.MustRunCommand(t, nil, "git", "-C", remoteRepoPath, "show", string(newHead))
+}
+
 func TestFailedReceivePackRequestDueToValidationError(t *testing.T) {
 	server, serverSocketPath := runSmartHTTPServer(t)
 	defer server.Stop()
@@ -3,10 +3,15 @@ package smarthttp
 import (
 	"io"
 	"os/exec"
+	"time"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
+	"gitlab.com/gitlab-org/gitaly/internal/bundleuri"
 	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
 	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/packstats"
+	"gitlab.com/gitlab-org/gitaly/internal/transfer"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 	"gitlab.com/gitlab-org/gitaly/streamio"
@@ -40,8 +45,15 @@ func (s *server) PostUploadPack(stream pb.SmartHTTPService_PostUploadPackServer)
 		return err
 	}
 
+	ctx, cancel, touch := transfer.SessionContext(stream.Context(), transfer.Timeouts(transfer.UploadPack))
+	defer cancel()
+
+	summary := packstats.NewSummary(stream.Context(), "PostUploadPack", req.Repository)
+	start := time.Now()
+
 	stdinReader := streamio.NewReader(func() ([]byte, error) {
 		resp, err := stream.Recv()
+		touch()
 		return resp.GetData(), err
 	})
 	pr, pw := io.Pipe()
@@ -52,7 +64,23 @@ func (s *server) PostUploadPack(stream pb.SmartHTTPService_PostUploadPackServer)
 		deepenCh <- scanDeepen(pr)
 	}()
 
+	wantsPr, wantsPw := io.Pipe()
+	defer wantsPw.Close()
+	stdin = io.TeeReader(stdin, wantsPw)
+	wantsCh := make(chan int, 1)
+	go func() {
+		wantsCh <- packstats.CountWants(wantsPr)
+	}()
+
+	recordSummary := func() {
+		wantsPw.Close()
+		summary.Wants = <-wantsCh
+		summary.Record(stream.Context(), time.Since(start))
+	}
+
 	stdout := streamio.NewWriter(func(p []byte) error {
+		touch()
+		summary.PackBytes += int64(len(p))
 		return stream.Send(&pb.PostUploadPackResponse{Data: p})
 	})
 	repoPath, err := helper.GetRepoPath(req.Repository)
@@ -60,15 +88,19 @@ func (s *server) PostUploadPack(stream pb.SmartHTTPService_PostUploadPackServer)
 		return err
 	}
 
-	args := []string{}
+	args := append([]string{}, git.UploadPackFilterArgs()...)
+	args = append(args, bundleuri.UploadPackArgs(req.Repository)...)
+
 	for _, params := range req.GitConfigOptions {
 		args = append(args, "-c", params)
 	}
 
 	args = append(args, "upload-pack", "--stateless-rpc", repoPath)
 
+	env := git.AddGitProtocolEnv(req, nil)
+
 	osCommand := exec.Command(command.GitPath(), args...)
-	cmd, err := command.New(stream.Context(), osCommand, stdin, stdout, nil)
+	cmd, err := command.New(ctx, osCommand, stdin, stdout, nil, env...)
 
 	if err != nil {
 		return status.Errorf(codes.Unavailable, "PostUploadPack: cmd: %v", err)
@@ -76,16 +108,22 @@ func (s *server) PostUploadPack(stream pb.SmartHTTPService_PostUploadPackServer)
 
 	if err := cmd.Wait(); err != nil {
 		pw.Close() // ensure scanDeepen returns
+		if timeoutErr := transfer.TimeoutError("PostUploadPack", ctx, stream.Context()); timeoutErr != nil {
+			return timeoutErr
+		}
 		if _, ok := command.ExitStatus(err); ok && <-deepenCh {
 			// We have seen a 'deepen' message in the request. It is expected that
 			// git-upload-pack has a non-zero exit status: don't treat this as an
 			// error.
 			deepenCount.Inc()
+			recordSummary()
 			return nil
 		}
 		return status.Errorf(codes.Unavailable, "PostUploadPack: %v", err)
 	}
 
+	recordSummary()
+
 	return nil
 }
 
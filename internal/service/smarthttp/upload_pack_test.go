@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"gitlab.com/gitlab-org/gitaly/internal/git"
 	"gitlab.com/gitlab-org/gitaly/internal/helper"
 	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
 
@@ -187,6 +188,30 @@ func TestFailedUploadPackRequestDueToValidationError(t *testing.T) {
 	}
 }
 
+func TestUploadPackRequestGitProtocolV2(t *testing.T) {
+	server, serverSocketPath := runSmartHTTPServer(t)
+	defer server.Stop()
+
+	testRepo := testhelper.TestRepository()
+
+	// 'ls-refs' is a protocol v2 command; with protocol v0 git-upload-pack
+	// would fail to parse this request.
+	requestBody := &bytes.Buffer{}
+	pktLine(requestBody, "command=ls-refs\n")
+	fmt.Fprint(requestBody, "0001") // delimiter packet
+	pktLine(requestBody, "ref-prefix refs/heads/master\n")
+	pktFlush(requestBody)
+
+	req := &pb.PostUploadPackRequest{
+		Repository:  testRepo,
+		GitProtocol: git.ProtocolV2,
+	}
+
+	responseBuffer, err := makePostUploadPackRequest(t, serverSocketPath, req, requestBody)
+	require.NoError(t, err)
+	require.Contains(t, responseBuffer.String(), "refs/heads/master")
+}
+
 func makePostUploadPackRequest(t *testing.T, serverSocketPath string, in *pb.PostUploadPackRequest, body io.Reader) (*bytes.Buffer, error) {
 	client, conn := newSmartHTTPClient(t, serverSocketPath)
 	defer conn.Close()
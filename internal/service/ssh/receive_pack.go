@@ -8,6 +8,9 @@ import (
 	log "github.com/sirupsen/logrus"
 	"gitlab.com/gitlab-org/gitaly/internal/command"
 	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/housekeeping"
+	"gitlab.com/gitlab-org/gitaly/internal/service/ref"
+	"gitlab.com/gitlab-org/gitaly/internal/transfer"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 	"gitlab.com/gitlab-org/gitaly/streamio"
@@ -31,14 +34,20 @@ func (s *server) SSHReceivePack(stream pb.SSHService_SSHReceivePackServer) error
 		return err
 	}
 
+	ctx, cancel, touch := transfer.SessionContext(stream.Context(), sessionTimeouts(transfer.ReceivePack))
+	defer cancel()
+
 	stdin := streamio.NewReader(func() ([]byte, error) {
 		request, err := stream.Recv()
+		touch()
 		return request.GetStdin(), err
 	})
 	stdout := streamio.NewWriter(func(p []byte) error {
+		touch()
 		return stream.Send(&pb.SSHReceivePackResponse{Stdout: p})
 	})
 	stderr := streamio.NewWriter(func(p []byte) error {
+		touch()
 		return stream.Send(&pb.SSHReceivePackResponse{Stderr: p})
 	})
 	env := []string{
@@ -49,6 +58,9 @@ func (s *server) SSHReceivePack(stream pb.SSHService_SSHReceivePackServer) error
 	if req.GlRepository != "" {
 		env = append(env, fmt.Sprintf("GL_REPOSITORY=%s", req.GlRepository))
 	}
+	if projectPath := req.GetRepository().GetGlProjectPath(); projectPath != "" {
+		env = append(env, fmt.Sprintf("GL_PROJECT_PATH=%s", projectPath))
+	}
 
 	repoPath, err := helper.GetRepoPath(req.Repository)
 	if err != nil {
@@ -56,13 +68,16 @@ func (s *server) SSHReceivePack(stream pb.SSHService_SSHReceivePackServer) error
 	}
 
 	osCommand := exec.Command(command.GitPath(), "receive-pack", repoPath)
-	cmd, err := command.New(stream.Context(), osCommand, stdin, stdout, stderr, env...)
+	cmd, err := command.New(ctx, osCommand, stdin, stdout, stderr, env...)
 
 	if err != nil {
 		return status.Errorf(codes.Unavailable, "SSHReceivePack: cmd: %v", err)
 	}
 
 	if err := cmd.Wait(); err != nil {
+		if timeoutErr := transfer.TimeoutError("SSHReceivePack", ctx, stream.Context()); timeoutErr != nil {
+			return timeoutErr
+		}
 		if status, ok := command.ExitStatus(err); ok {
 			return helper.DecorateError(
 				codes.Internal,
@@ -72,6 +87,9 @@ func (s *server) SSHReceivePack(stream pb.SSHService_SSHReceivePackServer) error
 		return status.Errorf(codes.Unavailable, "SSHReceivePack: %v", err)
 	}
 
+	ref.InvalidateDefaultBranchCache(req.Repository)
+	housekeeping.MaybeOptimize(req.Repository)
+
 	return nil
 }
 
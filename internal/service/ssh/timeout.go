@@ -0,0 +1,23 @@
+package ssh
+
+import (
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+	"gitlab.com/gitlab-org/gitaly/internal/transfer"
+)
+
+// sessionTimeouts returns the timeouts for an SSH transfer class. Fields
+// left at zero in [transfer_timeouts] fall back to the legacy [ssh]
+// settings so existing configurations keep working.
+func sessionTimeouts(class transfer.Class) config.TransferTimeout {
+	cfg := transfer.Timeouts(class)
+	legacy := config.Config.SSH
+
+	if cfg.InactivityTimeoutSeconds == 0 {
+		cfg.InactivityTimeoutSeconds = legacy.InactivityTimeoutSeconds
+	}
+	if cfg.MaxSessionSeconds == 0 {
+		cfg.MaxSessionSeconds = legacy.MaxSessionSeconds
+	}
+
+	return cfg
+}
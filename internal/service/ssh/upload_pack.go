@@ -1,12 +1,18 @@
 package ssh
 
 import (
+	"io"
 	"os/exec"
+	"time"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/logrus"
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/bundleuri"
 	"gitlab.com/gitlab-org/gitaly/internal/command"
+	"gitlab.com/gitlab-org/gitaly/internal/git"
 	"gitlab.com/gitlab-org/gitaly/internal/helper"
+	"gitlab.com/gitlab-org/gitaly/internal/packstats"
+	"gitlab.com/gitlab-org/gitaly/internal/transfer"
 	"gitlab.com/gitlab-org/gitaly/streamio"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -23,14 +29,32 @@ func (s *server) SSHUploadPack(stream pb.SSHService_SSHUploadPackServer) error {
 		return err
 	}
 
-	stdin := streamio.NewReader(func() ([]byte, error) {
+	ctx, cancel, touch := transfer.SessionContext(stream.Context(), sessionTimeouts(transfer.UploadPack))
+	defer cancel()
+
+	summary := packstats.NewSummary(stream.Context(), "SSHUploadPack", req.Repository)
+	start := time.Now()
+
+	stdinReader := streamio.NewReader(func() ([]byte, error) {
 		request, err := stream.Recv()
+		touch()
 		return request.GetStdin(), err
 	})
+	wantsPr, wantsPw := io.Pipe()
+	defer wantsPw.Close()
+	stdin := io.TeeReader(stdinReader, wantsPw)
+	wantsCh := make(chan int, 1)
+	go func() {
+		wantsCh <- packstats.CountWants(wantsPr)
+	}()
+
 	stdout := streamio.NewWriter(func(p []byte) error {
+		touch()
+		summary.PackBytes += int64(len(p))
 		return stream.Send(&pb.SSHUploadPackResponse{Stdout: p})
 	})
 	stderr := streamio.NewWriter(func(p []byte) error {
+		touch()
 		return stream.Send(&pb.SSHUploadPackResponse{Stderr: p})
 	})
 	repoPath, err := helper.GetRepoPath(req.Repository)
@@ -38,7 +62,8 @@ func (s *server) SSHUploadPack(stream pb.SSHService_SSHUploadPackServer) error {
 		return err
 	}
 
-	args := []string{}
+	args := append([]string{}, git.UploadPackFilterArgs()...)
+	args = append(args, bundleuri.UploadPackArgs(req.Repository)...)
 
 	for _, params := range req.GitConfigOptions {
 		args = append(args, "-c", params)
@@ -48,13 +73,16 @@ func (s *server) SSHUploadPack(stream pb.SSHService_SSHUploadPackServer) error {
 
 	osCommand := exec.Command(command.GitPath(), args...)
 
-	cmd, err := command.New(stream.Context(), osCommand, stdin, stdout, stderr)
+	cmd, err := command.New(ctx, osCommand, stdin, stdout, stderr)
 
 	if err != nil {
 		return status.Errorf(codes.Unavailable, "SSHUploadPack: cmd: %v", err)
 	}
 
 	if err := cmd.Wait(); err != nil {
+		if timeoutErr := transfer.TimeoutError("SSHUploadPack", ctx, stream.Context()); timeoutErr != nil {
+			return timeoutErr
+		}
 		if status, ok := command.ExitStatus(err); ok {
 			return helper.DecorateError(
 				codes.Internal,
@@ -64,6 +92,10 @@ func (s *server) SSHUploadPack(stream pb.SSHService_SSHUploadPackServer) error {
 		return status.Errorf(codes.Unavailable, "SSHUploadPack: %v", err)
 	}
 
+	wantsPw.Close()
+	summary.Wants = <-wantsCh
+	summary.Record(stream.Context(), time.Since(start))
+
 	return nil
 }
 
@@ -12,6 +12,7 @@ import (
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/stretchr/testify/require"
 
+	"gitlab.com/gitlab-org/gitaly/internal/config"
 	"gitlab.com/gitlab-org/gitaly/internal/testhelper"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
@@ -69,6 +70,31 @@ func TestFailedUploadPackRequestDueToValidationError(t *testing.T) {
 	}
 }
 
+func TestUploadPackInactivityTimeout(t *testing.T) {
+	oldSSHConfig := config.Config.SSH
+	config.Config.SSH.InactivityTimeoutSeconds = 1
+	defer func() { config.Config.SSH = oldSSHConfig }()
+
+	server, serverSocketPath := runSSHServer(t)
+	defer server.Stop()
+
+	client, conn := newSSHClient(t, serverSocketPath)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.SSHUploadPack(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&pb.SSHUploadPackRequest{Repository: testRepo}))
+
+	// Never send any data: upload-pack stalls waiting for the client and
+	// the inactivity timeout must kill the session.
+	err = drainPostUploadPackResponse(stream)
+	testhelper.AssertGrpcError(t, err, codes.DeadlineExceeded, "")
+}
+
 func TestUploadPackCloneSuccess(t *testing.T) {
 	server, serverSocketPath := runSSHServer(t)
 	defer server.Stop()
@@ -98,6 +124,23 @@ func TestUploadPackCloneSuccess(t *testing.T) {
 	}
 }
 
+func TestUploadPackPartialClone(t *testing.T) {
+	oldGitConfig := config.Config.Git
+	config.Config.Git.UploadPackAllowFilter = true
+	defer func() { config.Config.Git = oldGitConfig }()
+
+	server, serverSocketPath := runSSHServer(t)
+	defer server.Stop()
+
+	localRepoPath := path.Join(testRepoRoot, "gitlab-test-upload-pack-local-partial")
+
+	cmd := exec.Command("git", "clone", "--filter=blob:none", "git@localhost:test/test.git", localRepoPath)
+
+	lHead, rHead, _, _, err := testClone(t, serverSocketPath, testRepo.GetStorageName(), testRepo.GetRelativePath(), localRepoPath, "", cmd)
+	require.NoError(t, err, "partial clone failed")
+	require.Equal(t, lHead, rHead, "local and remote head not equal")
+}
+
 func TestUploadPackCloneHideTags(t *testing.T) {
 	server, serverSocketPath := runSSHServer(t)
 	defer server.Stop()
@@ -1,43 +1,109 @@
 package wiki
 
 import (
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/internal/git/catfile"
+	"gitlab.com/gitlab-org/gitaly/streamio"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 func (s *server) WikiFindFile(request *pb.WikiFindFileRequest, stream pb.WikiService_WikiFindFileServer) error {
-	ctx := stream.Context()
-
 	if len(request.GetName()) == 0 {
 		return status.Errorf(codes.InvalidArgument, "WikiFindFile: Empty Name")
 	}
+	if request.GetMaxSize() < 0 {
+		return status.Errorf(codes.InvalidArgument, "WikiFindFile: negative MaxSize")
+	}
 
-	client, err := s.WikiServiceClient(ctx)
-	if err != nil {
-		return err
+	revision := string(request.GetRevision())
+	if revision == "" {
+		revision = "HEAD"
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, request.GetRepository())
+	return catfile.CatFile(stream.Context(), request.GetRepository(), func(stdin io.Writer, stdout *bufio.Reader) error {
+		if _, err := fmt.Fprintf(stdin, "%s^{commit}\n", revision); err != nil {
+			return status.Errorf(codes.Internal, "WikiFindFile: stdin write: %v", err)
+		}
+
+		commitInfo, err := catfile.ParseObjectInfo(stdout)
+		if err != nil {
+			return status.Errorf(codes.Internal, "WikiFindFile: %v", err)
+		}
+		if commitInfo.Oid == "" {
+			return status.Errorf(codes.Unknown, "WikiFindFile: revision %q not found", revision)
+		}
+		if _, err := io.CopyN(ioutil.Discard, stdout, commitInfo.Size+1); err != nil {
+			return status.Errorf(codes.Internal, "WikiFindFile: discard commit: %v", err)
+		}
+
+		if _, err := fmt.Fprintf(stdin, "%s:%s\n", revision, request.GetName()); err != nil {
+			return status.Errorf(codes.Internal, "WikiFindFile: stdin write: %v", err)
+		}
+
+		blobInfo, err := catfile.ParseObjectInfo(stdout)
+		if err != nil {
+			return status.Errorf(codes.Internal, "WikiFindFile: %v", err)
+		}
+		if blobInfo.Oid == "" || blobInfo.Type != "blob" {
+			// An empty response means the file was not found
+			return stream.Send(&pb.WikiFindFileResponse{})
+		}
+
+		if maxSize := request.GetMaxSize(); maxSize > 0 && blobInfo.Size > maxSize {
+			return status.Errorf(codes.FailedPrecondition, "WikiFindFile: file size (%d) is bigger than the maximum allowed size (%d)", blobInfo.Size, maxSize)
+		}
+
+		return sendWikiFile(stream, request, blobInfo, stdout)
+	})
+}
+
+func sendWikiFile(stream pb.WikiService_WikiFindFileServer, request *pb.WikiFindFileRequest, blobInfo *catfile.ObjectInfo, stdout *bufio.Reader) error {
+	peekSize := blobInfo.Size
+	if peekSize > 512 {
+		// http.DetectContentType considers at most the first 512 bytes
+		peekSize = 512
+	}
+	contentHead, err := stdout.Peek(int(peekSize))
 	if err != nil {
-		return err
+		return status.Errorf(codes.Internal, "WikiFindFile: peek content: %v", err)
 	}
 
-	rubyStream, err := client.WikiFindFile(clientCtx, request)
-	if err != nil {
-		return err
+	firstMessage := &pb.WikiFindFileResponse{
+		Name:     []byte(path.Base(string(request.GetName()))),
+		MimeType: http.DetectContentType(contentHead),
+		Path:     request.GetName(),
 	}
 
-	return rubyserver.Proxy(func() error {
-		resp, err := rubyStream.Recv()
-		if err != nil {
-			md := rubyStream.Trailer()
-			stream.SetTrailer(md)
-			return err
+	if blobInfo.Size == 0 {
+		return stream.Send(firstMessage)
+	}
+
+	sw := streamio.NewWriter(func(p []byte) error {
+		msg := &pb.WikiFindFileResponse{}
+		if firstMessage != nil {
+			msg = firstMessage
+			firstMessage = nil
 		}
-		return stream.Send(resp)
+		msg.RawData = p
+		return stream.Send(msg)
 	})
+
+	n, err := io.Copy(sw, io.LimitReader(stdout, blobInfo.Size))
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "WikiFindFile: send: %v", err)
+	}
+	if n != blobInfo.Size {
+		return status.Errorf(codes.Unavailable, "WikiFindFile: short send: %d/%d bytes", n, blobInfo.Size)
+	}
+
+	return nil
 }
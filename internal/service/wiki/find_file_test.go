@@ -131,6 +131,21 @@ func TestSuccessfulWikiFindFileRequest(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("file bigger than MaxSize", func(t *testing.T) {
+		ctx, cancel := testhelper.Context()
+		defer cancel()
+
+		c, err := client.WikiFindFile(ctx, &pb.WikiFindFileRequest{
+			Repository: sandboxWiki,
+			Name:       []byte("clouds.png"),
+			MaxSize:    int64(len(content)) - 1,
+		})
+		require.NoError(t, err)
+
+		err = drainWikiFindFileResponse(c)
+		testhelper.AssertGrpcError(t, err, codes.FailedPrecondition, "bigger than the maximum allowed size")
+	})
 }
 
 func TestFailedWikiFindFileDueToValidation(t *testing.T) {
@@ -147,6 +162,7 @@ func TestFailedWikiFindFileDueToValidation(t *testing.T) {
 		desc     string
 		name     string
 		revision string
+		maxSize  int64
 		code     codes.Code
 	}{
 		{
@@ -161,6 +177,13 @@ func TestFailedWikiFindFileDueToValidation(t *testing.T) {
 			revision: "deadfacedeadfacedeadfacedeadfacedeadface",
 			code:     codes.Unknown,
 		},
+		{
+			desc:     "negative max size",
+			name:     "image.jpg",
+			revision: "master",
+			maxSize:  -1,
+			code:     codes.InvalidArgument,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -172,6 +195,7 @@ func TestFailedWikiFindFileDueToValidation(t *testing.T) {
 				Repository: wikiRepo,
 				Name:       []byte(testCase.name),
 				Revision:   []byte(testCase.revision),
+				MaxSize:    testCase.maxSize,
 			}
 
 			c, err := client.WikiFindFile(ctx, request)
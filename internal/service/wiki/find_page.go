@@ -1,7 +1,8 @@
 package wiki
 
 import (
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+	gitlog "gitlab.com/gitlab-org/gitaly/internal/git/log"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 
@@ -10,34 +11,61 @@ import (
 )
 
 func (s *server) WikiFindPage(request *pb.WikiFindPageRequest, stream pb.WikiService_WikiFindPageServer) error {
-	ctx := stream.Context()
-
 	if len(request.GetTitle()) == 0 {
 		return status.Errorf(codes.InvalidArgument, "WikiFindPage: Empty Title")
 	}
 
-	client, err := s.WikiServiceClient(ctx)
+	ctx := stream.Context()
+
+	repo, err := localrepo.New(request.GetRepository())
 	if err != nil {
 		return err
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, request.GetRepository())
-	if err != nil {
-		return err
+	headID, _ := repo.Run(ctx, nil, nil, "rev-parse", "--verify", "--quiet", "HEAD")
+
+	commitID := headID
+	if revision := string(request.GetRevision()); revision != "" {
+		commitID, _ = repo.Run(ctx, nil, nil, "rev-parse", "--verify", "--quiet", revision+"^{commit}")
+	}
+
+	// An empty wiki or an unresolvable revision holds no page: respond
+	// with an empty message, like a page that does not exist.
+	if commitID == "" {
+		return stream.Send(&pb.WikiFindPageResponse{})
 	}
 
-	rubyStream, err := client.WikiFindPage(clientCtx, request)
+	pages, err := listPages(ctx, repo, commitID)
 	if err != nil {
-		return err
+		return status.Errorf(codes.Internal, "WikiFindPage: ls-tree: %v", err)
 	}
 
-	return rubyserver.Proxy(func() error {
-		resp, err := rubyStream.Recv()
-		if err != nil {
-			md := rubyStream.Trailer()
-			stream.SetTrailer(md)
-			return err
+	var page *pageInfo
+	for _, candidate := range pages {
+		if candidate.matches(string(request.GetTitle()), string(request.GetDirectory())) {
+			page = candidate
+			break
 		}
-		return stream.Send(resp)
+	}
+
+	if page == nil {
+		return stream.Send(&pb.WikiFindPageResponse{})
+	}
+
+	commit, err := gitlog.GetCommit(ctx, repo.Repository, commitID, "")
+	if err != nil {
+		return status.Errorf(codes.Internal, "WikiFindPage: commit lookup: %v", err)
+	}
+
+	version := &pb.WikiPageVersion{Commit: commit, Format: page.format}
+	firstMessage := newWikiPage(page, version, commitID != headID)
+
+	err = sendPageContent(ctx, repo, firstMessage, commitID, page.path, func(msg *pb.WikiPage) error {
+		return stream.Send(&pb.WikiFindPageResponse{Page: msg})
 	})
+	if err != nil {
+		return status.Errorf(codes.Internal, "WikiFindPage: send: %v", err)
+	}
+
+	return nil
 }
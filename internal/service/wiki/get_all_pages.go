@@ -1,36 +1,62 @@
 package wiki
 
 import (
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+	gitlog "gitlab.com/gitlab-org/gitaly/internal/git/log"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (s *server) WikiGetAllPages(request *pb.WikiGetAllPagesRequest, stream pb.WikiService_WikiGetAllPagesServer) error {
 	ctx := stream.Context()
 
-	client, err := s.WikiServiceClient(ctx)
+	repo, err := localrepo.New(request.GetRepository())
 	if err != nil {
 		return err
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, request.GetRepository())
+	// An empty wiki holds no pages
+	headID, _ := repo.Run(ctx, nil, nil, "rev-parse", "--verify", "--quiet", "HEAD")
+	if headID == "" {
+		return nil
+	}
+
+	pages, err := listPages(ctx, repo, headID)
 	if err != nil {
-		return err
+		return status.Errorf(codes.Internal, "WikiGetAllPages: ls-tree: %v", err)
+	}
+
+	if limit := request.GetLimit(); limit > 0 && uint32(len(pages)) > limit {
+		pages = pages[:limit]
+	}
+
+	if len(pages) == 0 {
+		return nil
 	}
 
-	rubyStream, err := client.WikiGetAllPages(clientCtx, request)
+	commit, err := gitlog.GetCommit(ctx, repo.Repository, headID, "")
 	if err != nil {
-		return err
+		return status.Errorf(codes.Internal, "WikiGetAllPages: commit lookup: %v", err)
 	}
 
-	return rubyserver.Proxy(func() error {
-		resp, err := rubyStream.Recv()
+	for _, page := range pages {
+		version := &pb.WikiPageVersion{Commit: commit, Format: page.format}
+		firstMessage := newWikiPage(page, version, false)
+
+		err = sendPageContent(ctx, repo, firstMessage, headID, page.path, func(msg *pb.WikiPage) error {
+			return stream.Send(&pb.WikiGetAllPagesResponse{Page: msg})
+		})
 		if err != nil {
-			md := rubyStream.Trailer()
-			stream.SetTrailer(md)
-			return err
+			return status.Errorf(codes.Internal, "WikiGetAllPages: send: %v", err)
 		}
-		return stream.Send(resp)
-	})
+
+		if err := stream.Send(&pb.WikiGetAllPagesResponse{EndOfPage: true}); err != nil {
+			return status.Errorf(codes.Internal, "WikiGetAllPages: send: %v", err)
+		}
+	}
+
+	return nil
 }
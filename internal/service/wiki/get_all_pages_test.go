@@ -61,6 +61,32 @@ func TestSuccessfulWikiGetAllPagesRequest(t *testing.T) {
 	requireWikiPagesEqual(t, receivedPages[1], expectedPage2)
 }
 
+func TestWikiGetAllPagesLimit(t *testing.T) {
+	ctx, cancel := testhelper.Context()
+	defer cancel()
+
+	server, serverSocketPath := runWikiServiceServer(t)
+	defer server.Stop()
+
+	client, conn := newWikiClient(t, serverSocketPath)
+	defer conn.Close()
+
+	wikiRepo, _, cleanupFunc := setupWikiRepo(t)
+	defer cleanupFunc()
+
+	createTestWikiPage(t, client, wikiRepo, "Page 1")
+	createTestWikiPage(t, client, wikiRepo, "Page 2")
+
+	request := &pb.WikiGetAllPagesRequest{Repository: wikiRepo, Limit: 1}
+	c, err := client.WikiGetAllPages(ctx, request)
+	require.NoError(t, err)
+
+	receivedPages := readWikiPagesFromWikiGetAllPagesClient(t, c)
+
+	require.Len(t, receivedPages, 1)
+	require.Equal(t, "Page-1", receivedPages[0].UrlPath)
+}
+
 func TestFailedWikiGetAllPagesDueToValidation(t *testing.T) {
 	server, serverSocketPath := runWikiServiceServer(t)
 	defer server.Stop()
@@ -0,0 +1,145 @@
+package wiki
+
+import (
+	"io"
+	"path"
+	"strings"
+
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+
+	pb "gitlab.com/gitlab-org/gitaly-proto/go"
+	"gitlab.com/gitlab-org/gitaly/streamio"
+
+	"golang.org/x/net/context"
+)
+
+// wikiPageFormats maps page file extensions to wiki format names.
+var wikiPageFormats = map[string]string{
+	"adoc":     "asciidoc",
+	"asciidoc": "asciidoc",
+	"markdown": "markdown",
+	"md":       "markdown",
+	"org":      "org",
+	"rdoc":     "rdoc",
+	"textile":  "textile",
+}
+
+// extensionForFormat returns the file extension pages of the given
+// format are stored under.
+func extensionForFormat(format string) string {
+	if format == "markdown" {
+		return "md"
+	}
+
+	return format
+}
+
+// canonicalizeName turns a page name into the form it is stored under
+// on disk: spaces become hyphens, like Gollum does.
+func canonicalizeName(name string) string {
+	return strings.Replace(name, " ", "-", -1)
+}
+
+// pageInfo describes one page file inside the wiki repository.
+type pageInfo struct {
+	path    string // e.g. "Installing/Step-133-b.md"
+	urlPath string // e.g. "Installing/Step-133-b"
+	name    string // e.g. "Step 133 b"
+	format  string // e.g. "markdown"
+}
+
+// newPageInfo classifies a file in the wiki repository. It returns nil
+// when the file is not a wiki page.
+func newPageInfo(filePath string) *pageInfo {
+	ext := strings.TrimPrefix(path.Ext(filePath), ".")
+	format, ok := wikiPageFormats[strings.ToLower(ext)]
+	if !ok {
+		return nil
+	}
+
+	urlPath := strings.TrimSuffix(filePath, "."+ext)
+	name := strings.Replace(path.Base(urlPath), "-", " ", -1)
+
+	return &pageInfo{path: filePath, urlPath: urlPath, name: name, format: format}
+}
+
+// matches reports whether the page answers to title, optionally inside
+// directory. Comparison is case insensitive and treats spaces and
+// hyphens as equal, like Gollum page lookup does.
+func (p *pageInfo) matches(title, directory string) bool {
+	want := canonicalizeName(title)
+
+	if directory != "" || strings.ContainsRune(want, '/') {
+		want = path.Join(canonicalizeName(directory), want)
+		return strings.EqualFold(p.urlPath, want)
+	}
+
+	return strings.EqualFold(path.Base(p.urlPath), want)
+}
+
+// listPages returns the wiki pages present in commit, in repository
+// order.
+func listPages(ctx context.Context, repo *localrepo.Repo, commitID string) ([]*pageInfo, error) {
+	out, err := repo.Run(ctx, nil, nil, "ls-tree", "-r", "-z", "--name-only", commitID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []*pageInfo
+	for _, filePath := range strings.Split(out, "\x00") {
+		if page := newPageInfo(filePath); page != nil {
+			pages = append(pages, page)
+		}
+	}
+
+	return pages, nil
+}
+
+// newWikiPage assembles the attribute-bearing first message of a
+// WikiPage stream. RawData is attached by sendPageContent.
+func newWikiPage(page *pageInfo, version *pb.WikiPageVersion, historical bool) *pb.WikiPage {
+	return &pb.WikiPage{
+		Version:    version,
+		Format:     page.format,
+		Title:      []byte(page.name),
+		UrlPath:    page.urlPath,
+		Path:       []byte(page.path),
+		Name:       []byte(page.name),
+		Historical: historical,
+	}
+}
+
+// sendPageContent streams the raw data of the page at commit.
+// firstMessage is sent along with the first chunk of data, or on its
+// own when the page is empty.
+func sendPageContent(ctx context.Context, repo *localrepo.Repo, firstMessage *pb.WikiPage, commitID, pagePath string, send func(*pb.WikiPage) error) error {
+	cmd, err := repo.Exec(ctx, "cat-file", "blob", commitID+":"+pagePath)
+	if err != nil {
+		return err
+	}
+
+	sw := streamio.NewWriter(func(p []byte) error {
+		msg := &pb.WikiPage{RawData: p}
+		if firstMessage != nil {
+			msg = firstMessage
+			msg.RawData = p
+			firstMessage = nil
+		}
+
+		return send(msg)
+	})
+
+	if _, err := io.Copy(sw, cmd); err != nil {
+		return err
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return err
+	}
+
+	if firstMessage != nil {
+		return send(firstMessage)
+	}
+
+	return nil
+}
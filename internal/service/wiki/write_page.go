@@ -2,11 +2,15 @@ package wiki
 
 import (
 	"fmt"
+	"io"
+	"strings"
 
-	"gitlab.com/gitlab-org/gitaly/internal/rubyserver"
+	"gitlab.com/gitlab-org/gitaly/internal/git/localrepo"
+	"gitlab.com/gitlab-org/gitaly/internal/git/treebuilder"
 
 	pb "gitlab.com/gitlab-org/gitaly-proto/go"
 
+	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -23,43 +27,101 @@ func (s *server) WikiWritePage(stream pb.WikiService_WikiWritePageServer) error
 
 	ctx := stream.Context()
 
-	client, err := s.WikiServiceClient(ctx)
+	repo, err := localrepo.New(firstRequest.GetRepository())
 	if err != nil {
 		return err
 	}
 
-	clientCtx, err := rubyserver.SetHeaders(ctx, firstRequest.GetRepository())
+	content := append([]byte(nil), firstRequest.GetContent()...)
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		content = append(content, request.GetContent()...)
+	}
+
+	pagePath := canonicalizeName(string(firstRequest.GetName())) + "." + extensionForFormat(firstRequest.GetFormat())
+
+	response, err := writePage(ctx, repo, firstRequest.GetCommitDetails(), pagePath, content)
 	if err != nil {
 		return err
 	}
 
-	rubyStream, err := client.WikiWritePage(clientCtx)
+	return stream.SendAndClose(response)
+}
+
+// writePage commits a new page to the branch HEAD points at. When a
+// page already exists at pagePath the duplicate is reported in the
+// response instead of creating a commit.
+func writePage(ctx context.Context, repo *localrepo.Repo, commitDetails *pb.WikiCommitDetails, pagePath string, content []byte) (*pb.WikiWritePageResponse, error) {
+	parentID, _ := repo.Run(ctx, nil, nil, "rev-parse", "--verify", "--quiet", "HEAD")
+
+	tb, cleanup, err := treebuilder.New(repo)
 	if err != nil {
-		return err
+		return nil, status.Errorf(codes.Internal, "WikiWritePage: %v", err)
 	}
+	defer cleanup()
 
-	if err := rubyStream.Send(firstRequest); err != nil {
-		return err
+	if err := tb.Init(ctx, parentID); err != nil {
+		return nil, status.Errorf(codes.Internal, "WikiWritePage: read-tree: %v", err)
 	}
 
-	err = rubyserver.Proxy(func() error {
-		request, err := stream.Recv()
-		if err != nil {
-			return err
-		}
-		return rubyStream.Send(request)
-	})
+	if mode, _, err := tb.FileEntry(ctx, pagePath); err != nil {
+		return nil, status.Errorf(codes.Internal, "WikiWritePage: %v", err)
+	} else if mode != "" {
+		duplicateError := fmt.Sprintf("Cannot write //%s, found //%s.", pagePath, pagePath)
+		return &pb.WikiWritePageResponse{DuplicateError: []byte(duplicateError)}, nil
+	}
 
+	oid, err := tb.HashObject(ctx, content)
 	if err != nil {
-		return err
+		return nil, status.Errorf(codes.Internal, "WikiWritePage: hash-object: %v", err)
+	}
+
+	if err := tb.AddEntry(ctx, treebuilder.ModeFile, oid, pagePath); err != nil {
+		return nil, status.Errorf(codes.Internal, "WikiWritePage: update-index: %v", err)
 	}
 
-	response, err := rubyStream.CloseAndRecv()
+	treeID, err := tb.WriteTree(ctx)
 	if err != nil {
-		return err
+		return nil, status.Errorf(codes.Internal, "WikiWritePage: write-tree: %v", err)
 	}
 
-	return stream.SendAndClose(response)
+	commitID, err := commitWikiTree(ctx, repo, commitDetails, treeID, parentID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "WikiWritePage: commit-tree: %v", err)
+	}
+
+	// An empty old value makes update-ref fail when the branch was created
+	// behind our back, just like a stale parent OID does.
+	if _, err := repo.Run(ctx, nil, nil, "update-ref", "HEAD", commitID, parentID); err != nil {
+		return nil, status.Errorf(codes.Internal, "WikiWritePage: update-ref: %v", err)
+	}
+
+	return &pb.WikiWritePageResponse{}, nil
+}
+
+// commitWikiTree creates the page commit. The wiki commit details act
+// as both author and committer.
+func commitWikiTree(ctx context.Context, repo *localrepo.Repo, commitDetails *pb.WikiCommitDetails, treeID, parentID string) (string, error) {
+	env := []string{
+		"GIT_AUTHOR_NAME=" + string(commitDetails.GetName()),
+		"GIT_AUTHOR_EMAIL=" + string(commitDetails.GetEmail()),
+		"GIT_COMMITTER_NAME=" + string(commitDetails.GetName()),
+		"GIT_COMMITTER_EMAIL=" + string(commitDetails.GetEmail()),
+	}
+
+	args := []string{"commit-tree", treeID}
+	if parentID != "" {
+		args = append(args, "-p", parentID)
+	}
+
+	return repo.Run(ctx, env, strings.NewReader(string(commitDetails.GetMessage())), args...)
 }
 
 func validateWikiWritePageRequest(request *pb.WikiWritePageRequest) error {
@@ -369,12 +369,18 @@ func NewTestRepoWithWorktree(t *testing.T) (repo *pb.Repository, repoPath string
 	return cloneTestRepo(t, false)
 }
 
+// cloneTestRepo creates a copy of the test repository that borrows the
+// objects of the source repository through an objects/info/alternates
+// link instead of copying them. Only refs are written into the copy;
+// objects created by a test land in the copy and never touch the shared
+// fixture. This keeps repository setup cheap for the many tests that
+// need their own mutable copy.
 func cloneTestRepo(t *testing.T, bare bool) (repo *pb.Repository, repoPath string, cleanup func()) {
 	storagePath := GitlabTestStoragePath()
 	repo, repoPath, relativePath := createRepo(t, storagePath)
 	testRepo := TestRepository()
 	testRepoPath := path.Join(storagePath, testRepo.RelativePath)
-	args := []string{"clone", "--no-hardlinks", "--dissociate"}
+	args := []string{"clone", "--shared"}
 
 	if bare {
 		args = append(args, "--bare")
@@ -0,0 +1,65 @@
+// Package transfer enforces the timeout policy for git data transfers
+// (upload-pack and receive-pack), shared by the SSH and Smart HTTP
+// services.
+package transfer
+
+import (
+	"time"
+
+	"gitlab.com/gitlab-org/gitaly/internal/config"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Class is a transfer request class with its own timeout configuration.
+type Class int
+
+// The supported transfer classes.
+const (
+	UploadPack Class = iota
+	ReceivePack
+)
+
+// Timeouts returns the configured timeouts for a transfer class.
+func Timeouts(class Class) config.TransferTimeout {
+	if class == ReceivePack {
+		return config.Config.TransferTimeouts.ReceivePack
+	}
+	return config.Config.TransferTimeouts.UploadPack
+}
+
+// SessionContext derives a context for a transfer session with the given
+// timeouts applied. Canceling the returned context kills the underlying
+// git process via the command package. The returned touch function must
+// be called whenever data flows in either direction; it postpones the
+// inactivity timeout.
+func SessionContext(ctx context.Context, cfg config.TransferTimeout) (context.Context, context.CancelFunc, func()) {
+	var cancel context.CancelFunc
+	if cfg.MaxSessionSeconds > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(cfg.MaxSessionSeconds)*time.Second)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	touch := func() {}
+	if cfg.InactivityTimeoutSeconds > 0 {
+		inactivityTimeout := time.Duration(cfg.InactivityTimeoutSeconds) * time.Second
+		timer := time.AfterFunc(inactivityTimeout, cancel)
+		touch = func() { timer.Reset(inactivityTimeout) }
+	}
+
+	return ctx, cancel, touch
+}
+
+// TimeoutError maps a session that we terminated ourselves to
+// DeadlineExceeded. It returns nil when the session context was not the
+// cause, e.g. when the client went away or the process simply failed.
+func TimeoutError(rpc string, sessionCtx, streamCtx context.Context) error {
+	if sessionCtx.Err() == nil || streamCtx.Err() != nil {
+		return nil
+	}
+
+	return status.Errorf(codes.DeadlineExceeded, "%s: session timed out", rpc)
+}
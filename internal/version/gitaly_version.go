@@ -2,14 +2,16 @@ package version
 
 import (
 	"fmt"
+	"runtime"
 )
 
 var version string
 var buildtime string
+var commit string
 
 // GetVersionString returns a standard version header
 func GetVersionString() string {
-	return fmt.Sprintf("Gitaly, version %v, built %v", version, buildtime)
+	return fmt.Sprintf("Gitaly, version %v, commit %v, built %v with %v", version, commit, buildtime, runtime.Version())
 }
 
 // GetVersion returns the semver compatible version number
@@ -21,3 +23,13 @@ func GetVersion() string {
 func GetBuildTime() string {
 	return buildtime
 }
+
+// GetCommit returns the git SHA the binary was built from
+func GetCommit() string {
+	return commit
+}
+
+// GetGoVersion returns the version of the Go toolchain that built the binary
+func GetGoVersion() string {
+	return runtime.Version()
+}